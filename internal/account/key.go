@@ -87,6 +87,16 @@ func PrivateKeyToBytes(key *ecdsa.PrivateKey) ([]byte, error) {
 	}
 }
 
+// PublicKeyToHexString returns the hex-encoded uncompressed public key without the 0x04 prefix, i.e. the node ID
+// used in enode URLs
+func PublicKeyToHexString(key *ecdsa.PublicKey) (string, error) {
+	if key == nil || key.X == nil || key.Y == nil {
+		return "", errors.New("invalid public key")
+	}
+	pubBytes := elliptic.Marshal(secp256k1.S256(), key.X, key.Y)
+	return hex.EncodeToString(pubBytes[1:]), nil
+}
+
 func PrivateKeyToHexString(key *ecdsa.PrivateKey) (string, error) {
 	byt, err := PrivateKeyToBytes(key)
 	if err != nil {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/jpmorganchase/quorum/crypto/secp256k1"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
 )
 
 func TestNewKeyFromHexString(t *testing.T) {
@@ -170,3 +171,24 @@ func TestPrivateKeyToHexString_NilKeyError(t *testing.T) {
 
 	require.EqualError(t, err, "nil key")
 }
+
+func TestPublicKeyToHexString(t *testing.T) {
+	byt, _ := hex.DecodeString("1fe8f1ad4053326db20529257ac9401f2e6c769ef1d736b8c2f5aba5f787c72b")
+	pub := ecdsa.PublicKey{Curve: secp256k1.S256()}
+	pub.X, pub.Y = pub.Curve.ScalarBaseMult(byt)
+
+	got, err := PublicKeyToHexString(&pub)
+	require.NoError(t, err)
+	require.Len(t, got, 128)
+
+	// the address is the last 20 bytes of the hash of the public key
+	pubByt, _ := hex.DecodeString(got)
+	d := sha3.NewLegacyKeccak256()
+	_, _ = d.Write(pubByt)
+	require.Equal(t, "6038dc01869425004ca0b8370f6c81cf464213b3", hex.EncodeToString(d.Sum(nil)[12:]))
+}
+
+func TestPublicKeyToHexString_InvalidKey(t *testing.T) {
+	_, err := PublicKeyToHexString(&ecdsa.PublicKey{})
+	require.EqualError(t, err, "invalid public key")
+}
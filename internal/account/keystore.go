@@ -0,0 +1,131 @@
+package account
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+var KeystorePassphraseErr = errors.New("could not decrypt keystore with given passphrase")
+
+// encryptedKeyJSON is the version 3 Web3 Secret Storage format used by geth keystore files
+type encryptedKeyJSON struct {
+	Address string     `json:"address"`
+	Crypto  cryptoJSON `json:"crypto"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherParamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type cipherParamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// DecryptKeystore decrypts the private key in a geth keystore file's JSON contents
+func DecryptKeystore(keyJSON []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	var k encryptedKeyJSON
+	if err := json.Unmarshal(keyJSON, &k); err != nil {
+		return nil, fmt.Errorf("invalid keystore: %v", err)
+	}
+	if k.Version != 3 {
+		return nil, fmt.Errorf("keystore version %v not supported", k.Version)
+	}
+	if k.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("keystore cipher %v not supported", k.Crypto.Cipher)
+	}
+
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore mac: %v", err)
+	}
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore iv: %v", err)
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %v", err)
+	}
+
+	derivedKey, err := deriveKeystoreKey(k.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(derivedKey)
+
+	d := sha3.NewLegacyKeccak256()
+	d.Write(derivedKey[16:32])
+	d.Write(cipherText)
+	if !bytes.Equal(d.Sum(nil), mac) {
+		return nil, KeystorePassphraseErr
+	}
+
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("invalid keystore iv length")
+	}
+	plainText := make([]byte, len(cipherText))
+	defer zero(plainText)
+	cipher.NewCTR(block, iv).XORKeyStream(plainText, cipherText)
+
+	return newKey(plainText)
+}
+
+func deriveKeystoreKey(c cryptoJSON, passphrase string) ([]byte, error) {
+	salt, err := hex.DecodeString(stringParam(c.KDFParams, "salt"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt: %v", err)
+	}
+	dkLen := intParam(c.KDFParams, "dklen")
+	if dkLen < 32 {
+		return nil, errors.New("invalid keystore dklen")
+	}
+
+	switch c.KDF {
+	case "scrypt":
+		return scrypt.Key([]byte(passphrase), salt, intParam(c.KDFParams, "n"), intParam(c.KDFParams, "r"), intParam(c.KDFParams, "p"), dkLen)
+	case "pbkdf2":
+		if prf := stringParam(c.KDFParams, "prf"); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("keystore pbkdf2 prf %v not supported", prf)
+		}
+		return pbkdf2.Key([]byte(passphrase), salt, intParam(c.KDFParams, "c"), dkLen, sha256.New), nil
+	}
+	return nil, fmt.Errorf("keystore kdf %v not supported", c.KDF)
+}
+
+func stringParam(params map[string]interface{}, name string) string {
+	s, _ := params[name].(string)
+	return s
+}
+
+// intParam returns the named numeric param, which json.Unmarshal decodes as a float64
+func intParam(params map[string]interface{}, name string) int {
+	f, _ := params[name].(float64)
+	return int(f)
+}
+
+func zero(byt []byte) {
+	for i := range byt {
+		byt[i] = 0
+	}
+}
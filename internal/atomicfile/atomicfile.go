@@ -0,0 +1,97 @@
+// Package atomicfile writes files so that readers never see partially written contents, even if the process crashes
+// mid-write.  Contents are written to a hidden temporary file in the same directory which is synced to disk and then
+// renamed over the target.  Temporary files left behind by a crash have the TempExt extension so that directory
+// scanners can ignore them, and can be removed with RemoveStale.
+package atomicfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const TempExt = ".tmp"
+
+// IsTemp reports whether the file name is that of a temporary file created by Write
+func IsTemp(name string) bool {
+	base := filepath.Base(name)
+	return strings.HasPrefix(base, ".") && strings.HasSuffix(base, TempExt)
+}
+
+// Write atomically replaces the contents of the file at path with b
+func Write(path string, b []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	f, err := ioutil.TempFile(dir, fmt.Sprintf(".%v*%v", filepath.Base(path), TempExt))
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	if err := write(f, b, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(dir)
+}
+
+func write(f *os.File, b []byte, perm os.FileMode) error {
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	// make sure the contents are on disk before the rename makes them visible
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(f.Name(), perm)
+}
+
+// syncDir makes the rename durable.  Directories cannot be synced on Windows, where the rename is already durable once
+// it returns.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// RemoveStale removes temporary files under root that were last modified more than olderThan ago, returning the paths
+// of the removed files.  Recently modified temporary files are left in case they are being written by another process.
+func RemoveStale(root string, olderThan time.Duration) ([]string, error) {
+	var removed []string
+	cutoff := time.Now().Add(-olderThan)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !IsTemp(path) || info.ModTime().After(cutoff) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			log.Printf("[WARN] unable to remove stale temporary file %v: %v", path, err)
+			return nil
+		}
+		removed = append(removed, path)
+		return nil
+	})
+	return removed, err
+}
@@ -0,0 +1,61 @@
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+	require.NoError(t, Write(path, []byte("first"), 0600))
+	require.NoError(t, Write(path, []byte("second"), 0600))
+
+	got, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "second", string(got))
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temporary files should not be left behind")
+	require.Equal(t, os.FileMode(0600), entries[0].Mode().Perm())
+}
+
+func TestIsTemp(t *testing.T) {
+	require.True(t, IsTemp("/path/to/.UTC--2020-07-20T10-00-00.000000000Z--4d6d744b123456.tmp"))
+	require.False(t, IsTemp("/path/to/UTC--2020-07-20T10-00-00.000000000Z--4d6d744b"))
+	require.False(t, IsTemp("/path/to/acct.tmp"))
+}
+
+func TestRemoveStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		stale  = filepath.Join(dir, ".acct1234.tmp")
+		recent = filepath.Join(dir, ".acct5678.tmp")
+		acct   = filepath.Join(dir, "acct")
+	)
+	for _, f := range []string{stale, recent, acct} {
+		require.NoError(t, ioutil.WriteFile(f, []byte("{"), 0600))
+	}
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(stale, old, old))
+	require.NoError(t, os.Chtimes(acct, old, old))
+
+	removed, err := RemoveStale(dir, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, []string{stale}, removed)
+
+	require.FileExists(t, recent)
+	require.FileExists(t, acct)
+}
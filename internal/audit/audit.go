@@ -0,0 +1,192 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// gRPC metadata keys the host can use to identify the originator of a request
+const (
+	NodeIDKey    = "quorum-node-id"
+	RPCOriginKey = "quorum-rpc-origin"
+	UserIDKey    = "quorum-user-id"
+	// SignDomainKey declares what the digest of a signing request is, one of the SignDomain values
+	SignDomainKey = "quorum-sign-domain"
+	// SignPreviewKey, set to true, evaluates a signing request without producing a signature
+	SignPreviewKey = "quorum-sign-preview"
+)
+
+// Domains of the digests signed by Sign and UnlockAndSign requests
+const (
+	SignDomainTransaction     = "transaction"
+	SignDomainConsensus       = "consensus"
+	SignDomainPersonalMessage = "personal_message"
+	SignDomainTypedData       = "typed_data"
+)
+
+// Caller identifies the originator of a request, as reported by the host in the request's gRPC metadata.  All
+// fields are optional.
+type Caller struct {
+	NodeID    string `json:"nodeId,omitempty"`
+	RPCOrigin string `json:"rpcOrigin,omitempty"`
+	UserID    string `json:"userId,omitempty"`
+}
+
+// String describes the caller by its set fields, e.g. node=node1 user=alice, or returns an empty string if none are set
+func (c Caller) String() string {
+	var fields []string
+	for _, f := range []struct{ name, value string }{{"node", c.NodeID}, {"user", c.UserID}, {"origin", c.RPCOrigin}} {
+		if f.value != "" {
+			fields = append(fields, f.name+"="+f.value)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// CallerFromContext extracts the Caller from the incoming gRPC metadata of ctx
+func CallerFromContext(ctx context.Context) Caller {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return Caller{}
+	}
+	return Caller{
+		NodeID:    first(md.Get(NodeIDKey)),
+		RPCOrigin: first(md.Get(RPCOriginKey)),
+		UserID:    first(md.Get(UserIDKey)),
+	}
+}
+
+// SignDomainFromContext returns the digest domain declared in the incoming gRPC metadata of ctx, or an empty string if
+// none was declared
+func SignDomainFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	return first(md.Get(SignDomainKey))
+}
+
+// SignPreviewFromContext reports whether the incoming gRPC metadata of ctx requests a preview of a signing request
+func SignPreviewFromContext(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	return first(md.Get(SignPreviewKey)) == "true"
+}
+
+func first(vals []string) string {
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+// RequestIDHeader is sent on the Vault requests made while handling a request, so that Vault audit entries can be
+// joined to the plugin's Record if Vault is configured to audit the header
+const RequestIDHeader = "X-Quorum-Plugin-Request-Id"
+
+type correlationKey struct{}
+
+// correlation identifies a request and the Vault requests made while handling it
+type correlation struct {
+	requestID       string
+	mu              sync.Mutex
+	vaultRequestIDs []string
+}
+
+// WithRequestID returns a copy of ctx carrying a new random request ID, which is included in the Record of the request
+// along with the IDs of any Vault requests added with AddVaultRequestID
+func WithRequestID(ctx context.Context) context.Context {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("[WARN] unable to generate audit request ID: %v", err)
+		return ctx
+	}
+	return context.WithValue(ctx, correlationKey{}, &correlation{requestID: hex.EncodeToString(b)})
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string if there is none
+func RequestID(ctx context.Context) string {
+	if c, ok := ctx.Value(correlationKey{}).(*correlation); ok {
+		return c.requestID
+	}
+	return ""
+}
+
+// AddVaultRequestID records the ID Vault gave a request made while handling the request with ctx.  Vault includes
+// the same ID in its audit log entries for the request.
+func AddVaultRequestID(ctx context.Context, id string) {
+	c, ok := ctx.Value(correlationKey{}).(*correlation)
+	if !ok || id == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.vaultRequestIDs = append(c.vaultRequestIDs, id)
+}
+
+func vaultRequestIDs(ctx context.Context) []string {
+	c, ok := ctx.Value(correlationKey{}).(*correlation)
+	if !ok {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.vaultRequestIDs...)
+}
+
+// Record is a single entry in the audit trail.  Records never contain key material.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Account   string    `json:"account,omitempty"`
+	Caller    Caller    `json:"caller"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	// RequestID and VaultRequestIDs correlate the record with Vault audit log entries
+	RequestID       string   `json:"requestId,omitempty"`
+	VaultRequestIDs []string `json:"vaultRequestIds,omitempty"`
+	// Domain is the digest domain declared by the caller of a signing request
+	Domain string `json:"domain,omitempty"`
+	// Preview is set for signing requests that were evaluated without producing a signature
+	Preview bool `json:"preview,omitempty"`
+}
+
+// NewRecord creates a Record for the operation on account.  A non-nil err marks the operation as failed.
+func NewRecord(ctx context.Context, operation, account string, err error) Record {
+	r := Record{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Account:   account,
+		Caller:    CallerFromContext(ctx),
+		Success:   err == nil,
+
+		RequestID:       RequestID(ctx),
+		VaultRequestIDs: vaultRequestIDs(ctx),
+		Domain:          SignDomainFromContext(ctx),
+		Preview:         SignPreviewFromContext(ctx),
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// Log writes the Record to the plugin log, which is captured by the host process
+func Log(r Record) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		log.Printf("[ERROR] unable to marshal audit record: operation = %v, err = %v", r.Operation, err)
+		return
+	}
+	log.Printf("[INFO] audit: %s", b)
+}
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCallerFromContext(t *testing.T) {
+	md := metadata.Pairs(
+		NodeIDKey, "node1",
+		RPCOriginKey, "personal_sign",
+		UserIDKey, "alice",
+	)
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	got := CallerFromContext(ctx)
+
+	want := Caller{
+		NodeID:    "node1",
+		RPCOrigin: "personal_sign",
+		UserID:    "alice",
+	}
+	require.Equal(t, want, got)
+}
+
+func TestCallerFromContext_NoMetadata(t *testing.T) {
+	got := CallerFromContext(context.Background())
+
+	require.Equal(t, Caller{}, got)
+}
+
+func TestCaller_String(t *testing.T) {
+	require.Equal(t, "node=node1 user=alice origin=personal_sign", Caller{NodeID: "node1", RPCOrigin: "personal_sign", UserID: "alice"}.String())
+	require.Equal(t, "user=alice", Caller{UserID: "alice"}.String())
+	require.Empty(t, Caller{}.String())
+}
+
+func TestNewRecord(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(NodeIDKey, "node1"))
+
+	got := NewRecord(ctx, "Sign", "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", nil)
+	require.Equal(t, "Sign", got.Operation)
+	require.Equal(t, "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", got.Account)
+	require.Equal(t, "node1", got.Caller.NodeID)
+	require.True(t, got.Success)
+	require.Empty(t, got.Error)
+
+	got = NewRecord(ctx, "Sign", "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", errors.New("account locked"))
+	require.False(t, got.Success)
+	require.Equal(t, "account locked", got.Error)
+	require.Empty(t, got.Domain)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(SignDomainKey, SignDomainTypedData))
+	got = NewRecord(ctx, "Sign", "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", nil)
+	require.Equal(t, "typed_data", got.Domain)
+	require.False(t, got.Preview)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(SignPreviewKey, "true"))
+	got = NewRecord(ctx, "Sign", "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", nil)
+	require.True(t, got.Preview)
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(UserIDKey, "alice"))
+	Log(NewRecord(ctx, "Sign", "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", nil))
+
+	out := buf.String()
+	require.Contains(t, out, "[INFO] audit: ")
+
+	var got Record
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(out[strings.Index(out, "{"):])), &got))
+	require.Equal(t, "alice", got.Caller.UserID)
+	require.Equal(t, "Sign", got.Operation)
+}
+
+func TestWithRequestID(t *testing.T) {
+	require.Empty(t, RequestID(context.Background()))
+
+	ctx := WithRequestID(context.Background())
+	id := RequestID(ctx)
+	require.Len(t, id, 32)
+	require.NotEqual(t, id, RequestID(WithRequestID(context.Background())))
+
+	AddVaultRequestID(ctx, "vault-request-1")
+	AddVaultRequestID(ctx, "")
+	AddVaultRequestID(ctx, "vault-request-2")
+
+	got := NewRecord(ctx, "TimedUnlock", "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", nil)
+	require.Equal(t, id, got.RequestID)
+	require.Equal(t, []string{"vault-request-1", "vault-request-2"}, got.VaultRequestIDs)
+}
+
+func TestAddVaultRequestID_NoRequestID(t *testing.T) {
+	ctx := context.Background()
+	AddVaultRequestID(ctx, "vault-request-1")
+
+	got := NewRecord(ctx, "Sign", "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", nil)
+	require.Empty(t, got.RequestID)
+	require.Empty(t, got.VaultRequestIDs)
+}
@@ -0,0 +1,277 @@
+// Package backup creates and restores signed archives of the account config files in an account directory.  An
+// archive is a gzipped tar containing:
+//
+//	manifest.json      index of all other entries and their SHA-256 hashes
+//	manifest.sig       hex-encoded HMAC-SHA256 of manifest.json
+//	accounts/<file>    the account config files
+//	metadata/<secret>  (optional) Vault metadata for the secrets referenced by the account configs
+//
+// Private keys are never included.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/atomicfile"
+)
+
+const (
+	manifestName    = "manifest.json"
+	signatureName   = "manifest.sig"
+	accountsPrefix  = "accounts/"
+	metadataPrefix  = "metadata/"
+	manifestVersion = 1
+)
+
+var InvalidSignatureErr = errors.New("backup signature is invalid: the archive has been modified or a different key was used")
+
+type Manifest struct {
+	Version int
+	Created time.Time
+	Entries []Entry
+}
+
+type Entry struct {
+	Name   string
+	SHA256 string
+}
+
+// Create writes an archive of all account config files in acctDir, plus the provided metadata keyed by secret name,
+// to w.  The manifest is signed with key.
+func Create(w io.Writer, acctDir string, metadata map[string][]byte, key []byte) (Manifest, error) {
+	if len(key) == 0 {
+		return Manifest{}, errors.New("backup signing key must be set")
+	}
+
+	contents := make(map[string][]byte)
+
+	walkFn := func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			// ignore directories and hidden temp files from in-progress writes
+			return nil
+		}
+		rel, err := filepath.Rel(acctDir, p)
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		contents[accountsPrefix+filepath.ToSlash(rel)] = b
+		return nil
+	}
+	if err := filepath.Walk(acctDir, walkFn); err != nil {
+		return Manifest{}, err
+	}
+
+	for secretName, b := range metadata {
+		contents[metadataPrefix+secretName+".json"] = b
+	}
+
+	manifest := Manifest{
+		Version: manifestVersion,
+		Created: time.Now().UTC(),
+	}
+	for name, b := range contents {
+		manifest.Entries = append(manifest.Entries, Entry{Name: name, SHA256: hash(b)})
+	}
+	sort.Slice(manifest.Entries, func(i, j int) bool { return manifest.Entries[i].Name < manifest.Entries[j].Name })
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := writeEntry(tw, manifestName, manifestJSON); err != nil {
+		return Manifest{}, err
+	}
+	if err := writeEntry(tw, signatureName, []byte(sign(manifestJSON, key))); err != nil {
+		return Manifest{}, err
+	}
+	for _, e := range manifest.Entries {
+		if err := writeEntry(tw, e.Name, contents[e.Name]); err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return Manifest{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// Restore verifies the archive read from r was signed with key and has not been modified, then writes its account
+// config files to acctDir.  Files that already exist with identical contents are skipped.  Nothing is written if any
+// file already exists with different contents.  The returned slice contains the paths of the files written.
+func Restore(r io.Reader, acctDir string, key []byte) ([]string, error) {
+	if len(key) == 0 {
+		return nil, errors.New("backup signing key must be set")
+	}
+
+	manifest, contents, err := read(r, key)
+	if err != nil {
+		return nil, err
+	}
+
+	type toWrite struct {
+		path string
+		b    []byte
+	}
+	var files []toWrite
+
+	for _, e := range manifest.Entries {
+		if !strings.HasPrefix(e.Name, accountsPrefix) {
+			continue
+		}
+		p := filepath.Join(acctDir, filepath.FromSlash(strings.TrimPrefix(e.Name, accountsPrefix)))
+
+		existing, err := ioutil.ReadFile(p)
+		if err == nil {
+			if !bytes.Equal(existing, contents[e.Name]) {
+				return nil, fmt.Errorf("%v already exists with different contents", p)
+			}
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		files = append(files, toWrite{path: p, b: contents[e.Name]})
+	}
+
+	written := make([]string, 0, len(files))
+	for _, f := range files {
+		if err := writeFile(f.path, f.b); err != nil {
+			return written, err
+		}
+		written = append(written, f.path)
+	}
+	return written, nil
+}
+
+// read reads all entries of the archive, checking the manifest signature and that the entries match the manifest
+func read(r io.Reader, key []byte) (Manifest, map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("unable to read backup: %v", err)
+	}
+	defer gz.Close()
+
+	entries := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Manifest{}, nil, fmt.Errorf("unable to read backup: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return Manifest{}, nil, fmt.Errorf("unexpected entry in backup: %v", hdr.Name)
+		}
+		if clean := path.Clean(hdr.Name); clean != hdr.Name || strings.HasPrefix(clean, "../") || path.IsAbs(clean) {
+			return Manifest{}, nil, fmt.Errorf("invalid entry name in backup: %v", hdr.Name)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return Manifest{}, nil, fmt.Errorf("unable to read backup: %v", err)
+		}
+		entries[hdr.Name] = b
+	}
+
+	manifestJSON, ok := entries[manifestName]
+	if !ok {
+		return Manifest{}, nil, errors.New("backup does not contain a manifest")
+	}
+	sig, ok := entries[signatureName]
+	if !ok {
+		return Manifest{}, nil, errors.New("backup does not contain a signature")
+	}
+	if !hmac.Equal(sig, []byte(sign(manifestJSON, key))) {
+		return Manifest{}, nil, InvalidSignatureErr
+	}
+	delete(entries, manifestName)
+	delete(entries, signatureName)
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return Manifest{}, nil, fmt.Errorf("unable to unmarshal backup manifest: %v", err)
+	}
+	if manifest.Version != manifestVersion {
+		return Manifest{}, nil, fmt.Errorf("unsupported backup manifest version %v", manifest.Version)
+	}
+
+	if len(manifest.Entries) != len(entries) {
+		return Manifest{}, nil, errors.New("backup entries do not match manifest")
+	}
+	for _, e := range manifest.Entries {
+		b, ok := entries[e.Name]
+		if !ok {
+			return Manifest{}, nil, fmt.Errorf("backup does not contain %v", e.Name)
+		}
+		if hash(b) != e.SHA256 {
+			return Manifest{}, nil, fmt.Errorf("hash of %v does not match manifest", e.Name)
+		}
+	}
+
+	return manifest, entries, nil
+}
+
+func writeEntry(tw *tar.Writer, name string, b []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(b)),
+		ModTime: time.Now().UTC(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
+
+// writeFile writes to a temporary hidden file first then renames once complete so that the write appears atomic
+func writeFile(p string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return atomicfile.Write(p, b, 0600)
+}
+
+func hash(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func sign(b, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var key = []byte("backup-key")
+
+func acctDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "acctdir")
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "UTC--acct1"), []byte(`{"Address":"acct1"}`), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "nested", "UTC--acct2"), []byte(`{"Address":"acct2"}`), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".UTC--acct3123.tmp"), []byte(`{"Addr`), 0600))
+	return dir
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	src := acctDir(t)
+	defer os.RemoveAll(src)
+
+	var buf bytes.Buffer
+	manifest, err := Create(&buf, src, map[string][]byte{"path/to/acct1": []byte(`{"versions":{}}`)}, key)
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range manifest.Entries {
+		names = append(names, e.Name)
+	}
+	require.Equal(t, []string{"accounts/UTC--acct1", "accounts/nested/UTC--acct2", "metadata/path/to/acct1.json"}, names)
+
+	dst, err := ioutil.TempDir("", "acctdir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	written, err := Restore(bytes.NewReader(buf.Bytes()), dst, key)
+	require.NoError(t, err)
+	require.Len(t, written, 2)
+
+	b, err := ioutil.ReadFile(filepath.Join(dst, "nested", "UTC--acct2"))
+	require.NoError(t, err)
+	require.Equal(t, `{"Address":"acct2"}`, string(b))
+
+	// restoring again is a no-op
+	written, err = Restore(bytes.NewReader(buf.Bytes()), dst, key)
+	require.NoError(t, err)
+	require.Empty(t, written)
+}
+
+func TestRestore_WrongKey(t *testing.T) {
+	src := acctDir(t)
+	defer os.RemoveAll(src)
+
+	var buf bytes.Buffer
+	_, err := Create(&buf, src, nil, key)
+	require.NoError(t, err)
+
+	_, err = Restore(&buf, src, []byte("other-key"))
+	require.Equal(t, InvalidSignatureErr, err)
+}
+
+func TestRestore_ExistingFileWithDifferentContents(t *testing.T) {
+	src := acctDir(t)
+	defer os.RemoveAll(src)
+
+	var buf bytes.Buffer
+	_, err := Create(&buf, src, nil, key)
+	require.NoError(t, err)
+
+	dst, err := ioutil.TempDir("", "acctdir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dst, "UTC--acct1"), []byte(`{"Address":"changed"}`), 0600))
+
+	_, err = Restore(&buf, dst, key)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already exists with different contents")
+
+	// nothing is written
+	_, err = os.Stat(filepath.Join(dst, "nested", "UTC--acct2"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestRestore_ModifiedEntry(t *testing.T) {
+	src := acctDir(t)
+	defer os.RemoveAll(src)
+
+	var buf bytes.Buffer
+	_, err := Create(&buf, src, nil, key)
+	require.NoError(t, err)
+
+	// rewrite the archive, replacing the contents of an account file
+	gzr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gzr)
+
+	var modified bytes.Buffer
+	gzw := gzip.NewWriter(&modified)
+	tw := tar.NewWriter(gzw)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		b, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		if hdr.Name == "accounts/UTC--acct1" {
+			b = []byte(`{"Address":"attacker"}`)
+		}
+		require.NoError(t, writeEntry(tw, hdr.Name, b))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	_, err = Restore(&modified, src, key)
+	require.EqualError(t, err, "hash of accounts/UTC--acct1 does not match manifest")
+}
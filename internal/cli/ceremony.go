@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// ceremonyStatement is recorded in every transcript so that reviewers signing off know how the key was handled
+const ceremonyStatement = "private key generated in plugin memory, written only to Vault and zeroed; no private material was displayed or written to disk"
+
+// transcript is the record of a validator key ceremony.  It only contains public material and is intended to be
+// retained for audit sign-off.
+type transcript struct {
+	Ceremony            string    `json:"ceremony"`
+	Started             time.Time `json:"started"`
+	Completed           time.Time `json:"completed"`
+	Operator            string    `json:"operator"`
+	Witnesses           []string  `json:"witnesses,omitempty"`
+	Host                string    `json:"host"`
+	Vault               string    `json:"vault"`
+	KVEngine            string    `json:"kvEngine"`
+	SecretName          string    `json:"secretName"`
+	SecretVersion       int64     `json:"secretVersion"`
+	Address             string    `json:"address"`
+	PublicKey           string    `json:"publicKey"`
+	Enode               string    `json:"enode"`
+	AccountConfig       string    `json:"accountConfig"`
+	AccountConfigSHA256 string    `json:"accountConfigSha256"`
+	Statement           string    `json:"statement"`
+}
+
+func ceremony(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("ceremony", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	secretName := fs.String("secretname", "", "name of the Vault secret to store the validator key in")
+	operator := fs.String("operator", "", "name of the operator performing the ceremony (defaults to $USER)")
+	witnesses := fs.String("witnesses", "", "comma-separated names of the ceremony witnesses")
+	transcriptPath := fs.String("transcript", "", "path to also write the ceremony transcript to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *secretName == "" {
+		return errors.New("-secretname must be set")
+	}
+	if *operator == "" {
+		*operator = os.Getenv("USER")
+	}
+	if *operator == "" {
+		return errors.New("-operator must be set")
+	}
+
+	conf, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	am, err := newAccountManager(*configPath)
+	if err != nil {
+		return err
+	}
+	defer am.Close()
+
+	t := transcript{
+		Ceremony:  "validator-key",
+		Started:   time.Now().UTC(),
+		Operator:  *operator,
+		Witnesses: splitNames(*witnesses),
+		Vault:     conf.Vault.String(),
+		KVEngine:  conf.KVEngineName,
+		Statement: ceremonyStatement,
+	}
+	t.Host, _ = os.Hostname()
+
+	acct, err := am.NewValidatorAccount(config.NewAccount{SecretName: *secretName})
+	if err != nil {
+		audit.Log(audit.NewRecord(context.Background(), "ValidatorKeyCeremony", "", err))
+		return err
+	}
+	audit.Log(audit.NewRecord(context.Background(), "ValidatorKeyCeremony", "0x"+acct.Address.ToHexString(), nil))
+
+	t.Completed = time.Now().UTC()
+	t.SecretName = acct.SecretName
+	t.SecretVersion = acct.SecretVersion
+	t.Address = "0x" + acct.Address.ToHexString()
+	t.PublicKey = "0x04" + acct.PublicKey
+	t.Enode = "enode://" + acct.PublicKey
+	t.AccountConfig = acct.ConfigFile
+	t.AccountConfigSHA256 = acct.ConfigSHA256
+
+	if *transcriptPath != "" {
+		if err := writeTranscript(*transcriptPath, t); err != nil {
+			return err
+		}
+	}
+	return writeJSON(out, t)
+}
+
+func splitNames(s string) []string {
+	var names []string
+	for _, n := range strings.Split(s, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// writeTranscript writes the transcript to a new file, refusing to overwrite the transcript of an earlier ceremony
+func writeTranscript(path string, t transcript) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	err = writeJSON(f, t)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,523 @@
+// Package cli implements the operator commands that can be run by executing the plugin binary directly, e.g.
+//
+//	quorum-account-plugin-hashicorp-vault gc -config /path/to/config.json
+//
+// When started by Quorum the plugin is run without arguments and these commands are not used.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/backup"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp"
+)
+
+type command struct {
+	description string
+	run         func(args []string, out io.Writer) error
+}
+
+var commands = map[string]command{
+	"backup": {
+		description: "write a signed archive of all account config files",
+		run:         backupCmd,
+	},
+	"ceremony": {
+		description: "generate a validator (sealer) key in Vault, printing only public material and an audit transcript",
+		run:         ceremony,
+	},
+	"check": {
+		description: "report accounts whose Vault secret version is missing, deleted or unreadable",
+		run:         check,
+	},
+	"freeze": {
+		description: "stop an account from being unlocked or signing, while keeping it listed",
+		run:         freeze,
+	},
+	"gc": {
+		description: "report Vault secret versions not referenced by any account config (soft-delete them with -confirm)",
+		run:         gc,
+	},
+	"grant": {
+		description: "issue a single-use grant for an account to sign one payload, for accounts listed in signGrants",
+		run:         grant,
+	},
+	"pepper": {
+		description: "report accounts whose keys are not peppered (migrate them to peppered keys with -confirm)",
+		run:         pepper,
+	},
+	"promote": {
+		description: "promote a mirror node to signer by updating the configured mirror promotionSecret",
+		run:         promote,
+	},
+	"unfreeze": {
+		description: "allow a frozen account to be unlocked and sign again",
+		run:         unfreeze,
+	},
+	"settings": {
+		description: "print the effective settings of an account, and the layer of the config each was resolved from",
+		run:         settings,
+	},
+	"sign-config": {
+		description: "create a detached signature of a plugin config for plugins built with an embedded config signing key",
+		run:         signConfig,
+	},
+	"repair-addresses": {
+		description: "report account configs whose Vault secret holds the key for a different address (rewrite them with -confirm)",
+		run:         repairAddresses,
+	},
+	"rewrite-paths": {
+		description: "verify account config secrets at a new path after secrets are moved or the KV engine re-mounted (rewrite them with -confirm)",
+		run:         rewritePaths,
+	},
+	"restore": {
+		description: "restore account config files from a signed archive created by backup",
+		run:         restoreCmd,
+	},
+	"reconcile": {
+		description: "report discrepancies between account configs and Vault secrets (restore missing configs with -fix)",
+		run:         reconcile,
+	},
+}
+
+// Run executes the command named by args[0], returning the process exit code
+func Run(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage()
+		return 2
+	}
+	if err := cmd.run(args[1:], os.Stdout); err != nil {
+		if err != flag.ErrHelp {
+			log.Printf("[ERROR] %v: %v", args[0], err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: quorum-account-plugin-hashicorp-vault <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10v %v\n", name, commands[name].description)
+	}
+}
+
+// loadConfig reads the plugin config (as would be provided to Quorum) from the file at path
+func loadConfig(path string) (config.VaultClient, error) {
+	if path == "" {
+		return config.VaultClient{}, errors.New("-config must be set")
+	}
+	b, err := ioutil.ReadFile(strings.TrimPrefix(path, "file://"))
+	if err != nil {
+		return config.VaultClient{}, err
+	}
+	conf := new(config.VaultClient)
+	if err := json.Unmarshal(b, conf); err != nil {
+		return config.VaultClient{}, fmt.Errorf("unable to unmarshal config: %v", err)
+	}
+	if err := conf.Validate(); err != nil {
+		return config.VaultClient{}, err
+	}
+	return *conf, nil
+}
+
+func newAccountManager(configPath string) (hashicorp.AccountManager, error) {
+	conf, err := loadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	// commands should never hold keys in memory
+	conf.Unlock = nil
+	// the state directory is locked by the running plugin
+	conf.StateDirectory = nil
+	conf.TokenSink = config.VaultClientTokenSink{}
+	// commands do not follow the promotion secret
+	conf.Mirror = config.VaultClientMirror{}
+	// commands do not sign, so do not redeem sign grants
+	conf.SignGrants = config.VaultClientSignGrants{}
+	return hashicorp.NewAccountManager(conf)
+}
+
+// accountDirectory returns the filesystem path of the configured account directory.  Account configs in a remote
+// accountStore are backed up with the store's own tooling.
+func accountDirectory(conf config.VaultClient) (string, error) {
+	if conf.AccountStore.Type != "" {
+		return "", fmt.Errorf("account configs are stored in %v, not an accountDirectory", conf.AccountStore.Type)
+	}
+	return config.FilePath(conf.AccountDirectory), nil
+}
+
+// signingKey reads the backup signing key from the environment variable given as an env:// URL
+func signingKey(envURL string) ([]byte, error) {
+	u, err := url.Parse(envURL)
+	if err != nil || u.Scheme != "env" {
+		return nil, errors.New("-key must be an env:// URL, e.g. env://BACKUP_KEY")
+	}
+	env := config.EnvironmentVariable(*u)
+	if !env.IsSet() || env.Get() == "" {
+		return nil, fmt.Errorf("environment variable %v must be set", u.Host)
+	}
+	return []byte(env.Get()), nil
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(b))
+	return err
+}
+
+func gc(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	prefix := fs.String("prefix", "", "only consider secrets under this path of the KV engine")
+	confirm := fs.Bool("confirm", false, "soft-delete the orphaned secret versions")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	am, err := newAccountManager(*configPath)
+	if err != nil {
+		return err
+	}
+	report, err := am.GarbageCollect(*prefix, *confirm)
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, report)
+}
+
+func check(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	am, err := newAccountManager(*configPath)
+	if err != nil {
+		return err
+	}
+	results := am.CheckAccounts()
+	if err := writeJSON(out, results); err != nil {
+		return err
+	}
+
+	var degraded int
+	for _, r := range results {
+		if r.Degraded {
+			degraded++
+		}
+	}
+	if degraded > 0 {
+		return fmt.Errorf("%v degraded account(s)", degraded)
+	}
+	return nil
+}
+
+func reconcile(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	prefix := fs.String("prefix", "", "only consider secrets under this path of the KV engine")
+	fix := fs.Bool("fix", false, "write account configs for unreferenced secrets holding keys for unknown accounts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	am, err := newAccountManager(*configPath)
+	if err != nil {
+		return err
+	}
+	report, err := am.Reconcile(*prefix, *fix)
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, report)
+}
+
+func pepper(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("pepper", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	confirm := fs.Bool("confirm", false, "write the peppered keys to Vault and update the account configs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	am, err := newAccountManager(*configPath)
+	if err != nil {
+		return err
+	}
+	report, err := am.PepperAccounts(*confirm)
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, report)
+}
+
+func repairAddresses(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("repair-addresses", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	confirm := fs.Bool("confirm", false, "rewrite the account configs with the address of the key in Vault")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	am, err := newAccountManager(*configPath)
+	if err != nil {
+		return err
+	}
+	mismatches, err := am.RepairAddresses(*confirm)
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, mismatches)
+}
+
+func rewritePaths(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("rewrite-paths", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	from := fs.String("from", "", "the secret name prefix to replace")
+	to := fs.String("to", "", "the secret name prefix to replace it with")
+	backupDir := fs.String("backup", "", "directory to copy the account configs to before they are rewritten")
+	confirm := fs.Bool("confirm", false, "rewrite the account configs with the new secret names")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	am, err := newAccountManager(*configPath)
+	if err != nil {
+		return err
+	}
+	rewrites, err := am.RewritePaths(*from, *to, *backupDir, *confirm)
+	// the report includes why accounts could not be verified, so is written even if there is an error
+	if writeErr := writeJSON(out, rewrites); err == nil {
+		err = writeErr
+	}
+	return err
+}
+
+func freeze(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("freeze", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	acct := fs.String("account", "", "hex address of the account to freeze")
+	reason := fs.String("reason", "", "why the account is frozen, e.g. an incident reference")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return setFrozen(*configPath, *acct, out, func(am hashicorp.AccountManager, addr account.Address) error {
+		return am.Freeze(addr, *reason)
+	})
+}
+
+func unfreeze(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("unfreeze", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	acct := fs.String("account", "", "hex address of the account to unfreeze")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return setFrozen(*configPath, *acct, out, hashicorp.AccountManager.Unfreeze)
+}
+
+// setFrozen updates the account's configs with update.  Running plugins apply the change when sent SIGHUP.
+func setFrozen(configPath, acct string, out io.Writer, update func(hashicorp.AccountManager, account.Address) error) error {
+	if acct == "" {
+		return errors.New("-account must be set")
+	}
+	addr, err := account.NewAddressFromHexString(acct)
+	if err != nil {
+		return fmt.Errorf("invalid -account: %v", err)
+	}
+	am, err := newAccountManager(configPath)
+	if err != nil {
+		return err
+	}
+	if err := update(am, addr); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "account configs for 0x%v updated, send SIGHUP to running plugins to apply\n", addr.ToHexString())
+	return err
+}
+
+func settings(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("settings", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	acct := fs.String("account", "", "hex address of the account (defaults to the settings of accounts without overrides)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conf, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	var addr string
+	if *acct != "" {
+		a, err := account.NewAddressFromHexString(*acct)
+		if err != nil {
+			return fmt.Errorf("invalid -account: %v", err)
+		}
+		addr = "0x" + a.ToHexString()
+	}
+	s := conf.EffectiveAccountSettings(addr)
+	// durations are written as in the plugin config rather than as nanoseconds
+	return writeJSON(out, struct {
+		Address           string `json:",omitempty"`
+		MaxUnlockDuration string
+		ReadCache         bool
+		StrictSignDomains bool
+		VersionFallback   bool
+		Sources           map[string]string
+	}{
+		Address:           addr,
+		MaxUnlockDuration: s.MaxUnlockDuration.String(),
+		ReadCache:         s.ReadCache,
+		StrictSignDomains: s.StrictSignDomains,
+		VersionFallback:   s.VersionFallback,
+		Sources:           s.Sources,
+	})
+}
+
+func promote(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("promote", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	node := fs.String("node", "", "name of the node to promote to signer (defaults to the configured mirror node)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conf, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if conf.Mirror.PromotionSecret == "" {
+		return errors.New("mirror must be configured")
+	}
+	if *node == "" {
+		*node = conf.Mirror.Node
+	}
+
+	am, err := newAccountManager(*configPath)
+	if err != nil {
+		return err
+	}
+	if err := am.Promote(conf.Mirror.PromotionSecret, *node); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "promoted %v to signer, mirrors will follow within their pollInterval\n", *node)
+	return err
+}
+
+func backupCmd(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	outPath := fs.String("out", "", "path to write the archive to")
+	keyEnv := fs.String("key", "", "env:// URL of the environment variable holding the signing key")
+	withMetadata := fs.Bool("metadata", false, "include the Vault metadata of the secrets referenced by the account configs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *outPath == "" {
+		return errors.New("-out must be set")
+	}
+	key, err := signingKey(*keyEnv)
+	if err != nil {
+		return err
+	}
+	conf, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	dir, err := accountDirectory(conf)
+	if err != nil {
+		return err
+	}
+
+	var metadata map[string][]byte
+	if *withMetadata {
+		am, err := newAccountManager(*configPath)
+		if err != nil {
+			return err
+		}
+		if metadata, err = am.SecretMetadata(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(*outPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	manifest, err := backup.Create(f, dir, metadata, key)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*outPath)
+		return err
+	}
+	return writeJSON(out, manifest)
+}
+
+func restoreCmd(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file")
+	inPath := fs.String("in", "", "path of the archive to restore")
+	keyEnv := fs.String("key", "", "env:// URL of the environment variable holding the signing key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *inPath == "" {
+		return errors.New("-in must be set")
+	}
+	key, err := signingKey(*keyEnv)
+	if err != nil {
+		return err
+	}
+	conf, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	dir, err := accountDirectory(conf)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*inPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	written, err := backup.Restore(f, dir, key)
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, written)
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_UnknownCommand(t *testing.T) {
+	require.Equal(t, 2, Run([]string{"unknown"}))
+}
+
+func TestRun_NoCommand(t *testing.T) {
+	require.Equal(t, 2, Run(nil))
+}
+
+func TestLoadConfig_NotSet(t *testing.T) {
+	_, err := loadConfig("")
+	require.EqualError(t, err, "-config must be set")
+}
+
+func TestLoadConfig(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+
+	f, err := ioutil.TempFile("", "config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir",
+		"authentication": {
+			"token": "env://MY_TOKEN"
+		}
+	}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	got, err := loadConfig("file://" + f.Name())
+	require.NoError(t, err)
+	require.Equal(t, "engine", got.KVEngineName)
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	f, err := ioutil.TempFile("", "config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`{"kvEngineName": "engine"}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = loadConfig(f.Name())
+	require.EqualError(t, err, "vault must be a valid HTTP/HTTPS url")
+}
+
+func TestSigningKey(t *testing.T) {
+	_, err := signingKey("")
+	require.EqualError(t, err, "-key must be an env:// URL, e.g. env://BACKUP_KEY")
+
+	_, err = signingKey("env://CLI_TEST_BACKUP_KEY")
+	require.EqualError(t, err, "environment variable CLI_TEST_BACKUP_KEY must be set")
+
+	os.Setenv("CLI_TEST_BACKUP_KEY", "secret")
+	defer os.Unsetenv("CLI_TEST_BACKUP_KEY")
+
+	got, err := signingKey("env://CLI_TEST_BACKUP_KEY")
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret"), got)
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+	os.Setenv("CLI_TEST_BACKUP_KEY", "secret")
+	defer os.Unsetenv("CLI_TEST_BACKUP_KEY")
+
+	writeConfig := func(acctDir string) string {
+		f, err := ioutil.TempFile("", "config")
+		require.NoError(t, err)
+		_, err = f.WriteString(`{
+			"vault": "http://vault:1111",
+			"kvEngineName": "engine",
+			"accountDirectory": "file://` + acctDir + `",
+			"authentication": {
+				"token": "env://MY_TOKEN"
+			}
+		}`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		return f.Name()
+	}
+
+	src, err := ioutil.TempDir("", "src")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "UTC--acct"), []byte(`{"Address":"acct"}`), 0600))
+	srcConfig := writeConfig(src)
+	defer os.Remove(srcConfig)
+
+	dst, err := ioutil.TempDir("", "dst")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+	dstConfig := writeConfig(dst)
+	defer os.Remove(dstConfig)
+
+	archive := filepath.Join(dst, "..", filepath.Base(dst)+".tar.gz")
+	defer os.Remove(archive)
+
+	var out bytes.Buffer
+	require.NoError(t, backupCmd([]string{"-config", srcConfig, "-out", archive, "-key", "env://CLI_TEST_BACKUP_KEY"}, &out))
+	require.NoError(t, restoreCmd([]string{"-config", dstConfig, "-in", archive, "-key", "env://CLI_TEST_BACKUP_KEY"}, &out))
+
+	b, err := ioutil.ReadFile(filepath.Join(dst, "UTC--acct"))
+	require.NoError(t, err)
+	require.Equal(t, `{"Address":"acct"}`, string(b))
+
+	// backup refuses to overwrite an existing archive
+	require.Error(t, backupCmd([]string{"-config", srcConfig, "-out", archive, "-key", "env://CLI_TEST_BACKUP_KEY"}, &out))
+}
+
+func TestCeremony_SecretNameNotSet(t *testing.T) {
+	var out bytes.Buffer
+	require.EqualError(t, ceremony([]string{"-config", "/path/to/config.json"}, &out), "-secretname must be set")
+}
+
+func TestSplitNames(t *testing.T) {
+	require.Nil(t, splitNames(""))
+	require.Equal(t, []string{"alice", "bob"}, splitNames(" alice, ,bob "))
+}
+
+func TestSignConfig(t *testing.T) {
+	var keys bytes.Buffer
+	require.NoError(t, signConfig([]string{"-generate"}, &keys))
+	var pair map[string]string
+	require.NoError(t, json.Unmarshal(keys.Bytes(), &pair))
+
+	os.Setenv("CLI_TEST_CONFIG_KEY", pair["privateKey"])
+	defer os.Unsetenv("CLI_TEST_CONFIG_KEY")
+
+	f, err := ioutil.TempFile("", "config.json")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"vault": "https://vault:8200"}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	var out bytes.Buffer
+	require.NoError(t, signConfig([]string{"-config", f.Name(), "-key", "env://CLI_TEST_CONFIG_KEY"}, &out))
+
+	defer func(k string) { config.ConfigSigningKey = k }(config.ConfigSigningKey)
+	config.ConfigSigningKey = pair["publicKey"]
+	os.Setenv(config.ConfigSignatureEnv, out.String())
+	defer os.Unsetenv(config.ConfigSignatureEnv)
+
+	require.NoError(t, config.VerifyConfigSignature([]byte(`{"vault": "https://vault:8200"}`)))
+	require.Equal(t, config.InvalidConfigSignatureErr, config.VerifyConfigSignature([]byte(`{"vault": "https://rogue:8200"}`)))
+}
+
+func TestSignConfig_ConfigNotSet(t *testing.T) {
+	var out bytes.Buffer
+	require.EqualError(t, signConfig(nil, &out), "-config must be set")
+}
+
+func TestGrant(t *testing.T) {
+	os.Setenv("CLI_TEST_GRANT_KEY", "0123456789abcdef0123456789abcdef")
+	defer os.Unsetenv("CLI_TEST_GRANT_KEY")
+
+	var out bytes.Buffer
+	require.EqualError(t, grant([]string{"-key", "env://CLI_TEST_GRANT_KEY"}, &out), "-account and -hash must be set")
+
+	args := []string{"-key", "env://CLI_TEST_GRANT_KEY", "-account", "0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", "-hash", "0x0102", "-ttl", "1m"}
+	require.NoError(t, grant(args, &out))
+	require.Len(t, strings.Split(strings.TrimSpace(out.String()), "."), 2)
+
+	require.EqualError(t, grant(append(args, "-ttl", "2h"), &out), "grant ttl must be greater than 0 and at most 1h0m0s")
+}
+
+func TestFreeze_AccountNotSet(t *testing.T) {
+	var out bytes.Buffer
+	require.EqualError(t, freeze([]string{"-config", "/path/to/config.json"}, &out), "-account must be set")
+	require.EqualError(t, unfreeze([]string{"-config", "/path/to/config.json", "-account", "0xzz"}, &out), "invalid -account: invalid hex address: encoding/hex: invalid byte: U+007A 'z'")
+}
+
+func TestSettings(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+
+	f, err := ioutil.TempFile("", "config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir",
+		"authentication": {
+			"token": "env://MY_TOKEN"
+		},
+		"strictSignDomains": true,
+		"accountDefaults": {"maxUnlockDuration": "1h"},
+		"accountOverrides": {"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {"maxUnlockDuration": "5m"}}
+	}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	type effective struct {
+		Address           string
+		MaxUnlockDuration string
+		ReadCache         bool
+		StrictSignDomains bool
+		Sources           map[string]string
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, settings([]string{"-config", f.Name()}, &out))
+	var got effective
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Equal(t, "1h0m0s", got.MaxUnlockDuration)
+	require.True(t, got.ReadCache)
+	require.True(t, got.StrictSignDomains)
+	require.Equal(t, config.SettingSourcePlugin, got.Sources["maxUnlockDuration"])
+
+	out.Reset()
+	require.NoError(t, settings([]string{"-config", f.Name(), "-account", "4D6D744B6DA435B5BBDDE2526DC20E9A41CB72E5"}, &out))
+	got = effective{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Equal(t, "0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", got.Address)
+	require.Equal(t, "5m0s", got.MaxUnlockDuration)
+	require.Equal(t, config.SettingSourceAccount, got.Sources["maxUnlockDuration"])
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp"
+)
+
+// grant prints a single-use sign grant for the account and payload, to be presented in the hashicorp.SignGrantKey gRPC
+// metadata of the signing request.  The key must be the signGrants key of the plugin config.
+func grant(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("grant", flag.ContinueOnError)
+	keyEnv := fs.String("key", "", "env:// URL of the environment variable holding the signGrants key")
+	acct := fs.String("account", "", "hex address of the account to grant a signature for")
+	hash := fs.String("hash", "", "hex payload the account may sign")
+	ttl := fs.Duration("ttl", 5*time.Minute, fmt.Sprintf("how long the grant is valid for, at most %v", hashicorp.MaxSignGrantTTL))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *acct == "" || *hash == "" {
+		return errors.New("-account and -hash must be set")
+	}
+	addr, err := account.NewAddressFromHexString(*acct)
+	if err != nil {
+		return fmt.Errorf("invalid -account: %v", err)
+	}
+	toSign, err := hex.DecodeString(strings.TrimPrefix(*hash, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid -hash: %v", err)
+	}
+	key, err := signingKey(*keyEnv)
+	if err != nil {
+		return err
+	}
+
+	g, err := hashicorp.IssueSignGrant(string(key), addr, toSign, *ttl)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, g)
+	return err
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// signConfig writes a detached signature of the raw plugin config file, to be provided to the plugin in the
+// config.ConfigSignatureEnv environment variable.  With -generate a new signing key pair is printed instead.
+func signConfig(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("sign-config", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the plugin config file to sign")
+	keyEnv := fs.String("key", "", "env:// URL of the environment variable holding the base64-encoded ed25519 private key seed")
+	generate := fs.Bool("generate", false, "print a new signing key pair instead of signing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *generate {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			return err
+		}
+		return writeJSON(out, map[string]string{
+			"publicKey":  base64.StdEncoding.EncodeToString(pub),
+			"privateKey": base64.StdEncoding.EncodeToString(priv.Seed()),
+		})
+	}
+
+	if *configPath == "" {
+		return errors.New("-config must be set")
+	}
+	// the config is signed exactly as it will be provided to the plugin, so it is not parsed or validated
+	raw, err := ioutil.ReadFile(strings.TrimPrefix(*configPath, "file://"))
+	if err != nil {
+		return err
+	}
+	seed, err := signingKey(*keyEnv)
+	if err != nil {
+		return err
+	}
+	sig, err := config.SignConfig(raw, string(seed))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, sig)
+	return err
+}
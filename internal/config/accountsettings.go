@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The layers account settings are resolved from, in order of precedence from lowest to highest
+const (
+	SettingSourceDefault = "default" // the plugin's built-in default
+	SettingSourcePlugin  = "plugin"  // a top-level field or accountDefaults of the plugin config
+	SettingSourceAccount = "account" // the account's accountOverrides
+)
+
+// AccountSettings are settings that apply to each account, and can be set for all accounts in AccountDefaults and for
+// individual accounts in AccountOverrides.  A nil field is not set.
+type AccountSettings struct {
+	// MaxUnlockDuration caps the duration of timed unlocks, including unlocks without a duration.  0 is no cap.
+	MaxUnlockDuration *time.Duration
+	// ReadCache keeps the account's secret in the read cache (if enabled) once it has been read
+	ReadCache *bool
+	// StrictSignDomains refuses signing requests for the account that do not declare the domain of their digest
+	StrictSignDomains *bool
+	// VersionFallback unlocks the account using the latest version of its secret if the configured version is not found
+	VersionFallback *bool
+}
+
+// EffectiveAccountSettings are the settings that apply to an account once the layers have been resolved
+type EffectiveAccountSettings struct {
+	MaxUnlockDuration time.Duration
+	ReadCache         bool
+	StrictSignDomains bool
+	VersionFallback   bool
+	// Sources is the layer each setting was resolved from, one of the SettingSource consts, keyed by the setting's
+	// config field name
+	Sources map[string]string
+}
+
+type accountSettingsJSON struct {
+	MaxUnlockDuration string `json:",omitempty"`
+	ReadCache         *bool  `json:",omitempty"`
+	StrictSignDomains *bool  `json:",omitempty"`
+	VersionFallback   *bool  `json:",omitempty"`
+}
+
+// EffectiveAccountSettings resolves the settings for the account with the given address: the plugin's defaults,
+// overridden by the equivalent top-level fields, then AccountDefaults, then the account's AccountOverrides
+func (c VaultClient) EffectiveAccountSettings(addr string) EffectiveAccountSettings {
+	s := EffectiveAccountSettings{
+		ReadCache: true,
+		Sources: map[string]string{
+			"maxUnlockDuration": SettingSourceDefault,
+			"readCache":         SettingSourceDefault,
+			"strictSignDomains": SettingSourceDefault,
+			"versionFallback":   SettingSourceDefault,
+		},
+	}
+	if c.StrictSignDomains {
+		s.StrictSignDomains = true
+		s.Sources["strictSignDomains"] = SettingSourcePlugin
+	}
+	if c.VersionFallback {
+		s.VersionFallback = true
+		s.Sources["versionFallback"] = SettingSourcePlugin
+	}
+
+	s.apply(c.AccountDefaults, SettingSourcePlugin)
+	for a, o := range c.AccountOverrides {
+		if NormalizeAddress(a) == NormalizeAddress(addr) {
+			s.apply(o, SettingSourceAccount)
+		}
+	}
+	return s
+}
+
+// apply overrides the settings that are set in layer
+func (s *EffectiveAccountSettings) apply(layer AccountSettings, source string) {
+	if layer.MaxUnlockDuration != nil {
+		s.MaxUnlockDuration = *layer.MaxUnlockDuration
+		s.Sources["maxUnlockDuration"] = source
+	}
+	if layer.ReadCache != nil {
+		s.ReadCache = *layer.ReadCache
+		s.Sources["readCache"] = source
+	}
+	if layer.StrictSignDomains != nil {
+		s.StrictSignDomains = *layer.StrictSignDomains
+		s.Sources["strictSignDomains"] = source
+	}
+	if layer.VersionFallback != nil {
+		s.VersionFallback = *layer.VersionFallback
+		s.Sources["versionFallback"] = source
+	}
+}
+
+// CapUnlockDuration returns the duration an account is unlocked for when an unlock of duration is requested.  An
+// unlock without a duration is capped to MaxUnlockDuration.
+func (s EffectiveAccountSettings) CapUnlockDuration(duration time.Duration) time.Duration {
+	if s.MaxUnlockDuration > 0 && (duration <= 0 || duration > s.MaxUnlockDuration) {
+		return s.MaxUnlockDuration
+	}
+	return duration
+}
+
+func (c AccountSettings) validate() error {
+	if c.MaxUnlockDuration != nil && *c.MaxUnlockDuration < 0 {
+		return errors.New(InvalidAccountSettings)
+	}
+	return nil
+}
+
+func (c VaultClient) validateAccountSettings() error {
+	if err := c.AccountDefaults.validate(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(c.AccountOverrides))
+	for addr, o := range c.AccountOverrides {
+		// an account can only be overridden once, however its address is written
+		normalized := NormalizeAddress(addr)
+		if !isAccountAddress(addr) || seen[normalized] {
+			return errors.New(InvalidAccountSettings)
+		}
+		seen[normalized] = true
+		if err := o.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c accountSettingsJSON) accountSettings() (AccountSettings, error) {
+	s := AccountSettings{
+		ReadCache:         c.ReadCache,
+		StrictSignDomains: c.StrictSignDomains,
+		VersionFallback:   c.VersionFallback,
+	}
+	if c.MaxUnlockDuration != "" {
+		d, err := time.ParseDuration(c.MaxUnlockDuration)
+		if err != nil {
+			return AccountSettings{}, fmt.Errorf("invalid maxUnlockDuration: %v", err)
+		}
+		s.MaxUnlockDuration = &d
+	}
+	return s, nil
+}
+
+func (c AccountSettings) accountSettingsJSON() accountSettingsJSON {
+	return accountSettingsJSON{
+		MaxUnlockDuration: optionalDurationPtrString(c.MaxUnlockDuration),
+		ReadCache:         c.ReadCache,
+		StrictSignDomains: c.StrictSignDomains,
+		VersionFallback:   c.VersionFallback,
+	}
+}
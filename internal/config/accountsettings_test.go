@@ -0,0 +1,70 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultClient_EffectiveAccountSettings(t *testing.T) {
+	const (
+		overridden = "0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5"
+		other      = "dc99ddec13457de6c0f6bb8e6cf3955c86f55526"
+	)
+
+	// the plugin's defaults
+	got := VaultClient{}.EffectiveAccountSettings(other)
+	require.Equal(t, EffectiveAccountSettings{
+		ReadCache: true,
+		Sources: map[string]string{
+			"maxUnlockDuration": SettingSourceDefault,
+			"readCache":         SettingSourceDefault,
+			"strictSignDomains": SettingSourceDefault,
+			"versionFallback":   SettingSourceDefault,
+		},
+	}, got)
+
+	hour, fiveMinutes, no, yes := time.Hour, 5*time.Minute, false, true
+	conf := VaultClient{
+		StrictSignDomains: true,
+		VersionFallback:   true,
+		AccountDefaults:   AccountSettings{MaxUnlockDuration: &hour, VersionFallback: &no},
+		AccountOverrides: map[string]AccountSettings{
+			// overrides apply however the address is written
+			"4D6D744B6DA435B5BBDDE2526DC20E9A41CB72E5": {MaxUnlockDuration: &fiveMinutes, ReadCache: &no, StrictSignDomains: &no, VersionFallback: &yes},
+		},
+	}
+
+	got = conf.EffectiveAccountSettings(other)
+	require.Equal(t, hour, got.MaxUnlockDuration)
+	require.True(t, got.ReadCache)
+	require.True(t, got.StrictSignDomains)
+	// accountDefaults takes precedence over the top-level field
+	require.False(t, got.VersionFallback)
+	require.Equal(t, map[string]string{
+		"maxUnlockDuration": SettingSourcePlugin,
+		"readCache":         SettingSourceDefault,
+		"strictSignDomains": SettingSourcePlugin,
+		"versionFallback":   SettingSourcePlugin,
+	}, got.Sources)
+
+	got = conf.EffectiveAccountSettings(overridden)
+	require.Equal(t, fiveMinutes, got.MaxUnlockDuration)
+	require.False(t, got.ReadCache)
+	require.False(t, got.StrictSignDomains)
+	require.True(t, got.VersionFallback)
+	for setting, source := range got.Sources {
+		require.Equal(t, SettingSourceAccount, source, setting)
+	}
+}
+
+func TestEffectiveAccountSettings_CapUnlockDuration(t *testing.T) {
+	require.Equal(t, time.Duration(0), EffectiveAccountSettings{}.CapUnlockDuration(0))
+	require.Equal(t, time.Hour, EffectiveAccountSettings{}.CapUnlockDuration(time.Hour))
+
+	capped := EffectiveAccountSettings{MaxUnlockDuration: 10 * time.Minute}
+	require.Equal(t, 10*time.Minute, capped.CapUnlockDuration(0))
+	require.Equal(t, 10*time.Minute, capped.CapUnlockDuration(time.Hour))
+	require.Equal(t, time.Minute, capped.CapUnlockDuration(time.Minute))
+}
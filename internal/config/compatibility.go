@@ -0,0 +1,45 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ConfigSchemaVersion is incremented whenever a change to the config format means a config is interpreted differently
+// by different versions of the plugin
+const ConfigSchemaVersion = 1
+
+// CapabilityVersion is incremented whenever the plugin's behaviour changes in a way the host or operators may depend
+// on, e.g. new RPC semantics or config options
+const CapabilityVersion = 1
+
+// Compatibility pins the config schema and capability versions of the plugin that a config was written for, so that a
+// plugin of a different version refuses to start rather than failing mid-operation.  A version of 0 is not pinned.
+type Compatibility struct {
+	ConfigSchema int
+	Capability   int
+}
+
+// CheckCompatibility checks the compatibility pinned in the raw plugin configuration against this plugin.  It is done
+// before the config is unmarshalled, as a config written for a different schema may not unmarshal or may be
+// misinterpreted.
+func CheckCompatibility(raw []byte) error {
+	var pinned struct {
+		Compatibility Compatibility
+	}
+	if err := json.Unmarshal(raw, &pinned); err != nil {
+		// reported when the config is unmarshalled
+		return nil
+	}
+	return pinned.Compatibility.check()
+}
+
+func (c Compatibility) check() error {
+	if c.ConfigSchema != 0 && c.ConfigSchema != ConfigSchemaVersion {
+		return fmt.Errorf("plugin config is pinned to config schema version %v but this plugin uses version %v: deploy the plugin version the config was written for, or update the config and its compatibility.configSchema", c.ConfigSchema, ConfigSchemaVersion)
+	}
+	if c.Capability != 0 && c.Capability != CapabilityVersion {
+		return fmt.Errorf("plugin config is pinned to capability version %v but this plugin provides version %v: deploy the plugin version the config was written for, or update compatibility.capability", c.Capability, CapabilityVersion)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	compatible := []string{
+		`{"vault": "http://vault:1111"}`,
+		`{"compatibility": {}}`,
+		fmt.Sprintf(`{"compatibility": {"configSchema": %v}}`, ConfigSchemaVersion),
+		fmt.Sprintf(`{"compatibility": {"configSchema": %v, "capability": %v}}`, ConfigSchemaVersion, CapabilityVersion),
+		`not json`,
+	}
+	for _, raw := range compatible {
+		require.NoError(t, CheckCompatibility([]byte(raw)), raw)
+	}
+
+	err := CheckCompatibility([]byte(fmt.Sprintf(`{"compatibility": {"configSchema": %v}}`, ConfigSchemaVersion+1)))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("pinned to config schema version %v but this plugin uses version %v", ConfigSchemaVersion+1, ConfigSchemaVersion))
+
+	err = CheckCompatibility([]byte(fmt.Sprintf(`{"compatibility": {"configSchema": %v, "capability": %v}}`, ConfigSchemaVersion, CapabilityVersion+1)))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("pinned to capability version %v but this plugin provides version %v", CapabilityVersion+1, CapabilityVersion))
+}
+
+func TestVaultClient_UnmarshalJSON_Compatibility(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "compatibility": {"configSchema": 1, "capability": 2}}`), &got))
+	require.Equal(t, Compatibility{ConfigSchema: 1, Capability: 2}, got.Compatibility)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Compatibility, roundTrip.Compatibility)
+}
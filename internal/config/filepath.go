@@ -0,0 +1,25 @@
+package config
+
+import (
+	"net/url"
+	"runtime"
+	"strings"
+)
+
+// FilePath converts a file URL to a path for the current OS.  Any host is treated as the first element of a relative
+// path (e.g. file://path/to/file is path/to/file).  On Windows, the slash before a drive letter is removed and
+// separators are converted (e.g. file:///C:/path/to/dir is C:\path\to\dir).
+func FilePath(u *url.URL) string {
+	return filePath(u, runtime.GOOS)
+}
+
+func filePath(u *url.URL, goos string) string {
+	p := u.Host + u.Path
+	if goos != "windows" {
+		return p
+	}
+	if len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return strings.Replace(p, "/", `\`, -1)
+}
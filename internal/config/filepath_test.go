@@ -0,0 +1,30 @@
+package config
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePath(t *testing.T) {
+	tests := []struct {
+		url, goos, want string
+	}{
+		{url: "file:///path/to/dir/", goos: "linux", want: "/path/to/dir/"},
+		{url: "file://path/to/file", goos: "linux", want: "path/to/file"},
+		{url: "", goos: "linux", want: ""},
+		{url: "file:///C:/path/to/dir/", goos: "windows", want: `C:\path\to\dir\`},
+		{url: "file:///c:/path/to/file", goos: "windows", want: `c:\path\to\file`},
+		{url: "file://path/to/file", goos: "windows", want: `path\to\file`},
+		{url: "file:///path/to/file", goos: "windows", want: `\path\to\file`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos+" "+tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.want, filePath(u, tt.goos))
+		})
+	}
+}
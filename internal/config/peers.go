@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"net"
+)
+
+// NotLoopbackErr is returned by CheckLoopbackAddress for a valid host:port that is not a loopback address
+var NotLoopbackErr = errors.New("not a loopback address")
+
+// PeerAllowed returns true if addr is a unix socket or loopback address and AllowedPeerLocalhost is allowed, or if addr
+// is an IP address in one of the allowed CIDRs
+func PeerAllowed(allowed []string, addr net.Addr) bool {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UnixAddr:
+		for _, p := range allowed {
+			if p == AllowedPeerLocalhost {
+				return true
+			}
+		}
+		return false
+	case *net.TCPAddr:
+		ip = a.IP
+	default:
+		// the peer cannot be identified
+		return false
+	}
+
+	for _, p := range allowed {
+		if p == AllowedPeerLocalhost {
+			if ip.IsLoopback() {
+				return true
+			}
+			continue
+		}
+		if _, cidr, err := net.ParseCIDR(p); err == nil && cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckLoopbackAddress returns nil if addr is a host:port whose host is localhost or a loopback IP address, NotLoopbackErr
+// if it is any other host:port, and the parsing error if it is not a host:port
+func CheckLoopbackAddress(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return NotLoopbackErr
+}
+
+// isValidPeer returns true if p is AllowedPeerLocalhost or a CIDR
+func isValidPeer(p string) bool {
+	_, _, err := net.ParseCIDR(p)
+	return p == AllowedPeerLocalhost || err == nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerAllowed(t *testing.T) {
+	unix := &net.UnixAddr{Name: "/tmp/plugin123456", Net: "unix"}
+	loopback := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1234}
+	internal := &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 1234}
+	external := &net.TCPAddr{IP: net.ParseIP("203.0.113.1"), Port: 1234}
+
+	localhost := []string{AllowedPeerLocalhost}
+	require.True(t, PeerAllowed(localhost, unix))
+	require.True(t, PeerAllowed(localhost, loopback))
+	require.False(t, PeerAllowed(localhost, internal))
+
+	cidr := []string{"10.0.0.0/8"}
+	require.False(t, PeerAllowed(cidr, unix))
+	require.False(t, PeerAllowed(cidr, loopback))
+	require.True(t, PeerAllowed(cidr, internal))
+	require.False(t, PeerAllowed(cidr, external))
+
+	// a peer that cannot be identified is never allowed
+	require.False(t, PeerAllowed(append(localhost, "0.0.0.0/0"), nil))
+}
+
+func TestCheckLoopbackAddress(t *testing.T) {
+	for _, addr := range []string{"localhost:6060", "127.0.0.1:6060", "[::1]:6060", "127.0.0.1:0"} {
+		require.NoError(t, CheckLoopbackAddress(addr), addr)
+	}
+	for _, addr := range []string{"0.0.0.0:6060", ":6060", "10.0.0.1:6060", "example.com:6060"} {
+		require.Equal(t, NotLoopbackErr, CheckLoopbackAddress(addr), addr)
+	}
+	err := CheckLoopbackAddress("localhost")
+	require.Error(t, err)
+	require.NotEqual(t, NotLoopbackErr, err)
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ConfigSigningKey is the base64-encoded ed25519 public key that the raw plugin configuration must be signed with.  It is
+// embedded at build time, e.g.
+//
+//	go build -ldflags "-X github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config.ConfigSigningKey=..."
+//
+// If empty, the plugin configuration does not need to be signed.
+var ConfigSigningKey string
+
+// ConfigSignatureEnv is the environment variable holding the detached signature of the raw plugin configuration,
+// either base64-encoded or as a file:// URL of a file containing the base64-encoded signature
+const ConfigSignatureEnv = "HASHICORP_PLUGIN_CONFIG_SIGNATURE"
+
+var (
+	UnsignedConfigErr         = fmt.Errorf("plugin config must be signed: %v must be set", ConfigSignatureEnv)
+	InvalidConfigSignatureErr = errors.New("plugin config signature is not valid for the embedded signing key")
+)
+
+// ConfigSigningRequired reports whether the plugin was built with an embedded config signing key
+func ConfigSigningRequired() bool {
+	return ConfigSigningKey != ""
+}
+
+// VerifyConfigSignature checks the detached signature in the ConfigSignatureEnv environment variable against the raw
+// plugin configuration.  If the plugin was not built with an embedded signing key no verification is done.
+func VerifyConfigSignature(raw []byte) error {
+	if !ConfigSigningRequired() {
+		return nil
+	}
+	return verifyConfigSignature(raw, ConfigSigningKey, os.Getenv(ConfigSignatureEnv))
+}
+
+func verifyConfigSignature(raw []byte, key, sig string) error {
+	pub, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("embedded config signing key is not a base64-encoded ed25519 public key")
+	}
+	if strings.HasPrefix(sig, "file://") {
+		u, err := url.Parse(sig)
+		if err != nil {
+			return fmt.Errorf("invalid %v: %v", ConfigSignatureEnv, err)
+		}
+		sig = EnvironmentVariable(*u).Get()
+	}
+	if sig == "" {
+		return UnsignedConfigErr
+	}
+	s, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sig))
+	if err != nil || len(s) != ed25519.SignatureSize {
+		return InvalidConfigSignatureErr
+	}
+	if !ed25519.Verify(pub, raw, s) {
+		return InvalidConfigSignatureErr
+	}
+	return nil
+}
+
+// SignConfig creates a base64-encoded detached signature of the raw plugin configuration using the base64-encoded
+// ed25519 private key seed
+func SignConfig(raw []byte, seed string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(seed))
+	if err != nil || len(b) != ed25519.SeedSize {
+		return "", errors.New("signing key must be a base64-encoded ed25519 private key seed")
+	}
+	sig := ed25519.Sign(ed25519.NewKeyFromSeed(b), raw)
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newConfigSigningKey(t *testing.T) (pub, seed string) {
+	pk, sk, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(pk), base64.StdEncoding.EncodeToString(sk.Seed())
+}
+
+func TestVerifyConfigSignature(t *testing.T) {
+	pub, seed := newConfigSigningKey(t)
+	raw := []byte(`{"vault": "https://vault:8200"}`)
+
+	sig, err := SignConfig(raw, seed)
+	require.NoError(t, err)
+
+	require.NoError(t, verifyConfigSignature(raw, pub, sig))
+}
+
+func TestVerifyConfigSignature_File(t *testing.T) {
+	pub, seed := newConfigSigningKey(t)
+	raw := []byte(`{"vault": "https://vault:8200"}`)
+
+	sig, err := SignConfig(raw, seed)
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "config.sig")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(sig + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.NoError(t, verifyConfigSignature(raw, pub, "file://"+f.Name()))
+}
+
+func TestVerifyConfigSignature_ModifiedConfig(t *testing.T) {
+	pub, seed := newConfigSigningKey(t)
+
+	sig, err := SignConfig([]byte(`{"vault": "https://vault:8200"}`), seed)
+	require.NoError(t, err)
+
+	err = verifyConfigSignature([]byte(`{"vault": "https://rogue:8200"}`), pub, sig)
+	require.Equal(t, InvalidConfigSignatureErr, err)
+}
+
+func TestVerifyConfigSignature_WrongKey(t *testing.T) {
+	raw := []byte(`{"vault": "https://vault:8200"}`)
+	_, seed := newConfigSigningKey(t)
+	otherPub, _ := newConfigSigningKey(t)
+
+	sig, err := SignConfig(raw, seed)
+	require.NoError(t, err)
+
+	require.Equal(t, InvalidConfigSignatureErr, verifyConfigSignature(raw, otherPub, sig))
+}
+
+func TestVerifyConfigSignature_Unsigned(t *testing.T) {
+	pub, _ := newConfigSigningKey(t)
+
+	require.Equal(t, UnsignedConfigErr, verifyConfigSignature([]byte("{}"), pub, ""))
+	require.Equal(t, UnsignedConfigErr, verifyConfigSignature([]byte("{}"), pub, "file:///does/not/exist"))
+	require.Equal(t, InvalidConfigSignatureErr, verifyConfigSignature([]byte("{}"), pub, "not-base64"))
+}
+
+func TestVerifyConfigSignature_InvalidEmbeddedKey(t *testing.T) {
+	err := verifyConfigSignature([]byte("{}"), "bm90LWEta2V5", "")
+	require.EqualError(t, err, "embedded config signing key is not a base64-encoded ed25519 public key")
+}
+
+func TestVerifyConfigSignature_NotRequired(t *testing.T) {
+	require.False(t, ConfigSigningRequired())
+	require.NoError(t, VerifyConfigSignature([]byte("{}")))
+}
+
+func TestSignConfig_InvalidKey(t *testing.T) {
+	_, err := SignConfig([]byte("{}"), "bm90LWEta2V5")
+	require.EqualError(t, err, "signing key must be a base64-encoded ed25519 private key seed")
+}
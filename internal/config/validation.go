@@ -1,38 +1,473 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"unicode"
 )
 
 const (
 	InvalidVaultUrl            = "vault must be a valid HTTP/HTTPS url"
 	InvalidKVEngineName        = "kvEngineName must be set"
 	InvalidAccountDirectory    = "accountDirectory must be a valid absolute file url"
-	InvalidAuthentication      = "authentication must contain roleId, secretId and approlePath OR only token, and the given environment variables must be set"
+	InvalidAccountStore        = "accountStore type must be one of consul or etcd, address must be a valid HTTP/HTTPS url, prefix must be set, the given token environment variable must be set, and pollInterval cannot be negative"
+	InvalidAuthentication      = "authentication must contain roleId, secretId and approlePath OR only token OR only kubernetes OR only azure OR only cert OR only ldap OR only userpass, and the given environment variables must be set"
+	InvalidServiceAccountToken = "kubernetes serviceAccountToken must be a valid absolute file url"
+	InvalidAzureResource       = "azure resource must be a valid url"
+	InvalidCertAuthentication  = "cert authentication requires tls clientCert and clientKey to be set"
 	InvalidCaCert              = "caCert must be a valid absolute file url"
+	InvalidCaCertDir           = "caCertDir must be a valid absolute file url"
 	InvalidClientCert          = "clientCert must be a valid absolute file url"
 	InvalidClientKey           = "clientKey must be a valid absolute file url"
+	InvalidPin                 = "tls pins must be hex-encoded SHA-256 fingerprints"
 	InvalidSecretName          = "secretName must be set"
+	InvalidSecretNamePath      = "secretName must be a relative path without empty, . or .. segments, whitespace or any of ?#%\\ characters"
 	InvalidOverwriteProtection = "currentVersion and insecureDisable cannot both be set"
+	InvalidAccountRole         = "role must be one of validator, transaction or faucet, and sealer accounts must be validators"
+	InvalidNewAccountQuota     = "newAccountQuota perHour and perDay cannot be negative"
+	InvalidUnlockTOTP          = "unlockTotp engine must be set and keys must map account addresses to TOTP key names"
+	InvalidDRSecondary         = "drSecondary must be a valid HTTP/HTTPS url"
+	InvalidReadReplica         = "readReplica and readReplicas must be valid HTTP/HTTPS urls"
+	InvalidLocalities          = "localities must map the vault, readReplica or readReplicas urls to a region/zone"
+	InvalidRPCTimeout          = "rpcTimeout cannot be negative"
+	InvalidMaxStaleness        = "maxStaleness cannot be negative"
+	InvalidReadCacheSize       = "readCacheSize and readCacheMaxBytes cannot be negative"
+	InvalidDebugAddress        = "debug address must be a loopback host:port, e.g. localhost:6060"
+	InvalidMaxConcurrentReqs   = "maxConcurrentRequests cannot be negative"
+	InvalidHealthProbe         = "healthProbe interval and failureThreshold cannot be negative"
+	InvalidDNSRefreshInterval  = "dnsRefreshInterval cannot be negative"
+	InvalidStateDirectory      = "stateDirectory must be a valid absolute file url"
+	InvalidAccountOrder        = "accountOrder must be one of url, address or created"
+	InvalidAccountID           = "accountId must be one of random or deterministic"
+	InvalidTokenSink           = "tokenSink key must be an env url for a set environment variable, and stateDirectory must be set"
+	InvalidMirror              = "mirror node and promotionSecret must both be set, and pollInterval cannot be negative"
+	InvalidAllowedPeers        = "permissions allowedPeers must be localhost or CIDRs, e.g. 10.0.0.0/8"
+	InvalidDuplicateSignWindow = "duplicateSignWindow cannot be negative"
+	InvalidSigningLatencySLO   = "signingLatencySLO threshold and window cannot be negative, and percentile must be between 0 and 100"
+	InvalidEscrowPublicKey     = "escrow publicKey must be a valid absolute file url"
+	InvalidSecretsEngine       = "secretsEngine must be one of kv or cubbyhole, and cubbyhole cannot be used with kvEngineName or drSecondary"
+	InvalidHeaders             = "headers must have valid HTTP header names and values and cannot set X-Vault-* headers, and nodeId cannot contain control characters"
+	InvalidQuorumPermissioning = "quorumPermissioning rpc must be a valid HTTP/HTTPS url, accountManager must be a hex-encoded contract address, and cacheTTL cannot be negative"
+	InvalidPepper              = "pepper must be an env or file url for a set value of at least 16 characters"
+	InvalidCredentialFile      = "credential files must be absolute file urls of regular files that other users cannot read or write"
+	InvalidSignGrants          = "signGrants key must be an env or file url for a set value of at least 32 characters, and accounts must be account addresses"
+	InvalidAddressMismatch     = "addressMismatch must be one of fail, trustVault or trustConfig+alert"
+	InvalidSecretIdRotator     = "secretIdRotator must contain token and role, can only be used with approle authentication, and the given environment variable must be set"
+	InvalidStandbyTokens       = "standbyTokens must be between 0 and 5, and cannot be used with token authentication or dev"
+	InvalidUsageReport         = "usageReport interval cannot be negative and requires file or endpoint, file must be an absolute file url, endpoint must be a valid HTTP/HTTPS url, format must be one of json or csv, and token requires endpoint"
+	InvalidAccountSettings     = "accountDefaults and accountOverrides maxUnlockDuration cannot be negative, and accountOverrides must be keyed by account addresses, each overridden once"
+	InvalidRequestNamespaces   = "requestNamespaces allowed must be relative namespace paths without empty, . or .. segments or whitespace, peers must be localhost or CIDRs and requires allowed, and requestNamespaces cannot be used with dev or the cubbyhole secretsEngine"
+	InvalidSignPayloads        = "signPayloads maxBytes and maxCalldataBytes cannot be negative, and selectors must map account addresses, each listed once, to 4-byte hex function selectors, e.g. 0xa9059cbb"
+	InvalidDev                 = "dev cannot be used with vault, kvEngineName, secretsEngine, authentication, drSecondary, readReplica(s), locality, localities, unlockTOTP, mirror, healthProbe or tokenSink"
+)
+
+const (
+	// minPepperLength is the minimum number of characters in a pepper
+	minPepperLength = 16
+	// minSignGrantKeyLength is the minimum number of characters in a signGrants key
+	minSignGrantKeyLength = 32
+	// maxStandbyTokens limits the number of standby tokens, each of which is a separate login
+	maxStandbyTokens = 5
 )
 
 func (c VaultClient) Validate() error {
-	if c.Vault == nil || c.Vault.Scheme == "" {
-		return errors.New(InvalidVaultUrl)
+	if c.Dev {
+		if err := c.validateDev(); err != nil {
+			return err
+		}
+	} else {
+		if c.Vault == nil || c.Vault.Scheme == "" || !isValidHost(c.Vault.Host) {
+			return errors.New(InvalidVaultUrl)
+		}
+		switch c.SecretsEngine {
+		case "", SecretsEngineKV:
+			if c.KVEngineName == "" {
+				return errors.New(InvalidKVEngineName)
+			}
+		case SecretsEngineCubbyhole:
+			// cubbyholes are per-token so are not replicated to the DR secondary
+			if c.KVEngineName != "" || c.DRSecondary != nil {
+				return errors.New(InvalidSecretsEngine)
+			}
+		default:
+			return errors.New(InvalidSecretsEngine)
+		}
 	}
-	if c.KVEngineName == "" {
-		return errors.New(InvalidKVEngineName)
+	if c.AccountStore.Type == "" && c.AccountDirectory == nil {
+		return errors.New(InvalidAccountDirectory)
 	}
-	if c.AccountDirectory == nil || !isValidAbsFileUrl(c.AccountDirectory) {
+	if c.AccountDirectory != nil && !isValidAbsFileUrl(c.AccountDirectory) {
 		return errors.New(InvalidAccountDirectory)
 	}
-	if err := c.Authentication.validate(); err != nil {
+	if err := c.AccountStore.validate(); err != nil {
+		return err
+	}
+	if !c.Dev {
+		if err := c.Authentication.validate(); err != nil {
+			return err
+		}
+		if c.Authentication.Cert.Role != "" && (optionalURLString(c.TLS.ClientCert) == "" || optionalURLString(c.TLS.ClientKey) == "") {
+			return errors.New(InvalidCertAuthentication)
+		}
+	}
+	if err := c.validateCredentialFiles(runtime.GOOS); err != nil {
 		return err
 	}
 	if err := c.TLS.validate(); err != nil {
 		return err
 	}
+	if err := c.NewAccountQuota.validate(); err != nil {
+		return err
+	}
+	if err := c.UnlockTOTP.validate(); err != nil {
+		return err
+	}
+	if c.DRSecondary != nil && !isHTTPUrl(c.DRSecondary) {
+		return errors.New(InvalidDRSecondary)
+	}
+	if c.ReadReplica != nil && !isHTTPUrl(c.ReadReplica) {
+		return errors.New(InvalidReadReplica)
+	}
+	for _, r := range c.ReadReplicas {
+		if r == nil || !isHTTPUrl(r) {
+			return errors.New(InvalidReadReplica)
+		}
+	}
+	if err := c.validateLocalities(); err != nil {
+		return err
+	}
+	if c.MaxStaleness != nil && *c.MaxStaleness < 0 {
+		return errors.New(InvalidMaxStaleness)
+	}
+	if c.RPCTimeout < 0 {
+		return errors.New(InvalidRPCTimeout)
+	}
+	if c.ReadCacheSize < 0 || c.ReadCacheMaxBytes < 0 {
+		return errors.New(InvalidReadCacheSize)
+	}
+	if err := c.Debug.validate(); err != nil {
+		return err
+	}
+	if c.MaxConcurrentRequests < 0 {
+		return errors.New(InvalidMaxConcurrentReqs)
+	}
+	if c.HealthProbe.Interval < 0 || c.HealthProbe.FailureThreshold < 0 {
+		return errors.New(InvalidHealthProbe)
+	}
+	if c.DNSRefreshInterval < 0 {
+		return errors.New(InvalidDNSRefreshInterval)
+	}
+	if c.StateDirectory != nil && !isValidAbsFileUrl(c.StateDirectory) {
+		return errors.New(InvalidStateDirectory)
+	}
+	if err := c.TokenSink.validate(c.StateDirectory); err != nil {
+		return err
+	}
+	switch c.AccountOrder {
+	case "", AccountOrderURL, AccountOrderAddress, AccountOrderCreated:
+	default:
+		return errors.New(InvalidAccountOrder)
+	}
+	switch c.AccountID {
+	case "", AccountIDRandom, AccountIDDeterministic:
+	default:
+		return errors.New(InvalidAccountID)
+	}
+	if (c.Mirror.Node == "") != (c.Mirror.PromotionSecret == "") || c.Mirror.PollInterval < 0 {
+		return errors.New(InvalidMirror)
+	}
+	if err := c.QuorumPermissioning.validate(); err != nil {
+		return err
+	}
+	if c.DuplicateSignWindow < 0 {
+		return errors.New(InvalidDuplicateSignWindow)
+	}
+	if slo := c.SigningLatencySLO; slo.Threshold < 0 || slo.Window < 0 || slo.Percentile < 0 || slo.Percentile > 100 {
+		return errors.New(InvalidSigningLatencySLO)
+	}
+	if c.Escrow.PublicKey != nil && !isValidAbsFileUrl(c.Escrow.PublicKey) {
+		return errors.New(InvalidEscrowPublicKey)
+	}
+	for _, p := range c.Permissions.AllowedPeers {
+		if !isValidPeer(p) {
+			return errors.New(InvalidAllowedPeers)
+		}
+	}
+	if !isValidHeaderValue(c.NodeID) {
+		return errors.New(InvalidHeaders)
+	}
+	for name, value := range c.Headers {
+		// X-Vault-* headers carry the auth token, namespace and replication state set by the plugin
+		if !isValidHeaderName(name) || strings.HasPrefix(strings.ToLower(name), "x-vault-") || !isValidHeaderValue(value) {
+			return errors.New(InvalidHeaders)
+		}
+	}
+	if c.Pepper != nil && !isValidSecret(*c.Pepper, minPepperLength) {
+		return errors.New(InvalidPepper)
+	}
+	if err := c.SignGrants.validate(); err != nil {
+		return err
+	}
+	switch c.AddressMismatch {
+	case "", AddressMismatchFail, AddressMismatchTrustVault, AddressMismatchTrustConfigAlert:
+	default:
+		return errors.New(InvalidAddressMismatch)
+	}
+	if c.StandbyTokens < 0 || c.StandbyTokens > maxStandbyTokens {
+		return errors.New(InvalidStandbyTokens)
+	}
+	// a static token cannot be replaced by logging in
+	if c.StandbyTokens > 0 && (c.Dev || c.Authentication.Token.IsSet()) {
+		return errors.New(InvalidStandbyTokens)
+	}
+	if err := c.UsageReport.validate(); err != nil {
+		return err
+	}
+	if err := c.validateAccountSettings(); err != nil {
+		return err
+	}
+	if err := c.validateRequestNamespaces(); err != nil {
+		return err
+	}
+	if err := c.SignPayloads.validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateRequestNamespaces checks the namespaces cannot escape the Vault API path they prefix.  A cubbyhole belongs to
+// the plugin's token so cannot be read in another namespace.
+func (c VaultClient) validateRequestNamespaces() error {
+	n := c.RequestNamespaces
+	if len(n.Allowed) == 0 {
+		if len(n.Peers) != 0 {
+			return errors.New(InvalidRequestNamespaces)
+		}
+		return nil
+	}
+	if c.Dev || c.SecretsEngine == SecretsEngineCubbyhole {
+		return errors.New(InvalidRequestNamespaces)
+	}
+	for _, ns := range n.Allowed {
+		if !isValidSecretName(ns) {
+			return errors.New(InvalidRequestNamespaces)
+		}
+	}
+	for _, p := range n.Peers {
+		if !isValidPeer(p) {
+			return errors.New(InvalidRequestNamespaces)
+		}
+	}
+	return nil
+}
+
+// isValidSecret returns true if the secret is read from a set env var or file, and is long enough that it cannot be
+// guessed
+func isValidSecret(secret EnvironmentVariable, minLength int) bool {
+	if secret.Scheme != "env" && secret.Scheme != "file" {
+		return false
+	}
+	return secret.IsSet() && len(secret.Get()) >= minLength
+}
+
+// credentials returns the config's credentials, any of which can be read from a file.  Unset credentials are nil.
+func (c VaultClient) credentials() []*EnvironmentVariable {
+	auth := c.Authentication
+	return []*EnvironmentVariable{
+		auth.Token, auth.RoleId, auth.SecretId, auth.SecretIdRotator.Token,
+		auth.Ldap.Username, auth.Ldap.Password, auth.Userpass.Username, auth.Userpass.Password,
+		c.AccountStore.Token, c.TokenSink.Key, c.Pepper, c.SignGrants.Key, c.UsageReport.Token,
+	}
+}
+
+// validateCredentialFiles checks that credentials read from files cannot be read or replaced by other users, which
+// would expose them as much as the environment of the plugin process.  Group access is allowed, as mounted secrets are
+// commonly shared with the container's group.  Mode bits are not used on Windows, so are not checked.
+func (c VaultClient) validateCredentialFiles(goos string) error {
+	for _, e := range c.credentials() {
+		if e == nil || !e.IsFile() {
+			continue
+		}
+		u := url.URL(*e)
+		path := FilePath(&u)
+		// a missing file is reported by the validation of the credential
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !isValidAbsFileUrl(&u) || !info.Mode().IsRegular() || (goos != "windows" && info.Mode().Perm()&0007 != 0) {
+			return fmt.Errorf("%v: %v has mode %v", InvalidCredentialFile, path, info.Mode())
+		}
+	}
+	return nil
+}
+
+func (c VaultClientSignGrants) validate() error {
+	if c.Key == nil {
+		if len(c.Accounts) != 0 {
+			return errors.New(InvalidSignGrants)
+		}
+		return nil
+	}
+	if !isValidSecret(*c.Key, minSignGrantKeyLength) {
+		return errors.New(InvalidSignGrants)
+	}
+	for _, addr := range c.Accounts {
+		if !isAccountAddress(addr) {
+			return errors.New(InvalidSignGrants)
+		}
+	}
+	return nil
+}
+
+func (c VaultClientSignPayloads) validate() error {
+	if c.MaxBytes < 0 || c.MaxCalldataBytes < 0 {
+		return errors.New(InvalidSignPayloads)
+	}
+	seen := make(map[string]bool, len(c.Selectors))
+	for addr, selectors := range c.Selectors {
+		normalized := NormalizeAddress(addr)
+		if !isAccountAddress(addr) || seen[normalized] || len(selectors) == 0 {
+			return errors.New(InvalidSignPayloads)
+		}
+		seen[normalized] = true
+		for _, s := range selectors {
+			if !isFunctionSelector(s) {
+				return errors.New(InvalidSignPayloads)
+			}
+		}
+	}
+	return nil
+}
+
+// isFunctionSelector returns true if s is a 0x-prefixed hex-encoded 4-byte function selector
+func isFunctionSelector(s string) bool {
+	if !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	b, err := hex.DecodeString(s[2:])
+	return err == nil && len(b) == 4
+}
+
+// isAccountAddress returns true if addr is a hex-encoded account address, with or without a 0x prefix
+func isAccountAddress(addr string) bool {
+	b, err := hex.DecodeString(NormalizeAddress(addr))
+	return err == nil && len(b) == 20
+}
+
+// isValidHeaderName returns true if name is a non-empty HTTP token
+func isValidHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidHeaderValue returns true if value does not contain control characters, which could be used to inject
+// additional headers
+func isValidHeaderValue(value string) bool {
+	for _, r := range value {
+		if unicode.IsControl(r) && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+func (c VaultClientQuorumPermissioning) validate() error {
+	if c.RPC == nil && c.AccountManager == "" && c.CacheTTL == 0 {
+		return nil
+	}
+	if c.RPC == nil || !isHTTPUrl(c.RPC) || c.CacheTTL < 0 {
+		return errors.New(InvalidQuorumPermissioning)
+	}
+	if b, err := hex.DecodeString(strings.TrimPrefix(c.AccountManager, "0x")); err != nil || len(b) != 20 {
+		return errors.New(InvalidQuorumPermissioning)
+	}
+	return nil
+}
+
+func (c VaultClientUsageReport) validate() error {
+	if c.Interval < 0 {
+		return errors.New(InvalidUsageReport)
+	}
+	if c.Interval > 0 && c.File == nil && c.Endpoint == nil {
+		return errors.New(InvalidUsageReport)
+	}
+	if c.File != nil && !isValidAbsFileUrl(c.File) {
+		return errors.New(InvalidUsageReport)
+	}
+	switch c.Format {
+	case "", UsageReportFormatJSON, UsageReportFormatCSV:
+	default:
+		return errors.New(InvalidUsageReport)
+	}
+	if c.Endpoint != nil && !isHTTPUrl(c.Endpoint) {
+		return errors.New(InvalidUsageReport)
+	}
+	if c.Token != nil && c.Endpoint == nil {
+		return errors.New(InvalidUsageReport)
+	}
+	return nil
+}
+
+// validateDev checks that none of the fields that only apply when using Vault are set, as they would be ignored
+func (c VaultClient) validateDev() error {
+	vaultSpecific := (c.Vault != nil && c.Vault.String() != "") ||
+		c.KVEngineName != "" ||
+		c.SecretsEngine != "" ||
+		c.Authentication.isConfigured() ||
+		c.DRSecondary != nil ||
+		c.ReadReplica != nil ||
+		len(c.ReadReplicas) != 0 ||
+		c.Locality != "" ||
+		len(c.Localities) != 0 ||
+		c.UnlockTOTP.Engine != "" ||
+		len(c.UnlockTOTP.Keys) != 0 ||
+		c.Mirror.Node != "" ||
+		c.HealthProbe.Interval != 0 ||
+		c.TokenSink.Key != nil
+	if vaultSpecific {
+		return errors.New(InvalidDev)
+	}
+	return nil
+}
+
+// isConfigured reports whether any auth method is configured, regardless of whether its credentials are available
+func (c VaultClientAuthentication) isConfigured() bool {
+	for _, e := range []*EnvironmentVariable{c.Token, c.RoleId, c.SecretId} {
+		if e != nil && e.String() != "" {
+			return true
+		}
+	}
+	return c.ApprolePath != "" || c.Kubernetes.Role != "" || c.Azure.Role != "" || c.Cert.Role != "" ||
+		c.Ldap.Username != nil || c.Userpass.Username != nil
+}
+
+func (c VaultClient) validateLocalities() error {
+	addrs := map[string]bool{optionalURLString(c.Vault): true}
+	if c.ReadReplica != nil {
+		addrs[c.ReadReplica.String()] = true
+	}
+	for _, r := range c.ReadReplicas {
+		addrs[r.String()] = true
+	}
+	for addr, locality := range c.Localities {
+		if !addrs[addr] || locality == "" {
+			return errors.New(InvalidLocalities)
+		}
+	}
 	return nil
 }
 
@@ -42,7 +477,57 @@ func (c VaultClientAuthentication) validate() error {
 		roleIdIsSet      = c.RoleId.IsSet()
 		secretIdIsSet    = c.SecretId.IsSet()
 		approlePathIsSet = !(c.ApprolePath == "")
+		kubernetesIsSet  = !(c.Kubernetes.Role == "")
+		azureIsSet       = !(c.Azure.Role == "")
+		certIsSet        = !(c.Cert.Role == "")
+		ldapIsSet        = c.Ldap.Username != nil
+		userpassIsSet    = c.Userpass.Username != nil
 	)
+	if c.SecretIdWrapped && !secretIdIsSet {
+		return errors.New(InvalidAuthentication)
+	}
+	if rotator := c.SecretIdRotator; rotator.Token != nil || rotator.Role != "" {
+		if rotator.Token == nil || !rotator.Token.IsSet() || rotator.Token.Get() == "" || rotator.Role == "" || !secretIdIsSet {
+			return errors.New(InvalidSecretIdRotator)
+		}
+	}
+	if ldapIsSet || userpassIsSet {
+		if tokenIsSet || roleIdIsSet || secretIdIsSet || approlePathIsSet || kubernetesIsSet || azureIsSet || certIsSet || (ldapIsSet && userpassIsSet) {
+			return errors.New(InvalidAuthentication)
+		}
+		up := c.Ldap
+		if userpassIsSet {
+			up = c.Userpass
+		}
+		if !up.Username.IsSet() || up.Password == nil || !up.Password.IsSet() {
+			return errors.New(InvalidAuthentication)
+		}
+		return nil
+	}
+	if certIsSet {
+		if tokenIsSet || roleIdIsSet || secretIdIsSet || approlePathIsSet || kubernetesIsSet || azureIsSet {
+			return errors.New(InvalidAuthentication)
+		}
+		return nil
+	}
+	if azureIsSet {
+		if tokenIsSet || roleIdIsSet || secretIdIsSet || approlePathIsSet || kubernetesIsSet {
+			return errors.New(InvalidAuthentication)
+		}
+		if u, err := url.Parse(c.Azure.Resource); err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New(InvalidAzureResource)
+		}
+		return nil
+	}
+	if kubernetesIsSet {
+		if tokenIsSet || roleIdIsSet || secretIdIsSet || approlePathIsSet {
+			return errors.New(InvalidAuthentication)
+		}
+		if c.Kubernetes.ServiceAccountToken == nil || !isValidAbsFileUrl(c.Kubernetes.ServiceAccountToken) {
+			return errors.New(InvalidServiceAccountToken)
+		}
+		return nil
+	}
 	if !tokenIsSet && roleIdIsSet && secretIdIsSet && approlePathIsSet {
 		return nil
 	}
@@ -56,12 +541,68 @@ func (c VaultClientTLS) validate() error {
 	if c.CaCert == nil || (c.CaCert.String() != "" && !isValidAbsFileUrl(c.CaCert)) {
 		return errors.New(InvalidCaCert)
 	}
+	if c.CaCertDir != nil && !isValidAbsFileUrl(c.CaCertDir) {
+		return errors.New(InvalidCaCertDir)
+	}
 	if c.ClientCert == nil || (c.ClientCert.String() != "" && !isValidAbsFileUrl(c.ClientCert)) {
 		return errors.New(InvalidClientCert)
 	}
 	if c.ClientKey == nil || (c.ClientKey.String() != "" && !isValidAbsFileUrl(c.ClientKey)) {
 		return errors.New(InvalidClientKey)
 	}
+	for _, p := range c.Pins {
+		if b, err := hex.DecodeString(p); err != nil || len(b) != sha256.Size {
+			return errors.New(InvalidPin)
+		}
+	}
+	return nil
+}
+
+func (c VaultClientAccountStore) validate() error {
+	if c.Type == "" {
+		return nil
+	}
+	if c.Type != AccountStoreConsul && c.Type != AccountStoreEtcd {
+		return errors.New(InvalidAccountStore)
+	}
+	if c.Address == nil || !isHTTPUrl(c.Address) || strings.Trim(c.Prefix, "/") == "" || c.PollInterval < 0 {
+		return errors.New(InvalidAccountStore)
+	}
+	if c.Token != nil && !c.Token.IsSet() {
+		return errors.New(InvalidAccountStore)
+	}
+	return nil
+}
+
+func (c VaultClientTokenSink) validate(stateDirectory *url.URL) error {
+	if c.Key == nil {
+		return nil
+	}
+	if stateDirectory == nil || !c.Key.IsSet() || c.Key.Get() == "" {
+		return errors.New(InvalidTokenSink)
+	}
+	return nil
+}
+
+func (c VaultClientUnlockTOTP) validate() error {
+	if len(c.Keys) == 0 {
+		return nil
+	}
+	if c.Engine == "" {
+		return errors.New(InvalidUnlockTOTP)
+	}
+	for addr, key := range c.Keys {
+		if b, err := hex.DecodeString(strings.TrimPrefix(addr, "0x")); err != nil || len(b) != 20 || key == "" {
+			return errors.New(InvalidUnlockTOTP)
+		}
+	}
+	return nil
+}
+
+func (c VaultClientQuota) validate() error {
+	if c.PerHour < 0 || c.PerDay < 0 {
+		return errors.New(InvalidNewAccountQuota)
+	}
 	return nil
 }
 
@@ -69,9 +610,21 @@ func (c NewAccount) Validate() error {
 	if c.SecretName == "" {
 		return errors.New(InvalidSecretName)
 	}
+	if !isValidSecretName(c.SecretName) {
+		return errors.New(InvalidSecretNamePath)
+	}
 	if err := c.OverwriteProtection.validate(); err != nil {
 		return err
 	}
+	switch c.Role {
+	case "", AccountRoleValidator:
+	case AccountRoleTransaction, AccountRoleFaucet:
+		if c.Sealer {
+			return errors.New(InvalidAccountRole)
+		}
+	default:
+		return errors.New(InvalidAccountRole)
+	}
 	return nil
 }
 
@@ -82,6 +635,45 @@ func (c OverwriteProtection) validate() error {
 	return nil
 }
 
+func (c VaultClientDebug) validate() error {
+	if c.Address == "" {
+		return nil
+	}
+	if err := CheckLoopbackAddress(c.Address); err != nil {
+		return errors.New(InvalidDebugAddress)
+	}
+	return nil
+}
+
+// isValidSecretName returns false if the secret name could escape the KV engine or alter the Vault request URL, e.g.
+// ../../sys/policy or name?version=1
+func isValidSecretName(name string) bool {
+	if strings.ContainsAny(name, "?#%\\") {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
 func isValidAbsFileUrl(u *url.URL) bool {
 	return u.Scheme == "file" && u.Host == "" && u.Path != ""
 }
+
+func isHTTPUrl(u *url.URL) bool {
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && isValidHost(u.Host)
+}
+
+// isValidHost returns false for an IPv6 literal that is not in brackets, e.g. ::1:8200, as the port cannot be
+// distinguished from the address
+func isValidHost(host string) bool {
+	return strings.Count(host, ":") < 2 || strings.HasPrefix(host, "[")
+}
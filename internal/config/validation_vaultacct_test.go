@@ -34,6 +34,22 @@ func TestNewAccount_Validate_SecretName_Invalid(t *testing.T) {
 	require.EqualError(t, err, wantErr)
 }
 
+func TestNewAccount_Validate_SecretName_Path(t *testing.T) {
+	valid := []string{"acct", "team/acct", "team/acct-1.v2", "team_a/acct@node"}
+	for _, name := range valid {
+		conf := minimumValidNewAccountConfig()
+		conf.SecretName = name
+		require.NoError(t, conf.Validate(), name)
+	}
+
+	invalid := []string{"../sys/policy/root", "team/../../sys", "./acct", "/acct", "acct/", "team//acct", "acct?version=1", "acct#1", "acct%2F..", "team\\acct", "my acct", "acct\n"}
+	for _, name := range invalid {
+		conf := minimumValidNewAccountConfig()
+		conf.SecretName = name
+		require.EqualError(t, conf.Validate(), InvalidSecretNamePath, name)
+	}
+}
+
 func TestNewAccount_Validate_OverwriteProtection_Valid(t *testing.T) {
 	var (
 		conf NewAccount
@@ -72,3 +88,27 @@ func TestNewAccount_Validate_OverwriteProtection_Invalid(t *testing.T) {
 	err = conf.Validate()
 	require.EqualError(t, err, wantErr)
 }
+
+func TestNewAccount_Validate_Role_Valid(t *testing.T) {
+	for _, role := range []string{"", AccountRoleValidator, AccountRoleTransaction, AccountRoleFaucet} {
+		conf := minimumValidNewAccountConfig()
+		conf.Role = role
+		require.NoError(t, conf.Validate(), role)
+	}
+
+	conf := minimumValidNewAccountConfig()
+	conf.Sealer = true
+	conf.Role = AccountRoleValidator
+	require.NoError(t, conf.Validate())
+}
+
+func TestNewAccount_Validate_Role_Invalid(t *testing.T) {
+	conf := minimumValidNewAccountConfig()
+	conf.Role = "sealer"
+	require.EqualError(t, conf.Validate(), InvalidAccountRole)
+
+	conf = minimumValidNewAccountConfig()
+	conf.Sealer = true
+	conf.Role = AccountRoleTransaction
+	require.EqualError(t, conf.Validate(), InvalidAccountRole)
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
 	"github.com/stretchr/testify/require"
@@ -59,6 +63,8 @@ func TestVaultClient_Validate_VaultUrl_Valid(t *testing.T) {
 		"http://vault",
 		"https://vault:1111",
 		"http://127.0.0.1:1111",
+		"https://[::1]:1111",
+		"https://[fe80::1%25eth0]:1111",
 	}
 	for _, u := range vaultUrls {
 		t.Run(u, func(t *testing.T) {
@@ -80,6 +86,7 @@ func TestVaultClient_Validate_VaultUrl_Invalid(t *testing.T) {
 	vaultUrls := []string{
 		"",
 		"noscheme",
+		"https://::1:1111",
 	}
 	for _, u := range vaultUrls {
 		t.Run(u, func(t *testing.T) {
@@ -105,6 +112,64 @@ func TestVaultClient_Validate_KVEngineName_Invalid(t *testing.T) {
 	require.EqualError(t, gotErr, wantErrMsg)
 }
 
+func TestVaultClient_Validate_SecretsEngine(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.SecretsEngine = SecretsEngineKV
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.SecretsEngine = SecretsEngineCubbyhole
+	vaultClient.KVEngineName = ""
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "secretsEngine must be one of kv or cubbyhole, and cubbyhole cannot be used with kvEngineName or drSecondary"
+
+	vaultClient.KVEngineName = "engine"
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	vaultClient.KVEngineName = ""
+	vaultClient.DRSecondary, _ = url.Parse("http://dr:1111")
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	vaultClient.DRSecondary = nil
+	vaultClient.SecretsEngine = "transit"
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+}
+
+func TestVaultClient_Validate_Dev(t *testing.T) {
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Dev = true
+	vaultClient.Vault = nil
+	vaultClient.KVEngineName = ""
+	vaultClient.Authentication = VaultClientAuthentication{}
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "dev cannot be used with vault, kvEngineName, secretsEngine, authentication, drSecondary, readReplica(s), locality, localities, unlockTOTP, mirror, healthProbe or tokenSink"
+
+	withVault := vaultClient
+	withVault.Vault, _ = url.Parse("http://vault:1111")
+	require.EqualError(t, withVault.Validate(), wantErrMsg)
+
+	withAuth := vaultClient
+	withAuth.Authentication.Token = envVar(t, "env://TOKEN")
+	require.EqualError(t, withAuth.Validate(), wantErrMsg)
+
+	withEngine := vaultClient
+	withEngine.SecretsEngine = SecretsEngineCubbyhole
+	require.EqualError(t, withEngine.Validate(), wantErrMsg)
+
+	withProbe := vaultClient
+	withProbe.HealthProbe.Interval = time.Second
+	require.EqualError(t, withProbe.Validate(), wantErrMsg)
+
+	// an account directory or store is still required
+	vaultClient.AccountDirectory = nil
+	require.EqualError(t, vaultClient.Validate(), "accountDirectory must be a valid absolute file url")
+}
+
 func TestVaultClient_Validate_AccountDirectory_Valid(t *testing.T) {
 	defer testutil.UnsetAll()
 	testutil.SetRoleID()
@@ -217,7 +282,7 @@ func TestVaultClient_Validate_Authentication_Valid(t *testing.T) {
 }
 
 func TestVaultClient_Validate_Authentication_Invalid(t *testing.T) {
-	wantErrMsg := "authentication must contain roleId, secretId and approlePath OR only token, and the given environment variables must be set"
+	wantErrMsg := "authentication must contain roleId, secretId and approlePath OR only token OR only kubernetes OR only azure OR only cert OR only ldap OR only userpass, and the given environment variables must be set"
 
 	var auths = map[string]struct {
 		tokenUrl    string
@@ -461,3 +526,908 @@ func TestVaultClient_Validate_TLS_Invalid(t *testing.T) {
 		})
 	}
 }
+
+func TestVaultClient_Validate_NewAccountQuota_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	quotas := map[string]VaultClientQuota{
+		"negative_perHour": {PerHour: -1},
+		"negative_perDay":  {PerDay: -1},
+	}
+
+	for name, q := range quotas {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.NewAccountQuota = q
+
+			gotErr := vaultClient.Validate()
+
+			require.EqualError(t, gotErr, "newAccountQuota perHour and perDay cannot be negative")
+		})
+	}
+}
+
+func TestVaultClient_Validate_DRSecondary_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.DRSecondary, _ = url.Parse("vault-dr:8200")
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "drSecondary must be a valid HTTP/HTTPS url")
+}
+
+func TestVaultClient_Validate_ReadReplica_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.ReadReplica, _ = url.Parse("file:///vault-standby")
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "readReplica and readReplicas must be valid HTTP/HTTPS urls")
+
+	vaultClient.ReadReplica = nil
+	standby, _ := url.Parse("https://vault-standby:8200")
+	invalid, _ := url.Parse("vault-standby-2")
+	vaultClient.ReadReplicas = []*url.URL{standby, invalid}
+
+	require.EqualError(t, vaultClient.Validate(), "readReplica and readReplicas must be valid HTTP/HTTPS urls")
+}
+
+func TestVaultClient_Validate_RPCTimeout_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.RPCTimeout = -1
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "rpcTimeout cannot be negative")
+}
+
+func TestVaultClient_Validate_ReadCacheSize_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.ReadCacheSize = -1
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "readCacheSize and readCacheMaxBytes cannot be negative")
+}
+
+func TestVaultClient_Validate_Debug_Valid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	for _, addr := range []string{"", "localhost:6060", "127.0.0.1:6060", "[::1]:6060"} {
+		t.Run(addr, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Debug.Address = addr
+
+			require.NoError(t, vaultClient.Validate())
+		})
+	}
+}
+
+func TestVaultClient_Validate_Debug_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	for _, addr := range []string{"localhost", ":6060", "0.0.0.0:6060", "10.0.0.1:6060", "node1:6060"} {
+		t.Run(addr, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Debug.Address = addr
+
+			gotErr := vaultClient.Validate()
+
+			require.EqualError(t, gotErr, "debug address must be a loopback host:port, e.g. localhost:6060")
+		})
+	}
+}
+
+func TestVaultClient_Validate_MaxConcurrentRequests_Negative(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.MaxConcurrentRequests = -1
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "maxConcurrentRequests cannot be negative")
+}
+
+func TestVaultClient_Validate_DuplicateSignWindow_Negative(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.DuplicateSignWindow = -1
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "duplicateSignWindow cannot be negative")
+}
+
+func TestVaultClient_Validate_SigningLatencySLO(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.SigningLatencySLO = VaultClientSigningLatencySLO{Threshold: time.Second, Percentile: 99.9, Window: 100}
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "signingLatencySLO threshold and window cannot be negative, and percentile must be between 0 and 100"
+
+	invalid := []VaultClientSigningLatencySLO{
+		{Threshold: -1},
+		{Threshold: time.Second, Window: -1},
+		{Threshold: time.Second, Percentile: -1},
+		{Threshold: time.Second, Percentile: 101},
+	}
+	for _, slo := range invalid {
+		vaultClient.SigningLatencySLO = slo
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, slo)
+	}
+}
+
+func TestVaultClient_Validate_Headers(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.NodeID = "node1"
+	vaultClient.Headers = map[string]string{"X-Tenant": "quorum", "User-Agent": "my-agent"}
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "headers must have valid HTTP header names and values and cannot set X-Vault-* headers, and nodeId cannot contain control characters"
+
+	invalid := []map[string]string{
+		{"": "quorum"},
+		{"X Tenant": "quorum"},
+		{"X-Tenant:": "quorum"},
+		{"X-Tenant": "quorum\r\nX-Injected: true"},
+		{"X-Vault-Token": "mytoken"},
+		{"x-vault-namespace": "ns1"},
+	}
+	for _, h := range invalid {
+		vaultClient.Headers = h
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, h)
+	}
+
+	vaultClient.Headers = nil
+	vaultClient.NodeID = "node1\n"
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+}
+
+func TestVaultClient_Validate_HealthProbe_Negative(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.HealthProbe.FailureThreshold = -1
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "healthProbe interval and failureThreshold cannot be negative")
+}
+
+func TestVaultClient_Validate_DNSRefreshInterval_Negative(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.DNSRefreshInterval = -time.Second
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "dnsRefreshInterval cannot be negative")
+}
+
+func TestVaultClient_Validate_TLSPins(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.TLS.Pins = []string{"6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b"}
+	require.NoError(t, vaultClient.Validate())
+
+	for _, pin := range []string{"6b86b273", "not hex", "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b00"} {
+		t.Run(pin, func(t *testing.T) {
+			vaultClient.TLS.Pins = []string{pin}
+
+			gotErr := vaultClient.Validate()
+
+			require.EqualError(t, gotErr, "tls pins must be hex-encoded SHA-256 fingerprints")
+		})
+	}
+}
+
+func TestVaultClient_Validate_CaCertDir_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.TLS.CaCertDir, _ = url.Parse("/path/to/cas")
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "caCertDir must be a valid absolute file url")
+}
+
+func TestVaultClient_Validate_StateDirectory_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.StateDirectory, _ = url.Parse("relative/path")
+
+	gotErr := vaultClient.Validate()
+
+	require.EqualError(t, gotErr, "stateDirectory must be a valid absolute file url")
+}
+
+func TestVaultClient_Validate_TokenSink(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.TokenSink.Key = envVar(t, "env://"+testutil.MY_SECRET_ID)
+
+	require.EqualError(t, vaultClient.Validate(), "tokenSink key must be an env url for a set environment variable, and stateDirectory must be set")
+
+	vaultClient.StateDirectory, _ = url.Parse("file:///path/to/state")
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.TokenSink.Key = envVar(t, "env://NOT_SET")
+	require.EqualError(t, vaultClient.Validate(), "tokenSink key must be an env url for a set environment variable, and stateDirectory must be set")
+}
+
+func TestVaultClient_Validate_Pepper(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer os.Unsetenv("VALIDATION_TEST_PEPPER")
+
+	vaultClient := minimumValidClientConfig(t)
+	wantErrMsg := "pepper must be an env or file url for a set value of at least 16 characters"
+
+	vaultClient.Pepper = envVar(t, "env://VALIDATION_TEST_PEPPER")
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	require.NoError(t, os.Setenv("VALIDATION_TEST_PEPPER", "tooshort"))
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	require.NoError(t, os.Setenv("VALIDATION_TEST_PEPPER", "0123456789abcdef"))
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.Pepper = envVar(t, "http://pepper:8200")
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+}
+
+func TestVaultClient_Validate_SignGrants(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer os.Unsetenv("VALIDATION_TEST_GRANT_KEY")
+
+	vaultClient := minimumValidClientConfig(t)
+	wantErrMsg := "signGrants key must be an env or file url for a set value of at least 32 characters, and accounts must be account addresses"
+
+	vaultClient.SignGrants.Accounts = []string{"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5"}
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	vaultClient.SignGrants.Key = envVar(t, "env://VALIDATION_TEST_GRANT_KEY")
+	require.NoError(t, os.Setenv("VALIDATION_TEST_GRANT_KEY", "0123456789abcdef"))
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	require.NoError(t, os.Setenv("VALIDATION_TEST_GRANT_KEY", "0123456789abcdef0123456789abcdef"))
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.SignGrants.Accounts = append(vaultClient.SignGrants.Accounts, "0x4d6d")
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+}
+
+func TestVaultClient_Validate_CredentialFilePermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(path, []byte("mytoken"), 0600))
+
+	var unset EnvironmentVariable
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication = VaultClientAuthentication{
+		Token:    envVar(t, "file://"+path),
+		RoleId:   &unset,
+		SecretId: &unset,
+	}
+	require.NoError(t, vaultClient.Validate())
+
+	// mounted secrets can be shared with the container's group
+	require.NoError(t, os.Chmod(path, 0640))
+	require.NoError(t, vaultClient.Validate())
+
+	require.NoError(t, os.Chmod(path, 0644))
+	require.EqualError(t, vaultClient.Validate(), "credential files must be absolute file urls of regular files that other users cannot read or write: "+path+" has mode -rw-r--r--")
+
+	require.NoError(t, os.Chmod(path, 0600))
+	vaultClient.Authentication.Token = envVar(t, "file://"+dir)
+	require.EqualError(t, vaultClient.Validate(), "credential files must be absolute file urls of regular files that other users cannot read or write: "+dir+" has mode drwx------")
+
+	// not checked on Windows, where mode bits are not used
+	require.NoError(t, os.Chmod(path, 0644))
+	vaultClient.Authentication.Token = envVar(t, "file://"+path)
+	require.NoError(t, vaultClient.validateCredentialFiles("windows"))
+}
+
+func TestVaultClient_Validate_Kubernetes(t *testing.T) {
+	var unset EnvironmentVariable
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication = VaultClientAuthentication{
+		Token:    &unset,
+		RoleId:   &unset,
+		SecretId: &unset,
+		Kubernetes: VaultClientKubernetes{
+			Role: "quorum",
+			Path: "kubernetes",
+		},
+	}
+	vaultClient.Authentication.Kubernetes.ServiceAccountToken, _ = url.Parse("file:///path/to/token")
+
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.Authentication.Kubernetes.ServiceAccountToken, _ = url.Parse("relative/token")
+	require.EqualError(t, vaultClient.Validate(), "kubernetes serviceAccountToken must be a valid absolute file url")
+
+	vaultClient.Authentication.Kubernetes.ServiceAccountToken, _ = url.Parse("file:///path/to/token")
+	vaultClient.Authentication.ApprolePath = "myapprole"
+	require.EqualError(t, vaultClient.Validate(), "authentication must contain roleId, secretId and approlePath OR only token OR only kubernetes OR only azure OR only cert OR only ldap OR only userpass, and the given environment variables must be set")
+}
+
+func TestVaultClient_Validate_Azure(t *testing.T) {
+	var unset EnvironmentVariable
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication = VaultClientAuthentication{
+		Token:    &unset,
+		RoleId:   &unset,
+		SecretId: &unset,
+		Azure: VaultClientAzure{
+			Role:     "quorum",
+			Path:     "azure",
+			Resource: "https://management.azure.com/",
+		},
+	}
+
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.Authentication.Azure.Resource = "management.azure.com"
+	require.EqualError(t, vaultClient.Validate(), "azure resource must be a valid url")
+
+	vaultClient.Authentication.Azure.Resource = "https://management.azure.com/"
+	vaultClient.Authentication.Kubernetes.Role = "quorum"
+	require.EqualError(t, vaultClient.Validate(), "authentication must contain roleId, secretId and approlePath OR only token OR only kubernetes OR only azure OR only cert OR only ldap OR only userpass, and the given environment variables must be set")
+}
+
+func TestVaultClient_Validate_Cert(t *testing.T) {
+	var unset EnvironmentVariable
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication = VaultClientAuthentication{
+		Token:    &unset,
+		RoleId:   &unset,
+		SecretId: &unset,
+		Cert:     VaultClientCert{Role: "quorum", Path: "cert"},
+	}
+
+	// the client certificate and key are used to login
+	require.EqualError(t, vaultClient.Validate(), "cert authentication requires tls clientCert and clientKey to be set")
+
+	vaultClient.TLS.ClientCert, _ = url.Parse("file:///path/to/client.pem")
+	require.EqualError(t, vaultClient.Validate(), "cert authentication requires tls clientCert and clientKey to be set")
+
+	vaultClient.TLS.ClientKey, _ = url.Parse("file:///path/to/client.key")
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.Authentication.Azure.Role = "quorum"
+	require.EqualError(t, vaultClient.Validate(), "authentication must contain roleId, secretId and approlePath OR only token OR only kubernetes OR only azure OR only cert OR only ldap OR only userpass, and the given environment variables must be set")
+}
+
+func TestVaultClient_Validate_Userpass(t *testing.T) {
+	require.NoError(t, os.Setenv("VALIDATE_TEST_USERNAME", "quorum"))
+	defer os.Unsetenv("VALIDATE_TEST_USERNAME")
+
+	var unset EnvironmentVariable
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication = VaultClientAuthentication{
+		Token:    &unset,
+		RoleId:   &unset,
+		SecretId: &unset,
+		Ldap: VaultClientUserpass{
+			Username: envVar(t, "env://VALIDATE_TEST_USERNAME"),
+			Password: envVar(t, "env://VALIDATE_TEST_PASSWORD"),
+			Path:     "ldap",
+		},
+	}
+	wantErrMsg := "authentication must contain roleId, secretId and approlePath OR only token OR only kubernetes OR only azure OR only cert OR only ldap OR only userpass, and the given environment variables must be set"
+
+	// the password env var is not set
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	require.NoError(t, os.Setenv("VALIDATE_TEST_PASSWORD", "password"))
+	defer os.Unsetenv("VALIDATE_TEST_PASSWORD")
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.Authentication.Userpass = vaultClient.Authentication.Ldap
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	vaultClient.Authentication.Ldap = VaultClientUserpass{}
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.Authentication.ApprolePath = "myapprole"
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+}
+
+func TestVaultClient_Validate_SecretIdWrapped(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication.SecretIdWrapped = true
+	require.NoError(t, vaultClient.Validate())
+
+	testutil.UnsetAll()
+	testutil.SetToken()
+	var unset EnvironmentVariable
+	vaultClient.Authentication.RoleId = &unset
+	vaultClient.Authentication.SecretId = &unset
+	vaultClient.Authentication.ApprolePath = ""
+	require.EqualError(t, vaultClient.Validate(), "authentication must contain roleId, secretId and approlePath OR only token OR only kubernetes OR only azure OR only cert OR only ldap OR only userpass, and the given environment variables must be set")
+}
+
+func TestVaultClient_Validate_AccountOrder(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	for _, order := range []string{"", "url", "address", "created"} {
+		vaultClient.AccountOrder = order
+		require.NoError(t, vaultClient.Validate(), order)
+	}
+
+	vaultClient.AccountOrder = "random"
+	require.EqualError(t, vaultClient.Validate(), "accountOrder must be one of url, address or created")
+}
+
+func TestVaultClient_Validate_AccountID(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	for _, id := range []string{"", "random", "deterministic"} {
+		vaultClient.AccountID = id
+		require.NoError(t, vaultClient.Validate(), id)
+	}
+
+	vaultClient.AccountID = "sequential"
+	require.EqualError(t, vaultClient.Validate(), "accountId must be one of random or deterministic")
+}
+
+func TestVaultClient_Validate_AddressMismatch(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	for _, policy := range []string{"", "fail", "trustVault", "trustConfig+alert"} {
+		vaultClient.AddressMismatch = policy
+		require.NoError(t, vaultClient.Validate(), policy)
+	}
+
+	vaultClient.AddressMismatch = "trustConfig"
+	require.EqualError(t, vaultClient.Validate(), "addressMismatch must be one of fail, trustVault or trustConfig+alert")
+}
+
+func TestVaultClient_Validate_SecretIdRotator(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	testutil.SetToken()
+	wantErrMsg := "secretIdRotator must contain token and role, can only be used with approle authentication, and the given environment variable must be set"
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication.SecretIdRotator = VaultClientSecretIdRotator{Token: envVar(t, "env://"+testutil.MY_TOKEN), Role: "quorum"}
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.Authentication.SecretIdRotator.Role = ""
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	vaultClient.Authentication.SecretIdRotator = VaultClientSecretIdRotator{Role: "quorum"}
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	vaultClient.Authentication.SecretIdRotator = VaultClientSecretIdRotator{Token: envVar(t, "env://UNSET_ROTATOR_TOKEN"), Role: "quorum"}
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	// only approle secret_ids can be rotated
+	var unset EnvironmentVariable
+	vaultClient.Authentication.SecretIdRotator = VaultClientSecretIdRotator{Token: envVar(t, "env://"+testutil.MY_TOKEN), Role: "quorum"}
+	vaultClient.Authentication.RoleId = &unset
+	vaultClient.Authentication.SecretId = &unset
+	vaultClient.Authentication.ApprolePath = ""
+	vaultClient.Authentication.Token = envVar(t, "env://"+testutil.MY_TOKEN)
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+}
+
+func TestVaultClient_Validate_StandbyTokens(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	wantErrMsg := "standbyTokens must be between 0 and 5, and cannot be used with token authentication or dev"
+
+	vaultClient := minimumValidClientConfig(t)
+	for _, n := range []int{0, 1, 5} {
+		vaultClient.StandbyTokens = n
+		require.NoError(t, vaultClient.Validate(), n)
+	}
+	for _, n := range []int{-1, 6} {
+		vaultClient.StandbyTokens = n
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, n)
+	}
+
+	testutil.UnsetAll()
+	testutil.SetToken()
+	var unset EnvironmentVariable
+	vaultClient.Authentication.RoleId = &unset
+	vaultClient.Authentication.SecretId = &unset
+	vaultClient.Authentication.ApprolePath = ""
+	vaultClient.Authentication.Token = envVar(t, "env://"+testutil.MY_TOKEN)
+	vaultClient.StandbyTokens = 0
+	require.NoError(t, vaultClient.Validate())
+	vaultClient.StandbyTokens = 1
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+}
+
+func TestVaultClient_Validate_RequestNamespaces(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	wantErrMsg := "requestNamespaces allowed must be relative namespace paths without empty, . or .. segments or whitespace, peers must be localhost or CIDRs and requires allowed, and requestNamespaces cannot be used with dev or the cubbyhole secretsEngine"
+
+	vaultClient := minimumValidClientConfig(t)
+	valid := []VaultClientRequestNamespaces{
+		{},
+		{Allowed: []string{"tenant1", "tenant2/team"}},
+		{Allowed: []string{"tenant1"}, Peers: []string{"localhost", "10.0.0.0/8"}},
+	}
+	for _, n := range valid {
+		vaultClient.RequestNamespaces = n
+		require.NoError(t, vaultClient.Validate(), n)
+	}
+
+	invalid := []VaultClientRequestNamespaces{
+		{Peers: []string{"localhost"}},
+		{Allowed: []string{""}},
+		{Allowed: []string{"/tenant1"}},
+		{Allowed: []string{"tenant1/"}},
+		{Allowed: []string{"tenant1/../root"}},
+		{Allowed: []string{"tenant 1"}},
+		{Allowed: []string{"tenant1"}, Peers: []string{""}},
+		{Allowed: []string{"tenant1"}, Peers: []string{"node1"}},
+	}
+	for _, n := range invalid {
+		vaultClient.RequestNamespaces = n
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, n)
+	}
+
+	vaultClient.RequestNamespaces = VaultClientRequestNamespaces{Allowed: []string{"tenant1"}}
+	vaultClient.SecretsEngine = SecretsEngineCubbyhole
+	vaultClient.KVEngineName = ""
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+}
+
+func TestVaultClient_Validate_SignPayloads(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	wantErrMsg := "signPayloads maxBytes and maxCalldataBytes cannot be negative, and selectors must map account addresses, each listed once, to 4-byte hex function selectors, e.g. 0xa9059cbb"
+	addr := "0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5"
+
+	vaultClient := minimumValidClientConfig(t)
+	valid := []VaultClientSignPayloads{
+		{},
+		{MaxBytes: 131072, MaxCalldataBytes: 65536},
+		{Selectors: map[string][]string{addr: {"0xa9059cbb", "0x095EA7B3"}}},
+	}
+	for _, p := range valid {
+		vaultClient.SignPayloads = p
+		require.NoError(t, vaultClient.Validate(), p)
+	}
+
+	invalid := []VaultClientSignPayloads{
+		{MaxBytes: -1},
+		{MaxCalldataBytes: -1},
+		{Selectors: map[string][]string{"notanaddress": {"0xa9059cbb"}}},
+		{Selectors: map[string][]string{addr: {}}},
+		{Selectors: map[string][]string{addr: {"a9059cbb"}}},
+		{Selectors: map[string][]string{addr: {"0xa9059c"}}},
+		{Selectors: map[string][]string{addr: {"0xa9059cbbcc"}}},
+		{Selectors: map[string][]string{addr: {"0xa9059cbb"}, "4D6D744B6DA435B5BBDDE2526DC20E9A41CB72E5": {"0xa9059cbb"}}},
+	}
+	for _, p := range invalid {
+		vaultClient.SignPayloads = p
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, p)
+	}
+}
+
+func TestVaultClient_Validate_UnlockTOTP(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.UnlockTOTP = VaultClientUnlockTOTP{
+		Engine: "totp",
+		Keys:   map[string]string{"0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526": "acct1"},
+	}
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "unlockTotp engine must be set and keys must map account addresses to TOTP key names"
+
+	invalid := []VaultClientUnlockTOTP{
+		{Keys: map[string]string{"dc99ddec13457de6c0f6bb8e6cf3955c86f55526": "acct1"}},
+		{Engine: "totp", Keys: map[string]string{"notanaddress": "acct1"}},
+		{Engine: "totp", Keys: map[string]string{"dc99ddec13457de6c0f6bb8e6cf3955c86f55526": ""}},
+	}
+	for _, totp := range invalid {
+		vaultClient.UnlockTOTP = totp
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+	}
+}
+
+func TestVaultClient_Validate_Mirror(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Mirror = VaultClientMirror{Node: "node2", PromotionSecret: "signer"}
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "mirror node and promotionSecret must both be set, and pollInterval cannot be negative"
+
+	invalid := []VaultClientMirror{
+		{Node: "node2"},
+		{PromotionSecret: "signer"},
+		{Node: "node2", PromotionSecret: "signer", PollInterval: -1},
+	}
+	for _, m := range invalid {
+		vaultClient.Mirror = m
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, m)
+	}
+}
+
+func TestVaultClient_Validate_EscrowPublicKey(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Escrow.PublicKey = &url.URL{Scheme: "file", Path: "/path/to/escrow.pem"}
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.Escrow.PublicKey = &url.URL{Scheme: "file", Opaque: "escrow.pem"}
+	require.EqualError(t, vaultClient.Validate(), "escrow publicKey must be a valid absolute file url")
+}
+
+func TestVaultClient_Validate_AllowedPeers(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Permissions.AllowedPeers = []string{"localhost", "10.0.0.0/8", "fd00::/8"}
+	require.NoError(t, vaultClient.Validate())
+
+	for _, p := range []string{"", "10.0.0.1", "127.0.0.0/33", "quorum-node"} {
+		vaultClient.Permissions.AllowedPeers = []string{p}
+		require.EqualError(t, vaultClient.Validate(), "permissions allowedPeers must be localhost or CIDRs, e.g. 10.0.0.0/8", p)
+	}
+}
+
+func TestVaultClient_Validate_QuorumPermissioning(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	rpc := &url.URL{Scheme: "http", Host: "localhost:8545"}
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.QuorumPermissioning = VaultClientQuorumPermissioning{RPC: rpc, AccountManager: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34"}
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "quorumPermissioning rpc must be a valid HTTP/HTTPS url, accountManager must be a hex-encoded contract address, and cacheTTL cannot be negative"
+
+	invalid := []VaultClientQuorumPermissioning{
+		{AccountManager: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34"},
+		{RPC: &url.URL{Scheme: "file", Path: "/rpc"}, AccountManager: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34"},
+		{RPC: rpc},
+		{RPC: rpc, AccountManager: "0x1932c48b"},
+		{RPC: rpc, AccountManager: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", CacheTTL: -1},
+	}
+	for _, p := range invalid {
+		vaultClient.QuorumPermissioning = p
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, p)
+	}
+}
+
+func TestVaultClient_Validate_UsageReport(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	file := &url.URL{Scheme: "file", Path: "/path/to/usage.csv"}
+	endpoint := &url.URL{Scheme: "https", Host: "reports.example.com", Path: "/usage"}
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.UsageReport = VaultClientUsageReport{Interval: time.Hour, File: file, Format: UsageReportFormatCSV}
+	require.NoError(t, vaultClient.Validate())
+	vaultClient.UsageReport = VaultClientUsageReport{Interval: time.Hour, Endpoint: endpoint, Token: envVar(t, "env://"+testutil.MY_TOKEN)}
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "usageReport interval cannot be negative and requires file or endpoint, file must be an absolute file url, endpoint must be a valid HTTP/HTTPS url, format must be one of json or csv, and token requires endpoint"
+
+	invalid := []VaultClientUsageReport{
+		{Interval: -1, File: file},
+		{Interval: time.Hour},
+		{Interval: time.Hour, File: &url.URL{Scheme: "https", Host: "reports.example.com"}},
+		{Interval: time.Hour, File: file, Format: "xml"},
+		{Interval: time.Hour, Endpoint: &url.URL{Scheme: "file", Path: "/usage"}},
+		{Interval: time.Hour, File: file, Token: envVar(t, "env://"+testutil.MY_TOKEN)},
+	}
+	for _, r := range invalid {
+		vaultClient.UsageReport = r
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, r)
+	}
+}
+
+func TestVaultClient_Validate_AccountSettings(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	hour, negative := time.Hour, -time.Second
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.AccountDefaults = AccountSettings{MaxUnlockDuration: &hour}
+	vaultClient.AccountOverrides = map[string]AccountSettings{"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {}}
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "accountDefaults and accountOverrides maxUnlockDuration cannot be negative, and accountOverrides must be keyed by account addresses, each overridden once"
+
+	vaultClient.AccountDefaults = AccountSettings{MaxUnlockDuration: &negative}
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+	vaultClient.AccountDefaults = AccountSettings{}
+
+	invalid := []map[string]AccountSettings{
+		{"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {MaxUnlockDuration: &negative}},
+		{"myacct": {}},
+		{"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {}, "4D6D744B6DA435B5BBDDE2526DC20E9A41CB72E5": {}},
+	}
+	for _, o := range invalid {
+		vaultClient.AccountOverrides = o
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, o)
+	}
+}
+
+func TestVaultClient_Validate_Localities(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.ReadReplicas = []*url.URL{{Scheme: "https", Host: "replica"}}
+	vaultClient.Locality = "eu/eu-a"
+	vaultClient.Localities = map[string]string{
+		vaultClient.Vault.String(): "eu/eu-b",
+		"https://replica":          "eu/eu-a",
+	}
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "localities must map the vault, readReplica or readReplicas urls to a region/zone"
+
+	vaultClient.Localities = map[string]string{"https://unknown": "eu/eu-a"}
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+
+	vaultClient.Localities = map[string]string{"https://replica": ""}
+	require.EqualError(t, vaultClient.Validate(), wantErrMsg)
+}
+
+func TestVaultClient_Validate_MaxStaleness(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	for _, d := range []time.Duration{0, time.Minute} {
+		d := d
+		vaultClient.MaxStaleness = &d
+		require.NoError(t, vaultClient.Validate())
+	}
+
+	d := -time.Second
+	vaultClient.MaxStaleness = &d
+	require.EqualError(t, vaultClient.Validate(), "maxStaleness cannot be negative")
+}
+
+func TestVaultClient_Validate_AccountStore(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	consul, _ := url.Parse("http://consul:8500")
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.AccountDirectory = nil
+	vaultClient.AccountStore = VaultClientAccountStore{Type: AccountStoreConsul, Address: consul, Prefix: "quorum/accounts"}
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.AccountStore.Type = AccountStoreEtcd
+	require.NoError(t, vaultClient.Validate())
+
+	wantErrMsg := "accountStore type must be one of consul or etcd, address must be a valid HTTP/HTTPS url, prefix must be set, the given token environment variable must be set, and pollInterval cannot be negative"
+
+	fileURL, _ := url.Parse("file:///path/to/accounts")
+	unset := envVar(t, "env://ACCOUNT_STORE_TOKEN_NOT_SET")
+	invalid := []VaultClientAccountStore{
+		{Type: "zookeeper", Address: consul, Prefix: "quorum/accounts"},
+		{Type: AccountStoreConsul, Prefix: "quorum/accounts"},
+		{Type: AccountStoreConsul, Address: fileURL, Prefix: "quorum/accounts"},
+		{Type: AccountStoreConsul, Address: consul},
+		{Type: AccountStoreConsul, Address: consul, Prefix: "/"},
+		{Type: AccountStoreConsul, Address: consul, Prefix: "quorum/accounts", PollInterval: -1},
+		{Type: AccountStoreConsul, Address: consul, Prefix: "quorum/accounts", Token: unset},
+	}
+	for _, s := range invalid {
+		vaultClient.AccountStore = s
+		require.EqualError(t, vaultClient.Validate(), wantErrMsg, s)
+	}
+}
+
+func TestVaultClient_Validate_AccountStore_AccountDirectoryStillValidated(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	consul, _ := url.Parse("http://consul:8500")
+	notFile, _ := url.Parse("http://path/to/dir")
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.AccountDirectory = notFile
+	vaultClient.AccountStore = VaultClientAccountStore{Type: AccountStoreConsul, Address: consul, Prefix: "quorum/accounts"}
+	require.EqualError(t, vaultClient.Validate(), "accountDirectory must be a valid absolute file url")
+}
@@ -1,8 +1,18 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
+	"strings"
+	"time"
+)
+
+// Account roles restrict the signing requests an account's key can be used for
+const (
+	AccountRoleValidator   = "validator"
+	AccountRoleTransaction = "transaction"
+	AccountRoleFaucet      = "faucet"
 )
 
 type AccountFile struct {
@@ -11,14 +21,59 @@ type AccountFile struct {
 }
 
 type AccountFileJSON struct {
+	ID           string `json:",omitempty"` // a UUID identifying the account, empty for accounts created before IDs were added
 	Address      string
 	VaultAccount vaultAccountJSON
 	Version      int
+	Sealer       bool   `json:",omitempty"` // the account was created by a validator key ceremony for sealing blocks
+	Role         string `json:",omitempty"` // one of the AccountRole values, or empty if unrestricted
+	// Frozen is set while the account is frozen, e.g. while a suspected key compromise is investigated
+	Frozen *AccountFreeze `json:",omitempty"`
+}
+
+// AccountFreeze records why and when an account was frozen.  A frozen account is still listed, but cannot be unlocked
+// or sign.
+type AccountFreeze struct {
+	Reason string `json:",omitempty"`
+	Time   time.Time
+}
+
+// UnmarshalJSON normalizes the address so that account config files created by other tools, with a 0x-prefixed or
+// checksummed address, can be used
+func (c *AccountFileJSON) UnmarshalJSON(b []byte) error {
+	type accountFileJSON AccountFileJSON
+	var v accountFileJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	v.Address = NormalizeAddress(v.Address)
+	*c = AccountFileJSON(v)
+	return nil
+}
+
+// NormalizeAddress converts a hex address, with or without the 0x prefix and in any case (e.g. EIP-55 checksummed), to
+// the unprefixed lowercase hex written to account config files
+func NormalizeAddress(addr string) string {
+	if strings.HasPrefix(addr, "0x") || strings.HasPrefix(addr, "0X") {
+		addr = addr[2:]
+	}
+	return strings.ToLower(addr)
+}
+
+// AccountRole returns the role of the account.  Sealer accounts are validators unless another role is set.
+func (c *AccountFileJSON) AccountRole() string {
+	if c.Role == "" && c.Sealer {
+		return AccountRoleValidator
+	}
+	return c.Role
 }
 
 type vaultAccountJSON struct {
 	SecretName    string
 	SecretVersion int64
+	// SecretKey is the field of the secret holding the private key, so that related material can be kept in the same
+	// secret.  If empty the key is stored under the account's address and the secret can hold no other fields.
+	SecretKey string `json:",omitempty"`
 }
 
 func (c *AccountFileJSON) AccountURL(vaultURL, kvEngineName string) (*url.URL, error) {
@@ -36,6 +91,11 @@ func (c *AccountFileJSON) AccountURL(vaultURL, kvEngineName string) (*url.URL, e
 type NewAccount struct {
 	SecretName          string
 	OverwriteProtection OverwriteProtection
+	Sealer              bool
+	Role                string
+	// CreatedBy identifies who requested the account, written to the custom metadata of its secret.  It is set by the
+	// plugin from the request's gRPC metadata, never from the new account config.
+	CreatedBy string `json:"-"`
 }
 
 type OverwriteProtection struct {
@@ -47,12 +107,14 @@ func (c *NewAccount) AccountFile(path string, address string, secretVersion int6
 	return AccountFile{
 		Path: path,
 		Contents: AccountFileJSON{
-			Address: address,
+			Address: NormalizeAddress(address),
 			VaultAccount: vaultAccountJSON{
 				SecretName:    c.SecretName,
 				SecretVersion: secretVersion,
 			},
 			Version: 1,
+			Sealer:  c.Sealer,
+			Role:    c.Role,
 		},
 	}
 }
@@ -33,6 +33,21 @@ func TestNewAccount_UnmarshalJSON(t *testing.T) {
 	require.Equal(t, want, got)
 }
 
+func TestAccountFileJSON_AccountURL_IPv6(t *testing.T) {
+	conf := AccountFileJSON{
+		VaultAccount: vaultAccountJSON{
+			SecretName:    "path",
+			SecretVersion: 10,
+		},
+	}
+
+	got, err := conf.AccountURL("https://[::1]:8200", "engine")
+
+	require.NoError(t, err)
+	require.Equal(t, "https://[::1]:8200/v1/engine/data/path?version=10", got.String())
+	require.Equal(t, "::1", got.Hostname())
+}
+
 func TestAccountFileJSON_AccountURL(t *testing.T) {
 	conf := AccountFileJSON{
 		Address: "hexpubkey",
@@ -52,3 +67,43 @@ func TestAccountFileJSON_AccountURL(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, want, got)
 }
+
+func TestAccountFileJSON_AccountRole(t *testing.T) {
+	require.Equal(t, "", (&AccountFileJSON{}).AccountRole())
+	require.Equal(t, AccountRoleValidator, (&AccountFileJSON{Sealer: true}).AccountRole())
+	require.Equal(t, AccountRoleFaucet, (&AccountFileJSON{Role: AccountRoleFaucet}).AccountRole())
+}
+
+func TestAccountFileJSON_UnmarshalJSON_NormalizesAddress(t *testing.T) {
+	for _, addr := range []string{
+		"4d6d744b6da435b5bbdde2526dc20e9a41cb72e5",
+		"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5",
+		"0x4D6D744B6DA435b5bBdDE2526DC20E9a41cB72E5",
+		"0X4D6D744B6DA435B5BBDDE2526DC20E9A41CB72E5",
+	} {
+		var got AccountFileJSON
+		require.NoError(t, json.Unmarshal([]byte(`{"Address": "`+addr+`", "VaultAccount": {"SecretName": "acct", "SecretVersion": 1}, "Version": 1}`), &got))
+		require.Equal(t, "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", got.Address, addr)
+		require.Equal(t, "acct", got.VaultAccount.SecretName)
+	}
+}
+
+func TestNewAccount_AccountFile_NormalizesAddress(t *testing.T) {
+	conf := NewAccount{SecretName: "acct"}
+
+	got := conf.AccountFile("/path/to/file", "0x4D6D744B6DA435b5bBdDE2526DC20E9a41cB72E5", 1)
+
+	require.Equal(t, "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", got.Contents.Address)
+}
+
+func TestAccountFileJSON_SecretKey(t *testing.T) {
+	var got AccountFileJSON
+	require.NoError(t, json.Unmarshal([]byte(`{"Address": "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", "VaultAccount": {"SecretName": "acct", "SecretVersion": 1, "SecretKey": "privateKey"}, "Version": 1}`), &got))
+	require.Equal(t, "privateKey", got.VaultAccount.SecretKey)
+
+	// account configs without a SecretKey are written as before
+	got.VaultAccount.SecretKey = ""
+	b, err := json.Marshal(got)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "SecretKey")
+}
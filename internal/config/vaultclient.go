@@ -2,29 +2,175 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"strings"
+	"time"
+)
+
+const (
+	DefaultKubernetesPath      = "kubernetes"
+	DefaultServiceAccountToken = "file:///var/run/secrets/kubernetes.io/serviceaccount/token"
+	DefaultAzurePath           = "azure"
+	DefaultAzureResource       = "https://management.azure.com/"
+	DefaultCertPath            = "cert"
+	DefaultLdapPath            = "ldap"
+	DefaultUserpassPath        = "userpass"
+)
+
+const (
+	AccountOrderURL     = "url"
+	AccountOrderAddress = "address"
+	AccountOrderCreated = "created"
+)
+
+const (
+	AccountIDRandom        = "random"
+	AccountIDDeterministic = "deterministic"
+)
+
+const (
+	AccountStoreConsul = "consul"
+	AccountStoreEtcd   = "etcd"
+)
+
+const (
+	SecretsEngineKV        = "kv"
+	SecretsEngineCubbyhole = "cubbyhole"
+)
+
+const (
+	AddressMismatchFail             = "fail"
+	AddressMismatchTrustVault       = "trustVault"
+	AddressMismatchTrustConfigAlert = "trustConfig+alert"
+)
+
+const (
+	UsageReportFormatJSON = "json"
+	UsageReportFormatCSV  = "csv"
 )
 
 type VaultClient struct {
 	Vault            *url.URL
-	KVEngineName     string // the path of the K/V v2 secret engine
-	AccountDirectory *url.URL
+	KVEngineName     string   // the path of the K/V v2 secret engine
+	AccountDirectory *url.URL // nil if AccountStore is configured
 	Unlock           []string
 	Authentication   VaultClientAuthentication
 	TLS              VaultClientTLS
+	Permissions      VaultClientPermissions
+	NewAccountQuota  VaultClientQuota
+	UnlockTOTP       VaultClientUnlockTOTP
+	// CheckAccountSecrets probes Vault at startup for the secret referenced by each account config, marking
+	// accounts whose secret is missing as degraded
+	CheckAccountSecrets bool
+	// DRSecondary is the address of a Vault Disaster Recovery secondary to read from if the primary is unavailable
+	DRSecondary *url.URL
+	// ReadReplica is the address of Vault performance standby/secondary node(s) to send reads to
+	ReadReplica *url.URL
+	// ReadReplicas are the addresses of additional read replicas.  Reads are sent to the healthiest of all replicas.
+	ReadReplicas []*url.URL
+	// Locality is the region/zone of this node, e.g. eu-west-1/eu-west-1a
+	Locality string
+	// Localities tags the vault and read replica addresses with their region/zone, so that reads prefer the local cluster
+	Localities map[string]string
+	// MaxStaleness is how long after an account config changes its secret is read from the active node rather than a
+	// read replica, in case the secret version has not yet been replicated.  0 always reads from the active node, nil
+	// always reads from the read replicas.
+	MaxStaleness *time.Duration
+	// RPCTimeout is the deadline for handling each signing-path request, 0 is no deadline
+	RPCTimeout time.Duration
+	// ReadCacheSize is the maximum number of secret versions to cache in memory
+	ReadCacheSize int
+	// ReadCacheMaxBytes is the maximum estimated memory used by the read cache.  Caching is disabled if neither limit is set.
+	ReadCacheMaxBytes int64
+	Debug             VaultClientDebug
+	// MaxConcurrentRequests limits the number of concurrent requests sent to Vault, 0 is unlimited
+	MaxConcurrentRequests int
+	HealthProbe           VaultClientHealthProbe
+	// StateDirectory is a directory for persistent plugin state, nil if not configured
+	StateDirectory *url.URL
+	TokenSink      VaultClientTokenSink
+	// DNSRefreshInterval is how often the Vault hostname is re-resolved so that connections to a previous address are
+	// recycled, 0 is disabled
+	DNSRefreshInterval time.Duration
+	// AccountOrder is the order in which accounts are listed, one of the AccountOrder consts.  Defaults to url.
+	AccountOrder string
+	// AccountID is how the ID of new account config files is generated, one of the AccountID consts.  Defaults to
+	// random.
+	AccountID string
+	Mirror    VaultClientMirror
+	// AccountStore stores account configs in a remote key-value store instead of AccountDirectory
+	AccountStore  VaultClientAccountStore
+	Compatibility Compatibility
+	// QuorumPermissioning refuses to sign for accounts suspended or blacklisted by the Quorum permissioning contracts
+	QuorumPermissioning VaultClientQuorumPermissioning
+	Escrow              VaultClientEscrow
+	// DuplicateSignWindow is the number of recently signed hashes remembered per account, so that signing the same
+	// hash twice can be reported.  0 is disabled.
+	DuplicateSignWindow int
+	SigningLatencySLO   VaultClientSigningLatencySLO
+	// NodeID identifies this node in the User-Agent of Vault requests, so that Vault audit logs can attribute requests
+	// to a node
+	NodeID string
+	// Headers are added to every Vault request, e.g. for upstream proxies
+	Headers map[string]string
+	// SecretsEngine is the type of secrets engine keys are stored in, one of the SecretsEngine consts.  Defaults to kv.
+	SecretsEngine string
+	// VersionFallback unlocks an account using the latest version of its secret if the configured version is not
+	// found, provided the latest version still holds the account's key
+	VersionFallback bool
+	// Dev keeps secrets in memory instead of Vault, for integration tests and local development.  Nothing is sent to
+	// Vault, so the Vault-specific fields must not be set.  Keys are lost when the plugin stops.
+	Dev bool
+	// Pepper is an env:// or file:// URL of a second secret, held outside Vault, that new keys are masked with before
+	// being written to Vault, so that neither Vault nor the pepper alone is enough to recover a key.  nil if not
+	// configured.
+	Pepper     *EnvironmentVariable
+	SignGrants VaultClientSignGrants
+	// AddressMismatch is what to do when an account's secret holds the key for a different address to its account
+	// config, one of the AddressMismatch consts.  Defaults to fail.
+	AddressMismatch string
+	// StrictSignDomains refuses signing requests that do not declare the domain of the digest being signed
+	StrictSignDomains bool
+	// StandbyTokens is the number of additional tokens kept logged in with the configured auth method, so that an
+	// invalidated or expiring token can be replaced without waiting for a login.  0 is disabled.
+	StandbyTokens int
+	UsageReport   VaultClientUsageReport
+	// AccountDefaults are the settings for all accounts, overriding the plugin's defaults and the equivalent top-level
+	// fields
+	AccountDefaults AccountSettings
+	// AccountOverrides are the settings for individual accounts, keyed by account address, overriding AccountDefaults
+	AccountOverrides map[string]AccountSettings
+	// RequestNamespaces are the Vault namespaces callers can read account secrets from.  Disabled if not configured.
+	RequestNamespaces VaultClientRequestNamespaces
+	SignPayloads      VaultClientSignPayloads
 }
 
+// EnvironmentVariable is a URL referencing a credential.  env://VAR references the value of the VAR environment
+// variable.  file:///path/to/file references the contents of a file, e.g. a Docker or Kubernetes secret mounted at
+// /var/run/secrets/..., which is re-read on each use so that the credential can be rotated without a restart.
 type EnvironmentVariable url.URL
 
 func (e EnvironmentVariable) Get() string {
 	u := url.URL(e)
+	if e.IsFile() {
+		b, err := ioutil.ReadFile(FilePath(&u))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(b))
+	}
 	return os.Getenv(u.Host)
 }
 
 func (e EnvironmentVariable) IsSet() bool {
 	u := url.URL(e)
+	if e.IsFile() {
+		_, err := os.Stat(FilePath(&u))
+		return err == nil
+	}
 	if u.Host == "" {
 		return false
 	}
@@ -32,6 +178,11 @@ func (e EnvironmentVariable) IsSet() bool {
 	return b
 }
 
+// IsFile reports whether the credential is read from a file
+func (e EnvironmentVariable) IsFile() bool {
+	return e.Scheme == "file" && e.Path != ""
+}
+
 func (e EnvironmentVariable) String() string {
 	u := url.URL(e)
 	return u.String()
@@ -42,34 +193,361 @@ type VaultClientAuthentication struct {
 	RoleId      *EnvironmentVariable
 	SecretId    *EnvironmentVariable
 	ApprolePath string
+	Kubernetes  VaultClientKubernetes
+	Azure       VaultClientAzure
+	Cert        VaultClientCert
+	Ldap        VaultClientUserpass
+	Userpass    VaultClientUserpass
+	// SecretIdWrapped is true if SecretId references a response-wrapping token for the approle secret_id, rather than
+	// the secret_id itself
+	SecretIdWrapped bool
+	// SecretIdRotator generates a new approle secret_id when the configured secret_id is no longer accepted, e.g. its
+	// TTL or number of uses is exhausted
+	SecretIdRotator VaultClientSecretIdRotator
+}
+
+// VaultClientSecretIdRotator configures the generation of approle secret_ids using a long-lived token whose policy
+// allows it to write to auth/<approlePath>/role/<Role>/secret-id.  It is used if Token is set.
+type VaultClientSecretIdRotator struct {
+	// Token is the env or file URL of the rotator token, nil if not configured
+	Token *EnvironmentVariable
+	Role  string
+}
+
+// VaultClientKubernetes configures authentication using the Vault Kubernetes auth method.  It is used if Role is set.
+type VaultClientKubernetes struct {
+	Role string
+	// Path is the path of the Kubernetes auth engine, defaults to kubernetes
+	Path string
+	// ServiceAccountToken is the file URL of the service account token, defaults to the token mounted in the pod
+	ServiceAccountToken *url.URL
+}
+
+// VaultClientAzure configures authentication using the Vault Azure auth method with a token for the VM's or pod's
+// managed identity, obtained from the Azure Instance Metadata Service.  It is used if Role is set.
+type VaultClientAzure struct {
+	Role string
+	// Path is the path of the Azure auth engine, defaults to azure
+	Path string
+	// Resource is the audience of the managed identity token, which must match the resource configured in the Azure
+	// auth engine, defaults to https://management.azure.com/
+	Resource string
+	// ClientID selects a user-assigned managed identity if more than one is assigned, empty to use the default identity
+	ClientID string
+}
+
+// VaultClientCert configures authentication using the Vault TLS certificate auth method with the client certificate
+// and key configured in tls.  It is used if Role is set.
+type VaultClientCert struct {
+	Role string
+	// Path is the path of the cert auth engine, defaults to cert
+	Path string
+}
+
+// VaultClientUserpass configures authentication using a username and password with the Vault LDAP or userpass auth
+// method.  It is used if Username is set.
+type VaultClientUserpass struct {
+	// Username and Password are env or file URLs of the credentials, nil if not configured
+	Username *EnvironmentVariable
+	Password *EnvironmentVariable
+	// Path is the path of the auth engine, defaults to ldap or userpass
+	Path string
 }
 
 type VaultClientTLS struct {
-	CaCert     *url.URL
-	ClientCert *url.URL
-	ClientKey  *url.URL
+	CaCert *url.URL
+	// CaCertDir is a directory of PEM-encoded CA certificates, nil if not configured
+	CaCertDir *url.URL
+	// AppendSystemRoots adds the CA certificates to the system roots rather than replacing them
+	AppendSystemRoots bool
+	ClientCert        *url.URL
+	ClientKey         *url.URL
+	// ServerName is the hostname used to verify the Vault server's certificate, if different to the vault url's host
+	ServerName string
+	// Pins are hex-encoded SHA-256 fingerprints of certificates or public keys, one of which must be presented by the
+	// Vault server in addition to passing CA validation
+	Pins []string
+	// FIPS restricts Vault connections to TLS 1.2 with FIPS 140-2 approved cipher suites, curves and certificates
+	FIPS bool
+}
+
+// VaultClientPermissions controls which account provisioning operations the plugin will perform.  Disabling both
+// allows production signer nodes to force key provisioning through controlled pipelines.
+type VaultClientPermissions struct {
+	NewAccounts bool
+	ImportKeys  bool
+	// AllowedPeers restricts the peer addresses that can make signing and provisioning requests, either
+	// AllowedPeerLocalhost or CIDRs.  All peers are allowed if empty.
+	AllowedPeers []string
+}
+
+// AllowedPeerLocalhost allows requests over unix sockets or from loopback addresses
+const AllowedPeerLocalhost = "localhost"
+
+// VaultClientQuota limits the number of accounts that can be created in a rolling hour/day.  0 is unlimited.
+type VaultClientQuota struct {
+	PerHour int
+	PerDay  int
+}
+
+// VaultClientUnlockTOTP requires a TOTP code, verified by the Vault TOTP secrets engine, to unlock the accounts in Keys.
+// The code is provided as the unlock password.
+type VaultClientUnlockTOTP struct {
+	Engine string            // the path of the TOTP secret engine
+	Keys   map[string]string // account address -> name of the account's key in the TOTP engine
+}
+
+// VaultClientDebug configures the optional debug listener.  It is disabled if Address is not set.
+type VaultClientDebug struct {
+	Address string // loopback host:port to listen on, e.g. localhost:6060
+}
+
+// VaultClientHealthProbe configures the periodic Vault connectivity probe.  It is disabled if Interval is not set.
+type VaultClientHealthProbe struct {
+	Interval         time.Duration
+	FailureThreshold int // consecutive failed probes before wallets are dropped, defaults to 3
+}
+
+// VaultClientMirror runs the plugin as a read-only mirror of a primary signer.  The mirror reports wallets but refuses
+// to sign until PromotionSecret names Node as the signer.  It is disabled if Node is not set.
+type VaultClientMirror struct {
+	Node            string        // the name of this node
+	PromotionSecret string        // the secret in the KV engine naming the node promoted to signer
+	PollInterval    time.Duration // how often PromotionSecret is read, defaults to 5s
+}
+
+// VaultClientAccountStore stores account configs in a Consul or etcd key-value store shared by a cluster of nodes.  The
+// store is watched for changes so that accounts created by other nodes become available.  It is disabled if Type is not
+// set.
+type VaultClientAccountStore struct {
+	Type         string               // one of the AccountStore consts
+	Address      *url.URL             // the HTTP API address of the store, e.g. http://localhost:8500 for Consul
+	Prefix       string               // the key prefix under which account configs are stored
+	Token        *EnvironmentVariable // the Consul ACL token or etcd auth token, nil if not required
+	PollInterval time.Duration        // how often etcd is checked for changes, defaults to 10s
+}
+
+// VaultClientQuorumPermissioning checks the status of an account in the Quorum permissioning AccountManager contract
+// before signing.  It is disabled if RPC is not set.
+type VaultClientQuorumPermissioning struct {
+	RPC            *url.URL      // the JSON-RPC endpoint of a Quorum node, e.g. http://localhost:8545
+	AccountManager string        // the address of the permissioning AccountManager contract
+	CacheTTL       time.Duration // how long an account's status is cached, defaults to 10s
+}
+
+// VaultClientSigningLatencySLO reports when the latency of signing requests exceeds Threshold at Percentile, over the
+// last Window requests.  It is disabled if Threshold is not set.
+type VaultClientSigningLatencySLO struct {
+	Threshold  time.Duration
+	Percentile float64 // defaults to 99
+	Window     int     // the number of most recent requests, defaults to 1000
+}
+
+// VaultClientUsageReport periodically exports how each account has been used, e.g. for key hygiene reviews that retire
+// unused accounts.  It is disabled if Interval is not set.
+type VaultClientUsageReport struct {
+	Interval time.Duration
+	File     *url.URL             // an absolute file url the report is written to, nil if not configured
+	Format   string               // the format of File, one of the UsageReportFormat consts.  Defaults to json.
+	Endpoint *url.URL             // an HTTP/HTTPS url the report is POSTed to as JSON, nil if not configured
+	Token    *EnvironmentVariable // sent as a bearer token to Endpoint, nil if not configured
+}
+
+// VaultClientEscrow encrypts each new private key to an offline public key and stores the ciphertext as a sibling Vault
+// secret, so that a custodian holding the private key can recover accounts.  It is disabled if PublicKey is not set.
+type VaultClientEscrow struct {
+	PublicKey *url.URL // a PEM-encoded RSA public key file
+}
+
+// VaultClientSignGrants requires a single-use grant, authenticated with Key, to sign with the accounts in Accounts, so
+// that upstream systems can pre-authorize exactly one signature for exactly one payload.  It is disabled if Key is nil.
+type VaultClientSignGrants struct {
+	// Key is an env:// or file:// URL of the key shared with the systems that issue grants
+	Key      *EnvironmentVariable
+	Accounts []string
+}
+
+// VaultClientSignPayloads checks the unsigned transaction a caller declares in the gRPC metadata of a signing request
+// before its digest is signed.  It is disabled if no field is set.
+type VaultClientSignPayloads struct {
+	// MaxBytes is the maximum size of a declared transaction.  0 is no limit.
+	MaxBytes int
+	// MaxCalldataBytes is the maximum size of the calldata of a declared transaction.  0 is no limit.
+	MaxCalldataBytes int
+	// Selectors are the only function selectors, e.g. 0xa9059cbb, that each account can sign transactions calling,
+	// keyed by account address.  Accounts that are not listed can sign any transaction.
+	Selectors map[string][]string
+}
+
+// VaultClientRequestNamespaces allows callers to read account secrets from a Vault Enterprise namespace named in the
+// request's gRPC metadata, e.g. to serve the accounts of several tenants from one plugin.  It is disabled if Allowed is
+// empty.
+type VaultClientRequestNamespaces struct {
+	// Allowed are the namespaces that can be requested, relative to the namespace of the plugin's token, e.g. tenant1 or
+	// tenant1/team
+	Allowed []string
+	// Peers, if set, are the only peer addresses that can request a namespace, either AllowedPeerLocalhost or CIDRs.
+	// Unlike the caller metadata, the peer address cannot be chosen by the caller.
+	Peers []string
+}
+
+// VaultClientTokenSink persists the Vault token obtained from an AppRole login to the state directory, encrypted with
+// Key, so that a restarted plugin can resume with the existing token.  It is disabled if Key is not set.
+type VaultClientTokenSink struct {
+	Key *EnvironmentVariable
 }
 
 type vaultClientJSON struct {
-	Vault            string
-	KVEngineName     string
-	AccountDirectory string
-	Unlock           []string
-	Authentication   vaultClientAuthenticationJSON
-	Tls              vaultClientTLSJSON
+	Vault                 string
+	KVEngineName          string
+	AccountDirectory      string
+	AccountStore          vaultClientAccountStoreJSON
+	Unlock                []string
+	Authentication        vaultClientAuthenticationJSON
+	Tls                   vaultClientTLSJSON
+	Permissions           vaultClientPermissionsJSON
+	NewAccountQuota       VaultClientQuota
+	UnlockTOTP            VaultClientUnlockTOTP
+	CheckAccountSecrets   bool
+	DRSecondary           string
+	ReadReplica           string
+	ReadReplicas          []string
+	Locality              string
+	Localities            map[string]string
+	MaxStaleness          string
+	RPCTimeout            string
+	ReadCacheSize         int
+	ReadCacheMaxBytes     int64
+	Debug                 VaultClientDebug
+	MaxConcurrentRequests int
+	HealthProbe           vaultClientHealthProbeJSON
+	StateDirectory        string
+	TokenSink             vaultClientTokenSinkJSON
+	DNSRefreshInterval    string
+	AccountOrder          string
+	AccountID             string
+	Mirror                vaultClientMirrorJSON
+	Compatibility         Compatibility
+	QuorumPermissioning   vaultClientQuorumPermissioningJSON
+	Escrow                vaultClientEscrowJSON
+	DuplicateSignWindow   int
+	SigningLatencySLO     vaultClientSigningLatencySLOJSON
+	NodeID                string
+	Headers               map[string]string
+	SecretsEngine         string
+	VersionFallback       bool
+	Dev                   bool
+	Pepper                string
+	SignGrants            vaultClientSignGrantsJSON
+	AddressMismatch       string
+	StrictSignDomains     bool
+	StandbyTokens         int
+	UsageReport           vaultClientUsageReportJSON
+	AccountDefaults       accountSettingsJSON
+	AccountOverrides      map[string]accountSettingsJSON
+	RequestNamespaces     VaultClientRequestNamespaces
+	SignPayloads          VaultClientSignPayloads
+}
+
+type vaultClientSignGrantsJSON struct {
+	Key      string
+	Accounts []string
+}
+
+type vaultClientSigningLatencySLOJSON struct {
+	Threshold  string
+	Percentile float64
+	Window     int
+}
+
+type vaultClientUsageReportJSON struct {
+	Interval string
+	File     string
+	Format   string
+	Endpoint string
+	Token    string
+}
+
+type vaultClientEscrowJSON struct {
+	PublicKey string
+}
+
+type vaultClientQuorumPermissioningJSON struct {
+	RPC            string
+	AccountManager string
+	CacheTTL       string
+}
+
+type vaultClientMirrorJSON struct {
+	Node            string
+	PromotionSecret string
+	PollInterval    string
+}
+
+type vaultClientAccountStoreJSON struct {
+	Type         string
+	Address      string
+	Prefix       string
+	Token        string
+	PollInterval string
+}
+
+type vaultClientTokenSinkJSON struct {
+	Key string
+}
+
+type vaultClientHealthProbeJSON struct {
+	Interval         string
+	FailureThreshold int
 }
 
 type vaultClientAuthenticationJSON struct {
-	Token       string
-	RoleId      string
-	SecretId    string
-	ApprolePath string
+	Token           string
+	RoleId          string
+	SecretId        string
+	ApprolePath     string
+	Kubernetes      vaultClientKubernetesJSON
+	Azure           VaultClientAzure
+	Cert            VaultClientCert
+	Ldap            vaultClientUserpassJSON
+	Userpass        vaultClientUserpassJSON
+	SecretIdWrapped bool
+	SecretIdRotator vaultClientSecretIdRotatorJSON
+}
+
+type vaultClientSecretIdRotatorJSON struct {
+	Token string
+	Role  string
+}
+
+type vaultClientUserpassJSON struct {
+	Username string
+	Password string
+	Path     string
+}
+
+type vaultClientKubernetesJSON struct {
+	Role                string
+	Path                string
+	ServiceAccountToken string
 }
 
 type vaultClientTLSJSON struct {
-	CaCert     string
-	ClientCert string
-	ClientKey  string
+	CaCert            string
+	CaCertDir         string
+	AppendSystemRoots bool
+	ClientCert        string
+	ClientKey         string
+	ServerName        string
+	Pins              []string
+	FIPS              bool
+}
+
+// vaultClientPermissionsJSON uses pointers so that omitted permissions can default to enabled
+type vaultClientPermissionsJSON struct {
+	NewAccounts  *bool
+	ImportKeys   *bool
+	AllowedPeers []string
 }
 
 func (c *VaultClient) UnmarshalJSON(b []byte) error {
@@ -98,11 +576,17 @@ func (c vaultClientJSON) vaultClient() (VaultClient, error) {
 	if err != nil {
 		return VaultClient{}, err
 	}
+	vault = NormalizeVaultURL(vault)
 
-	if !strings.HasSuffix(c.AccountDirectory, "/") {
+	if c.AccountDirectory != "" && !strings.HasSuffix(c.AccountDirectory, "/") {
 		c.AccountDirectory = c.AccountDirectory + "/"
 	}
-	accountDirectory, err := url.Parse(c.AccountDirectory)
+	accountDirectory, err := parseOptionalURL(c.AccountDirectory)
+	if err != nil {
+		return VaultClient{}, err
+	}
+
+	accountStore, err := c.AccountStore.vaultClientAccountStore()
 	if err != nil {
 		return VaultClient{}, err
 	}
@@ -117,16 +601,227 @@ func (c vaultClientJSON) vaultClient() (VaultClient, error) {
 		return VaultClient{}, err
 	}
 
+	drSecondary, err := parseOptionalURL(c.DRSecondary)
+	if err != nil {
+		return VaultClient{}, err
+	}
+	drSecondary = NormalizeVaultURL(drSecondary)
+
+	readReplica, err := parseOptionalURL(c.ReadReplica)
+	if err != nil {
+		return VaultClient{}, err
+	}
+	readReplica = NormalizeVaultURL(readReplica)
+
+	var readReplicas []*url.URL
+	for _, r := range c.ReadReplicas {
+		u, err := url.Parse(r)
+		if err != nil {
+			return VaultClient{}, err
+		}
+		readReplicas = append(readReplicas, NormalizeVaultURL(u))
+	}
+
+	localities, err := normalizeLocalities(c.Localities)
+	if err != nil {
+		return VaultClient{}, err
+	}
+
+	var maxStaleness *time.Duration
+	if c.MaxStaleness != "" {
+		d, err := time.ParseDuration(c.MaxStaleness)
+		if err != nil {
+			return VaultClient{}, fmt.Errorf("invalid maxStaleness: %v", err)
+		}
+		maxStaleness = &d
+	}
+
+	var rpcTimeout time.Duration
+	if c.RPCTimeout != "" {
+		if rpcTimeout, err = time.ParseDuration(c.RPCTimeout); err != nil {
+			return VaultClient{}, fmt.Errorf("invalid rpcTimeout: %v", err)
+		}
+	}
+
+	var dnsRefreshInterval time.Duration
+	if c.DNSRefreshInterval != "" {
+		if dnsRefreshInterval, err = time.ParseDuration(c.DNSRefreshInterval); err != nil {
+			return VaultClient{}, fmt.Errorf("invalid dnsRefreshInterval: %v", err)
+		}
+	}
+
+	healthProbe, err := c.HealthProbe.vaultClientHealthProbe()
+	if err != nil {
+		return VaultClient{}, err
+	}
+
+	mirror, err := c.Mirror.vaultClientMirror()
+	if err != nil {
+		return VaultClient{}, err
+	}
+
+	stateDirectory, err := parseOptionalURL(c.StateDirectory)
+	if err != nil {
+		return VaultClient{}, err
+	}
+
+	quorumPermissioning, err := c.QuorumPermissioning.vaultClientQuorumPermissioning()
+	if err != nil {
+		return VaultClient{}, err
+	}
+
+	signingLatencySLO, err := c.SigningLatencySLO.vaultClientSigningLatencySLO()
+	if err != nil {
+		return VaultClient{}, err
+	}
+
+	usageReport, err := c.UsageReport.vaultClientUsageReport()
+	if err != nil {
+		return VaultClient{}, err
+	}
+
+	accountDefaults, err := c.AccountDefaults.accountSettings()
+	if err != nil {
+		return VaultClient{}, fmt.Errorf("invalid accountDefaults: %v", err)
+	}
+	var accountOverrides map[string]AccountSettings
+	if c.AccountOverrides != nil {
+		accountOverrides = make(map[string]AccountSettings, len(c.AccountOverrides))
+		for addr, o := range c.AccountOverrides {
+			if accountOverrides[addr], err = o.accountSettings(); err != nil {
+				return VaultClient{}, fmt.Errorf("invalid accountOverrides for %v: %v", addr, err)
+			}
+		}
+	}
+
+	escrowPublicKey, err := parseOptionalURL(c.Escrow.PublicKey)
+	if err != nil {
+		return VaultClient{}, fmt.Errorf("invalid escrow publicKey: %v", err)
+	}
+
+	tokenSinkKey, err := parseOptionalURL(c.TokenSink.Key)
+	if err != nil {
+		return VaultClient{}, err
+	}
+	var tokenSink VaultClientTokenSink
+	if tokenSinkKey != nil {
+		key := EnvironmentVariable(*tokenSinkKey)
+		tokenSink.Key = &key
+	}
+
+	signGrantsKey, err := parseOptionalURL(c.SignGrants.Key)
+	if err != nil {
+		return VaultClient{}, fmt.Errorf("invalid signGrants key: %v", err)
+	}
+	signGrants := VaultClientSignGrants{Accounts: c.SignGrants.Accounts}
+	if signGrantsKey != nil {
+		key := EnvironmentVariable(*signGrantsKey)
+		signGrants.Key = &key
+	}
+
+	pepperURL, err := parseOptionalURL(c.Pepper)
+	if err != nil {
+		return VaultClient{}, fmt.Errorf("invalid pepper: %v", err)
+	}
+	var pepper *EnvironmentVariable
+	if pepperURL != nil {
+		p := EnvironmentVariable(*pepperURL)
+		pepper = &p
+	}
+
 	return VaultClient{
-		Vault:            vault,
-		KVEngineName:     c.KVEngineName,
-		AccountDirectory: accountDirectory,
-		Unlock:           c.Unlock,
-		Authentication:   authentication,
-		TLS:              tls,
+		Vault:                 vault,
+		KVEngineName:          c.KVEngineName,
+		AccountDirectory:      accountDirectory,
+		AccountStore:          accountStore,
+		Unlock:                c.Unlock,
+		Authentication:        authentication,
+		TLS:                   tls,
+		Permissions:           c.Permissions.vaultClientPermissions(),
+		NewAccountQuota:       c.NewAccountQuota,
+		UnlockTOTP:            c.UnlockTOTP,
+		CheckAccountSecrets:   c.CheckAccountSecrets,
+		DRSecondary:           drSecondary,
+		ReadReplica:           readReplica,
+		ReadReplicas:          readReplicas,
+		Locality:              c.Locality,
+		Localities:            localities,
+		MaxStaleness:          maxStaleness,
+		RPCTimeout:            rpcTimeout,
+		ReadCacheSize:         c.ReadCacheSize,
+		ReadCacheMaxBytes:     c.ReadCacheMaxBytes,
+		Debug:                 c.Debug,
+		MaxConcurrentRequests: c.MaxConcurrentRequests,
+		HealthProbe:           healthProbe,
+		StateDirectory:        stateDirectory,
+		TokenSink:             tokenSink,
+		DNSRefreshInterval:    dnsRefreshInterval,
+		AccountOrder:          c.AccountOrder,
+		AccountID:             c.AccountID,
+		Mirror:                mirror,
+		Compatibility:         c.Compatibility,
+		QuorumPermissioning:   quorumPermissioning,
+		Escrow:                VaultClientEscrow{PublicKey: escrowPublicKey},
+		DuplicateSignWindow:   c.DuplicateSignWindow,
+		SigningLatencySLO:     signingLatencySLO,
+		NodeID:                c.NodeID,
+		Headers:               c.Headers,
+		SecretsEngine:         c.SecretsEngine,
+		VersionFallback:       c.VersionFallback,
+		Dev:                   c.Dev,
+		Pepper:                pepper,
+		SignGrants:            signGrants,
+		AddressMismatch:       c.AddressMismatch,
+		StrictSignDomains:     c.StrictSignDomains,
+		StandbyTokens:         c.StandbyTokens,
+		UsageReport:           usageReport,
+		AccountDefaults:       accountDefaults,
+		AccountOverrides:      accountOverrides,
+		RequestNamespaces:     c.RequestNamespaces,
+		SignPayloads:          c.SignPayloads,
 	}, nil
 }
 
+// parseOptionalURL returns nil if s is empty
+func parseOptionalURL(s string) (*url.URL, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return url.Parse(s)
+}
+
+// optionalURLString returns an empty string if u is nil
+func optionalURLString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+// optionalEnvString returns an empty string if e is nil
+func optionalEnvString(e *EnvironmentVariable) string {
+	if e == nil {
+		return ""
+	}
+	return e.String()
+}
+
+// optionalDurationString returns an empty string if d is 0
+func optionalDurationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// optionalDurationPtrString returns an empty string if d is nil
+func optionalDurationPtrString(d *time.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
 func (c vaultClientAuthenticationJSON) vaultClientAuthentication() (VaultClientAuthentication, error) {
 	token, err := url.Parse(c.Token)
 	if err != nil {
@@ -149,14 +844,131 @@ func (c vaultClientAuthenticationJSON) vaultClientAuthentication() (VaultClientA
 		sEnv = EnvironmentVariable(*secretId)
 	)
 
+	kubernetes, err := c.Kubernetes.vaultClientKubernetes()
+	if err != nil {
+		return VaultClientAuthentication{}, err
+	}
+
+	ldap, err := c.Ldap.vaultClientUserpass(DefaultLdapPath)
+	if err != nil {
+		return VaultClientAuthentication{}, fmt.Errorf("invalid ldap: %v", err)
+	}
+
+	userpass, err := c.Userpass.vaultClientUserpass(DefaultUserpassPath)
+	if err != nil {
+		return VaultClientAuthentication{}, fmt.Errorf("invalid userpass: %v", err)
+	}
+
+	rotator, err := c.SecretIdRotator.vaultClientSecretIdRotator()
+	if err != nil {
+		return VaultClientAuthentication{}, fmt.Errorf("invalid secretIdRotator: %v", err)
+	}
+
 	return VaultClientAuthentication{
-		Token:       &tEnv,
-		RoleId:      &rEnv,
-		SecretId:    &sEnv,
-		ApprolePath: c.ApprolePath,
+		Token:           &tEnv,
+		RoleId:          &rEnv,
+		SecretId:        &sEnv,
+		ApprolePath:     c.ApprolePath,
+		Kubernetes:      kubernetes,
+		Azure:           c.Azure.withDefaults(),
+		Cert:            c.Cert.withDefaults(),
+		Ldap:            ldap,
+		Userpass:        userpass,
+		SecretIdWrapped: c.SecretIdWrapped,
+		SecretIdRotator: rotator,
 	}, nil
 }
 
+// vaultClientSecretIdRotator parses the token URL if it is set
+func (c vaultClientSecretIdRotatorJSON) vaultClientSecretIdRotator() (VaultClientSecretIdRotator, error) {
+	if c.Token == "" {
+		return VaultClientSecretIdRotator{Role: c.Role}, nil
+	}
+	token, err := url.Parse(c.Token)
+	if err != nil {
+		return VaultClientSecretIdRotator{}, err
+	}
+	tEnv := EnvironmentVariable(*token)
+	return VaultClientSecretIdRotator{Token: &tEnv, Role: c.Role}, nil
+}
+
+func (c vaultClientKubernetesJSON) vaultClientKubernetes() (VaultClientKubernetes, error) {
+	if c.Role == "" {
+		return VaultClientKubernetes{}, nil
+	}
+
+	k := VaultClientKubernetes{
+		Role: c.Role,
+		Path: c.Path,
+	}
+	if k.Path == "" {
+		k.Path = DefaultKubernetesPath
+	}
+	if c.ServiceAccountToken == "" {
+		c.ServiceAccountToken = DefaultServiceAccountToken
+	}
+	var err error
+	if k.ServiceAccountToken, err = url.Parse(c.ServiceAccountToken); err != nil {
+		return VaultClientKubernetes{}, err
+	}
+	return k, nil
+}
+
+// vaultClientUserpass parses the credential URLs and sets the default path if Username is set
+func (c vaultClientUserpassJSON) vaultClientUserpass(defaultPath string) (VaultClientUserpass, error) {
+	if c.Username == "" {
+		return VaultClientUserpass{}, nil
+	}
+
+	username, err := url.Parse(c.Username)
+	if err != nil {
+		return VaultClientUserpass{}, err
+	}
+	password, err := url.Parse(c.Password)
+	if err != nil {
+		return VaultClientUserpass{}, err
+	}
+
+	var (
+		uEnv = EnvironmentVariable(*username)
+		pEnv = EnvironmentVariable(*password)
+	)
+	u := VaultClientUserpass{
+		Username: &uEnv,
+		Password: &pEnv,
+		Path:     c.Path,
+	}
+	if u.Path == "" {
+		u.Path = defaultPath
+	}
+	return u, nil
+}
+
+// withDefaults sets the default path and resource if Role is set
+func (c VaultClientAzure) withDefaults() VaultClientAzure {
+	if c.Role == "" {
+		return VaultClientAzure{}
+	}
+	if c.Path == "" {
+		c.Path = DefaultAzurePath
+	}
+	if c.Resource == "" {
+		c.Resource = DefaultAzureResource
+	}
+	return c
+}
+
+// withDefaults sets the default path if Role is set
+func (c VaultClientCert) withDefaults() VaultClientCert {
+	if c.Role == "" {
+		return VaultClientCert{}
+	}
+	if c.Path == "" {
+		c.Path = DefaultCertPath
+	}
+	return c
+}
+
 func (c vaultClientTLSJSON) vaultClientTls() (VaultClientTLS, error) {
 	caCert, err := url.Parse(c.CaCert)
 	if err != nil {
@@ -173,37 +985,338 @@ func (c vaultClientTLSJSON) vaultClientTls() (VaultClientTLS, error) {
 		return VaultClientTLS{}, err
 	}
 
+	caCertDir, err := parseOptionalURL(c.CaCertDir)
+	if err != nil {
+		return VaultClientTLS{}, err
+	}
+
 	return VaultClientTLS{
-		CaCert:     caCert,
-		ClientCert: clientCert,
-		ClientKey:  clientKey,
+		CaCert:            caCert,
+		CaCertDir:         caCertDir,
+		AppendSystemRoots: c.AppendSystemRoots,
+		ClientCert:        clientCert,
+		ClientKey:         clientKey,
+		ServerName:        c.ServerName,
+		Pins:              normalizePins(c.Pins),
+		FIPS:              c.FIPS,
 	}, nil
 }
 
+// normalizeLocalities normalizes the addresses so that they match the normalized vault and read replica urls
+func normalizeLocalities(localities map[string]string) (map[string]string, error) {
+	if len(localities) == 0 {
+		return nil, nil
+	}
+	normalized := make(map[string]string, len(localities))
+	for addr, locality := range localities {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid localities address: %v", err)
+		}
+		normalized[NormalizeVaultURL(u).String()] = locality
+	}
+	return normalized, nil
+}
+
+// normalizePins converts fingerprints to lowercase hex without separators, e.g. as output by openssl
+func normalizePins(pins []string) []string {
+	if len(pins) == 0 {
+		return nil
+	}
+	normalized := make([]string, 0, len(pins))
+	for _, p := range pins {
+		normalized = append(normalized, strings.ToLower(strings.Replace(p, ":", "", -1)))
+	}
+	return normalized
+}
+
+func (c vaultClientHealthProbeJSON) vaultClientHealthProbe() (VaultClientHealthProbe, error) {
+	p := VaultClientHealthProbe{FailureThreshold: c.FailureThreshold}
+	if c.Interval != "" {
+		var err error
+		if p.Interval, err = time.ParseDuration(c.Interval); err != nil {
+			return VaultClientHealthProbe{}, fmt.Errorf("invalid healthProbe interval: %v", err)
+		}
+	}
+	return p, nil
+}
+
+func (c vaultClientAccountStoreJSON) vaultClientAccountStore() (VaultClientAccountStore, error) {
+	s := VaultClientAccountStore{Type: c.Type, Prefix: c.Prefix}
+	var err error
+	if s.Address, err = parseOptionalURL(c.Address); err != nil {
+		return VaultClientAccountStore{}, fmt.Errorf("invalid accountStore address: %v", err)
+	}
+	token, err := parseOptionalURL(c.Token)
+	if err != nil {
+		return VaultClientAccountStore{}, fmt.Errorf("invalid accountStore token: %v", err)
+	}
+	if token != nil {
+		t := EnvironmentVariable(*token)
+		s.Token = &t
+	}
+	if c.PollInterval != "" {
+		if s.PollInterval, err = time.ParseDuration(c.PollInterval); err != nil {
+			return VaultClientAccountStore{}, fmt.Errorf("invalid accountStore pollInterval: %v", err)
+		}
+	}
+	return s, nil
+}
+
+func (c vaultClientMirrorJSON) vaultClientMirror() (VaultClientMirror, error) {
+	m := VaultClientMirror{Node: c.Node, PromotionSecret: c.PromotionSecret}
+	if c.PollInterval != "" {
+		var err error
+		if m.PollInterval, err = time.ParseDuration(c.PollInterval); err != nil {
+			return VaultClientMirror{}, fmt.Errorf("invalid mirror pollInterval: %v", err)
+		}
+	}
+	return m, nil
+}
+
+func (c vaultClientQuorumPermissioningJSON) vaultClientQuorumPermissioning() (VaultClientQuorumPermissioning, error) {
+	p := VaultClientQuorumPermissioning{AccountManager: c.AccountManager}
+	var err error
+	if p.RPC, err = parseOptionalURL(c.RPC); err != nil {
+		return VaultClientQuorumPermissioning{}, fmt.Errorf("invalid quorumPermissioning rpc: %v", err)
+	}
+	if c.CacheTTL != "" {
+		if p.CacheTTL, err = time.ParseDuration(c.CacheTTL); err != nil {
+			return VaultClientQuorumPermissioning{}, fmt.Errorf("invalid quorumPermissioning cacheTTL: %v", err)
+		}
+	}
+	return p, nil
+}
+
+func (c vaultClientSigningLatencySLOJSON) vaultClientSigningLatencySLO() (VaultClientSigningLatencySLO, error) {
+	slo := VaultClientSigningLatencySLO{Percentile: c.Percentile, Window: c.Window}
+	if c.Threshold != "" {
+		var err error
+		if slo.Threshold, err = time.ParseDuration(c.Threshold); err != nil {
+			return VaultClientSigningLatencySLO{}, fmt.Errorf("invalid signingLatencySLO threshold: %v", err)
+		}
+	}
+	return slo, nil
+}
+
+func (c vaultClientUsageReportJSON) vaultClientUsageReport() (VaultClientUsageReport, error) {
+	r := VaultClientUsageReport{Format: c.Format}
+	var err error
+	if c.Interval != "" {
+		if r.Interval, err = time.ParseDuration(c.Interval); err != nil {
+			return VaultClientUsageReport{}, fmt.Errorf("invalid usageReport interval: %v", err)
+		}
+	}
+	if r.File, err = parseOptionalURL(c.File); err != nil {
+		return VaultClientUsageReport{}, fmt.Errorf("invalid usageReport file: %v", err)
+	}
+	if r.Endpoint, err = parseOptionalURL(c.Endpoint); err != nil {
+		return VaultClientUsageReport{}, fmt.Errorf("invalid usageReport endpoint: %v", err)
+	}
+	token, err := parseOptionalURL(c.Token)
+	if err != nil {
+		return VaultClientUsageReport{}, fmt.Errorf("invalid usageReport token: %v", err)
+	}
+	if token != nil {
+		t := EnvironmentVariable(*token)
+		r.Token = &t
+	}
+	return r, nil
+}
+
+func (c vaultClientPermissionsJSON) vaultClientPermissions() VaultClientPermissions {
+	p := VaultClientPermissions{
+		NewAccounts:  true,
+		ImportKeys:   true,
+		AllowedPeers: c.AllowedPeers,
+	}
+	if c.NewAccounts != nil {
+		p.NewAccounts = *c.NewAccounts
+	}
+	if c.ImportKeys != nil {
+		p.ImportKeys = *c.ImportKeys
+	}
+	return p
+}
+
 func (c VaultClient) vaultClientJSON() (vaultClientJSON, error) {
+	var readReplicas []string
+	for _, r := range c.ReadReplicas {
+		readReplicas = append(readReplicas, r.String())
+	}
+	var accountOverrides map[string]accountSettingsJSON
+	if c.AccountOverrides != nil {
+		accountOverrides = make(map[string]accountSettingsJSON, len(c.AccountOverrides))
+		for addr, o := range c.AccountOverrides {
+			accountOverrides[addr] = o.accountSettingsJSON()
+		}
+	}
+
 	return vaultClientJSON{
-		Vault:            c.Vault.String(),
-		KVEngineName:     c.KVEngineName,
-		AccountDirectory: c.AccountDirectory.String(),
-		Unlock:           c.Unlock,
-		Authentication:   c.Authentication.vaultClientAuthenticationJSON(),
-		Tls:              c.TLS.vaultClientTLSJSON(),
+		Vault:                 c.Vault.String(),
+		KVEngineName:          c.KVEngineName,
+		AccountDirectory:      optionalURLString(c.AccountDirectory),
+		AccountStore:          c.AccountStore.vaultClientAccountStoreJSON(),
+		Unlock:                c.Unlock,
+		Authentication:        c.Authentication.vaultClientAuthenticationJSON(),
+		Tls:                   c.TLS.vaultClientTLSJSON(),
+		Permissions:           c.Permissions.vaultClientPermissionsJSON(),
+		NewAccountQuota:       c.NewAccountQuota,
+		UnlockTOTP:            c.UnlockTOTP,
+		CheckAccountSecrets:   c.CheckAccountSecrets,
+		DRSecondary:           optionalURLString(c.DRSecondary),
+		ReadReplica:           optionalURLString(c.ReadReplica),
+		ReadReplicas:          readReplicas,
+		Locality:              c.Locality,
+		Localities:            c.Localities,
+		MaxStaleness:          optionalDurationPtrString(c.MaxStaleness),
+		RPCTimeout:            optionalDurationString(c.RPCTimeout),
+		ReadCacheSize:         c.ReadCacheSize,
+		ReadCacheMaxBytes:     c.ReadCacheMaxBytes,
+		Debug:                 c.Debug,
+		MaxConcurrentRequests: c.MaxConcurrentRequests,
+		HealthProbe:           c.HealthProbe.vaultClientHealthProbeJSON(),
+		StateDirectory:        optionalURLString(c.StateDirectory),
+		TokenSink:             c.TokenSink.vaultClientTokenSinkJSON(),
+		DNSRefreshInterval:    optionalDurationString(c.DNSRefreshInterval),
+		AccountOrder:          c.AccountOrder,
+		AccountID:             c.AccountID,
+		Mirror:                c.Mirror.vaultClientMirrorJSON(),
+		Compatibility:         c.Compatibility,
+		QuorumPermissioning:   c.QuorumPermissioning.vaultClientQuorumPermissioningJSON(),
+		Escrow:                vaultClientEscrowJSON{PublicKey: optionalURLString(c.Escrow.PublicKey)},
+		DuplicateSignWindow:   c.DuplicateSignWindow,
+		SigningLatencySLO:     c.SigningLatencySLO.vaultClientSigningLatencySLOJSON(),
+		NodeID:                c.NodeID,
+		Headers:               c.Headers,
+		SecretsEngine:         c.SecretsEngine,
+		VersionFallback:       c.VersionFallback,
+		Dev:                   c.Dev,
+		Pepper:                optionalEnvString(c.Pepper),
+		SignGrants: vaultClientSignGrantsJSON{
+			Key:      optionalEnvString(c.SignGrants.Key),
+			Accounts: c.SignGrants.Accounts,
+		},
+		AddressMismatch:   c.AddressMismatch,
+		StrictSignDomains: c.StrictSignDomains,
+		StandbyTokens:     c.StandbyTokens,
+		UsageReport:       c.UsageReport.vaultClientUsageReportJSON(),
+		AccountDefaults:   c.AccountDefaults.accountSettingsJSON(),
+		AccountOverrides:  accountOverrides,
+		RequestNamespaces: c.RequestNamespaces,
+		SignPayloads:      c.SignPayloads,
 	}, nil
 }
 
+func (c VaultClientAccountStore) vaultClientAccountStoreJSON() vaultClientAccountStoreJSON {
+	j := vaultClientAccountStoreJSON{
+		Type:         c.Type,
+		Address:      optionalURLString(c.Address),
+		Prefix:       c.Prefix,
+		PollInterval: optionalDurationString(c.PollInterval),
+	}
+	if c.Token != nil {
+		j.Token = c.Token.String()
+	}
+	return j
+}
+
+func (c VaultClientMirror) vaultClientMirrorJSON() vaultClientMirrorJSON {
+	return vaultClientMirrorJSON{
+		Node:            c.Node,
+		PromotionSecret: c.PromotionSecret,
+		PollInterval:    optionalDurationString(c.PollInterval),
+	}
+}
+
+func (c VaultClientQuorumPermissioning) vaultClientQuorumPermissioningJSON() vaultClientQuorumPermissioningJSON {
+	return vaultClientQuorumPermissioningJSON{
+		RPC:            optionalURLString(c.RPC),
+		AccountManager: c.AccountManager,
+		CacheTTL:       optionalDurationString(c.CacheTTL),
+	}
+}
+
+func (c VaultClientSigningLatencySLO) vaultClientSigningLatencySLOJSON() vaultClientSigningLatencySLOJSON {
+	return vaultClientSigningLatencySLOJSON{
+		Threshold:  optionalDurationString(c.Threshold),
+		Percentile: c.Percentile,
+		Window:     c.Window,
+	}
+}
+
+func (c VaultClientUsageReport) vaultClientUsageReportJSON() vaultClientUsageReportJSON {
+	return vaultClientUsageReportJSON{
+		Interval: optionalDurationString(c.Interval),
+		File:     optionalURLString(c.File),
+		Format:   c.Format,
+		Endpoint: optionalURLString(c.Endpoint),
+		Token:    optionalEnvString(c.Token),
+	}
+}
+
+func (c VaultClientHealthProbe) vaultClientHealthProbeJSON() vaultClientHealthProbeJSON {
+	return vaultClientHealthProbeJSON{
+		Interval:         optionalDurationString(c.Interval),
+		FailureThreshold: c.FailureThreshold,
+	}
+}
+
+func (c VaultClientTokenSink) vaultClientTokenSinkJSON() vaultClientTokenSinkJSON {
+	if c.Key == nil {
+		return vaultClientTokenSinkJSON{}
+	}
+	return vaultClientTokenSinkJSON{Key: c.Key.String()}
+}
+
 func (c VaultClientAuthentication) vaultClientAuthenticationJSON() vaultClientAuthenticationJSON {
 	return vaultClientAuthenticationJSON{
 		Token:       c.Token.String(),
 		RoleId:      c.RoleId.String(),
 		SecretId:    c.SecretId.String(),
 		ApprolePath: c.ApprolePath,
+		Kubernetes: vaultClientKubernetesJSON{
+			Role:                c.Kubernetes.Role,
+			Path:                c.Kubernetes.Path,
+			ServiceAccountToken: optionalURLString(c.Kubernetes.ServiceAccountToken),
+		},
+		Azure:           c.Azure,
+		Cert:            c.Cert,
+		Ldap:            c.Ldap.vaultClientUserpassJSON(),
+		Userpass:        c.Userpass.vaultClientUserpassJSON(),
+		SecretIdWrapped: c.SecretIdWrapped,
+		SecretIdRotator: vaultClientSecretIdRotatorJSON{
+			Token: optionalEnvString(c.SecretIdRotator.Token),
+			Role:  c.SecretIdRotator.Role,
+		},
+	}
+}
+
+func (c VaultClientUserpass) vaultClientUserpassJSON() vaultClientUserpassJSON {
+	return vaultClientUserpassJSON{
+		Username: optionalEnvString(c.Username),
+		Password: optionalEnvString(c.Password),
+		Path:     c.Path,
 	}
 }
 
 func (c VaultClientTLS) vaultClientTLSJSON() vaultClientTLSJSON {
 	return vaultClientTLSJSON{
-		CaCert:     c.CaCert.String(),
-		ClientCert: c.ClientCert.String(),
-		ClientKey:  c.ClientKey.String(),
+		CaCert:            c.CaCert.String(),
+		CaCertDir:         optionalURLString(c.CaCertDir),
+		AppendSystemRoots: c.AppendSystemRoots,
+		ClientCert:        c.ClientCert.String(),
+		ClientKey:         c.ClientKey.String(),
+		ServerName:        c.ServerName,
+		Pins:              c.Pins,
+		FIPS:              c.FIPS,
+	}
+}
+
+func (c VaultClientPermissions) vaultClientPermissionsJSON() vaultClientPermissionsJSON {
+	return vaultClientPermissionsJSON{
+		NewAccounts:  &c.NewAccounts,
+		ImportKeys:   &c.ImportKeys,
+		AllowedPeers: c.AllowedPeers,
 	}
 }
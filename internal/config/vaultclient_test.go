@@ -2,9 +2,11 @@ package config
 
 import (
 	"encoding/json"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -26,7 +28,8 @@ func TestVaultClient_UnmarshalJSON(t *testing.T) {
 		"tls": {
 			"caCert": "file:///path/to/ca.pem",
 			"clientCert": "file:///path/to/client.pem",
-			"clientKey": "file:///path/to/client.key"
+			"clientKey": "file:///path/to/client.key",
+			"serverName": "vault.example.com"
 		}
 	}`)
 
@@ -72,6 +75,7 @@ func TestVaultClient_UnmarshalJSON(t *testing.T) {
 				Scheme: "file",
 				Path:   "/path/to/client.key",
 			},
+			ServerName: "vault.example.com",
 		},
 	}
 
@@ -165,6 +169,52 @@ func TestVaultClient_UnmarshalJSON_AddsTrailingSlashToAcctDir(t *testing.T) {
 	require.Equal(t, want.TLS, got.TLS)
 }
 
+func TestVaultClient_UnmarshalJSON_PermissionsDefaultToEnabled(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir"
+	}`)
+
+	var got VaultClient
+
+	err := json.Unmarshal(b, &got)
+
+	require.NoError(t, err)
+	require.True(t, got.Permissions.NewAccounts)
+	require.True(t, got.Permissions.ImportKeys)
+}
+
+func TestVaultClient_UnmarshalJSON_Permissions(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir",
+		"permissions": {
+			"newAccounts": false,
+			"importKeys": false,
+			"allowedPeers": ["localhost", "10.0.0.0/8"]
+		}
+	}`)
+
+	var got VaultClient
+
+	err := json.Unmarshal(b, &got)
+
+	require.NoError(t, err)
+	require.False(t, got.Permissions.NewAccounts)
+	require.False(t, got.Permissions.ImportKeys)
+	require.Equal(t, []string{"localhost", "10.0.0.0/8"}, got.Permissions.AllowedPeers)
+
+	// check the permissions survive a round trip
+	b, err = json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Permissions, roundTrip.Permissions)
+}
+
 func TestEnvironmentVariable_IsSet(t *testing.T) {
 	u, err := url.Parse("env://TEST_ENV")
 	require.NoError(t, err)
@@ -198,3 +248,622 @@ func TestEnvironmentVariable_Get(t *testing.T) {
 
 	require.Equal(t, "val", env.Get())
 }
+
+func TestVaultClient_UnmarshalJSON_AdditionalVaults(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir",
+		"drSecondary": "https://vault-dr:8200",
+		"readReplica": "https://vault-standby:8200",
+		"readReplicas": ["https://vault-standby-2:8200", "https://VAULT-STANDBY-3:443/"]
+	}`)
+
+	var got VaultClient
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Equal(t, "https://vault-dr:8200", got.DRSecondary.String())
+	require.Equal(t, "https://vault-standby:8200", got.ReadReplica.String())
+	require.Len(t, got.ReadReplicas, 2)
+	require.Equal(t, "https://vault-standby-2:8200", got.ReadReplicas[0].String())
+	require.Equal(t, "https://vault-standby-3", got.ReadReplicas[1].String())
+
+	// unset if not configured
+	var notSet VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111"}`), &notSet))
+	require.Nil(t, notSet.DRSecondary)
+	require.Nil(t, notSet.ReadReplica)
+	require.Nil(t, notSet.ReadReplicas)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.DRSecondary, roundTrip.DRSecondary)
+	require.Equal(t, got.ReadReplica, roundTrip.ReadReplica)
+	require.Equal(t, got.ReadReplicas, roundTrip.ReadReplicas)
+}
+
+func TestVaultClient_UnmarshalJSON_RPCTimeout(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "rpcTimeout": "1500ms"}`), &got))
+	require.Equal(t, 1500*time.Millisecond, got.RPCTimeout)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.RPCTimeout, roundTrip.RPCTimeout)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "rpcTimeout": "1500"}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid rpcTimeout")
+}
+
+func TestVaultClient_UnmarshalJSON_DNSRefreshInterval(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "dnsRefreshInterval": "30s"}`), &got))
+	require.Equal(t, 30*time.Second, got.DNSRefreshInterval)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.DNSRefreshInterval, roundTrip.DNSRefreshInterval)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "dnsRefreshInterval": "30"}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid dnsRefreshInterval")
+}
+
+func TestVaultClient_UnmarshalJSON_HealthProbe(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "healthProbe": {"interval": "10s", "failureThreshold": 5}}`), &got))
+	require.Equal(t, VaultClientHealthProbe{Interval: 10 * time.Second, FailureThreshold: 5}, got.HealthProbe)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.HealthProbe, roundTrip.HealthProbe)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "healthProbe": {"interval": "10"}}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid healthProbe interval")
+}
+
+func TestVaultClient_UnmarshalJSON_NormalizesPins(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "tls": {"pins": ["AB:CD:EF", "0123ab"]}}`), &got))
+	require.Equal(t, []string{"abcdef", "0123ab"}, got.TLS.Pins)
+}
+
+func TestVaultClient_UnmarshalJSON_FIPS(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "https://vault:1111", "tls": {"fips": true}}`), &got))
+	require.True(t, got.TLS.FIPS)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.True(t, roundTrip.TLS.FIPS)
+}
+
+func TestVaultClient_UnmarshalJSON_CACertDir(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "tls": {"caCertDir": "file:///path/to/cas", "appendSystemRoots": true}}`), &got))
+	require.Equal(t, &url.URL{Scheme: "file", Path: "/path/to/cas"}, got.TLS.CaCertDir)
+	require.True(t, got.TLS.AppendSystemRoots)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.TLS, roundTrip.TLS)
+}
+
+func TestVaultClient_UnmarshalJSON_TokenSink(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "stateDirectory": "file:///path/to/state", "tokenSink": {"key": "env://SINK_KEY"}}`), &got))
+	require.Equal(t, &url.URL{Scheme: "file", Path: "/path/to/state"}, got.StateDirectory)
+	require.Equal(t, &EnvironmentVariable{Scheme: "env", Host: "SINK_KEY"}, got.TokenSink.Key)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.StateDirectory, roundTrip.StateDirectory)
+	require.Equal(t, got.TokenSink, roundTrip.TokenSink)
+}
+
+func TestVaultClient_UnmarshalJSON_TokenSinkNotConfigured(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111"}`), &got))
+	require.Nil(t, got.StateDirectory)
+	require.Nil(t, got.TokenSink.Key)
+}
+
+func TestVaultClient_UnmarshalJSON_Kubernetes(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "authentication": {"kubernetes": {"role": "quorum"}}}`), &got))
+	require.Equal(t, "quorum", got.Authentication.Kubernetes.Role)
+	require.Equal(t, "kubernetes", got.Authentication.Kubernetes.Path)
+	require.Equal(t, &url.URL{Scheme: "file", Path: "/var/run/secrets/kubernetes.io/serviceaccount/token"}, got.Authentication.Kubernetes.ServiceAccountToken)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Authentication.Kubernetes, roundTrip.Authentication.Kubernetes)
+}
+
+func TestVaultClient_UnmarshalJSON_KubernetesNotConfigured(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "authentication": {"kubernetes": {"path": "k8s"}}}`), &got))
+	require.Equal(t, VaultClientKubernetes{}, got.Authentication.Kubernetes)
+}
+
+func TestVaultClient_UnmarshalJSON_Azure(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "authentication": {"azure": {"role": "quorum", "clientId": "myclientid"}}}`), &got))
+	require.Equal(t, VaultClientAzure{
+		Role:     "quorum",
+		Path:     "azure",
+		Resource: "https://management.azure.com/",
+		ClientID: "myclientid",
+	}, got.Authentication.Azure)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Authentication.Azure, roundTrip.Authentication.Azure)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "authentication": {"azure": {"path": "azure-aks"}}}`), &got))
+	require.Equal(t, VaultClientAzure{}, got.Authentication.Azure)
+}
+
+func TestVaultClient_UnmarshalJSON_Cert(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "https://vault:1111", "authentication": {"cert": {"role": "quorum"}}}`), &got))
+	require.Equal(t, VaultClientCert{Role: "quorum", Path: "cert"}, got.Authentication.Cert)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Authentication.Cert, roundTrip.Authentication.Cert)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "https://vault:1111", "authentication": {"cert": {"path": "mtls"}}}`), &got))
+	require.Equal(t, VaultClientCert{}, got.Authentication.Cert)
+}
+
+func TestVaultClient_UnmarshalJSON_Userpass(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "authentication": {"ldap": {"username": "env://LDAP_USERNAME", "password": "file:///var/run/secrets/vault/ldap-password"}, "userpass": {"path": "up"}}}`), &got))
+	require.Equal(t, VaultClientUserpass{
+		Username: &EnvironmentVariable{Scheme: "env", Host: "LDAP_USERNAME"},
+		Password: &EnvironmentVariable{Scheme: "file", Path: "/var/run/secrets/vault/ldap-password"},
+		Path:     "ldap",
+	}, got.Authentication.Ldap)
+	// userpass is not configured as no username is set
+	require.Equal(t, VaultClientUserpass{}, got.Authentication.Userpass)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Authentication.Ldap, roundTrip.Authentication.Ldap)
+	require.Equal(t, got.Authentication.Userpass, roundTrip.Authentication.Userpass)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "authentication": {"userpass": {"username": "env://USERNAME", "password": "env://PASSWORD"}}}`), &got))
+	require.Equal(t, "userpass", got.Authentication.Userpass.Path)
+}
+
+func TestVaultClient_UnmarshalJSON_SecretIdWrapped(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "authentication": {"roleId": "env://ROLE_ID", "secretId": "file:///var/run/secrets/vault/wrapped-secret-id", "approlePath": "approle", "secretIdWrapped": true}}`), &got))
+	require.True(t, got.Authentication.SecretIdWrapped)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.True(t, roundTrip.Authentication.SecretIdWrapped)
+}
+
+func TestVaultClient_UnmarshalJSON_SecretIdRotator(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "authentication": {"roleId": "env://ROLE_ID", "secretId": "env://SECRET_ID", "approlePath": "approle", "secretIdRotator": {"token": "file:///var/run/secrets/vault/rotator-token", "role": "quorum"}}}`), &got))
+	require.Equal(t, "file:///var/run/secrets/vault/rotator-token", got.Authentication.SecretIdRotator.Token.String())
+	require.Equal(t, "quorum", got.Authentication.SecretIdRotator.Role)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Authentication.SecretIdRotator, roundTrip.Authentication.SecretIdRotator)
+
+	// not configured
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111"}`), &got))
+	require.Nil(t, got.Authentication.SecretIdRotator.Token)
+}
+
+func TestEnvironmentVariable_File(t *testing.T) {
+	f, err := ioutil.TempFile("", "credential")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("my-role-id\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	u, err := url.Parse("file://" + f.Name())
+	require.NoError(t, err)
+	e := EnvironmentVariable(*u)
+
+	require.True(t, e.IsFile())
+	require.True(t, e.IsSet())
+	require.Equal(t, "my-role-id", e.Get())
+
+	u, err = url.Parse("file:///does/not/exist")
+	require.NoError(t, err)
+	e = EnvironmentVariable(*u)
+
+	require.False(t, e.IsSet())
+	require.Equal(t, "", e.Get())
+}
+
+func TestVaultClient_UnmarshalJSON_Mirror(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "mirror": {"node": "node2", "promotionSecret": "signer", "pollInterval": "2s"}}`), &got))
+	require.Equal(t, VaultClientMirror{Node: "node2", PromotionSecret: "signer", PollInterval: 2 * time.Second}, got.Mirror)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Mirror, roundTrip.Mirror)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "mirror": {"pollInterval": "2"}}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid mirror pollInterval")
+}
+
+func TestVaultClient_UnmarshalJSON_QuorumPermissioning(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "quorumPermissioning": {"rpc": "http://localhost:8545", "accountManager": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "cacheTTL": "1m"}}`), &got))
+	require.Equal(t, VaultClientQuorumPermissioning{
+		RPC:            &url.URL{Scheme: "http", Host: "localhost:8545"},
+		AccountManager: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+		CacheTTL:       time.Minute,
+	}, got.QuorumPermissioning)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.QuorumPermissioning, roundTrip.QuorumPermissioning)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "quorumPermissioning": {"cacheTTL": "1"}}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid quorumPermissioning cacheTTL")
+}
+
+func TestVaultClient_UnmarshalJSON_Escrow(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "escrow": {"publicKey": "file:///path/to/escrow.pem"}}`), &got))
+	require.Equal(t, &url.URL{Scheme: "file", Path: "/path/to/escrow.pem"}, got.Escrow.PublicKey)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Escrow, roundTrip.Escrow)
+
+	// escrow is disabled if not configured
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111"}`), &roundTrip))
+	require.Nil(t, roundTrip.Escrow.PublicKey)
+}
+
+func TestVaultClient_UnmarshalJSON_SigningLatencySLO(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "signingLatencySLO": {"threshold": "250ms", "percentile": 99.5, "window": 500}}`), &got))
+	require.Equal(t, VaultClientSigningLatencySLO{Threshold: 250 * time.Millisecond, Percentile: 99.5, Window: 500}, got.SigningLatencySLO)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.SigningLatencySLO, roundTrip.SigningLatencySLO)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "signingLatencySLO": {"threshold": "250"}}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid signingLatencySLO threshold")
+}
+
+func TestVaultClient_UnmarshalJSON_Headers(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "nodeId": "node1", "headers": {"X-Tenant": "quorum"}}`), &got))
+	require.Equal(t, "node1", got.NodeID)
+	require.Equal(t, map[string]string{"X-Tenant": "quorum"}, got.Headers)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.NodeID, roundTrip.NodeID)
+	require.Equal(t, got.Headers, roundTrip.Headers)
+}
+
+func TestVaultClient_UnmarshalJSON_SecretsEngine(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "secretsEngine": "cubbyhole"}`), &got))
+	require.Equal(t, SecretsEngineCubbyhole, got.SecretsEngine)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.SecretsEngine, roundTrip.SecretsEngine)
+}
+
+func TestVaultClient_UnmarshalJSON_VersionFallback(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "versionFallback": true}`), &got))
+	require.True(t, got.VersionFallback)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.True(t, roundTrip.VersionFallback)
+}
+
+func TestVaultClient_UnmarshalJSON_Dev(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"dev": true, "accountDirectory": "file:///path/to/dir"}`), &got))
+	require.True(t, got.Dev)
+	require.NoError(t, got.Validate())
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.True(t, roundTrip.Dev)
+}
+
+func TestVaultClient_UnmarshalJSON_Pepper(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "pepper": "file:///path/to/pepper"}`), &got))
+	require.Equal(t, "file:///path/to/pepper", got.Pepper.String())
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Pepper, roundTrip.Pepper)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111"}`), &roundTrip))
+	require.Nil(t, roundTrip.Pepper)
+}
+
+func TestVaultClient_UnmarshalJSON_SignGrants(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "signGrants": {"key": "env://GRANT_KEY", "accounts": ["0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5"]}}`), &got))
+	require.Equal(t, "env://GRANT_KEY", got.SignGrants.Key.String())
+	require.Equal(t, []string{"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5"}, got.SignGrants.Accounts)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.SignGrants, roundTrip.SignGrants)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111"}`), &roundTrip))
+	require.Nil(t, roundTrip.SignGrants.Key)
+}
+
+func TestVaultClient_UnmarshalJSON_AddressMismatch(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "addressMismatch": "trustConfig+alert"}`), &got))
+	require.Equal(t, AddressMismatchTrustConfigAlert, got.AddressMismatch)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.AddressMismatch, roundTrip.AddressMismatch)
+}
+
+func TestVaultClient_UnmarshalJSON_StrictSignDomains(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "strictSignDomains": true}`), &got))
+	require.True(t, got.StrictSignDomains)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.True(t, roundTrip.StrictSignDomains)
+}
+
+func TestVaultClient_UnmarshalJSON_StandbyTokens(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "standbyTokens": 2}`), &got))
+	require.Equal(t, 2, got.StandbyTokens)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, 2, roundTrip.StandbyTokens)
+}
+
+func TestVaultClient_UnmarshalJSON_RequestNamespaces(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "requestNamespaces": {"allowed": ["tenant1", "tenant2/team"], "peers": ["10.0.0.0/8"]}}`), &got))
+	require.Equal(t, VaultClientRequestNamespaces{Allowed: []string{"tenant1", "tenant2/team"}, Peers: []string{"10.0.0.0/8"}}, got.RequestNamespaces)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.RequestNamespaces, roundTrip.RequestNamespaces)
+}
+
+func TestVaultClient_UnmarshalJSON_SignPayloads(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "signPayloads": {"maxBytes": 131072, "maxCalldataBytes": 65536, "selectors": {"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": ["0xa9059cbb"]}}}`), &got))
+	require.Equal(t, VaultClientSignPayloads{
+		MaxBytes:         131072,
+		MaxCalldataBytes: 65536,
+		Selectors:        map[string][]string{"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {"0xa9059cbb"}},
+	}, got.SignPayloads)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.SignPayloads, roundTrip.SignPayloads)
+}
+
+func TestVaultClient_UnmarshalJSON_Localities(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"vault": "http://vault:1111",
+		"readReplicas": ["HTTPS://Replica:443/"],
+		"locality": "eu-west-1/eu-west-1a",
+		"localities": {"HTTPS://Replica:443/": "eu-west-1/eu-west-1b"}
+	}`), &got))
+	require.Equal(t, "eu-west-1/eu-west-1a", got.Locality)
+	require.Equal(t, map[string]string{"https://replica": "eu-west-1/eu-west-1b"}, got.Localities)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Localities, roundTrip.Localities)
+}
+
+func TestVaultClient_UnmarshalJSON_UsageReport(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "usageReport": {"interval": "24h", "file": "file:///path/to/usage.csv", "format": "csv", "endpoint": "https://reports.example.com/usage", "token": "env://REPORT_TOKEN"}}`), &got))
+	require.Equal(t, VaultClientUsageReport{
+		Interval: 24 * time.Hour,
+		File:     &url.URL{Scheme: "file", Path: "/path/to/usage.csv"},
+		Format:   UsageReportFormatCSV,
+		Endpoint: &url.URL{Scheme: "https", Host: "reports.example.com", Path: "/usage"},
+		Token:    &EnvironmentVariable{Scheme: "env", Host: "REPORT_TOKEN"},
+	}, got.UsageReport)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.UsageReport, roundTrip.UsageReport)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "usageReport": {"interval": "24"}}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid usageReport interval")
+}
+
+func TestVaultClient_UnmarshalJSON_AccountSettings(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "accountDefaults": {"maxUnlockDuration": "1h", "readCache": false}, "accountOverrides": {"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {"maxUnlockDuration": "5m", "strictSignDomains": true}}}`), &got))
+	hour, fiveMinutes, no, yes := time.Hour, 5*time.Minute, false, true
+	require.Equal(t, AccountSettings{MaxUnlockDuration: &hour, ReadCache: &no}, got.AccountDefaults)
+	require.Equal(t, map[string]AccountSettings{
+		"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {MaxUnlockDuration: &fiveMinutes, StrictSignDomains: &yes},
+	}, got.AccountOverrides)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.AccountDefaults, roundTrip.AccountDefaults)
+	require.Equal(t, got.AccountOverrides, roundTrip.AccountOverrides)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "accountOverrides": {"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {"maxUnlockDuration": "5"}}}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid accountOverrides for 0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5: invalid maxUnlockDuration")
+}
+
+func TestVaultClient_UnmarshalJSON_MaxStaleness(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111"}`), &got))
+	require.Nil(t, got.MaxStaleness)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "maxStaleness": "0s"}`), &got))
+	require.NotNil(t, got.MaxStaleness)
+	require.Equal(t, time.Duration(0), *got.MaxStaleness)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "maxStaleness": "5m"}`), &got))
+	require.Equal(t, 5*time.Minute, *got.MaxStaleness)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.MaxStaleness, roundTrip.MaxStaleness)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "maxStaleness": "5"}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid maxStaleness")
+}
+
+func TestVaultClient_UnmarshalJSON_AccountStore(t *testing.T) {
+	var got VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{"vault": "http://vault:1111", "accountStore": {"type": "consul", "address": "http://consul:8500", "prefix": "quorum/accounts", "token": "env://CONSUL_TOKEN", "pollInterval": "1m"}}`), &got))
+	require.Nil(t, got.AccountDirectory)
+	require.Equal(t, VaultClientAccountStore{
+		Type:         AccountStoreConsul,
+		Address:      &url.URL{Scheme: "http", Host: "consul:8500"},
+		Prefix:       "quorum/accounts",
+		Token:        &EnvironmentVariable{Scheme: "env", Host: "CONSUL_TOKEN"},
+		PollInterval: time.Minute,
+	}, got.AccountStore)
+
+	b, err := json.Marshal(&got)
+	require.NoError(t, err)
+
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.AccountStore, roundTrip.AccountStore)
+	require.Nil(t, roundTrip.AccountDirectory)
+
+	err = json.Unmarshal([]byte(`{"vault": "http://vault:1111", "accountStore": {"pollInterval": "1"}}`), &got)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid accountStore pollInterval")
+}
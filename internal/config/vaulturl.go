@@ -0,0 +1,46 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// NormalizeVaultURL returns u in a canonical form so that the same Vault server written in different ways results in
+// the same client address and account URLs: the scheme and host are lowercased, the default port for the scheme is
+// removed and a root path of "/" is removed.  A nil u is returned as nil.
+func NormalizeVaultURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = lowerHost(n.Host)
+
+	if host, port, err := net.SplitHostPort(n.Host); err == nil {
+		if (n.Scheme == "http" && port == "80") || (n.Scheme == "https" && port == "443") {
+			n.Host = host
+			if strings.Contains(host, ":") {
+				// IPv6 literal
+				n.Host = "[" + host + "]"
+			}
+		}
+	}
+
+	if n.Path == "/" {
+		n.Path = ""
+		n.RawPath = ""
+	}
+	return &n
+}
+
+// lowerHost lowercases host, except for the zone of an IPv6 literal (e.g. [fe80::1%eth0]) as zone names are
+// OS interface names
+func lowerHost(host string) string {
+	if i := strings.Index(host, "%"); i >= 0 && strings.HasPrefix(host, "[") {
+		if j := strings.Index(host[i:], "]"); j >= 0 {
+			return strings.ToLower(host[:i]) + host[i:i+j] + strings.ToLower(host[i+j:])
+		}
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeVaultURL(t *testing.T) {
+	tests := map[string]string{
+		"https://vault:8200":           "https://vault:8200",
+		"https://vault:8200/":          "https://vault:8200",
+		"HTTPS://Vault.Example.COM/":   "https://vault.example.com",
+		"https://vault:443":            "https://vault",
+		"http://vault:80/":             "http://vault",
+		"http://vault:443":             "http://vault:443",
+		"https://vault:8200/prefix/":   "https://vault:8200/prefix/",
+		"https://[FE80::1]:443":        "https://[fe80::1]",
+		"https://[fe80::1]:8200/":      "https://[fe80::1]:8200",
+		"https://[FE80::1%25Eth0]:443": "https://[fe80::1%25Eth0]",
+		"https://vault:8200/v1?ver=1":  "https://vault:8200/v1?ver=1",
+	}
+	for in, want := range tests {
+		u, err := url.Parse(in)
+		require.NoError(t, err)
+		require.Equal(t, want, NormalizeVaultURL(u).String(), in)
+	}
+}
+
+func TestNormalizeVaultURL_Nil(t *testing.T) {
+	require.Nil(t, NormalizeVaultURL(nil))
+}
@@ -0,0 +1,155 @@
+// Package debug implements an optional HTTP listener for troubleshooting a running plugin.  It serves:
+//
+//	/debug/pprof/         Go runtime profiles
+//	/debug/vars           expvar metrics
+//	/debug/state          a JSON snapshot of the plugin's internal state
+//	/debug/events         recently emitted events
+//	/debug/events/stream  events as they are emitted, as server-sent events
+//
+// The listener must only be bound to a loopback address as it is unauthenticated.  Handlers must never expose key
+// material.
+package debug
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+)
+
+// StateFunc returns a snapshot of the plugin's internal state, to be marshalled as JSON
+type StateFunc func() interface{}
+
+// streamBuffer is the number of events buffered for each stream client, further events are dropped if the client is
+// slow to read them
+const streamBuffer = 64
+
+type Server struct {
+	mux      *http.ServeMux
+	server   *http.Server
+	listener net.Listener
+}
+
+type state struct {
+	Goroutines int
+	Plugin     interface{}
+}
+
+// Start starts serving on addr, which must be a loopback address
+func Start(addr string, pluginState StateFunc) (*Server, error) {
+	if err := config.CheckLoopbackAddress(addr); err == config.NotLoopbackErr {
+		return nil, fmt.Errorf("debug listener address must be a loopback address, got %v", addr)
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid debug listener address: %v", err)
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start debug listener: %v", err)
+	}
+
+	s := &Server{
+		mux:      http.NewServeMux(),
+		listener: l,
+	}
+	s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.mux.Handle("/debug/vars", expvar.Handler())
+	s.mux.HandleFunc("/debug/state", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, state{
+			Goroutines: runtime.NumGoroutine(),
+			Plugin:     pluginState(),
+		})
+	})
+	s.mux.HandleFunc("/debug/events", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, event.Recent())
+	})
+	s.mux.HandleFunc("/debug/events/stream", streamEvents)
+
+	s.server = &http.Server{Handler: s.mux}
+	go func() {
+		if err := s.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Printf("[ERROR] debug listener stopped: %v", err)
+		}
+	}()
+	log.Printf("[INFO] debug listener started on %v", l.Addr())
+
+	return s, nil
+}
+
+// streamEvents writes events to the client as they are emitted until the client disconnects.  The optional kind query
+// parameter filters by kind prefix (e.g. kind=AUTH_) and subject by exact subject (e.g. subject=approle/myapprole).
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	var (
+		kind    = r.URL.Query().Get("kind")
+		subject = r.URL.Query().Get("subject")
+	)
+
+	events, unsubscribe := event.Subscribe(streamBuffer)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			if !strings.HasPrefix(string(e.Kind), kind) || (subject != "" && e.Subject != subject) {
+				continue
+			}
+			b, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Addr returns the address the server is listening on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Handle registers an additional handler on the server
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// WriteJSON writes v to w as indented JSON
+func WriteJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,104 @@
+package debug
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, s *Server, path string) []byte {
+	resp, err := http.Get("http://" + s.Addr().String() + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return b
+}
+
+func TestStart_NonLoopbackRejected(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:6060", ":6060", "10.0.0.1:6060", "example.com:6060"} {
+		_, err := Start(addr, nil)
+		require.EqualError(t, err, "debug listener address must be a loopback address, got "+addr, addr)
+	}
+}
+
+func TestStart_InvalidAddress(t *testing.T) {
+	_, err := Start("localhost", nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid debug listener address")
+}
+
+func TestServer_Endpoints(t *testing.T) {
+	s, err := Start("127.0.0.1:0", func() interface{} {
+		return map[string]int{"Accounts": 2}
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	var got struct {
+		Goroutines int
+		Plugin     map[string]int
+	}
+	require.NoError(t, json.Unmarshal(get(t, s, "/debug/state"), &got))
+	require.NotZero(t, got.Goroutines)
+	require.Equal(t, 2, got.Plugin["Accounts"])
+
+	vars := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(get(t, s, "/debug/vars"), &vars))
+	require.Contains(t, vars, "memstats")
+
+	require.Contains(t, string(get(t, s, "/debug/pprof/")), "goroutine")
+
+	get(t, s, "/debug/events")
+}
+
+func TestServer_EventStream(t *testing.T) {
+	s, err := Start("127.0.0.1:0", nil)
+	require.NoError(t, err)
+	defer s.Close()
+
+	resp, err := http.Get("http://" + s.Addr().String() + "/debug/events/stream?kind=AUTH_&subject=approle/myapprole")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// the handler has subscribed once the headers have been received
+	event.Emit(event.AccountDegraded, "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", "secret not found")
+	event.Emit(event.AuthRenewed, "approle/otherapprole", "ttl 1h0m0s")
+	event.Emit(event.AuthRenewalFailed, "approle/myapprole", "permission denied")
+
+	lines := make(chan string)
+	go func() {
+		r := bufio.NewReader(resp.Body)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			if strings.HasPrefix(line, "data: ") {
+				lines <- strings.TrimPrefix(strings.TrimSpace(line), "data: ")
+			}
+		}
+	}()
+
+	select {
+	case line := <-lines:
+		var got event.Event
+		require.NoError(t, json.Unmarshal([]byte(line), &got))
+		require.Equal(t, event.AuthRenewalFailed, got.Kind)
+		require.Equal(t, "approle/myapprole", got.Subject)
+		require.Equal(t, "permission denied", got.Message)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
@@ -0,0 +1,134 @@
+// Package event publishes notable changes in plugin state (e.g. an account becoming unusable).  Events are written
+// to the plugin log, retained in a bounded in-memory history, and delivered to any subscribers.
+package event
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	AccountDegraded  Kind = "ACCOUNT_DEGRADED"
+	AccountRecovered Kind = "ACCOUNT_RECOVERED"
+	VaultFailover    Kind = "VAULT_FAILOVER"
+	VaultRecovered   Kind = "VAULT_RECOVERED"
+	WalletDropped    Kind = "WALLET_DROPPED"
+	WalletArrived    Kind = "WALLET_ARRIVED"
+
+	// auth lifecycle events have the auth method and path as their subject, e.g. approle/myapprole
+	AuthRenewed              Kind = "AUTH_RENEWED"
+	AuthRenewalFailed        Kind = "AUTH_RENEWAL_FAILED"
+	AuthTokenNearExpiry      Kind = "AUTH_TOKEN_NEAR_EXPIRY"
+	AuthReauthenticated      Kind = "AUTH_REAUTHENTICATED"
+	AuthReauthenticateFailed Kind = "AUTH_REAUTHENTICATE_FAILED"
+	AuthTokenRevoked         Kind = "AUTH_TOKEN_REVOKED"
+	AuthSecretIDRotated      Kind = "AUTH_SECRET_ID_ROTATED"
+
+	UnlockApprovalPending Kind = "UNLOCK_APPROVAL_PENDING"
+	UnlockApproved        Kind = "UNLOCK_APPROVED"
+	UnlockApprovalExpired Kind = "UNLOCK_APPROVAL_EXPIRED"
+
+	// the account store, e.g. consul://localhost:8500/quorum/accounts, is the subject
+	AccountsReloaded Kind = "ACCOUNTS_RELOADED"
+
+	// mirror events have the mirror's node name as their subject
+	MirrorPromoted Kind = "MIRROR_PROMOTED"
+	MirrorDemoted  Kind = "MIRROR_DEMOTED"
+
+	// the account address is the subject
+	DuplicateSign Kind = "DUPLICATE_SIGN"
+
+	// signing latency events have signing as their subject
+	SigningDegraded  Kind = "SIGNING_DEGRADED"
+	SigningRecovered Kind = "SIGNING_RECOVERED"
+
+	// the account address is the subject
+	SecretVersionFallback Kind = "SECRET_VERSION_FALLBACK"
+
+	// the account address is the subject, and the freeze reason the message
+	AccountFrozen   Kind = "ACCOUNT_FROZEN"
+	AccountUnfrozen Kind = "ACCOUNT_UNFROZEN"
+
+	// the account config's address is the subject
+	AddressMismatch Kind = "ADDRESS_MISMATCH"
+
+	// the name of the background worker that panicked is the subject, e.g. connectivity probe
+	WorkerRestarted Kind = "WORKER_RESTARTED"
+)
+
+const historySize = 100
+
+type Event struct {
+	Time    time.Time `json:"time"`
+	Kind    Kind      `json:"kind"`
+	Subject string    `json:"subject,omitempty"` // e.g. the account address or vault the event relates to
+	Message string    `json:"message,omitempty"`
+}
+
+var (
+	mu          sync.Mutex
+	history     []Event
+	subscribers = make(map[chan Event]struct{})
+)
+
+// Emit publishes a new event of the given kind
+func Emit(kind Kind, subject, message string) {
+	e := Event{
+		Time:    time.Now().UTC(),
+		Kind:    kind,
+		Subject: subject,
+		Message: message,
+	}
+
+	if b, err := json.Marshal(e); err == nil {
+		log.Printf("[INFO] event: %s", b)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	history = append(history, e)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	for ch := range subscribers {
+		select {
+		case ch <- e:
+		default:
+			// never block the emitter on a slow subscriber
+		}
+	}
+}
+
+// Recent returns the most recently emitted events, oldest first
+func Recent() []Event {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r := make([]Event, len(history))
+	copy(r, history)
+	return r
+}
+
+// Subscribe returns a channel on which all subsequently emitted events are delivered, and a func to unsubscribe.
+// Events are dropped if the channel's buffer is full.
+func Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	mu.Lock()
+	subscribers[ch] = struct{}{}
+	mu.Unlock()
+
+	return ch, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := subscribers[ch]; ok {
+			delete(subscribers, ch)
+			close(ch)
+		}
+	}
+}
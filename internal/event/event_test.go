@@ -0,0 +1,46 @@
+package event
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmit_Subscribe(t *testing.T) {
+	ch, unsubscribe := Subscribe(1)
+	defer unsubscribe()
+
+	Emit(AccountDegraded, "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", "secret not found")
+
+	got := <-ch
+	require.Equal(t, AccountDegraded, got.Kind)
+	require.Equal(t, "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", got.Subject)
+	require.Equal(t, "secret not found", got.Message)
+}
+
+func TestEmit_SlowSubscriberDoesNotBlock(t *testing.T) {
+	_, unsubscribe := Subscribe(0)
+	defer unsubscribe()
+
+	Emit(AccountRecovered, "subject", "")
+}
+
+func TestUnsubscribe_ClosesChannel(t *testing.T) {
+	ch, unsubscribe := Subscribe(1)
+	unsubscribe()
+	unsubscribe() // is safe to call multiple times
+
+	_, ok := <-ch
+	require.False(t, ok)
+}
+
+func TestRecent_IsBounded(t *testing.T) {
+	for i := 0; i < historySize+10; i++ {
+		Emit(AccountRecovered, fmt.Sprintf("%v", i), "")
+	}
+
+	got := Recent()
+	require.Len(t, got, historySize)
+	require.Equal(t, fmt.Sprintf("%v", historySize+9), got[len(got)-1].Subject)
+}
@@ -0,0 +1,47 @@
+package hashicorp
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// accountIDNamespace is the namespace of deterministic (version 5) account IDs, b201bc8c-687d-455d-808b-c44c836b79be
+var accountIDNamespace = [16]byte{0xb2, 0x01, 0xbc, 0x8c, 0x68, 0x7d, 0x45, 0x5d, 0x80, 0x8b, 0xc4, 0x4c, 0x83, 0x6b, 0x79, 0xbe}
+
+// newAccountID returns the ID for a new account config.  Deterministic IDs are derived from the address and the
+// secret's path in Vault (but not the Vault address, which can differ between nodes using the same Vault cluster), so
+// the same account provisioned on multiple nodes has the same ID.
+func newAccountID(mode, kvEngineName, secretName, addrHex string) (string, error) {
+	if mode == config.AccountIDDeterministic {
+		name := fmt.Sprintf("%v/data/%v/0x%v", kvEngineName, secretName, config.NormalizeAddress(addrHex))
+		return uuidV5(accountIDNamespace, name), nil
+	}
+	return uuidV4()
+}
+
+func uuidV4() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	return formatUUID(u, 4), nil
+}
+
+func uuidV5(namespace [16]byte, name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	return formatUUID(u, 5)
+}
+
+// formatUUID sets the version and RFC 4122 variant bits and formats the UUID
+func formatUUID(u [16]byte, version byte) string {
+	u[6] = (u[6] & 0x0f) | version<<4
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
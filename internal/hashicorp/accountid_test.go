@@ -0,0 +1,69 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+var uuidV4Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestUUIDV5(t *testing.T) {
+	dns := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+	require.Equal(t, "2ed6657d-e927-568b-95e1-2665a8aea6a2", uuidV5(dns, "www.example.com"))
+}
+
+func TestNewAccountID_Deterministic(t *testing.T) {
+	want := "557a28c8-7e8e-560e-b873-c98130f3ba0a"
+
+	for _, addr := range []string{"4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", "0x4D6D744B6DA435b5bBdDE2526DC20E9a41cB72E5"} {
+		got, err := newAccountID(config.AccountIDDeterministic, "kv", "acct1", addr)
+		require.NoError(t, err)
+		require.Equal(t, want, got, addr)
+	}
+
+	other, err := newAccountID(config.AccountIDDeterministic, "kv", "acct2", "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+	require.NoError(t, err)
+	require.NotEqual(t, want, other)
+}
+
+func TestNewAccountID_Random(t *testing.T) {
+	for _, mode := range []string{"", config.AccountIDRandom} {
+		first, err := newAccountID(mode, "kv", "acct1", "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+		require.NoError(t, err)
+		second, err := newAccountID(mode, "kv", "acct1", "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+		require.NoError(t, err)
+
+		require.Regexp(t, uuidV4Pattern, first)
+		require.Regexp(t, uuidV4Pattern, second)
+		require.NotEqual(t, first, second)
+	}
+}
+
+func TestWriteToFile_DeterministicAccountID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accountid")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := reconcileAccountManager(t, "http://vault:8200", dir)
+	a.accountID = config.AccountIDDeterministic
+
+	fileData, err := a.writeToFile("4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", 1, config.NewAccount{SecretName: "acct1"})
+	require.NoError(t, err)
+	require.Equal(t, "557a28c8-7e8e-560e-b873-c98130f3ba0a", fileData.Contents.ID)
+
+	u, err := url.Parse(fileData.Path)
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(config.FilePath(u))
+	require.NoError(t, err)
+	var written config.AccountFileJSON
+	require.NoError(t, json.Unmarshal(b, &written))
+	require.Equal(t, fileData.Contents, written)
+}
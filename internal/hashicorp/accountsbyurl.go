@@ -3,6 +3,10 @@ package hashicorp
 import (
 	"errors"
 	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
@@ -44,3 +48,68 @@ func (m accountsByURL) GetAccountWithAddress(address account.Address) (config.Ac
 	}
 	return acct, nil
 }
+
+// sortedURLs returns the account URLs in the given config.AccountOrder.  Ties are broken by URL so that the order is
+// stable across restarts.
+func (m accountsByURL) sortedURLs(order string) []*url.URL {
+	urls := make([]*url.URL, 0, len(m))
+	for u := range m {
+		urls = append(urls, u)
+	}
+
+	byURL := func(i, j int) bool {
+		return urls[i].String() < urls[j].String()
+	}
+
+	switch order {
+	case config.AccountOrderAddress:
+		sort.Slice(urls, func(i, j int) bool {
+			ai, aj := m[urls[i]].Contents.Address, m[urls[j]].Contents.Address
+			if ai != aj {
+				return ai < aj
+			}
+			return byURL(i, j)
+		})
+	case config.AccountOrderCreated:
+		created := make(map[*url.URL]time.Time, len(m))
+		for u, file := range m {
+			if t, ok := accountCreated(file.Path); ok {
+				created[u] = t
+			}
+		}
+		sort.Slice(urls, func(i, j int) bool {
+			ci, iok := created[urls[i]]
+			cj, jok := created[urls[j]]
+			if iok != jok {
+				// accounts with an unknown creation time are listed last
+				return iok
+			}
+			if !ci.Equal(cj) {
+				return ci.Before(cj)
+			}
+			return byURL(i, j)
+		})
+	default:
+		sort.Slice(urls, byURL)
+	}
+	return urls
+}
+
+// accountFileTimeFormat is the format of the creation time in the name of an account config, as also used by geth for
+// keystore files
+const accountFileTimeFormat = "2006-01-02T15-04-05.000000000Z"
+
+// accountCreated returns the creation time in the UTC--<time>--<address> name of the account config at the given path
+// or store location.  The name is set when the account is created, so unlike the modification time it is unchanged if
+// the config is rewritten.
+func accountCreated(location string) (time.Time, bool) {
+	parts := strings.SplitN(filepath.Base(location), "--", 3)
+	if len(parts) != 3 || parts[0] != "UTC" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(accountFileTimeFormat, parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
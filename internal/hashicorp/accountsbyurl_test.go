@@ -2,8 +2,12 @@ package hashicorp
 
 import (
 	"encoding/hex"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
@@ -213,3 +217,68 @@ func TestAccountsByURL_HasAccountWithAddress_NotFound_Error(t *testing.T) {
 
 	require.EqualError(t, err, unknownAccountErr.Error())
 }
+
+func TestAccountsByURL_SortedURLs(t *testing.T) {
+	u1, _ := url.Parse("http://vault:1111/v1/kv/data/acct1?version=1")
+	u2, _ := url.Parse("http://vault:1111/v1/kv/data/acct2?version=1")
+	u3, _ := url.Parse("http://vault:1111/v1/kv/data/acct3?version=1")
+	u4, _ := url.Parse("http://vault:1111/v1/kv/data/acct4?version=1")
+
+	now := time.Now().UTC()
+	a := accountsByURL{
+		u1: {Path: "/path/to/UTC--" + now.Format(accountFileTimeFormat) + "--dc62574e0f79f5e9585dca30d7161d729496f14e", Contents: config.AccountFileJSON{Address: "dc62574e0f79f5e9585dca30d7161d729496f14e"}},
+		u2: {Path: "consul://consul:8500/quorum/accounts/UTC--" + now.Add(-time.Hour).Format(accountFileTimeFormat) + "--2ea32174140e8f9b24aaf4a066a7dc2dcb6c4166", Contents: config.AccountFileJSON{Address: "2ea32174140e8f9b24aaf4a066a7dc2dcb6c4166"}},
+		u3: {Path: "etcd://etcd:2379/quorum/accounts/UTC--" + now.Add(-time.Hour).Format(accountFileTimeFormat) + "--2ea32174140e8f9b24aaf4a066a7dc2dcb6c4166", Contents: config.AccountFileJSON{Address: "2ea32174140e8f9b24aaf4a066a7dc2dcb6c4166"}},
+		u4: {Path: "/path/to/acct4.json", Contents: config.AccountFileJSON{Address: "0000000000000000000000000000000000000000"}},
+	}
+
+	require.Equal(t, []*url.URL{u1, u2, u3, u4}, a.sortedURLs(""))
+	require.Equal(t, []*url.URL{u1, u2, u3, u4}, a.sortedURLs(config.AccountOrderURL))
+	require.Equal(t, []*url.URL{u4, u2, u3, u1}, a.sortedURLs(config.AccountOrderAddress))
+	require.Equal(t, []*url.URL{u2, u3, u1, u4}, a.sortedURLs(config.AccountOrderCreated))
+}
+
+func TestAccountsByURL_SortedURLs_CreatedUnchangedByRewrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	u1, _ := url.Parse("http://vault:1111/v1/kv/data/acct1?version=1")
+	u2, _ := url.Parse("http://vault:1111/v1/kv/data/acct2?version=1")
+
+	now := time.Now().UTC()
+	newFile := func(addr string, created time.Time) config.AccountFile {
+		path := filepath.Join(dir, "UTC--"+created.Format(accountFileTimeFormat)+"--"+addr)
+		require.NoError(t, ioutil.WriteFile(path, []byte("{}"), 0600))
+		return config.AccountFile{Path: path, Contents: config.AccountFileJSON{Address: addr}}
+	}
+	a := accountsByURL{
+		u1: newFile("dc62574e0f79f5e9585dca30d7161d729496f14e", now.Add(-time.Hour)),
+		u2: newFile("2ea32174140e8f9b24aaf4a066a7dc2dcb6c4166", now),
+	}
+	require.Equal(t, []*url.URL{u1, u2}, a.sortedURLs(config.AccountOrderCreated))
+
+	// e.g. frozen or with its path rewritten, after the newer account was created
+	rewritten := now.Add(time.Hour)
+	require.NoError(t, ioutil.WriteFile(a[u1].Path, []byte(`{"Frozen": true}`), 0600))
+	require.NoError(t, os.Chtimes(a[u1].Path, rewritten, rewritten))
+
+	require.Equal(t, []*url.URL{u1, u2}, a.sortedURLs(config.AccountOrderCreated))
+}
+
+func TestAccountCreated(t *testing.T) {
+	want := time.Date(2020, 5, 1, 12, 30, 15, 123456789, time.UTC)
+
+	got, ok := accountCreated("/path/to/UTC--2020-05-01T12-30-15.123456789Z--dc62574e0f79f5e9585dca30d7161d729496f14e")
+	require.True(t, ok)
+	require.True(t, want.Equal(got))
+
+	got, ok = accountCreated("consul://consul:8500/quorum/accounts/UTC--2020-05-01T12-30-15.123456789Z--dc62574e0f79f5e9585dca30d7161d729496f14e")
+	require.True(t, ok)
+	require.True(t, want.Equal(got))
+
+	for _, location := range []string{"/path/to/acct1.json", "/path/to/UTC--notatime--dc62574e0f79f5e9585dca30d7161d729496f14e", ""} {
+		_, ok = accountCreated(location)
+		require.False(t, ok, location)
+	}
+}
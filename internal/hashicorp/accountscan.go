@@ -0,0 +1,66 @@
+package hashicorp
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AccountScanState describes the most recent load of account configs from the accountDirectory or accountStore, and
+// the accounts currently known
+type AccountScanState struct {
+	Time               time.Time
+	Duration           string
+	IgnoredFiles       []string `json:",omitempty"` // temporary files of in-progress or interrupted writes
+	AccountsByVault    map[string]int
+	AmbiguousAddresses []string `json:",omitempty"` // addresses with more than one account config, which cannot be used
+}
+
+// accountScan records the most recent load of the account store
+type accountScan struct {
+	time     time.Time
+	duration time.Duration
+	ignored  []string
+	version  uint64 // of the account store
+}
+
+// byVault returns the number of accounts for each Vault server
+func (m accountsByURL) byVault() map[string]int {
+	counts := make(map[string]int)
+	for u := range m {
+		counts[fmt.Sprintf("%v://%v", u.Scheme, u.Host)]++
+	}
+	return counts
+}
+
+// ambiguousAddresses returns the addresses that have more than one account config, sorted
+func (m accountsByURL) ambiguousAddresses() []string {
+	counts := make(map[string]int)
+	for _, file := range m {
+		counts[file.Contents.Address]++
+	}
+	var ambiguous []string
+	for addr, n := range counts {
+		if n > 1 {
+			ambiguous = append(ambiguous, fmt.Sprintf("0x%v", addr))
+		}
+	}
+	sort.Strings(ambiguous)
+	return ambiguous
+}
+
+// scanState returns nil if the account directory has not been loaded
+func (c *vaultClient) scanState() *AccountScanState {
+	c.acctsMu.RLock()
+	defer c.acctsMu.RUnlock()
+	if c.scan.time.IsZero() {
+		return nil
+	}
+	return &AccountScanState{
+		Time:               c.scan.time,
+		Duration:           c.scan.duration.String(),
+		IgnoredFiles:       c.scan.ignored,
+		AccountsByVault:    c.accts.byVault(),
+		AmbiguousAddresses: c.accts.ambiguousAddresses(),
+	}
+}
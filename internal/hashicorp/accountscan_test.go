@@ -0,0 +1,43 @@
+package hashicorp
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAccounts_ScanState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accountscan")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	acct := func(addr, secret string) []byte {
+		return []byte(`{"Address": "` + addr + `", "VaultAccount": {"SecretName": "` + secret + `", "SecretVersion": 1}, "Version": 1}`)
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "acct1"), acct(reconcileAddr1, "acct1"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "acct1-copy"), acct("0x"+reconcileAddr1, "acct1-copy"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "acct2"), acct("dc99ddec13457de6c0f6bb8e6cf3955c86f55526", "acct2"), 0600))
+	temp := filepath.Join(dir, ".acct3123.tmp")
+	require.NoError(t, ioutil.WriteFile(temp, []byte("{"), 0600))
+
+	acctDir, _ := url.Parse("file://" + dir + "/")
+	c := testVaultClient(t, "http://vault:8200")
+	c.kvEngineName, c.store = "kv", &dirStore{dir: acctDir}
+
+	require.Nil(t, c.scanState())
+
+	c.accts, err = c.loadAccounts()
+	require.NoError(t, err)
+
+	got := c.scanState()
+	require.NotNil(t, got)
+	require.False(t, got.Time.IsZero())
+	require.NotEmpty(t, got.Duration)
+	require.Equal(t, []string{temp}, got.IgnoredFiles)
+	require.Equal(t, map[string]int{"http://vault:8200": 3}, got.AccountsByVault)
+	require.Equal(t, []string{"0x" + reconcileAddr1}, got.AmbiguousAddresses)
+}
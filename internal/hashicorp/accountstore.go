@@ -0,0 +1,172 @@
+package hashicorp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/atomicfile"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+const (
+	defaultStorePollInterval = 10 * time.Second
+	storeRetryInterval       = 5 * time.Second
+	storeRequestTimeout      = 30 * time.Second
+)
+
+// staleTempFileAge is the age after which a temporary file in the account directory is assumed to have been left behind
+// by an interrupted write, rather than being written by another process (e.g. the restore command)
+const staleTempFileAge = time.Minute
+
+// accountStore is where account configs are kept, either the accountDirectory or a remote key-value store
+type accountStore interface {
+	// load returns all account configs in the store
+	load() (storeContents, error)
+	// create stores a new account config, returning its location
+	create(name string, contents []byte) (string, error)
+	String() string
+}
+
+// watchableStore is an accountStore that is shared with other nodes, and so is watched for changes
+type watchableStore interface {
+	accountStore
+	// wait blocks until the store's version is different to version or a timeout elapses, and returns the current
+	// version
+	wait(version uint64) (uint64, error)
+}
+
+type storedAccount struct {
+	location string // the file path or key of the account config
+	contents []byte
+}
+
+type storeContents struct {
+	accounts []storedAccount
+	ignored  []string // temporary files of in-progress or interrupted writes
+	version  uint64   // changes whenever the contents of a watchableStore change
+}
+
+func newAccountStore(conf config.VaultClient) accountStore {
+	switch conf.AccountStore.Type {
+	case config.AccountStoreConsul:
+		return newConsulStore(conf.AccountStore)
+	case config.AccountStoreEtcd:
+		return newEtcdStore(conf.AccountStore)
+	default:
+		return &dirStore{dir: conf.AccountDirectory}
+	}
+}
+
+// dirStore keeps each account config in a file in the accountDirectory.  The directory is not watched.
+type dirStore struct {
+	dir *url.URL
+}
+
+func (s *dirStore) String() string {
+	return config.FilePath(s.dir)
+}
+
+func (s *dirStore) load() (storeContents, error) {
+	var contents storeContents
+
+	walkFn := filepath.WalkFunc(func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// do nothing with directories
+			return nil
+		}
+		if atomicfile.IsTemp(path) {
+			// an in-progress or interrupted write
+			log.Printf("[DEBUG] Ignoring temporary file %v", path)
+			contents.ignored = append(contents.ignored, path)
+			return nil
+		}
+		log.Printf("[DEBUG] Loading %v", path)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		contents.accounts = append(contents.accounts, storedAccount{location: path, contents: b})
+		return nil
+	})
+
+	root := config.FilePath(s.dir)
+
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		log.Printf("[DEBUG] Creating empty directory at %v", root)
+		if err := os.Mkdir(root, os.ModeDir+0755); err != nil {
+			return storeContents{}, err
+		}
+		return contents, nil
+	}
+
+	// temporary files left behind if the plugin crashed while writing an account file
+	removed, err := atomicfile.RemoveStale(root, staleTempFileAge)
+	if err != nil {
+		return storeContents{}, err
+	}
+	for _, f := range removed {
+		log.Printf("[INFO] Removed stale temporary file %v", f)
+	}
+
+	log.Printf("[DEBUG] Loading accts from %v", root)
+	if err := filepath.Walk(root, walkFn); err != nil {
+		return storeContents{}, err
+	}
+	return contents, nil
+}
+
+// create writes to a temporary hidden file first then renames once complete so that the write appears atomic.
+// Temporary files are ignored when loading the account directory.
+func (s *dirStore) create(name string, contents []byte) (string, error) {
+	fullpath, err := s.dir.Parse(name)
+	if err != nil {
+		return "", err
+	}
+	filePath := config.FilePath(fullpath)
+	log.Printf("[DEBUG] writing to file %v", filePath)
+
+	if err := atomicfile.Write(filePath, contents, 0600); err != nil {
+		return "", err
+	}
+	return fullpath.String(), nil
+}
+
+// replace overwrites the account config file at location, which is either a file path as loaded from the directory or a
+// file URL as returned by create
+func (s *dirStore) replace(location string, contents []byte) error {
+	path := location
+	if u, err := url.Parse(location); err == nil && u.Scheme == "file" {
+		path = config.FilePath(u)
+	}
+	log.Printf("[DEBUG] replacing file %v", path)
+	return atomicfile.Write(path, contents, 0600)
+}
+
+// storeToken returns the value of the store's auth token, or an empty string if not configured
+func storeToken(token *config.EnvironmentVariable) string {
+	if token == nil {
+		return ""
+	}
+	return token.Get()
+}
+
+// storeKey joins the configured prefix and name to give a key in a remote store
+func storeKey(prefix, name string) string {
+	return fmt.Sprintf("%v/%v", strings.Trim(prefix, "/"), name)
+}
+
+func storePollInterval(conf config.VaultClientAccountStore) time.Duration {
+	if conf.PollInterval == 0 {
+		return defaultStorePollInterval
+	}
+	return conf.PollInterval
+}
@@ -0,0 +1,64 @@
+package hashicorp
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is a watchableStore whose version is incremented by each create
+type memoryStore struct {
+	mu       sync.Mutex
+	accounts []storedAccount
+	version  uint64
+}
+
+func (s *memoryStore) String() string {
+	return "memory"
+}
+
+func (s *memoryStore) load() (storeContents, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return storeContents{accounts: append([]storedAccount(nil), s.accounts...), version: s.version}, nil
+}
+
+func (s *memoryStore) create(name string, contents []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts = append(s.accounts, storedAccount{location: "memory/" + name, contents: contents})
+	s.version++
+	return "memory/" + name, nil
+}
+
+func (s *memoryStore) wait(version uint64) (uint64, error) {
+	time.Sleep(time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version, nil
+}
+
+func TestVaultClient_WatchAccountStore(t *testing.T) {
+	store := &memoryStore{}
+	c := testVaultClient(t, "http://vault:8200")
+	c.kvEngineName, c.store = "kv", store
+	var err error
+	c.accts, err = c.loadAccounts()
+	require.NoError(t, err)
+	require.Empty(t, c.accounts())
+
+	go c.watchAccountStore(store, c.scan.version)
+
+	// an account created by another node
+	_, err = store.create("acct1", []byte(`{"Address": "`+reconcileAddr1+`", "VaultAccount": {"SecretName": "acct1", "SecretVersion": 1}, "Version": 1}`))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(c.accounts()) == 1
+	}, time.Second, 10*time.Millisecond)
+	for _, f := range c.accounts() {
+		require.Equal(t, "memory/acct1", f.Path)
+	}
+}
@@ -1,36 +1,134 @@
 package hashicorp
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"os"
-	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/hashicorp/vault/api"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/state"
 	"github.com/jpmorganchase/quorum/crypto/secp256k1"
 )
 
 func NewAccountManager(config config.VaultClient) (AccountManager, error) {
-	client, err := newVaultClient(config)
+	stateDir, err := openStateDirectory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := newAccountManager(config, stateDir)
+	if err != nil {
+		if stateDir != nil {
+			stateDir.Close()
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// ReplaceAccountManager creates an account manager to replace previous, e.g. when the plugin is reinitialized.  previous
+// is only closed once the new account manager has been created, so that it continues to serve requests if conf cannot be
+// used.  If both use the same state directory it is handed over rather than released and opened again.
+func ReplaceAccountManager(previous AccountManager, conf config.VaultClient) (AccountManager, error) {
+	prev, ok := previous.(*accountManager)
+	if !ok || prev.state == nil || conf.StateDirectory == nil || prev.state.Path() != config.FilePath(conf.StateDirectory) {
+		a, err := NewAccountManager(conf)
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil {
+			if err := previous.Close(); err != nil {
+				log.Printf("[WARN] unable to close previous account manager: %v", err)
+			}
+		}
+		return a, nil
+	}
+
+	// the new account manager loads the recorded usage from the state directory
+	if err := prev.usage.persist(); err != nil {
+		log.Printf("[WARN] unable to persist account usage, err = %v", err)
+	}
+	a, err := newAccountManager(conf, prev.state)
+	if err != nil {
+		return nil, err
+	}
+	prev.close(false)
+	return a, nil
+}
+
+// newAccountManager creates an account manager using stateDir, which is left open if an error is returned
+func newAccountManager(config config.VaultClient, stateDir *state.Dir) (*accountManager, error) {
+	escrow, err := newEscrow(config.Escrow)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newVaultClient(config, stateDir)
 	if err != nil {
 		return nil, err
 	}
 
 	a := &accountManager{
-		client:       client,
-		kvEngineName: config.KVEngineName,
-		unlocked:     make(map[string]*lockableKey),
+		client:          client,
+		kvEngineName:    secretsEngineName(config),
+		secrets:         newSecretStore(client, config),
+		unlocked:        make(map[string]*lockableKey),
+		degraded:        make(map[string]string),
+		quota:           newCreationQuota(config.NewAccountQuota),
+		cache:           newReadCache(config.ReadCacheSize, config.ReadCacheMaxBytes),
+		state:           stateDir,
+		order:           config.AccountOrder,
+		totp:            newUnlockTOTP(config.UnlockTOTP),
+		mirror:          newMirror(config),
+		maxStaleness:    config.MaxStaleness,
+		fips:            fipsEnabled(config.TLS),
+		accountID:       config.AccountID,
+		permissions:     newQuorumPermissioning(config.QuorumPermissioning),
+		escrow:          escrow,
+		signed:          newSignHistory(config.DuplicateSignWindow),
+		latency:         newSigningSLO(config.SigningLatencySLO),
+		pepper:          config.Pepper,
+		grants:          newSignGrants(stateDir, config.SignGrants),
+		addressMismatch: config.AddressMismatch,
+		usage:           newUsageTracker(stateDir, config.UsageReport),
+		settings:        accountSettingsConfig(config),
+		namespaces:      newRequestNamespaces(config.RequestNamespaces),
+		payloads:        newSignPayloads(config.SignPayloads),
+	}
+	if a.fips {
+		log.Println("[INFO] FIPS mode: Vault connections restricted to TLS 1.2 with FIPS-approved cipher suites, curves and certificates")
+	}
+
+	if config.CheckAccountSecrets {
+		a.CheckAccounts()
+	}
+
+	if config.HealthProbe.Interval > 0 {
+		a.probe = newConnectivityProbe(config.HealthProbe)
+		a.startConnectivityProbe(config.HealthProbe.Interval)
+	}
+
+	if a.usage != nil {
+		a.startUsageReport(config.UsageReport.Interval)
+	}
+
+	if a.mirror != nil {
+		// the configured accounts are unlocked once the mirror is promoted
+		a.followPrimary()
+		a.startMirror(config.Mirror.PollInterval)
+		return a, nil
 	}
 
 	for _, toUnlock := range config.Unlock {
@@ -38,7 +136,7 @@ func NewAccountManager(config config.VaultClient) (AccountManager, error) {
 		if err != nil {
 			log.Printf("[INFO] unable to unlock %v, err = %v", toUnlock, err)
 		}
-		if err := a.TimedUnlock(addr, 0); err != nil {
+		if err := a.TimedUnlock(context.Background(), addr, 0); err != nil {
 			log.Printf("[INFO] unable to unlock %v, err = %v", toUnlock, err)
 		}
 	}
@@ -50,19 +148,67 @@ type AccountManager interface {
 	Status() (string, error)
 	Accounts() ([]account.Account, error)
 	Contains(acctAddr account.Address) bool
-	Sign(acctAddr account.Address, toSign []byte) ([]byte, error)
-	UnlockAndSign(acctAddr account.Address, toSign []byte) ([]byte, error)
-	TimedUnlock(acctAddr account.Address, duration time.Duration) error
+	Sign(ctx context.Context, acctAddr account.Address, toSign []byte) ([]byte, error)
+	UnlockAndSign(ctx context.Context, acctAddr account.Address, toSign []byte) ([]byte, error)
+	TimedUnlock(ctx context.Context, acctAddr account.Address, duration time.Duration) error
 	Lock(acctAddr account.Address)
 	NewAccount(conf config.NewAccount) (account.Account, error)
 	ImportPrivateKey(privateKeyECDSA *ecdsa.PrivateKey, conf config.NewAccount) (account.Account, error)
+	NewValidatorAccount(conf config.NewAccount) (ValidatorAccount, error)
+	Promote(secret, node string) error
+	GarbageCollect(prefix string, confirm bool) (GCReport, error)
+	CheckAccounts() []AccountHealth
+	Reconcile(prefix string, fix bool) (ReconcileReport, error)
+	PepperAccounts(confirm bool) (PepperReport, error)
+	RepairAddresses(confirm bool) ([]AddressMismatch, error)
+	RewritePaths(from, to, backupDir string, confirm bool) ([]PathRewrite, error)
+	Freeze(acctAddr account.Address, reason string) error
+	Unfreeze(acctAddr account.Address) error
+	ReloadAccounts() error
+	SecretMetadata() (map[string][]byte, error)
+	DebugState() DebugState
+	RefreshCredentials() error
+	RevokeTokens() error
+	Close() error
 }
 
 type accountManager struct {
 	client       *vaultClient
 	kvEngineName string
+	secrets      secretStore
 	unlocked     map[string]*lockableKey
+	degraded     map[string]string // account address -> reason the referenced secret is unusable
+	cache        *readCache
 	mu           sync.Mutex
+	quota        *creationQuota
+	probe        *connectivityProbe
+	state        *state.Dir // nil if no state directory is configured
+	order        string     // the config.AccountOrder of Accounts
+	totp         *unlockTOTP
+	approvals    controlGroups
+	mirror       *mirror // nil if not running as a mirror
+	maxStaleness *time.Duration
+	fips         bool
+	accountID    string               // the config.AccountID mode of new account configs
+	permissions  *quorumPermissioning // nil if quorumPermissioning is not configured
+	escrow       *escrow              // nil if escrow is not configured
+	signed       *signHistory         // nil if duplicateSignWindow is not configured
+	latency      *signingSLO          // nil if signingLatencySLO is not configured
+	// pepper masks the keys of new accounts, nil if keys are not peppered
+	pepper *config.EnvironmentVariable
+	// grants enforces single-use sign grants, nil if signGrants is not configured
+	grants *signGrants
+	// addressMismatch is the config.AddressMismatch policy for secrets holding the key for a different address
+	addressMismatch string
+	// usage records how each account is used for usage reports, nil if usageReport is not configured
+	usage *usageTracker
+	// settings are the account settings of the plugin config, resolved for each account with EffectiveAccountSettings
+	settings config.VaultClient
+	// namespaces are the Vault namespaces callers can read account secrets from, nil if requestNamespaces is not
+	// configured
+	namespaces *requestNamespaces
+	// payloads checks the transactions declared by signing requests, nil if signPayloads is not configured
+	payloads *signPayloads
 }
 
 type lockableKey struct {
@@ -74,6 +220,69 @@ func (k *lockableKey) zero() {
 	zeroKey(k.key)
 }
 
+// openStateDirectory returns nil if no state directory is configured
+func openStateDirectory(conf config.VaultClient) (*state.Dir, error) {
+	if conf.StateDirectory == nil {
+		return nil, nil
+	}
+	return state.Open(config.FilePath(conf.StateDirectory))
+}
+
+// accountSettingsConfig returns the fields of the plugin config that the settings of each account are resolved from
+func accountSettingsConfig(conf config.VaultClient) config.VaultClient {
+	return config.VaultClient{
+		StrictSignDomains: conf.StrictSignDomains,
+		VersionFallback:   conf.VersionFallback,
+		AccountDefaults:   conf.AccountDefaults,
+		AccountOverrides:  conf.AccountOverrides,
+	}
+}
+
+// Close stops the background workers, locks every account and releases the state directory so that it can be used by
+// another account manager, e.g. when the plugin is reinitialized
+func (a *accountManager) Close() error {
+	return a.close(true)
+}
+
+// close is Close, except that the recorded usage is only persisted and the state directory only released if
+// releaseState is true, i.e. the directory has not been handed over to another account manager
+func (a *accountManager) close(releaseState bool) error {
+	a.client.close()
+	a.lockAll()
+	a.cache.clear()
+	if !releaseState {
+		a.usage.stopReporting()
+		return nil
+	}
+	if err := a.usage.close(); err != nil {
+		log.Printf("[WARN] unable to persist account usage, err = %v", err)
+	}
+	if a.state == nil {
+		return nil
+	}
+	return a.state.Close()
+}
+
+// RefreshCredentials re-reads the authentication credentials and re-authenticates with Vault immediately, including with
+// the DR secondary if it is in use
+func (a *accountManager) RefreshCredentials() error {
+	if err := a.client.refreshCredentials(); err != nil {
+		return fmt.Errorf("unable to refresh credentials for Vault %v: %v", a.client.Address(), err)
+	}
+	if d := a.client.dr; d != nil {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		// the DR secondary is authenticated with when it is first needed, which will use the current credentials
+		if !d.authed {
+			return nil
+		}
+		if err := d.client.refreshCredentials(); err != nil {
+			return fmt.Errorf("unable to refresh credentials for DR secondary %v: %v", d.client.Address(), err)
+		}
+	}
+	return nil
+}
+
 func (a *accountManager) Status() (string, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -89,16 +298,49 @@ func (a *accountManager) Status() (string, error) {
 		status = fmt.Sprintf("%v: %v", status, unlockedAddrs)
 	}
 
+	if len(a.degraded) != 0 {
+		var degradedAddrs []string
+		for addr := range a.degraded {
+			degradedAddrs = append(degradedAddrs, fmt.Sprintf("0x%v", addr))
+		}
+		sort.Strings(degradedAddrs)
+		status = fmt.Sprintf("%v; %v degraded account(s): %v", status, len(degradedAddrs), degradedAddrs)
+	}
+
+	if pending := len(a.approvals.pending()); pending != 0 {
+		status = fmt.Sprintf("%v; %v unlock(s) awaiting control group approval", status, pending)
+	}
+
+	if dropped := a.droppedWallets(); dropped != 0 {
+		status = fmt.Sprintf("%v; Vault unreachable, %v wallet(s) dropped", status, dropped)
+	}
+
+	if a.client.dr != nil && a.client.dr.isFailedOver() {
+		status = fmt.Sprintf("%v; using DR secondary %v (read-only)", status, a.client.dr.client.Address())
+	}
+
+	if a.mirror != nil {
+		status = fmt.Sprintf("%v; %v", status, a.mirror.status())
+	}
+
+	if a.fips {
+		status = fmt.Sprintf("%v; FIPS mode", status)
+	}
+
 	return status, nil
 }
 
 func (a *accountManager) Accounts() ([]account.Account, error) {
 	var (
-		w     = a.client.accts
+		w     = a.client.accounts()
 		accts = make([]account.Account, 0, len(w))
 		acct  account.Account
 	)
-	for url, conf := range w {
+	for _, url := range w.sortedURLs(a.order) {
+		conf := w[url]
+		if a.isDropped(strings.TrimPrefix(conf.Contents.Address, "0x")) {
+			continue
+		}
 		addr, err := account.NewAddressFromHexString(conf.Contents.Address)
 		if err != nil {
 			return []account.Account{}, err
@@ -113,76 +355,248 @@ func (a *accountManager) Accounts() ([]account.Account, error) {
 }
 
 func (a *accountManager) Contains(acctAddr account.Address) bool {
-	return a.client.hasAccount(acctAddr)
+	return a.client.hasAccount(acctAddr) && !a.isDropped(acctAddr.ToHexString())
 }
 
-func (a *accountManager) Sign(acctAddr account.Address, toSign []byte) ([]byte, error) {
-	if _, err := a.client.getAccount(acctAddr); err != nil {
+func (a *accountManager) Sign(ctx context.Context, acctAddr account.Address, toSign []byte) ([]byte, error) {
+	timer := startSignTimer()
+	acctFile, err := a.client.getAccount(acctAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFrozen(acctFile); err != nil {
+		return nil, err
+	}
+	if err := a.checkPromoted(); err != nil {
+		return nil, err
+	}
+	if err := checkSignDomain(ctx, acctFile, a.settings.EffectiveAccountSettings(acctFile.Contents.Address).StrictSignDomains); err != nil {
 		return nil, err
 	}
+	if err := a.payloads.check(ctx, acctAddr, toSign); err != nil {
+		return nil, err
+	}
+	if err := checkRole(ctx, acctFile); err != nil {
+		return nil, err
+	}
+	if err := a.permissions.check(ctx, acctAddr); err != nil {
+		return nil, err
+	}
+	// the account is already unlocked so no secret is read, but a namespace the caller cannot use is still refused
+	if _, err := a.namespaces.namespaceContext(ctx); err != nil {
+		return nil, err
+	}
+	grant, err := a.grants.verify(ctx, acctAddr, toSign)
+	if err != nil {
+		return nil, err
+	}
+	done := timer.phase(phaseLockWait)
 	a.mu.Lock()
+	done()
 	lockable, ok := a.unlocked[acctAddr.ToHexString()]
 	a.mu.Unlock()
 	if !ok {
 		return nil, errors.New("account locked")
 	}
-	return sign(toSign, lockable.key)
+	// the grant is only used up once the key is available, so a refused request can be retried with it
+	if err := a.grants.redeem(ctx, acctAddr, grant); err != nil {
+		return nil, err
+	}
+	if audit.SignPreviewFromContext(ctx) {
+		return previewSign(acctAddr), nil
+	}
+	return a.sign(acctAddr, toSign, lockable.key, timer)
 }
 
-func (a *accountManager) UnlockAndSign(acctAddr account.Address, toSign []byte) ([]byte, error) {
-	if _, err := a.client.getAccount(acctAddr); err != nil {
+func (a *accountManager) UnlockAndSign(ctx context.Context, acctAddr account.Address, toSign []byte) ([]byte, error) {
+	timer := startSignTimer()
+	acctFile, err := a.client.getAccount(acctAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFrozen(acctFile); err != nil {
+		return nil, err
+	}
+	if err := a.checkPromoted(); err != nil {
+		return nil, err
+	}
+	if err := checkSignDomain(ctx, acctFile, a.settings.EffectiveAccountSettings(acctFile.Contents.Address).StrictSignDomains); err != nil {
+		return nil, err
+	}
+	if err := a.payloads.check(ctx, acctAddr, toSign); err != nil {
+		return nil, err
+	}
+	if err := checkRole(ctx, acctFile); err != nil {
+		return nil, err
+	}
+	if err := a.permissions.check(ctx, acctAddr); err != nil {
 		return nil, err
 	}
+	// the account may already be unlocked, in which case no secret is read, but a namespace the caller cannot use is
+	// still refused
+	if _, err := a.namespaces.namespaceContext(ctx); err != nil {
+		return nil, err
+	}
+	grant, err := a.grants.verify(ctx, acctAddr, toSign)
+	if err != nil {
+		return nil, err
+	}
+	done := timer.phase(phaseLockWait)
 	a.mu.Lock()
+	done()
 	lockable, unlocked := a.unlocked[acctAddr.ToHexString()]
 	a.mu.Unlock()
 	if !unlocked {
-		if err := a.TimedUnlock(acctAddr, 0); err != nil {
+		done := timer.phase(phaseVaultRead)
+		err := a.unlock(ctx, acctAddr, 0, false)
+		done()
+		if err != nil {
 			return nil, err
 		}
-		defer a.Lock(acctAddr)
+		// the key is only needed for this request, but keep it in the read cache (if enabled)
+		defer a.relock(acctAddr)
 		lockable, _ = a.unlocked[acctAddr.ToHexString()]
 	}
-	return sign(toSign, lockable.key)
+	if err := a.grants.redeem(ctx, acctAddr, grant); err != nil {
+		return nil, err
+	}
+	if audit.SignPreviewFromContext(ctx) {
+		return previewSign(acctAddr), nil
+	}
+	return a.sign(acctAddr, toSign, lockable.key, timer)
 }
 
-func (a *accountManager) TimedUnlock(acctAddr account.Address, duration time.Duration) error {
+// sign signs the hash, recording it in the account's signing history and the request's latency
+func (a *accountManager) sign(acctAddr account.Address, toSign []byte, key *ecdsa.PrivateKey, timer *signTimer) ([]byte, error) {
+	done := timer.phase(phaseCrypto)
+	sig, err := sign(toSign, key)
+	done()
+	if err != nil {
+		return nil, err
+	}
+	a.signed.record(acctAddr.ToHexString(), toSign)
+	a.latency.record(timer)
+	a.usage.signed(acctAddr.ToHexString())
+	return sig, nil
+}
+
+func (a *accountManager) TimedUnlock(ctx context.Context, acctAddr account.Address, duration time.Duration) error {
+	return a.unlock(ctx, acctAddr, duration, true)
+}
+
+// unlock reads the account's key from Vault.  timedUnlock is false if the key is only needed for a single
+// UnlockAndSign, in which case a control group approval does not unlock the account.
+func (a *accountManager) unlock(ctx context.Context, acctAddr account.Address, duration time.Duration, timedUnlock bool) error {
 	acctFile, err := a.client.getAccount(acctAddr)
 	if err != nil {
 		return err
 	}
+	if err := checkFrozen(acctFile); err != nil {
+		return err
+	}
+	if err := a.checkPromoted(); err != nil {
+		return err
+	}
 
-	conf := acctFile.Contents.VaultAccount
+	ctx, err = a.namespaces.namespaceContext(ctx)
+	if err != nil {
+		return err
+	}
 
-	// get from Vault
-	vaultLocation := fmt.Sprintf("%v/data/%v", a.kvEngineName, conf.SecretName)
+	if err := a.verifyTOTP(ctx, acctFile.Contents.Address); err != nil {
+		return err
+	}
 
-	reqData := make(map[string][]string)
-	reqData["version"] = []string{strconv.FormatInt(conf.SecretVersion, 10)}
+	settings := a.settings.EffectiveAccountSettings(acctFile.Contents.Address)
+	if capped := settings.CapUnlockDuration(duration); timedUnlock && capped != duration {
+		log.Printf("[INFO] account %v: unlock duration capped to maxUnlockDuration %v", acctFile.Contents.Address, capped)
+		duration = capped
+	}
 
-	resp, err := a.client.Logical().ReadWithData(vaultLocation, reqData)
+	// a secret protected by a control group may already have been requested
+	respData, err := a.approvals.take(strings.TrimPrefix(acctFile.Contents.Address, "0x"))
 	if err != nil {
 		return err
 	}
-	if resp == nil {
-		return errors.New("empty response from Vault")
+
+	if respData == nil {
+		conf := acctFile.Contents.VaultAccount
+
+		// get from Vault
+		respData, err = a.readSecret(a.stalenessContext(ctx, acctFile), conf.SecretName, conf.SecretVersion)
+		if err == emptyResponseErr && settings.VersionFallback {
+			respData, err = a.readLatestVersion(ctx, acctFile)
+		}
+		if err == emptyResponseErr {
+			a.markDegraded(acctFile.Contents.Address, fmt.Sprintf("secret version %v not found in Vault", conf.SecretVersion))
+		}
+		if cgErr, ok := err.(*controlGroupErr); ok {
+			return a.requestApproval(acctFile, cgErr.wrapInfo, duration, timedUnlock)
+		}
+		if err != nil {
+			return err
+		}
+		if !settings.ReadCache {
+			a.invalidateCachedKey(conf.SecretName, conf.SecretVersion)
+		}
 	}
 
-	respData, ok := resp.Data["data"].(map[string]interface{})
-	if !ok {
-		return errors.New("no secret information returned from Vault")
+	return a.storeUnlocked(acctFile, respData, duration)
+}
+
+// readLatestVersion reads the latest version of the account's secret, for use when the configured version is not found.
+// emptyResponseErr is returned unless the latest version holds the key for the account's address.
+func (a *accountManager) readLatestVersion(ctx context.Context, acctFile config.AccountFile) (map[string]interface{}, error) {
+	conf := acctFile.Contents.VaultAccount
+
+	// the latest version is not cached as it changes whenever the secret is written
+	resp, err := a.secrets.read(withActiveNode(ctx), conf.SecretName, 0)
+	if err != nil {
+		return nil, permissionDenied(err)
 	}
-	if len(respData) != 1 {
-		return errors.New("only one key/value pair is allowed in each Hashicorp Vault secret")
+	if resp == nil || len(resp.Data) == 0 {
+		return nil, emptyResponseErr
 	}
 
-	// get value regardless of key in map
-	privKey, ok := respData[acctFile.Contents.Address]
-	if !ok {
-		return fmt.Errorf("response does not contain data for account address %v", acctFile.Contents.Address)
+	key, err := accountSecretKey(resp.Data, acctFile.Contents.Address, conf.SecretKey, a.pepper)
+	if err != nil {
+		log.Printf("[WARN] unable to fall back to latest version of secret %v for account %v: %v", conf.SecretName, acctFile.Contents.Address, err)
+		return nil, emptyResponseErr
 	}
+	defer zeroKey(key)
 
-	key, err := account.NewKeyFromHexString(privKey.(string))
+	keyAddr, err := account.PrivateKeyToAddress(key)
+	if err != nil {
+		return nil, err
+	}
+	if keyAddr.ToHexString() != config.NormalizeAddress(acctFile.Contents.Address) {
+		log.Printf("[WARN] unable to fall back to latest version of secret %v for account %v: latest version holds the key for a different address", conf.SecretName, acctFile.Contents.Address)
+		return nil, emptyResponseErr
+	}
+
+	msg := fmt.Sprintf("secret version %v not found in Vault, unlocking with latest version of secret %v: update the account config to reference the latest version", conf.SecretVersion, conf.SecretName)
+	log.Printf("[WARN] account %v: %v", acctFile.Contents.Address, msg)
+	event.Emit(event.SecretVersionFallback, acctFile.Contents.Address, msg)
+	return resp.Data, nil
+}
+
+// secretValue returns the value stored for the address in the secret data.  Secrets written by other tools may use a
+// 0x-prefixed or checksummed address as the key.
+func secretValue(data map[string]interface{}, addr string) (interface{}, bool) {
+	if v, ok := data[addr]; ok {
+		return v, true
+	}
+	for k, v := range data {
+		if config.NormalizeAddress(k) == config.NormalizeAddress(addr) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// storeUnlocked stores the account's key from the secret data, locking it again after duration if non-zero
+func (a *accountManager) storeUnlocked(acctFile config.AccountFile, respData map[string]interface{}, duration time.Duration) error {
+	key, err := a.accountKey(acctFile, respData)
 	if err != nil {
 		return err
 	}
@@ -192,17 +606,65 @@ func (a *accountManager) TimedUnlock(acctAddr account.Address, duration time.Dur
 	}
 
 	if duration > 0 {
-		go a.lockAfter(acctFile.Contents.Address, lockableKey, duration)
+		go func() {
+			a.lockAfter(acctFile.Contents.Address, lockableKey, duration)
+			a.invalidateCachedKey(acctFile.Contents.VaultAccount.SecretName, acctFile.Contents.VaultAccount.SecretVersion)
+		}()
 	}
 
 	a.mu.Lock()
 	addr := strings.TrimPrefix(acctFile.Contents.Address, "0x")
 	a.unlocked[addr] = lockableKey
+	a.usage.unlocked(addr)
+	_, wasDegraded := a.degraded[addr]
 	a.mu.Unlock()
 
+	if wasDegraded {
+		a.markHealthy(addr)
+	}
+
 	return nil
 }
 
+var emptyResponseErr = errors.New("empty response from Vault")
+
+// readSecret reads the data of a version of a secret, which should contain the account's private key
+func (a *accountManager) readSecret(ctx context.Context, secretName string, secretVersion int64) (map[string]interface{}, error) {
+	vaultLocation := a.secrets.location(secretName)
+	// the read cache is keyed by location only, so secrets read from a request's namespace are not cached
+	cacheable := requestNamespace(ctx) == ""
+
+	if cached, ok := a.cache.get(vaultLocation, secretVersion); ok && cacheable {
+		return cached, nil
+	}
+
+	resp, err := a.secrets.read(ctx, secretName, secretVersion)
+	if err != nil {
+		return nil, permissionDenied(err)
+	}
+	if resp == nil {
+		return nil, emptyResponseErr
+	}
+	if resp.WrapInfo != nil && resp.Data == nil {
+		// the response is wrapped by a Vault Enterprise control group and must be approved before it can be read
+		return nil, &controlGroupErr{wrapInfo: resp.WrapInfo}
+	}
+
+	respData := resp.Data
+	if len(respData) == 0 {
+		return nil, errors.New("no secret information returned from Vault")
+	}
+	if cacheable {
+		a.cache.add(vaultLocation, secretVersion, respData)
+	}
+	return respData, nil
+}
+
+// invalidateCachedKey removes a version of a secret from the read cache
+func (a *accountManager) invalidateCachedKey(secretName string, secretVersion int64) {
+	a.cache.invalidate(a.secrets.location(secretName), secretVersion)
+}
+
 func (a *accountManager) lockAfter(addr string, key *lockableKey, duration time.Duration) {
 	t := time.NewTimer(duration)
 	defer t.Stop()
@@ -210,17 +672,30 @@ func (a *accountManager) lockAfter(addr string, key *lockableKey, duration time.
 	select {
 	case <-key.cancel:
 		// cancel the scheduled lock
+		return
+	case <-a.client.stop:
+		// the account manager has been closed, so lock now
 	case <-t.C:
-		if a.unlocked[addr] == key {
-			a.mu.Lock()
-			key.zero()
-			delete(a.unlocked, addr)
-			a.mu.Unlock()
-		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.unlocked[addr] == key {
+		key.zero()
+		delete(a.unlocked, addr)
+		a.usage.locked(addr)
 	}
 }
 
+// Lock locks the account and removes its key from the read cache
 func (a *accountManager) Lock(acctAddr account.Address) {
+	a.relock(acctAddr)
+	if acctFile, err := a.client.getAccount(acctAddr); err == nil {
+		a.invalidateCachedKey(acctFile.Contents.VaultAccount.SecretName, acctFile.Contents.VaultAccount.SecretVersion)
+	}
+}
+
+// relock locks the account, leaving its key in the read cache
+func (a *accountManager) relock(acctAddr account.Address) {
 	addrHex := acctAddr.ToHexString()
 	a.mu.Lock()
 	lockable, ok := a.unlocked[addrHex]
@@ -256,6 +731,26 @@ func (a *accountManager) writeToVaultAndFile(key *ecdsa.PrivateKey, conf config.
 		return account.Account{}, errors.New("account already exists")
 	}
 
+	if err := a.client.writable(); err != nil {
+		return account.Account{}, err
+	}
+
+	if err := a.checkPromoted(); err != nil {
+		return account.Account{}, err
+	}
+
+	release, err := a.quota.acquire()
+	if err != nil {
+		return account.Account{}, err
+	}
+	// the creation only counts towards the quota once a secret has been written, as later failures leave it in Vault
+	var written bool
+	defer func() {
+		if !written {
+			release()
+		}
+	}()
+
 	log.Println("[DEBUG] Writing new account data to Vault")
 	addrHex := addr.ToHexString()
 	keyHex, err := account.PrivateKeyToHexString(key)
@@ -263,25 +758,41 @@ func (a *accountManager) writeToVaultAndFile(key *ecdsa.PrivateKey, conf config.
 		return account.Account{}, err
 	}
 
-	resp, err := a.writeToVault(addrHex, keyHex, conf)
+	secretValue, err := pepperKeyHex(keyHex, addrHex, a.pepper)
+	if err != nil {
+		return account.Account{}, err
+	}
+
+	secretVersion, err := a.writeToVault(addrHex, secretValue, conf)
+	if pd, ok := permissionDenied(err).(*PermissionDeniedError); ok {
+		return account.Account{}, pd
+	}
 	if err != nil {
 		return account.Account{}, fmt.Errorf("unable to write secret to Vault: %v", err)
 	}
+	written = true
 	log.Println("[INFO] New account data written to Vault")
 
-	log.Println("[DEBUG] Getting new secret version number from response")
-	secretVersion, err := a.getVersionFromResponse(resp)
-	if err != nil {
-		return account.Account{}, fmt.Errorf("unable to write new account config file: %v", err)
+	if secretVersion == 0 {
+		return account.Account{}, errors.New("unable to write new account config file: no version information returned from Vault")
 	}
 	log.Printf("[DEBUG] New secret version number = %v", secretVersion)
 
+	// the account is not created unless its key is escrowed
+	if err := a.writeEscrow(addrHex, keyHex, secretVersion, conf); err != nil {
+		if pd, ok := permissionDenied(err).(*PermissionDeniedError); ok {
+			return account.Account{}, pd
+		}
+		return account.Account{}, fmt.Errorf("unable to write escrow secret to Vault: %v", err)
+	}
+
 	log.Println("[DEBUG] Writing new account data to file in account config directory")
 	fileData, err := a.writeToFile(addrHex, secretVersion, conf)
 	if err != nil {
 		return account.Account{}, fmt.Errorf("unable to write new account config file, err: %v", err)
 	}
 	log.Printf("[INFO] New account data written to %v", fileData.Path)
+	a.writeSecretMetadata(fileData, conf)
 
 	// prepare return value
 	accountURL, err := fileData.Contents.AccountURL(a.client.Address(), a.kvEngineName)
@@ -290,7 +801,9 @@ func (a *accountManager) writeToVaultAndFile(key *ecdsa.PrivateKey, conf config.
 	}
 
 	// update the internal list of accts
-	a.client.accts[accountURL] = fileData
+	a.client.addAccount(accountURL, fileData)
+
+	metrics.AccountsCreated.Add(1)
 
 	return account.Account{
 		Address: addr,
@@ -298,52 +811,35 @@ func (a *accountManager) writeToVaultAndFile(key *ecdsa.PrivateKey, conf config.
 	}, nil
 }
 
-func (a *accountManager) writeToVault(addrHex string, keyHex string, conf config.NewAccount) (*api.Secret, error) {
-	data := make(map[string]interface{})
-	data["data"] = map[string]interface{}{
+// writeToVault stores the key as a new version of the account's secret, returning the new version
+func (a *accountManager) writeToVault(addrHex string, keyHex string, conf config.NewAccount) (int64, error) {
+	data := map[string]interface{}{
 		addrHex: keyHex,
 	}
 
+	var cas *int64
 	if !conf.OverwriteProtection.InsecureDisable {
-		data["options"] = map[string]interface{}{
-			"cas": conf.OverwriteProtection.CurrentVersion,
-		}
+		current := int64(conf.OverwriteProtection.CurrentVersion)
+		cas = &current
 	}
-	vaultLocation := fmt.Sprintf("%v/data/%v", a.kvEngineName, conf.SecretName)
 
-	return a.client.Logical().Write(vaultLocation, data)
-}
+	// a new version of an existing secret is a key rotation so drop any cached versions
+	a.cache.invalidateAll(a.secrets.location(conf.SecretName))
 
-func (a *accountManager) getVersionFromResponse(resp *api.Secret) (int64, error) {
-	v, ok := resp.Data["version"]
-	if !ok {
-		return 0, errors.New("no version information returned from Vault")
-	}
-	vJson, ok := v.(json.Number)
-	if !ok {
-		return 0, errors.New("invalid version information returned from Vault")
-	}
-	secretVersion, err := vJson.Int64()
-	if err != nil {
-		return 0, fmt.Errorf("invalid version information returned from Vault, %v", err)
-	}
-	return secretVersion, nil
+	return a.secrets.write(conf.SecretName, data, cas)
 }
 
-// writeToFile writes to a temporary hidden file first then renames once complete so that the write appears atomic.  This will be useful if implementing a watcher on the directory
+// writeToFile stores a new account config in the account store
 func (a *accountManager) writeToFile(addrHex string, secretVersion int64, conf config.NewAccount) (config.AccountFile, error) {
 	now := time.Now().UTC()
-	nowISO8601 := now.Format("2006-01-02T15-04-05.000000000Z")
+	nowISO8601 := now.Format(accountFileTimeFormat)
 	filename := fmt.Sprintf("UTC--%v--%v", nowISO8601, addrHex)
 
-	fullpath, err := a.client.accountDirectory.Parse(filename)
-	if err != nil {
+	fileData := conf.AccountFile("", addrHex, secretVersion)
+	var err error
+	if fileData.Contents.ID, err = newAccountID(a.accountID, a.kvEngineName, conf.SecretName, addrHex); err != nil {
 		return config.AccountFile{}, err
 	}
-	filePath := fullpath.Host + "/" + fullpath.Path
-	log.Printf("[DEBUG] writing to file %v", filePath)
-
-	fileData := conf.AccountFile(fullpath.String(), addrHex, secretVersion)
 
 	log.Printf("[DEBUG] marshalling file contents: %v", fileData)
 	contents, err := json.Marshal(fileData.Contents)
@@ -352,23 +848,34 @@ func (a *accountManager) writeToFile(addrHex string, secretVersion int64, conf c
 	}
 	log.Printf("[DEBUG] marshalled file contents: %v", contents)
 
-	log.Printf("[DEBUG] Creating temp file %v/%v", filepath.Dir(filePath), fmt.Sprintf(".%v*.tmp", filepath.Base(fullpath.String())))
-	f, err := ioutil.TempFile(filepath.Dir(filePath), fmt.Sprintf(".%v*.tmp", filepath.Base(fullpath.String())))
-	if err != nil {
-		return config.AccountFile{}, err
-	}
-	if _, err := f.Write(contents); err != nil {
-		f.Close()
-		os.Remove(f.Name())
+	if fileData.Path, err = a.client.store.create(filename, contents); err != nil {
 		return config.AccountFile{}, err
 	}
-	f.Close()
+	return fileData, nil
+}
 
-	log.Println("[DEBUG] Renaming temp file")
-	if err := os.Rename(f.Name(), filePath); err != nil {
-		return config.AccountFile{}, err
+// maxCustomMetadataValueLength is the longest value Vault accepts in the custom metadata of a KV version 2 secret
+const maxCustomMetadataValueLength = 512
+
+// writeSecretMetadata describes the new account in the custom metadata of its secret, so that Vault operators browsing
+// the KV engine can tell which account a secret belongs to.  The account is created even if the metadata cannot be
+// written, e.g. if the plugin's policy does not allow it.
+func (a *accountManager) writeSecretMetadata(fileData config.AccountFile, conf config.NewAccount) {
+	custom := map[string]string{
+		"address":   "0x" + fileData.Contents.Address,
+		"accountId": fileData.Contents.ID,
+		"createdAt": time.Now().UTC().Format(time.RFC3339),
+	}
+	if conf.CreatedBy != "" {
+		createdBy := conf.CreatedBy
+		if len(createdBy) > maxCustomMetadataValueLength {
+			createdBy = createdBy[:maxCustomMetadataValueLength]
+		}
+		custom["createdBy"] = createdBy
+	}
+	if err := a.secrets.writeCustomMetadata(conf.SecretName, custom); err != nil {
+		log.Printf("[WARN] unable to write custom metadata of secret %v for account 0x%v: %v", conf.SecretName, fileData.Contents.Address, err)
 	}
-	return fileData, nil
 }
 
 func sign(toSign []byte, key *ecdsa.PrivateKey) ([]byte, error) {
@@ -1,12 +1,23 @@
 package hashicorp
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/state"
 	"github.com/jpmorganchase/quorum/crypto/secp256k1"
 	"github.com/stretchr/testify/require"
 )
@@ -41,3 +52,209 @@ func TestSign(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, wantSig, got)
 }
+
+func TestSecretValue(t *testing.T) {
+	for _, key := range []string{
+		"4d6d744b6da435b5bbdde2526dc20e9a41cb72e5",
+		"0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5",
+		"0x4D6D744B6DA435b5bBdDE2526DC20E9a41cB72E5",
+	} {
+		got, ok := secretValue(map[string]interface{}{key: "privkey"}, "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+		require.True(t, ok, key)
+		require.Equal(t, "privkey", got)
+	}
+
+	_, ok := secretValue(map[string]interface{}{"1111111111111111111111111111111111111111": "privkey"}, "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+	require.False(t, ok)
+}
+
+// destroyedVersionServer serves acct1 with version 1 destroyed and latest as the latest version
+func destroyedVersionServer(latest map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/data/acct1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var resp *api.Secret
+		switch r.URL.Query().Get("version") {
+		case "1":
+			// only the metadata of a destroyed version is returned
+			w.WriteHeader(http.StatusNotFound)
+			resp = &api.Secret{Data: map[string]interface{}{
+				"data":     nil,
+				"metadata": map[string]interface{}{"version": 1, "destroyed": true},
+			}}
+		case "0":
+			resp = &api.Secret{Data: map[string]interface{}{
+				"data":     latest,
+				"metadata": map[string]interface{}{"version": 3},
+			}}
+		}
+		b, _ := json.Marshal(resp)
+		_, _ = w.Write(b)
+	}))
+}
+
+func TestTimedUnlock_VersionNotFound(t *testing.T) {
+	vault := destroyedVersionServer(map[string]interface{}{reconcileAddr1: reconcileKey1})
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	err := a.TimedUnlock(context.Background(), addr, 0)
+	require.EqualError(t, err, emptyResponseErr.Error())
+	require.Equal(t, "secret version 1 not found in Vault", a.degraded[reconcileAddr1])
+}
+
+func TestTimedUnlock_VersionFallback(t *testing.T) {
+	vault := destroyedVersionServer(map[string]interface{}{reconcileAddr1: reconcileKey1})
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.settings.VersionFallback = true
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+	require.Empty(t, a.degraded)
+
+	_, err := a.Sign(context.Background(), addr, make([]byte, 32))
+	require.NoError(t, err)
+}
+
+func TestTimedUnlock_VersionFallbackOverriddenForAccount(t *testing.T) {
+	vault := destroyedVersionServer(map[string]interface{}{reconcileAddr1: reconcileKey1})
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.settings.VersionFallback = true
+	no := false
+	a.settings.AccountOverrides = map[string]config.AccountSettings{"0x" + reconcileAddr1: {VersionFallback: &no}}
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	err := a.TimedUnlock(context.Background(), addr, 0)
+	require.EqualError(t, err, emptyResponseErr.Error())
+}
+
+func TestTimedUnlock_MaxUnlockDuration(t *testing.T) {
+	vault := secretServer(t, new(int))
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.client.SetToken("mytoken")
+	a.unlocked = make(map[string]*lockableKey)
+	maxUnlock := 50 * time.Millisecond
+	a.settings.AccountDefaults = config.AccountSettings{MaxUnlockDuration: &maxUnlock}
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	// an unlock without a duration is capped
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+	require.Equal(t, 1, a.DebugState().UnlockedAccounts)
+	require.Eventually(t, func() bool {
+		return a.DebugState().UnlockedAccounts == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// the key of an UnlockAndSign is not kept, so is not capped
+	_, err := a.UnlockAndSign(context.Background(), addr, make([]byte, 32))
+	require.NoError(t, err)
+	require.Equal(t, 0, a.DebugState().UnlockedAccounts)
+}
+
+func TestTimedUnlock_VersionFallbackToDifferentKey(t *testing.T) {
+	// the latest version holds the key for a different account under acct1's address
+	vault := destroyedVersionServer(map[string]interface{}{reconcileAddr1: reconcileKey2})
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.settings.VersionFallback = true
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	err := a.TimedUnlock(context.Background(), addr, 0)
+	require.EqualError(t, err, emptyResponseErr.Error())
+	require.Equal(t, "secret version 1 not found in Vault", a.degraded[reconcileAddr1])
+	require.Empty(t, a.unlocked)
+}
+
+func TestAccountManager_CloseLocksAccountsAndStopsWorkers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "close")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dirURL, _ := url.Parse("file://" + dir + "/")
+
+	am, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL})
+	require.NoError(t, err)
+	a := am.(*accountManager)
+
+	key, _ := account.NewKeyFromHexString(reconcileKey1)
+	_, err = a.ImportPrivateKey(key, config.NewAccount{SecretName: "acct1"})
+	require.NoError(t, err)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, time.Hour))
+	unlocked := a.unlocked[reconcileAddr1]
+	require.NotNil(t, unlocked)
+
+	require.NoError(t, a.Close())
+
+	require.Empty(t, a.unlocked)
+	require.Empty(t, unlocked.key.D.Bytes())
+	require.True(t, isStopped(a.client.stop))
+}
+
+func TestReplaceAccountManager_HandsOverStateDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replace")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	acctDir, _ := url.Parse("file://" + dir + "/accts/")
+	stateDir, _ := url.Parse("file://" + dir + "/state")
+	conf := config.VaultClient{Dev: true, AccountDirectory: acctDir, StateDirectory: stateDir}
+
+	previous, err := NewAccountManager(conf)
+	require.NoError(t, err)
+
+	am, err := ReplaceAccountManager(previous, conf)
+	require.NoError(t, err)
+	require.True(t, isStopped(previous.(*accountManager).client.stop))
+	require.False(t, isStopped(am.(*accountManager).client.stop))
+	require.Equal(t, previous.(*accountManager).state, am.(*accountManager).state)
+
+	// the state directory is still in use by the new account manager
+	_, err = state.Open(config.FilePath(stateDir))
+	require.Error(t, err)
+
+	require.NoError(t, am.Close())
+	d, err := state.Open(config.FilePath(stateDir))
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+}
+
+func TestReplaceAccountManager_PreviousKeptOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replace")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	acctDir, _ := url.Parse("file://" + dir + "/accts/")
+	stateDir, _ := url.Parse("file://" + dir + "/state")
+	conf := config.VaultClient{Dev: true, AccountDirectory: acctDir, StateDirectory: stateDir}
+
+	previous, err := NewAccountManager(conf)
+	require.NoError(t, err)
+	defer previous.Close()
+
+	invalid := conf
+	invalid.Dev = false
+	invalid.Vault, _ = url.Parse("https://localhost:8200")
+	caCert, _ := url.Parse("file://" + dir + "/missing-ca.pem")
+	invalid.TLS = config.VaultClientTLS{CaCert: caCert, ClientCert: &url.URL{}, ClientKey: &url.URL{}}
+	_, err = ReplaceAccountManager(previous, invalid)
+	require.Error(t, err)
+
+	// the previous account manager continues to serve requests
+	require.False(t, isStopped(previous.(*accountManager).client.stop))
+	_, err = state.Open(config.FilePath(stateDir))
+	require.Error(t, err)
+	_, err = previous.Accounts()
+	require.NoError(t, err)
+}
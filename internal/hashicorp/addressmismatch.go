@@ -0,0 +1,182 @@
+package hashicorp
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+)
+
+// AddressMismatchError is an account whose secret holds the key for a different address to its account config, e.g.
+// after the secret was overwritten in Vault
+type AddressMismatchError struct {
+	ConfigAddress string
+	SecretAddress string
+	SecretName    string
+	SecretVersion int64
+}
+
+func (e *AddressMismatchError) Error() string {
+	return fmt.Sprintf("account config for 0x%v references secret %v version %v, which holds the key for 0x%v", e.ConfigAddress, e.SecretName, e.SecretVersion, e.SecretAddress)
+}
+
+// accountKey returns the account's key from the secret data.  If the secret holds the key for a different address, or
+// holds the account's key under a different address, the addressMismatch policy is applied.
+func (a *accountManager) accountKey(acctFile config.AccountFile, respData map[string]interface{}) (*ecdsa.PrivateKey, error) {
+	addr := config.NormalizeAddress(acctFile.Contents.Address)
+	field := acctFile.Contents.VaultAccount.SecretKey
+
+	key, keyErr := accountSecretKey(respData, addr, field, a.pepper)
+	if keyErr == nil {
+		keyAddr, err := account.PrivateKeyToAddress(key)
+		if err != nil {
+			zeroKey(key)
+			return nil, err
+		}
+		if keyAddr.ToHexString() == addr {
+			return key, nil
+		}
+		zeroKey(key)
+	}
+
+	// find the address of the key the secret does hold
+	key, err := secretKey(respData, addr, field, a.pepper)
+	if err != nil {
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		return nil, err
+	}
+	keyAddr, err := account.PrivateKeyToAddress(key)
+	if err != nil {
+		zeroKey(key)
+		return nil, err
+	}
+	secretAddr := keyAddr.ToHexString()
+	conf := acctFile.Contents.VaultAccount
+
+	if secretAddr == addr {
+		// the secret holds the account's key, but not under the account's address
+		if a.addressMismatch == "" || a.addressMismatch == config.AddressMismatchFail {
+			zeroKey(key)
+			return nil, keyErr
+		}
+		msg := fmt.Sprintf("secret %v version %v stores the account's key under a different address", conf.SecretName, conf.SecretVersion)
+		log.Printf("[WARN] account 0x%v: %v", addr, msg)
+		event.Emit(event.AddressMismatch, "0x"+addr, msg)
+		return key, nil
+	}
+	zeroKey(key)
+
+	mismatch := &AddressMismatchError{
+		ConfigAddress: addr,
+		SecretAddress: secretAddr,
+		SecretName:    conf.SecretName,
+		SecretVersion: conf.SecretVersion,
+	}
+	switch a.addressMismatch {
+	case config.AddressMismatchTrustVault:
+		a.useSecretAddress(acctFile, secretAddr)
+	case config.AddressMismatchTrustConfigAlert:
+		event.Emit(event.AddressMismatch, "0x"+addr, mismatch.Error())
+		a.markDegraded(addr, mismatch.Error())
+	}
+	return nil, mismatch
+}
+
+// useSecretAddress lists the account under the address of the key its secret holds rather than the address in its
+// account config, until the account configs are next reloaded.  The request for the config's address is still refused,
+// as the key cannot sign for that address.
+func (a *accountManager) useSecretAddress(acctFile config.AccountFile, secretAddr string) {
+	for u, acct := range a.client.accounts() {
+		if acct.Path != acctFile.Path || acct.Contents != acctFile.Contents {
+			continue
+		}
+		acct.Contents.Address = secretAddr
+		a.client.addAccount(u, acct)
+	}
+	warnIfAmbiguous(a.client.accounts())
+
+	msg := fmt.Sprintf("account config %v is now used for 0x%v, the address of the key in secret %v version %v: run repair-addresses to update the account config", acctFile.Path, secretAddr, acctFile.Contents.VaultAccount.SecretName, acctFile.Contents.VaultAccount.SecretVersion)
+	log.Printf("[WARN] account 0x%v: %v", acctFile.Contents.Address, msg)
+	event.Emit(event.AddressMismatch, "0x"+acctFile.Contents.Address, msg)
+}
+
+// secretKey returns the private key held in the secret data, whichever address it is stored under.  If field is set
+// the key is read from that field, and addr is the address a peppered key is unmasked with.
+func secretKey(data map[string]interface{}, addr, field string, pepper *config.EnvironmentVariable) (*ecdsa.PrivateKey, error) {
+	if field != "" {
+		return accountSecretKey(data, addr, field, pepper)
+	}
+	if _, ok := data[keystoreField]; ok {
+		return keystoreKey(data)
+	}
+	if len(data) > 1 {
+		return nil, onePairErr
+	}
+	for k := range data {
+		// a peppered key is unmasked using the address it is stored under
+		return keyFromSecret(data, k, pepper)
+	}
+	return nil, errors.New("no secret information returned from Vault")
+}
+
+// RepairAddresses reports account configs whose secret holds the key for a different address, e.g. after the secret
+// was overwritten in Vault.  If confirm is true the account configs are rewritten with the address of the key in
+// Vault.  Running plugins apply the change when sent SIGHUP.
+func (a *accountManager) RepairAddresses(confirm bool) ([]AddressMismatch, error) {
+	var store *dirStore
+	if confirm {
+		s, ok := a.client.store.(*dirStore)
+		if !ok {
+			return nil, fmt.Errorf("account configs are stored in %v, not an accountDirectory", a.client.store)
+		}
+		store = s
+	}
+
+	var mismatches []AddressMismatch
+	accts := a.client.accounts()
+	for _, u := range accts.sortedURLs(config.AccountOrderURL) {
+		acct := accts[u]
+		conf := acct.Contents.VaultAccount
+
+		secretAddr, err := a.secretAddress(conf.SecretName, conf.SecretVersion, conf.SecretKey, acct.Contents.Address)
+		if err != nil {
+			log.Printf("[WARN] unable to check the address of account config %v: %v", acct.Path, err)
+			continue
+		}
+		if config.NormalizeAddress(secretAddr) == config.NormalizeAddress(acct.Contents.Address) {
+			continue
+		}
+		mismatches = append(mismatches, AddressMismatch{
+			URL:           u.String(),
+			SecretName:    conf.SecretName,
+			SecretVersion: conf.SecretVersion,
+			ConfigAddress: "0x" + config.NormalizeAddress(acct.Contents.Address),
+			SecretAddress: secretAddr,
+		})
+		if !confirm {
+			continue
+		}
+
+		acct.Contents.Address = config.NormalizeAddress(secretAddr)
+		contents, err := json.Marshal(acct.Contents)
+		if err != nil {
+			return mismatches, err
+		}
+		if err := store.replace(acct.Path, contents); err != nil {
+			return mismatches, fmt.Errorf("unable to update account config %v: %v", acct.Path, err)
+		}
+		log.Printf("[INFO] repaired account config %v: address changed from %v to %v", acct.Path, mismatches[len(mismatches)-1].ConfigAddress, secretAddr)
+	}
+
+	if confirm && len(mismatches) != 0 {
+		return mismatches, a.ReloadAccounts()
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,138 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/stretchr/testify/require"
+)
+
+func mismatchedAccount() (*url.URL, config.AccountFile) {
+	u, _ := url.Parse("file:///path/to/acct2")
+	acct := config.AccountFile{Path: "/path/to/acct2"}
+	acct.Contents.Address = reconcileAddr2
+	acct.Contents.VaultAccount.SecretName = "acct2"
+	acct.Contents.VaultAccount.SecretVersion = 1
+	return u, acct
+}
+
+func TestAccountKey_AddressMismatch(t *testing.T) {
+	u, acct := mismatchedAccount()
+	// the secret holds the key for reconcileAddr1 under the account's address
+	respData := map[string]interface{}{reconcileAddr2: reconcileKey1}
+	wantErr := "account config for 0x" + reconcileAddr2 + " references secret acct2 version 1, which holds the key for 0x" + reconcileAddr1
+
+	for _, policy := range []string{"", config.AddressMismatchFail, config.AddressMismatchTrustConfigAlert, config.AddressMismatchTrustVault} {
+		t.Run(policy, func(t *testing.T) {
+			a := &accountManager{
+				client:          &vaultClient{accts: accountsByURL{u: acct}},
+				addressMismatch: policy,
+			}
+			events, unsubscribe := event.Subscribe(10)
+			defer unsubscribe()
+
+			_, err := a.accountKey(acct, respData)
+			require.IsType(t, &AddressMismatchError{}, err)
+			require.EqualError(t, err, wantErr)
+
+			switch policy {
+			case config.AddressMismatchTrustConfigAlert:
+				require.Equal(t, event.AddressMismatch, (<-events).Kind)
+				require.Equal(t, wantErr, a.degraded[reconcileAddr2])
+				require.Equal(t, reconcileAddr2, a.client.accounts()[u].Contents.Address)
+			case config.AddressMismatchTrustVault:
+				require.Equal(t, event.AddressMismatch, (<-events).Kind)
+				require.Equal(t, reconcileAddr1, a.client.accounts()[u].Contents.Address)
+			default:
+				require.Empty(t, events)
+				require.Equal(t, reconcileAddr2, a.client.accounts()[u].Contents.Address)
+			}
+		})
+	}
+}
+
+func TestAccountKey_KeyStoredUnderDifferentAddress(t *testing.T) {
+	u, acct := mismatchedAccount()
+	// the secret holds the account's key, but under another address
+	respData := map[string]interface{}{reconcileAddr1: reconcileKey2}
+
+	a := &accountManager{client: &vaultClient{accts: accountsByURL{u: acct}}}
+	_, err := a.accountKey(acct, respData)
+	require.EqualError(t, err, "response does not contain data for account address "+reconcileAddr2)
+
+	a.addressMismatch = config.AddressMismatchTrustConfigAlert
+	key, err := a.accountKey(acct, respData)
+	require.NoError(t, err)
+	require.NotNil(t, key)
+}
+
+func TestRepairAddresses(t *testing.T) {
+	vault := reconcileVaultServer()
+	defer vault.Close()
+
+	dir, err := ioutil.TempDir("", "repair")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := reconcileAccountManager(t, vault.URL, dir)
+
+	got, err := a.RepairAddresses(false)
+	require.NoError(t, err)
+	want := []AddressMismatch{{
+		URL:           "file:///path/to/acct2",
+		SecretName:    "acct2",
+		SecretVersion: 1,
+		ConfigAddress: "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526",
+		SecretAddress: "0x" + reconcileAddr1,
+	}}
+	require.Equal(t, want, got)
+
+	// write the mismatched account config to the account directory so that it can be rewritten
+	path := filepath.Join(dir, "acct2.json")
+	var (
+		u    *url.URL
+		acct config.AccountFile
+	)
+	for acctURL, f := range a.client.accounts() {
+		if acctURL.String() == "file:///path/to/acct2" {
+			u, acct = acctURL, f
+		}
+	}
+	acct.Path = path
+	contents, err := json.Marshal(acct.Contents)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, contents, 0600))
+	a.client.addAccount(u, acct)
+
+	got, err = a.RepairAddresses(true)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var repaired config.AccountFileJSON
+	require.NoError(t, json.Unmarshal(b, &repaired))
+	require.Equal(t, reconcileAddr1, repaired.Address)
+	require.Equal(t, "acct2", repaired.VaultAccount.SecretName)
+}
+
+func TestAccountKey_SecretKey(t *testing.T) {
+	u, acct := mismatchedAccount()
+	acct.Contents.VaultAccount.SecretKey = "privateKey"
+	a := &accountManager{client: &vaultClient{accts: accountsByURL{u: acct}}}
+
+	// related material is kept in the same secret as the key
+	key, err := a.accountKey(acct, map[string]interface{}{"privateKey": reconcileKey2, "note": "treasury hot wallet"})
+	require.NoError(t, err)
+	require.NotNil(t, key)
+
+	_, err = a.accountKey(acct, map[string]interface{}{"privateKey": reconcileKey1, "note": "treasury hot wallet"})
+	require.IsType(t, &AddressMismatchError{}, err)
+	require.EqualError(t, err, "account config for 0x"+reconcileAddr2+" references secret acct2 version 1, which holds the key for 0x"+reconcileAddr1)
+}
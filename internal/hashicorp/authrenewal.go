@@ -1,67 +1,283 @@
 package hashicorp
 
 import (
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+)
+
+const (
+	authStatic           = "static token"
+	authNotRenewable     = "auth token not renewable"
+	authRenewing         = "renewing auth token"
+	authReauthenticating = "reauthenticating"
+	authDev              = "none (dev mode)"
+	authRevoked          = "auth token revoked"
+	authShutdown         = "auth token revoked on shutdown"
 )
 
 type renewable struct {
 	*api.Secret
 }
 
+// startAuthenticationRenewal installs the new token and keeps it valid, either by renewing it or by logging in again
+// before it expires.  Any renewal of a previous token is stopped.
 func (r *renewable) startAuthenticationRenewal(client *vaultClient, conf config.VaultClientAuthentication) error {
+	_, err := r.startRenewal(client, conf, nil)
+	return err
+}
+
+// startRenewal is startAuthenticationRenewal, except that the token is not installed if superseded is closed, i.e. the
+// renewal that led to the login has itself been superseded in the meantime.  installed is false if the token was not
+// installed.
+func (r *renewable) startRenewal(client *vaultClient, conf config.VaultClientAuthentication, superseded <-chan struct{}) (installed bool, err error) {
+	token, err := r.TokenID()
+	if err != nil {
+		return false, err
+	}
+
 	if isRenewable, _ := r.TokenIsRenewable(); !isRenewable {
-		return nil
+		stop, ok := client.installToken(token, authStateAuthenticated, authNotRenewable, superseded)
+		if !ok {
+			return false, nil
+		}
+		// Kubernetes, Azure, cert, LDAP and userpass roles are commonly configured to issue non-renewable tokens, so log in
+		// again before the token expires
+		_, _, isUserpass := userpassAuth(conf)
+		if ttl, _ := r.TokenTTL(); (conf.Kubernetes.Role != "" || conf.Azure.Role != "" || conf.Cert.Role != "" || isUserpass) && ttl > 0 {
+			superviseAuth(client, conf, stop, func() { r.reloginLoop(reloginAfter(ttl), client, conf, stop) })
+		}
+		return true, nil
 	}
 
 	renewer, err := client.NewRenewer(&api.RenewerInput{Secret: r.Secret})
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	go r.renewalLoop(renewer, client, conf)
-	return nil
+	stop, ok := client.installToken(token, authStateRenewing, authRenewing, superseded)
+	if !ok {
+		return false, nil
+	}
+	superviseAuth(client, conf, stop, func() { r.renewalLoop(renewer, client, conf, stop) })
+	return true, nil
+}
+
+// superviseAuth runs loop as a supervised worker.  If it panics, the plugin logs in again rather than restarting loop,
+// as the token loop was keeping valid may have expired in the meantime.
+func superviseAuth(client *vaultClient, conf config.VaultClientAuthentication, stop <-chan struct{}, loop func()) {
+	started := false
+	supervise("auth renewal", stop, func() {
+		if started {
+			client.reauthenticate(conf, stop)
+			return
+		}
+		started = true
+		loop()
+	})
+}
+
+// supersedeRenewal stops any renewal of a previous token, returning a channel that is closed when the renewal about to
+// be started is itself superseded
+func (c *vaultClient) supersedeRenewal() <-chan struct{} {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return c.supersedeRenewalLocked()
+}
+
+// supersedeRenewalLocked is supersedeRenewal for callers holding c.authMu
+func (c *vaultClient) supersedeRenewalLocked() <-chan struct{} {
+	if c.renewalStop != nil {
+		close(c.renewalStop)
+	}
+	c.renewalStop = make(chan struct{})
+	return c.renewalStop
+}
+
+// stopRenewal stops the renewal of the token, e.g. when the client is closed.  A login in progress installs its token
+// first, so that the renewal of that token is stopped instead.
+func (c *vaultClient) stopRenewal() {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	c.supersedeRenewal()
+}
+
+// isStopped reports whether stop is closed.  A nil stop is never closed.
+func isStopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// reloginLoop waits until the non-renewable auth token is close to expiry and then re-authenticates
+func (r *renewable) reloginLoop(wait time.Duration, client *vaultClient, conf config.VaultClientAuthentication, stop <-chan struct{}) {
+	select {
+	case <-time.After(wait):
+	case <-stop:
+		return
+	}
+	log.Printf("[DEBUG] Vault auth token nearing expiry, attempting re-authentication: %v", authMethod(conf))
+	event.Emit(event.AuthTokenNearExpiry, authID(conf), "token not renewable, reauthenticating")
+	client.reauthenticate(conf, stop)
 }
 
 // renewalLoop starts the background process for renewing the auth token.  If the renewal fails, reauthentication will
-// be attempted indefinitely.
-func (r *renewable) renewalLoop(renewer *api.Renewer, client *vaultClient, conf config.VaultClientAuthentication) {
+// be attempted indefinitely.  The loop exits without reauthenticating if stop is closed.
+func (r *renewable) renewalLoop(renewer *api.Renewer, client *vaultClient, conf config.VaultClientAuthentication, stop <-chan struct{}) {
 	go renewer.Renew()
+	defer renewer.Stop()
 
 	for {
 		select {
-		case _ = <-renewer.RenewCh():
-			log.Printf("[DEBUG] successfully renewed Vault auth token: approle = %v", conf.ApprolePath)
+		case <-stop:
+			return
+
+		case renewal := <-renewer.RenewCh():
+			log.Printf("[DEBUG] successfully renewed Vault auth token: %v", authMethod(conf))
+			event.Emit(event.AuthRenewed, authID(conf), renewalMessage(renewal))
 
 		case err := <-renewer.DoneCh():
+			// the token may have been replaced while the renewal was failing, in which case the failure no longer matters
+			if isStopped(stop) {
+				return
+			}
+			if isTokenRevoked(err) {
+				// log in again without reporting a renewal failure, as the token was revoked rather than expired
+				client.tokenRevokedUnlessStopped(err, stop)
+				return
+			}
 			// Renewal has stopped either due to an unexpected reason (i.e. some error) or an expected reason
 			// (e.g. token TTL exceeded).  Either way we must re-authenticate and get a new token.
 			switch err {
 			case nil:
-				log.Printf("[DEBUG] renewal of Vault auth token failed, attempting re-authentication: approle = %v", conf.ApprolePath)
+				log.Printf("[DEBUG] renewal of Vault auth token failed, attempting re-authentication: %v", authMethod(conf))
+				event.Emit(event.AuthTokenNearExpiry, authID(conf), "token can no longer be renewed, reauthenticating")
 			default:
-				log.Printf("[DEBUG] renewal of Vault auth token failed, attempting re-authentication: approle = %v, err = %v", conf.ApprolePath, err)
+				log.Printf("[DEBUG] renewal of Vault auth token failed, attempting re-authentication: %v, err = %v", authMethod(conf), err)
+				event.Emit(event.AuthRenewalFailed, authID(conf), err.Error())
 			}
 
-			for i := 1; ; i++ {
-				renewable, err := client.authenticateWithApprole(conf)
-				if err != nil {
-					log.Printf("[ERROR] unable to reauthenticate with Vault (attempt %v): approle = %v, err = %v", i, conf.ApprolePath, err)
-					time.Sleep(reauthRetryInterval)
-					continue
-				}
-				log.Printf("[DEBUG] successfully re-authenticated with Vault: approle = %v", conf.ApprolePath)
-
-				if err := renewable.startAuthenticationRenewal(client, conf); err != nil {
-					log.Printf("[ERROR] unable to start renewal of authentication with Vault: approle = %v, err = %v", conf.ApprolePath, err)
-					time.Sleep(reauthRetryInterval)
-					continue
-				}
-				return
-			}
+			client.reauthenticate(conf, stop)
+			return
+		}
+	}
+}
+
+// reauthenticate switches to a standby token if one is available, otherwise it logs in to Vault again, retrying
+// indefinitely, and restarts renewal of the new token.  Retrying stops if stop is closed, i.e. the token has been
+// replaced in the meantime, e.g. because the credentials have been refreshed.  A token obtained after stop is closed is
+// discarded rather than replacing the newer token.
+func (c *vaultClient) reauthenticate(conf config.VaultClientAuthentication, stop <-chan struct{}) {
+	if !c.transitionUnlessStopped(stop, authStateReauthenticating, authReauthenticating) {
+		return
+	}
+	degraded := false
+	for i := 1; ; i++ {
+		err := c.loginAndInstall(conf, stop)
+		if err == nil {
+			return
+		}
+		log.Printf("[ERROR] unable to reauthenticate with Vault (attempt %v): %v, err = %v", i, authMethod(conf), err)
+		event.Emit(event.AuthReauthenticateFailed, authID(conf), fmt.Sprintf("attempt %v: %v", i, err))
+		if !degraded {
+			degraded = c.transitionUnlessStopped(stop, authStateDegraded, err.Error())
+		}
+		if !sleepUnlessStopped(reauthRetryInterval, stop) {
+			return
+		}
+	}
+}
+
+// loginAndInstall switches to a standby token or logs in, and installs the new token unless stop is closed.  Logins are
+// serialized by c.loginMu, so a login started by a renewal that has since been superseded never replaces the token of
+// the renewal that superseded it.
+func (c *vaultClient) loginAndInstall(conf config.VaultClientAuthentication, stop <-chan struct{}) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	if isStopped(stop) {
+		return nil
+	}
+	if c.useStandbyToken(conf, stop) {
+		return nil
+	}
+
+	renewable, err := c.login(conf)
+	if err != nil {
+		return err
+	}
+	installed, err := renewable.startRenewal(c, conf, stop)
+	if err != nil {
+		return fmt.Errorf("unable to start renewal of authentication with Vault: %v", err)
+	}
+	if !installed {
+		log.Printf("[DEBUG] discarding Vault auth token as it was replaced during re-authentication: %v", authMethod(conf))
+		return nil
+	}
+	log.Printf("[DEBUG] successfully re-authenticated with Vault: %v", authMethod(conf))
+	event.Emit(event.AuthReauthenticated, authID(conf), "")
+	return nil
+}
+
+// sleepUnlessStopped waits for d, returning false if stop is closed first
+func sleepUnlessStopped(d time.Duration, stop <-chan struct{}) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// refreshCredentials re-reads the authentication credentials and logs in to Vault again immediately, rather than waiting
+// for the current token to expire or fail renewal.  This allows operators to rotate credentials (e.g. an AppRole
+// secret_id) proactively.  If the login fails the current token continues to be used.
+func (c *vaultClient) refreshCredentials() error {
+	if c.dev {
+		return nil
+	}
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	conf := c.auth
+	if conf.Token.IsSet() {
+		token := conf.Token.Get()
+		if token == "" {
+			return fmt.Errorf("%v is empty", conf.Token.String())
 		}
+		c.installToken(token, authStateAuthenticated, authStatic, nil)
+		log.Printf("[INFO] refreshed Vault auth token: %v", authMethod(conf))
+		event.Emit(event.AuthReauthenticated, authID(conf), "credentials refreshed")
+		return nil
+	}
+
+	renewable, err := c.login(conf)
+	if err != nil {
+		event.Emit(event.AuthReauthenticateFailed, authID(conf), fmt.Sprintf("credential refresh: %v", err))
+		return err
+	}
+	if err := renewable.startAuthenticationRenewal(c, conf); err != nil {
+		return err
+	}
+	log.Printf("[INFO] refreshed credentials and re-authenticated with Vault: %v", authMethod(conf))
+	event.Emit(event.AuthReauthenticated, authID(conf), "credentials refreshed")
+	go c.pool.flush(conf)
+	return nil
+}
+
+// renewalMessage describes the lease of a renewed token
+func renewalMessage(renewal *api.RenewOutput) string {
+	if renewal == nil || renewal.Secret == nil {
+		return ""
+	}
+	ttl, err := renewal.Secret.TokenTTL()
+	if err != nil {
+		return ""
 	}
+	return fmt.Sprintf("ttl %v", ttl)
 }
@@ -0,0 +1,77 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultClient_RefreshCredentials_RereadsRotatedSecretID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "approle")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "secretid")
+	require.NoError(t, ioutil.WriteFile(path, []byte("firstsecret"), 0600))
+	secretIDURL, _ := url.Parse("file://" + path)
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/auth/myapprole/login", r.URL.Path)
+		body := make(map[string]string)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["secret_id"] == "revokedsecret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["invalid secret id"]}`))
+			return
+		}
+		b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: "token-" + body["secret_id"]}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	c := testVaultClient(t, vault.URL)
+
+	roleID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "UNSET_ROLE_ID"})
+	secretID := config.EnvironmentVariable(*secretIDURL)
+	auth := config.VaultClientAuthentication{Token: &config.EnvironmentVariable{}, RoleId: &roleID, SecretId: &secretID, ApprolePath: "myapprole"}
+
+	require.NoError(t, c.authenticate(auth))
+	require.Equal(t, "token-firstsecret", c.Token())
+
+	// the operator rotates the secret_id
+	require.NoError(t, ioutil.WriteFile(path, []byte("secondsecret"), 0600))
+	require.NoError(t, c.refreshCredentials())
+	require.Equal(t, "token-secondsecret", c.Token())
+
+	// a failed refresh leaves the current token in use
+	require.NoError(t, ioutil.WriteFile(path, []byte("revokedsecret"), 0600))
+	require.Error(t, c.refreshCredentials())
+	require.Equal(t, "token-secondsecret", c.Token())
+}
+
+func TestVaultClient_SupersedeRenewal(t *testing.T) {
+	c := &vaultClient{}
+
+	first := c.supersedeRenewal()
+	second := c.supersedeRenewal()
+
+	select {
+	case <-first:
+	default:
+		t.Fatal("first renewal not stopped")
+	}
+	select {
+	case <-second:
+		t.Fatal("current renewal stopped")
+	default:
+	}
+}
@@ -0,0 +1,120 @@
+package hashicorp
+
+import (
+	"log"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+// authState is the state of the client's Vault token in the renewal lifecycle.  authStatus describes the token in more
+// detail (e.g. static, revoked) for troubleshooting.
+type authState int
+
+const (
+	authStateUnauthenticated  authState = iota // no token has been installed yet, or the client is in dev mode
+	authStateAuthenticated                     // a token is in use that is not renewed, e.g. a static or non-renewable token
+	authStateRenewing                          // a token is in use and is being renewed
+	authStateReauthenticating                  // the token can no longer be used and the client is logging in again
+	authStateDegraded                          // logging in again has failed and is being retried
+)
+
+func (s authState) String() string {
+	switch s {
+	case authStateAuthenticated:
+		return "authenticated"
+	case authStateRenewing:
+		return "renewing"
+	case authStateReauthenticating:
+		return "reauthenticating"
+	case authStateDegraded:
+		return "degraded"
+	default:
+		return "unauthenticated"
+	}
+}
+
+// authTransitions are the states each state can move to.  A new token can be installed in any state.
+var authTransitions = map[authState][]authState{
+	authStateUnauthenticated:  {authStateAuthenticated, authStateRenewing},
+	authStateAuthenticated:    {authStateAuthenticated, authStateRenewing, authStateReauthenticating},
+	authStateRenewing:         {authStateAuthenticated, authStateRenewing, authStateReauthenticating},
+	authStateReauthenticating: {authStateAuthenticated, authStateRenewing, authStateDegraded},
+	authStateDegraded:         {authStateAuthenticated, authStateRenewing, authStateReauthenticating},
+}
+
+func (s authState) canTransition(to authState) bool {
+	for _, allowed := range authTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// authTransition is a change of authState, passed to the client's onAuthTransition hook
+type authTransition struct {
+	From       authState
+	To         authState
+	Generation uint64 // the number of tokens installed so far, including the current one
+	Reason     string
+}
+
+// transitionLocked moves the client to state to, returning false if the transition is not allowed from the current
+// state.  Moving to authStateAuthenticated or authStateRenewing installs a new token generation.  It must be called
+// with c.authMu held.
+func (c *vaultClient) transitionLocked(to authState, reason string) bool {
+	from := c.authState
+	if !from.canTransition(to) {
+		log.Printf("[DEBUG] ignoring Vault auth state transition from %v to %v: %v", from, to, reason)
+		return false
+	}
+	if to == authStateAuthenticated || to == authStateRenewing {
+		c.authGeneration++
+	}
+	c.authState = to
+	metrics.AuthStateTransitions.Add(to.String(), 1)
+	log.Printf("[DEBUG] Vault auth state changed from %v to %v: %v", from, to, reason)
+	if c.onAuthTransition != nil {
+		c.onAuthTransition(authTransition{From: from, To: to, Generation: c.authGeneration, Reason: reason})
+	}
+	return true
+}
+
+// transitionUnlessStopped moves the client to state to, returning false if stop is closed, i.e. the token whose renewal
+// is moving the client has already been replaced.  As renewals are superseded with c.authMu held, a superseded renewal
+// can never change the state of the token that replaced it.
+func (c *vaultClient) transitionUnlessStopped(stop <-chan struct{}, to authState, reason string) bool {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if isStopped(stop) {
+		return false
+	}
+	if to == authStateReauthenticating {
+		c.authStatus = authReauthenticating
+	}
+	c.transitionLocked(to, reason)
+	return true
+}
+
+// installToken uses token for requests and moves the client to state to, stopping the renewal of any previous token.
+// The returned channel is closed when the token is itself replaced.  Setting the token, superseding the previous renewal
+// and changing state are done together, so that a renewal of the previous token cannot interleave with them.  The token
+// is not installed, and ok is false, if superseded is closed.
+func (c *vaultClient) installToken(token string, to authState, status string, superseded <-chan struct{}) (stop <-chan struct{}, ok bool) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if isStopped(superseded) {
+		return nil, false
+	}
+	c.SetToken(token)
+	stop = c.supersedeRenewalLocked()
+	c.authStatus = status
+	c.transitionLocked(to, status)
+	return stop, true
+}
+
+func (c *vaultClient) getAuthState() (authState, uint64) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return c.authState, c.authGeneration
+}
@@ -0,0 +1,226 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// recordTransitions records the client's auth state transitions, returning a func that gets those recorded so far
+func recordTransitions(c *vaultClient) func() []authTransition {
+	var (
+		mu   sync.Mutex
+		got  []authTransition
+		hook = func(tr authTransition) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, tr)
+		}
+	)
+	c.onAuthTransition = hook
+	return func() []authTransition {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]authTransition(nil), got...)
+	}
+}
+
+// blockingLoginServer responds to approle logins with a non-renewable token-<n>, where n counts the logins.  Each login
+// is announced on started and waits for release.  Logins fail while fail is set.
+type blockingLoginServer struct {
+	*httptest.Server
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	logins  int
+	fail    bool
+}
+
+func newBlockingLoginServer(t *testing.T) *blockingLoginServer {
+	s := &blockingLoginServer{started: make(chan struct{}, 10), release: make(chan struct{}, 10)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/auth/approle/login", r.URL.Path)
+		s.started <- struct{}{}
+		<-s.release
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.fail {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["invalid role or secret ID"]}`))
+			return
+		}
+		s.logins++
+		b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: "token-" + string(rune('0'+s.logins))}})
+		_, _ = w.Write(b)
+	}))
+	return s
+}
+
+func blockingLoginClient(t *testing.T, vaultURL string) (*vaultClient, config.VaultClientAuthentication) {
+	roleID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "UNSET_ROLE_ID"})
+	secretID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "UNSET_SECRET_ID"})
+	auth := config.VaultClientAuthentication{Token: &config.EnvironmentVariable{}, RoleId: &roleID, SecretId: &secretID, ApprolePath: "approle"}
+	c := testVaultClient(t, vaultURL)
+	c.auth = auth
+	return c, auth
+}
+
+func TestAuthState_Transitions(t *testing.T) {
+	c := &vaultClient{}
+	transitions := recordTransitions(c)
+
+	c.authMu.Lock()
+	require.False(t, c.transitionLocked(authStateReauthenticating, "not authenticated yet"))
+	require.True(t, c.transitionLocked(authStateRenewing, "logged in"))
+	require.False(t, c.transitionLocked(authStateDegraded, "renewing"))
+	require.True(t, c.transitionLocked(authStateReauthenticating, "renewal failed"))
+	require.False(t, c.transitionLocked(authStateReauthenticating, "already reauthenticating"))
+	require.True(t, c.transitionLocked(authStateDegraded, "login failed"))
+	require.True(t, c.transitionLocked(authStateReauthenticating, "retrying"))
+	require.True(t, c.transitionLocked(authStateAuthenticated, "logged in"))
+	c.authMu.Unlock()
+
+	require.Equal(t, []authTransition{
+		{From: authStateUnauthenticated, To: authStateRenewing, Generation: 1, Reason: "logged in"},
+		{From: authStateRenewing, To: authStateReauthenticating, Generation: 1, Reason: "renewal failed"},
+		{From: authStateReauthenticating, To: authStateDegraded, Generation: 1, Reason: "login failed"},
+		{From: authStateDegraded, To: authStateReauthenticating, Generation: 1, Reason: "retrying"},
+		{From: authStateReauthenticating, To: authStateAuthenticated, Generation: 2, Reason: "logged in"},
+	}, transitions())
+	state, generation := c.getAuthState()
+	require.Equal(t, authStateAuthenticated, state)
+	require.Equal(t, uint64(2), generation)
+}
+
+func TestVaultClient_InstallToken(t *testing.T) {
+	c := testVaultClient(t, "http://vault:8200")
+
+	first, ok := c.installToken("first", authStateRenewing, authRenewing, nil)
+	require.True(t, ok)
+	require.Equal(t, "first", c.Token())
+
+	second, ok := c.installToken("second", authStateAuthenticated, authNotRenewable, nil)
+	require.True(t, ok)
+	require.True(t, isStopped(first))
+	require.False(t, isStopped(second))
+
+	// a token obtained by a superseded renewal does not replace the current token
+	_, ok = c.installToken("stale", authStateRenewing, authRenewing, first)
+	require.False(t, ok)
+	require.Equal(t, "second", c.Token())
+	require.False(t, isStopped(second))
+	state, generation := c.getAuthState()
+	require.Equal(t, authStateAuthenticated, state)
+	require.Equal(t, uint64(2), generation)
+	require.Equal(t, authNotRenewable, c.getAuthStatus())
+}
+
+func TestVaultClient_TransitionUnlessStopped(t *testing.T) {
+	c := &vaultClient{}
+	stop := c.supersedeRenewal()
+	c.authMu.Lock()
+	c.transitionLocked(authStateRenewing, "logged in")
+	c.authMu.Unlock()
+
+	c.supersedeRenewal()
+	require.False(t, c.transitionUnlessStopped(stop, authStateReauthenticating, "renewal of replaced token failed"))
+	state, _ := c.getAuthState()
+	require.Equal(t, authStateRenewing, state)
+}
+
+func TestVaultClient_Reauthenticate_SupersededDuringLogin(t *testing.T) {
+	vault := newBlockingLoginServer(t)
+	defer vault.Close()
+	c, auth := blockingLoginClient(t, vault.URL)
+	stop, _ := c.installToken("token-0", authStateRenewing, authRenewing, nil)
+	transitions := recordTransitions(c)
+
+	// renewal of token-0 fails and the client logs in again
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.reauthenticate(auth, stop)
+	}()
+	<-vault.started
+
+	// the credentials are refreshed while the login is in progress, the refresh waiting for the login to complete
+	refreshed := make(chan error)
+	go func() { refreshed <- c.refreshCredentials() }()
+	vault.release <- struct{}{}
+	<-done
+	require.Equal(t, "token-1", c.Token())
+
+	<-vault.started
+	vault.release <- struct{}{}
+	require.NoError(t, <-refreshed)
+	require.Equal(t, "token-2", c.Token())
+
+	// the renewal of token-0 cannot reauthenticate again, nor change the state of the current token
+	c.reauthenticate(auth, stop)
+	require.Equal(t, "token-2", c.Token())
+	require.Equal(t, []authTransition{
+		{From: authStateRenewing, To: authStateReauthenticating, Generation: 1, Reason: authReauthenticating},
+		{From: authStateReauthenticating, To: authStateAuthenticated, Generation: 2, Reason: authNotRenewable},
+		{From: authStateAuthenticated, To: authStateAuthenticated, Generation: 3, Reason: authNotRenewable},
+	}, transitions())
+}
+
+func TestVaultClient_Reauthenticate_DiscardsTokenOfSupersededRenewal(t *testing.T) {
+	vault := newBlockingLoginServer(t)
+	defer vault.Close()
+	c, auth := blockingLoginClient(t, vault.URL)
+	stop, _ := c.installToken("token-0", authStateRenewing, authRenewing, nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.reauthenticate(auth, stop)
+	}()
+	<-vault.started
+
+	// a new token is installed, e.g. from the token file, while the login is in progress
+	c.installToken("newer", authStateAuthenticated, authStatic, nil)
+	vault.release <- struct{}{}
+	<-done
+
+	require.Equal(t, "newer", c.Token())
+	state, generation := c.getAuthState()
+	require.Equal(t, authStateAuthenticated, state)
+	require.Equal(t, uint64(2), generation)
+	require.Equal(t, authStatic, c.getAuthStatus())
+}
+
+func TestVaultClient_Reauthenticate_Degraded(t *testing.T) {
+	vault := newBlockingLoginServer(t)
+	defer vault.Close()
+	vault.fail = true
+	c, auth := blockingLoginClient(t, vault.URL)
+	stop, _ := c.installToken("token-0", authStateRenewing, authRenewing, nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.reauthenticate(auth, stop)
+	}()
+	<-vault.started
+	vault.release <- struct{}{}
+	awaitCondition(t, func() bool {
+		state, _ := c.getAuthState()
+		return state == authStateDegraded
+	})
+	require.Equal(t, "token-0", c.Token())
+
+	// retrying stops once the token is replaced
+	c.installToken("newer", authStateAuthenticated, authStatic, nil)
+	<-done
+	state, _ := c.getAuthState()
+	require.Equal(t, authStateAuthenticated, state)
+}
@@ -0,0 +1,109 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// azureMetadataAddress is the Azure Instance Metadata Service, which issues managed identity tokens and describes the
+// VM.  In AKS, requests from pods are intercepted to use the pod's managed identity.
+var azureMetadataAddress = "http://169.254.169.254"
+
+var azureMetadataClient = &http.Client{Timeout: 10 * time.Second}
+
+// azureInstance is the part of the instance metadata the Vault Azure auth method uses to verify the VM
+type azureInstance struct {
+	Compute struct {
+		SubscriptionID    string `json:"subscriptionId"`
+		ResourceGroupName string `json:"resourceGroupName"`
+		Name              string `json:"name"`
+		VMScaleSetName    string `json:"vmScaleSetName"`
+	} `json:"compute"`
+}
+
+// authenticateWithAzure logs in to Vault using a token for the managed identity.  Managed identity tokens expire, so a
+// new token is requested on every login.
+func (c *vaultClient) authenticateWithAzure(conf config.VaultClientAzure) (*renewable, error) {
+	jwt, err := azureIdentityToken(conf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get Azure managed identity token: %v", err)
+	}
+
+	var instance azureInstance
+	if err := azureMetadata("/metadata/instance", url.Values{"api-version": {"2017-08-01"}}, &instance); err != nil {
+		return nil, fmt.Errorf("unable to get Azure instance metadata: %v", err)
+	}
+
+	body := map[string]interface{}{
+		"role":                conf.Role,
+		"jwt":                 jwt,
+		"subscription_id":     instance.Compute.SubscriptionID,
+		"resource_group_name": instance.Compute.ResourceGroupName,
+	}
+	if instance.Compute.VMScaleSetName != "" {
+		body["vmss_name"] = instance.Compute.VMScaleSetName
+	} else {
+		body["vm_name"] = instance.Compute.Name
+	}
+
+	resp, err := c.Logical().Write(fmt.Sprintf("auth/%s/login", conf.Path), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := resp.TokenID(); err != nil {
+		return nil, err
+	}
+	return &renewable{Secret: resp}, nil
+}
+
+// azureIdentityToken gets an access token for the managed identity with conf.Resource as its audience
+func azureIdentityToken(conf config.VaultClientAzure) (string, error) {
+	params := url.Values{"api-version": {"2018-02-01"}, "resource": {conf.Resource}}
+	if conf.ClientID != "" {
+		params.Set("client_id", conf.ClientID)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := azureMetadata("/metadata/identity/oauth2/token", params, &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("no access token returned")
+	}
+	return token.AccessToken, nil
+}
+
+// azureMetadata reads a JSON response from the Instance Metadata Service into v
+func azureMetadata(path string, params url.Values, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, azureMetadataAddress+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	// the metadata service rejects requests without this header, to protect against SSRF
+	req.Header.Set("Metadata", "true")
+
+	resp, err := azureMetadataClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v %v: %s", resp.Status, path, b)
+	}
+	return json.Unmarshal(b, v)
+}
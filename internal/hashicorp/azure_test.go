@@ -0,0 +1,107 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// azureMetadataServer serves instance metadata for a VM in a scale set if vmss is set, and a token for each identity
+// request
+func azureMetadataServer(t *testing.T, vmss string) *httptest.Server {
+	tokens := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "true", r.Header.Get("Metadata"))
+
+		var resp interface{}
+		switch r.URL.Path {
+		case "/metadata/identity/oauth2/token":
+			require.Equal(t, "https://vault.example.com", r.URL.Query().Get("resource"))
+			require.Equal(t, "myclientid", r.URL.Query().Get("client_id"))
+			tokens++
+			resp = map[string]string{"access_token": fmt.Sprintf("jwt%v", tokens)}
+		case "/metadata/instance":
+			resp = map[string]interface{}{"compute": map[string]string{
+				"subscriptionId":    "mysubscription",
+				"resourceGroupName": "myresourcegroup",
+				"name":              "myvm",
+				"vmScaleSetName":    vmss,
+			}}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b, _ := json.Marshal(resp)
+		_, _ = w.Write(b)
+	}))
+}
+
+func TestVaultClient_AuthenticateWithAzure(t *testing.T) {
+	for _, vmss := range []string{"", "myvmss"} {
+		metadata := azureMetadataServer(t, vmss)
+
+		var gotBodies []map[string]string
+		vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/v1/auth/azure-aks/login", r.URL.Path)
+			body := make(map[string]string)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			gotBodies = append(gotBodies, body)
+
+			b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: "token-" + body["jwt"], LeaseDuration: 60}})
+			_, _ = w.Write(b)
+		}))
+
+		defer func(addr string) { azureMetadataAddress = addr }(azureMetadataAddress)
+		azureMetadataAddress = metadata.URL
+
+		c := testVaultClient(t, vault.URL)
+
+		azure := config.VaultClientAzure{Role: "quorum", Path: "azure-aks", Resource: "https://vault.example.com", ClientID: "myclientid"}
+
+		r, err := c.authenticateWithAzure(azure)
+		require.NoError(t, err)
+		require.Equal(t, "token-jwt1", r.Auth.ClientToken)
+
+		// a new managed identity token is requested for each login
+		r, err = c.login(config.VaultClientAuthentication{Azure: azure})
+		require.NoError(t, err)
+		require.Equal(t, "token-jwt2", r.Auth.ClientToken)
+
+		want := map[string]string{
+			"role":                "quorum",
+			"jwt":                 "jwt1",
+			"subscription_id":     "mysubscription",
+			"resource_group_name": "myresourcegroup",
+		}
+		if vmss != "" {
+			want["vmss_name"] = vmss
+		} else {
+			want["vm_name"] = "myvm"
+		}
+		require.Equal(t, want, gotBodies[0], vmss)
+
+		metadata.Close()
+		vault.Close()
+	}
+}
+
+func TestVaultClient_AuthenticateWithAzure_NoIdentity(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_request","error_description":"Identity not found"}`))
+	}))
+	defer metadata.Close()
+
+	defer func(addr string) { azureMetadataAddress = addr }(azureMetadataAddress)
+	azureMetadataAddress = metadata.URL
+
+	c := &vaultClient{}
+	_, err := c.authenticateWithAzure(config.VaultClientAzure{Role: "quorum", Path: "azure", Resource: config.DefaultAzureResource})
+	require.EqualError(t, err, `unable to get Azure managed identity token: 400 Bad Request /metadata/identity/oauth2/token: {"error":"invalid_request","error_description":"Identity not found"}`)
+}
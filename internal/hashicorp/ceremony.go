@@ -0,0 +1,66 @@
+package hashicorp
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum/crypto/secp256k1"
+)
+
+// ValidatorAccount is a sealer account created by a validator key ceremony.  It only contains public material.
+type ValidatorAccount struct {
+	account.Account
+	PublicKey     string // the hex-encoded uncompressed public key without the 0x04 prefix, i.e. the enode node ID
+	SecretName    string
+	SecretVersion int64
+	ConfigFile    string
+	ConfigSHA256  string // the hex-encoded SHA-256 hash of the account config, as stored
+}
+
+// NewValidatorAccount generates a key, stores it in Vault and writes an account config marked as a sealer.  The
+// private key is zeroed once stored and is never returned.
+func (a *accountManager) NewValidatorAccount(conf config.NewAccount) (ValidatorAccount, error) {
+	key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+	if err != nil {
+		return ValidatorAccount{}, err
+	}
+	defer zeroKey(key)
+
+	pub, err := account.PublicKeyToHexString(&key.PublicKey)
+	if err != nil {
+		return ValidatorAccount{}, err
+	}
+
+	conf.Sealer = true
+	conf.Role = config.AccountRoleValidator
+	acct, err := a.writeToVaultAndFile(key, conf)
+	if err != nil {
+		return ValidatorAccount{}, err
+	}
+
+	fileData, ok := a.client.accounts()[acct.URL]
+	if !ok {
+		return ValidatorAccount{}, errors.New("new account config not found")
+	}
+
+	contents, err := json.Marshal(fileData.Contents)
+	if err != nil {
+		return ValidatorAccount{}, err
+	}
+	sum := sha256.Sum256(contents)
+
+	return ValidatorAccount{
+		Account:       acct,
+		PublicKey:     pub,
+		SecretName:    fileData.Contents.VaultAccount.SecretName,
+		SecretVersion: fileData.Contents.VaultAccount.SecretVersion,
+		ConfigFile:    fileData.Path,
+		ConfigSHA256:  hex.EncodeToString(sum[:]),
+	}, nil
+}
@@ -0,0 +1,59 @@
+package hashicorp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewValidatorAccount(t *testing.T) {
+	var written map[string]interface{}
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveSecretMetadata(w, r) {
+			return
+		}
+		require.Equal(t, "/v1/kv/data/validator", r.URL.Path)
+		body := make(map[string]interface{})
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		written = body["data"].(map[string]interface{})
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"version": 3}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	dir, err := ioutil.TempDir("", "ceremony")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := reconcileAccountManager(t, vault.URL, dir)
+
+	got, err := a.NewValidatorAccount(config.NewAccount{SecretName: "validator"})
+	require.NoError(t, err)
+
+	require.Equal(t, "validator", got.SecretName)
+	require.EqualValues(t, 3, got.SecretVersion)
+	require.Len(t, got.PublicKey, 128)
+	require.Contains(t, written, got.Address.ToHexString())
+
+	u, err := url.Parse(got.ConfigFile)
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(config.FilePath(u))
+	require.NoError(t, err)
+	sum := sha256.Sum256(b)
+	require.Equal(t, hex.EncodeToString(sum[:]), got.ConfigSHA256)
+	var contents config.AccountFileJSON
+	require.NoError(t, json.Unmarshal(b, &contents))
+	require.True(t, contents.Sealer)
+	require.Equal(t, config.AccountRoleValidator, contents.Role)
+	require.Equal(t, got.Address.ToHexString(), contents.Address)
+}
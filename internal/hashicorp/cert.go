@@ -0,0 +1,24 @@
+package hashicorp
+
+import (
+	"fmt"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// authenticateWithCert logs in to Vault using the cert auth method.  The client certificate configured for mTLS is
+// presented during the TLS handshake, so no other credentials are sent.  If the certificate files are reloaded the new
+// certificate is presented on new connections.
+func (c *vaultClient) authenticateWithCert(conf config.VaultClientCert) (*renewable, error) {
+	body := map[string]interface{}{"name": conf.Role}
+
+	resp, err := c.Logical().Write(fmt.Sprintf("auth/%s/login", conf.Path), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := resp.TokenID(); err != nil {
+		return nil, err
+	}
+	return &renewable{Secret: resp}, nil
+}
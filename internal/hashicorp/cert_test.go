@@ -0,0 +1,70 @@
+package hashicorp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultClient_AuthenticateWithCert(t *testing.T) {
+	var gotBody map[string]string
+	vault := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/auth/mtls/login", r.URL.Path)
+		require.NotEmpty(t, r.TLS.PeerCertificates)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+
+		b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: "token-cert", Renewable: true, LeaseDuration: 60}})
+		_, _ = w.Write(b)
+	}))
+
+	caCert, err := ioutil.ReadFile(testCACert)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caCert)
+	serverCert, err := tls.LoadX509KeyPair(testServerCert, "../test/testdata/tls/server-localhost-with-san.key.pem")
+	require.NoError(t, err)
+	vault.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    roots,
+	}
+	vault.StartTLS()
+	defer vault.Close()
+
+	fileURL := func(path string) *url.URL {
+		abs, err := filepath.Abs(path)
+		require.NoError(t, err)
+		return &url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+	}
+	vaultURL, err := url.Parse(vault.URL)
+	require.NoError(t, err)
+	stop := make(chan struct{})
+	defer close(stop)
+	client, err := newAPIClient(vaultURL, config.VaultClientTLS{
+		CaCert:     fileURL(testCACert),
+		ClientCert: fileURL(testClientCert),
+		ClientKey:  fileURL(testClientKey),
+	}, 0, stop)
+	require.NoError(t, err)
+	client.SetMaxRetries(0)
+	c := &vaultClient{Client: client}
+
+	auth := config.VaultClientAuthentication{Token: &config.EnvironmentVariable{}, Cert: config.VaultClientCert{Role: "quorum", Path: "mtls"}}
+	require.NoError(t, c.authenticate(auth))
+	defer c.supersedeRenewal()
+
+	require.Equal(t, "token-cert", c.Token())
+	require.Equal(t, map[string]string{"name": "quorum"}, gotBody)
+	require.Equal(t, authRenewing, c.getAuthStatus())
+	require.Equal(t, "cert/mtls", authID(auth))
+}
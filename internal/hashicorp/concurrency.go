@@ -0,0 +1,42 @@
+package hashicorp
+
+import (
+	"context"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+// requestLimiter limits the number of concurrent requests sent to Vault so that a burst of signing traffic cannot
+// exhaust Vault-side rate limits or local file descriptors.  Excess requests are queued.  A nil requestLimiter is
+// unlimited.
+type requestLimiter chan struct{}
+
+func newRequestLimiter(max int) requestLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return make(requestLimiter, max)
+}
+
+// acquire waits for a free slot, returning a func to release it.  An error is returned if ctx is done before a slot
+// is free.
+func (l requestLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	metrics.VaultRequestsQueued.Add(1)
+	select {
+	case l <- struct{}{}:
+		metrics.VaultRequestsQueued.Add(-1)
+	case <-ctx.Done():
+		metrics.VaultRequestsQueued.Add(-1)
+		return nil, ctx.Err()
+	}
+
+	metrics.VaultRequestsInFlight.Add(1)
+	return func() {
+		metrics.VaultRequestsInFlight.Add(-1)
+		<-l
+	}, nil
+}
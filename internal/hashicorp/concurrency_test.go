@@ -0,0 +1,61 @@
+package hashicorp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLimiter_NilIsUnlimited(t *testing.T) {
+	l := newRequestLimiter(0)
+	require.Nil(t, l)
+
+	for i := 0; i < 100; i++ {
+		_, err := l.acquire(context.Background())
+		require.NoError(t, err)
+	}
+}
+
+func TestRequestLimiter_QueuesWhenFull(t *testing.T) {
+	l := newRequestLimiter(1)
+
+	release, err := l.acquire(context.Background())
+	require.NoError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		r, err := l.acquire(context.Background())
+		require.NoError(t, err)
+		r()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired slot while limiter full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("slot not acquired after release")
+	}
+}
+
+func TestRequestLimiter_ContextDone(t *testing.T) {
+	l := newRequestLimiter(1)
+
+	_, err := l.acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = l.acquire(ctx)
+	require.EqualError(t, err, "context deadline exceeded")
+}
@@ -0,0 +1,76 @@
+package hashicorp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// vaultIndexHeader is returned by Vault Enterprise on writes and identifies the replication state containing the write.
+// Sending it on a subsequent request makes a performance standby/secondary wait until it has caught up to that state,
+// so that a read of a just-written secret version is not served from stale (missing) data.
+const vaultIndexHeader = "X-Vault-Index"
+
+// write writes data to path on the primary, recording the replication state of the write so that it is sent on all
+// subsequent requests
+func (c *vaultClient) write(path string, data map[string]interface{}) (*api.Secret, error) {
+	return c.writeWithContext(context.Background(), path, data)
+}
+
+// writeWithContext is equivalent to write but the request is sent to the namespace carried by ctx, if any, and is
+// cancelled when ctx is done.  The write waits for a free request slot if the number of concurrent requests is limited.
+func (c *vaultClient) writeWithContext(ctx context.Context, path string, data map[string]interface{}) (*api.Secret, error) {
+	release, err := c.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	r := c.NewRequest("PUT", fmt.Sprintf("/v1/%v", namespacedPath(ctx, path)))
+	if err := r.SetJSONBody(data); err != nil {
+		return nil, err
+	}
+	setAuditRequestID(ctx, r)
+
+	resp, err := c.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		c.detectRevocation(err)
+		return nil, err
+	}
+
+	if state := resp.Header.Get(vaultIndexHeader); state != "" {
+		c.recordIndex(state)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// e.g. 204 No Content
+		return nil, nil
+	}
+	return api.ParseSecret(resp.Body)
+}
+
+// recordIndex sets the replication state to send on requests to the primary and read replica
+func (c *vaultClient) recordIndex(state string) {
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+
+	log.Printf("[DEBUG] recording Vault replication state: %v", state)
+	clients := []*api.Client{c.Client}
+	if c.replicas != nil {
+		clients = append(clients, c.replicas.clients()...)
+	}
+	for _, client := range clients {
+		h := client.Headers()
+		if h == nil {
+			h = make(http.Header)
+		}
+		h.Set(vaultIndexHeader, state)
+		client.SetHeaders(h)
+	}
+}
@@ -0,0 +1,55 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite_ReplicationStateSentOnSubsequentReads(t *testing.T) {
+	var primaryReadIndex, replicaReadIndex string
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.Header().Set("X-Vault-Index", "state-after-write")
+			b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"version": 1}})
+			_, _ = w.Write(b)
+			return
+		}
+		primaryReadIndex = r.Header.Get("X-Vault-Index")
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	}))
+	defer primary.Close()
+
+	replica := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replicaReadIndex = r.Header.Get("X-Vault-Index")
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	}))
+	defer replica.Close()
+
+	a := reconcileAccountManager(t, primary.URL, "/path/to/dir")
+	replicaURL, _ := url.Parse(replica.URL)
+	replicaClient, err := newAPIClient(replicaURL, config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}}, 0, nil)
+	require.NoError(t, err)
+	a.client.replicas = newReplicaSet(replicaClient)
+
+	_, _ = a.readSecret(context.Background(), "acct1", 1)
+	require.Empty(t, replicaReadIndex)
+
+	version, err := a.writeToVault(reconcileAddr1, reconcileKey1, config.NewAccount{SecretName: "acct1"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+
+	_, _ = a.readSecret(context.Background(), "acct1", 1)
+	require.Equal(t, "state-after-write", replicaReadIndex)
+
+	_, _ = a.client.Logical().Read("kv/metadata/acct1")
+	require.Equal(t, "state-after-write", primaryReadIndex)
+}
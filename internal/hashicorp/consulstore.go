@@ -0,0 +1,154 @@
+package hashicorp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// consulStore keeps each account config as a key under the prefix in the Consul KV store.  Changes are watched for
+// with Consul blocking queries.
+type consulStore struct {
+	client  *http.Client
+	address *url.URL
+	prefix  string
+	token   *config.EnvironmentVariable
+	maxWait time.Duration // the maximum duration of a blocking query
+}
+
+type consulKV struct {
+	Key   string
+	Value []byte
+}
+
+func newConsulStore(conf config.VaultClientAccountStore) *consulStore {
+	wait := storePollInterval(conf)
+	return &consulStore{
+		// Consul adds up to wait/16 to the duration of a blocking query
+		client:  &http.Client{Timeout: wait + storeRequestTimeout},
+		address: conf.Address,
+		prefix:  strings.Trim(conf.Prefix, "/"),
+		token:   conf.Token,
+		maxWait: wait,
+	}
+}
+
+func (s *consulStore) String() string {
+	return fmt.Sprintf("consul://%v/%v", s.address.Host, s.prefix)
+}
+
+func (s *consulStore) load() (storeContents, error) {
+	resp, err := s.do(http.MethodGet, s.prefix+"/", url.Values{"recurse": {""}}, nil)
+	if err != nil {
+		return storeContents{}, err
+	}
+	defer resp.Body.Close()
+
+	version, err := consulIndex(resp)
+	if err != nil {
+		return storeContents{}, err
+	}
+	contents := storeContents{version: version}
+	if resp.StatusCode == http.StatusNotFound {
+		// no account configs have been stored yet
+		return contents, nil
+	}
+
+	var kvs []consulKV
+	if err := json.NewDecoder(resp.Body).Decode(&kvs); err != nil {
+		return storeContents{}, fmt.Errorf("unable to decode Consul response: %v", err)
+	}
+	for _, kv := range kvs {
+		if strings.HasSuffix(kv.Key, "/") {
+			// a folder
+			continue
+		}
+		contents.accounts = append(contents.accounts, storedAccount{location: s.location(kv.Key), contents: kv.Value})
+	}
+	return contents, nil
+}
+
+// create uses a check-and-set index of 0 so that an existing key is never overwritten
+func (s *consulStore) create(name string, contents []byte) (string, error) {
+	key := storeKey(s.prefix, name)
+	resp, err := s.do(http.MethodPut, key, url.Values{"cas": {"0"}}, bytes.NewReader(contents))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response from Consul: %v", resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(b)) != "true" {
+		return "", fmt.Errorf("account config %v already exists", s.location(key))
+	}
+	return s.location(key), nil
+}
+
+// wait makes a blocking query which returns when the index of the prefix changes or the wait elapses
+func (s *consulStore) wait(version uint64) (uint64, error) {
+	query := url.Values{
+		"keys":  {""},
+		"index": {strconv.FormatUint(version, 10)},
+		"wait":  {s.maxWait.String()},
+	}
+	resp, err := s.do(http.MethodGet, s.prefix+"/", query, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	return consulIndex(resp)
+}
+
+func (s *consulStore) do(method, key string, query url.Values, body io.Reader) (*http.Response, error) {
+	u := *s.address
+	u.Path = "/v1/kv/" + key
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if token := storeToken(s.token); token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected response from Consul: %v: %v", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+func (s *consulStore) location(key string) string {
+	return fmt.Sprintf("consul://%v/%v", s.address.Host, key)
+}
+
+func consulIndex(resp *http.Response) (uint64, error) {
+	index, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid X-Consul-Index in Consul response: %v", err)
+	}
+	return index, nil
+}
@@ -0,0 +1,127 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsul implements the subset of the Consul KV API used by consulStore
+type fakeConsul struct {
+	mu     sync.Mutex
+	kvs    map[string][]byte
+	index  uint64
+	tokens []string
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens = append(f.tokens, r.Header.Get("X-Consul-Token"))
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+
+	switch r.Method {
+	case http.MethodPut:
+		if _, ok := f.kvs[key]; ok && r.URL.Query().Get("cas") == "0" {
+			w.Write([]byte("false"))
+			return
+		}
+		f.kvs[key], _ = ioutil.ReadAll(r.Body)
+		f.index++
+		w.Write([]byte("true"))
+	case http.MethodGet:
+		var kvs []consulKV
+		for k, v := range f.kvs {
+			if strings.HasPrefix(k, key) {
+				kvs = append(kvs, consulKV{Key: k, Value: v})
+			}
+		}
+		if len(kvs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(kvs)
+	}
+}
+
+func newFakeConsulStore(t *testing.T, token *config.EnvironmentVariable) (*consulStore, *fakeConsul, func()) {
+	fake := &fakeConsul{kvs: make(map[string][]byte), index: 1}
+	server := httptest.NewServer(fake)
+	addr, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	s := newConsulStore(config.VaultClientAccountStore{
+		Type:    config.AccountStoreConsul,
+		Address: addr,
+		Prefix:  "/quorum/accounts/",
+		Token:   token,
+	})
+	return s, fake, server.Close
+}
+
+func TestConsulStore_Load_Empty(t *testing.T) {
+	s, _, stop := newFakeConsulStore(t, nil)
+	defer stop()
+
+	got, err := s.load()
+	require.NoError(t, err)
+	require.Empty(t, got.accounts)
+	require.EqualValues(t, 1, got.version)
+}
+
+func TestConsulStore_CreateAndLoad(t *testing.T) {
+	s, fake, stop := newFakeConsulStore(t, nil)
+	defer stop()
+
+	location, err := s.create("UTC--acct1", []byte(`{"Address": "acct1"}`))
+	require.NoError(t, err)
+	require.Equal(t, "consul://"+s.address.Host+"/quorum/accounts/UTC--acct1", location)
+	require.Contains(t, fake.kvs, "quorum/accounts/UTC--acct1")
+
+	got, err := s.load()
+	require.NoError(t, err)
+	require.Equal(t, []storedAccount{{location: location, contents: []byte(`{"Address": "acct1"}`)}}, got.accounts)
+	require.EqualValues(t, 2, got.version)
+
+	version, err := s.wait(1)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, version)
+}
+
+func TestConsulStore_Create_DoesNotOverwrite(t *testing.T) {
+	s, fake, stop := newFakeConsulStore(t, nil)
+	defer stop()
+
+	_, err := s.create("UTC--acct1", []byte("original"))
+	require.NoError(t, err)
+
+	_, err = s.create("UTC--acct1", []byte("overwritten"))
+	require.EqualError(t, err, "account config consul://"+s.address.Host+"/quorum/accounts/UTC--acct1 already exists")
+	require.Equal(t, []byte("original"), fake.kvs["quorum/accounts/UTC--acct1"])
+}
+
+func TestConsulStore_Token(t *testing.T) {
+	require.NoError(t, os.Setenv("CONSUL_TOKEN", "secret-token"))
+	defer os.Unsetenv("CONSUL_TOKEN")
+	token, _ := url.Parse("env://CONSUL_TOKEN")
+	env := config.EnvironmentVariable(*token)
+
+	s, fake, stop := newFakeConsulStore(t, &env)
+	defer stop()
+
+	_, err := s.load()
+	require.NoError(t, err)
+	require.Equal(t, []string{"secret-token"}, fake.tokens)
+}
@@ -0,0 +1,226 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+)
+
+// controlGroupPollInterval is how often Vault is checked for approval of a pending control group request
+var controlGroupPollInterval = 10 * time.Second
+
+// ApprovalPendingError is returned when an account's secret is protected by a Vault Enterprise Control Group and the
+// unlock is waiting for approval.  Once approved, a TimedUnlock completes automatically.  An UnlockAndSign must be
+// retried after approval.
+type ApprovalPendingError struct {
+	Accessor string // the control group request accessor, for approvers to authorize
+}
+
+func (e *ApprovalPendingError) Error() string {
+	return fmt.Sprintf("unlock awaiting Vault control group approval: accessor = %v", e.Accessor)
+}
+
+// controlGroupErr is returned when a read is wrapped by a control group rather than returning the secret
+type controlGroupErr struct {
+	wrapInfo *api.SecretWrapInfo
+}
+
+func (e *controlGroupErr) Error() string {
+	return fmt.Sprintf("secret is protected by a Vault control group: accessor = %v", e.wrapInfo.Accessor)
+}
+
+// controlGroupRequest is an unlock waiting for control group approval
+type controlGroupRequest struct {
+	acctFile    config.AccountFile
+	accessor    string
+	token       string
+	requested   time.Time
+	expires     time.Time
+	duration    time.Duration
+	timedUnlock bool                   // unlock when approved, otherwise hold the approved secret for the retry
+	approved    map[string]interface{} // the approved secret, if held
+}
+
+// controlGroups tracks pending control group requests by account address
+type controlGroups struct {
+	mu       sync.Mutex
+	requests map[string]*controlGroupRequest
+}
+
+func (g *controlGroups) get(addr string) *controlGroupRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.requests[addr]
+}
+
+func (g *controlGroups) add(addr string, r *controlGroupRequest) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.requests == nil {
+		g.requests = make(map[string]*controlGroupRequest)
+	}
+	g.requests[addr] = r
+}
+
+func (g *controlGroups) remove(addr string, r *controlGroupRequest) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.requests[addr] == r {
+		delete(g.requests, addr)
+	}
+}
+
+// take returns the approved secret for the account, removing the request, or an ApprovalPendingError if approval is
+// still pending.  Both are nil if there is no request for the account.
+func (g *controlGroups) take(addr string) (map[string]interface{}, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	r, ok := g.requests[addr]
+	if !ok {
+		return nil, nil
+	}
+	if r.approved == nil {
+		return nil, &ApprovalPendingError{Accessor: r.accessor}
+	}
+	delete(g.requests, addr)
+	return r.approved, nil
+}
+
+func (g *controlGroups) setApproved(r *controlGroupRequest, data map[string]interface{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r.approved = data
+}
+
+// PendingApproval is an unlock waiting for Vault control group approval
+type PendingApproval struct {
+	Address   string
+	Accessor  string
+	Requested time.Time
+	Expires   time.Time
+	Approved  bool // approved but waiting for the UnlockAndSign to be retried
+}
+
+func (g *controlGroups) pending() []PendingApproval {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p := make([]PendingApproval, 0, len(g.requests))
+	for addr, r := range g.requests {
+		p = append(p, PendingApproval{
+			Address:   "0x" + addr,
+			Accessor:  r.accessor,
+			Requested: r.requested,
+			Expires:   r.expires,
+			Approved:  r.approved != nil,
+		})
+	}
+	sort.Slice(p, func(i, j int) bool { return p[i].Address < p[j].Address })
+	return p
+}
+
+// requestApproval records the control group request for the account and starts waiting for it to be approved
+func (a *accountManager) requestApproval(acctFile config.AccountFile, wrapInfo *api.SecretWrapInfo, duration time.Duration, timedUnlock bool) error {
+	addr := strings.TrimPrefix(acctFile.Contents.Address, "0x")
+	now := time.Now()
+	r := &controlGroupRequest{
+		acctFile:    acctFile,
+		accessor:    wrapInfo.Accessor,
+		token:       wrapInfo.Token,
+		requested:   now,
+		expires:     now.Add(time.Duration(wrapInfo.TTL) * time.Second),
+		duration:    duration,
+		timedUnlock: timedUnlock,
+	}
+	a.approvals.add(addr, r)
+
+	log.Printf("[INFO] unlock of account 0x%v awaiting Vault control group approval: accessor = %v", addr, r.accessor)
+	event.Emit(event.UnlockApprovalPending, "0x"+addr, fmt.Sprintf("accessor %v", r.accessor))
+
+	go a.awaitApproval(addr, r)
+	return &ApprovalPendingError{Accessor: r.accessor}
+}
+
+// awaitApproval polls Vault until the request is approved or expires.  Once approved, the wrapped secret is retrieved
+// and either the account unlocked or the secret held for the retried request.
+func (a *accountManager) awaitApproval(addr string, r *controlGroupRequest) {
+	t := time.NewTicker(controlGroupPollInterval)
+	defer t.Stop()
+
+	for range t.C {
+		if time.Now().After(r.expires) {
+			log.Printf("[WARN] Vault control group request for account 0x%v expired without approval: accessor = %v", addr, r.accessor)
+			event.Emit(event.UnlockApprovalExpired, "0x"+addr, fmt.Sprintf("accessor %v", r.accessor))
+			a.approvals.remove(addr, r)
+			return
+		}
+
+		approved, err := a.isApproved(r.accessor)
+		if err != nil {
+			log.Printf("[WARN] unable to check Vault control group request for account 0x%v: accessor = %v, err = %v", addr, r.accessor, err)
+			continue
+		}
+		if !approved {
+			continue
+		}
+
+		data, err := a.unwrapSecret(r.token)
+		if err != nil {
+			log.Printf("[ERROR] unable to retrieve approved secret for account 0x%v: accessor = %v, err = %v", addr, r.accessor, err)
+			a.approvals.remove(addr, r)
+			return
+		}
+		event.Emit(event.UnlockApproved, "0x"+addr, fmt.Sprintf("accessor %v", r.accessor))
+
+		if !r.timedUnlock {
+			a.approvals.setApproved(r, data)
+			// don't hold the secret beyond the lifetime of the request
+			time.AfterFunc(time.Until(r.expires), func() { a.approvals.remove(addr, r) })
+			return
+		}
+
+		a.approvals.remove(addr, r)
+		if err := a.storeUnlocked(r.acctFile, data, r.duration); err != nil {
+			log.Printf("[ERROR] unable to unlock account 0x%v after control group approval: %v", addr, err)
+			return
+		}
+		log.Printf("[INFO] unlocked account 0x%v after control group approval", addr)
+		return
+	}
+}
+
+func (a *accountManager) isApproved(accessor string) (bool, error) {
+	resp, err := a.client.Logical().Write("sys/control-group/request", map[string]interface{}{"accessor": accessor})
+	if err != nil {
+		return false, err
+	}
+	if resp == nil || resp.Data == nil {
+		return false, emptyResponseErr
+	}
+	approved, _ := resp.Data["approved"].(bool)
+	return approved, nil
+}
+
+func (a *accountManager) unwrapSecret(token string) (map[string]interface{}, error) {
+	resp, err := a.client.Logical().Unwrap(token)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, emptyResponseErr
+	}
+	data, ok := resp.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("no secret information returned from Vault")
+	}
+	return data, nil
+}
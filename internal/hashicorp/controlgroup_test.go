@@ -0,0 +1,120 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/stretchr/testify/require"
+)
+
+// controlGroupServer wraps reads of acct1 in a control group that is approved once approve is closed
+func controlGroupServer(t *testing.T, approve chan struct{}) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var resp *api.Secret
+		switch r.URL.Path {
+		case "/v1/kv/data/acct1":
+			resp = &api.Secret{WrapInfo: &api.SecretWrapInfo{Token: "wraptoken", Accessor: "wrapaccessor", TTL: 3600}}
+		case "/v1/sys/control-group/request":
+			approved := false
+			select {
+			case <-approve:
+				approved = true
+			default:
+			}
+			resp = &api.Secret{Data: map[string]interface{}{"approved": approved}}
+		case "/v1/sys/wrapping/unwrap":
+			resp = &api.Secret{Data: map[string]interface{}{
+				"data": map[string]interface{}{reconcileAddr1: reconcileKey1},
+			}}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b, _ := json.Marshal(resp)
+		_, _ = w.Write(b)
+	}))
+}
+
+func awaitCondition(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for condition")
+}
+
+func TestTimedUnlock_ControlGroupApproval(t *testing.T) {
+	defer func(d time.Duration) { controlGroupPollInterval = d }(controlGroupPollInterval)
+	controlGroupPollInterval = 10 * time.Millisecond
+
+	approve := make(chan struct{})
+	vault := controlGroupServer(t, approve)
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	err := a.TimedUnlock(context.Background(), addr, 0)
+	require.EqualError(t, err, "unlock awaiting Vault control group approval: accessor = wrapaccessor")
+	require.IsType(t, &ApprovalPendingError{}, err)
+
+	pending := a.DebugState().PendingApprovals
+	require.Len(t, pending, 1)
+	require.Equal(t, "0x"+reconcileAddr1, pending[0].Address)
+	require.Equal(t, "wrapaccessor", pending[0].Accessor)
+
+	status, err := a.Status()
+	require.NoError(t, err)
+	require.Contains(t, status, "1 unlock(s) awaiting control group approval")
+
+	// repeated requests do not create a new control group request
+	require.IsType(t, &ApprovalPendingError{}, a.TimedUnlock(context.Background(), addr, 0))
+
+	close(approve)
+	awaitCondition(t, func() bool { s := a.DebugState(); return len(s.PendingApprovals) == 0 && s.UnlockedAccounts == 1 })
+
+	_, err = a.Sign(context.Background(), addr, make([]byte, 32))
+	require.NoError(t, err)
+}
+
+func TestUnlockAndSign_ControlGroupApproval(t *testing.T) {
+	defer func(d time.Duration) { controlGroupPollInterval = d }(controlGroupPollInterval)
+	controlGroupPollInterval = 10 * time.Millisecond
+
+	approve := make(chan struct{})
+	vault := controlGroupServer(t, approve)
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	_, err := a.UnlockAndSign(context.Background(), addr, make([]byte, 32))
+	require.IsType(t, &ApprovalPendingError{}, err)
+
+	close(approve)
+	awaitCondition(t, func() bool {
+		pending := a.DebugState().PendingApprovals
+		return len(pending) == 1 && pending[0].Approved
+	})
+
+	// the approval is used by the retried request, without unlocking the account
+	_, err = a.UnlockAndSign(context.Background(), addr, make([]byte, 32))
+	require.NoError(t, err)
+	require.Equal(t, 0, a.DebugState().UnlockedAccounts)
+	require.Empty(t, a.DebugState().PendingApprovals)
+}
@@ -0,0 +1,89 @@
+package hashicorp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// cubbyholeVersion is the only version of a cubbyhole secret, as cubbyhole secrets are not versioned
+const cubbyholeVersion = 1
+
+// cubbyholeStore keeps secrets in the cubbyhole of the plugin's Vault token.  Vault destroys the cubbyhole when the token
+// expires or is revoked, so no key material outlives the token.  Cubbyholes are local to the token, so are always read
+// from the active node rather than a read replica.
+type cubbyholeStore struct {
+	client *vaultClient
+}
+
+func (s *cubbyholeStore) location(name string) string {
+	return fmt.Sprintf("cubbyhole/%v", name)
+}
+
+func (s *cubbyholeStore) read(ctx context.Context, name string, version int64) (*api.Secret, error) {
+	if version != 0 && version != cubbyholeVersion {
+		return nil, nil
+	}
+	return s.client.readWithRetry(withActiveNode(ctx), s.location(name), nil)
+}
+
+// write replaces the secret.  If cas is not nil, the write fails unless *cas is 0 and the secret does not exist, or *cas
+// is cubbyholeVersion and it does.
+func (s *cubbyholeStore) write(name string, data map[string]interface{}, cas *int64) (int64, error) {
+	if cas != nil {
+		existing, err := s.read(context.Background(), name, 0)
+		if err != nil {
+			return 0, err
+		}
+		var current int64
+		if existing != nil {
+			current = cubbyholeVersion
+		}
+		if *cas != current {
+			return 0, fmt.Errorf("check-and-set parameter did not match the current version of cubbyhole secret %v", name)
+		}
+	}
+	if _, err := s.client.write(s.location(name), data); err != nil {
+		return 0, err
+	}
+	return cubbyholeVersion, nil
+}
+
+func (s *cubbyholeStore) delete(name string, versions []int64) error {
+	for _, v := range versions {
+		if v == cubbyholeVersion {
+			_, err := s.client.Logical().Delete(s.location(name))
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *cubbyholeStore) list(prefix string) ([]string, error) {
+	resp, err := s.client.read(withActiveNode(context.Background()), func(c *api.Client) (*api.Secret, error) {
+		return c.Logical().List(s.location(prefix))
+	})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	return listKeys(resp)
+}
+
+// metadata describes the secret as having a single live version, as cubbyholes do not keep any metadata
+func (s *cubbyholeStore) metadata(name string) (map[string]interface{}, error) {
+	existing, err := s.read(context.Background(), name, 0)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"versions": map[string]interface{}{
+			fmt.Sprint(cubbyholeVersion): map[string]interface{}{"deletion_time": "", "destroyed": false},
+		},
+	}, nil
+}
+
+// writeCustomMetadata does nothing as cubbyhole secrets have no metadata
+func (s *cubbyholeStore) writeCustomMetadata(string, map[string]string) error {
+	return nil
+}
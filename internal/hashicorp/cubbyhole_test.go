@@ -0,0 +1,131 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// cubbyholeServer emulates the cubbyhole of a single token
+func cubbyholeServer(t *testing.T) *httptest.Server {
+	secrets := make(map[string]map[string]interface{})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/v1/cubbyhole/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("list") == "true":
+			var keys []string
+			for k := range secrets {
+				keys = append(keys, k)
+			}
+			if len(keys) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"keys": keys}})
+			_, _ = w.Write(b)
+		case r.Method == http.MethodGet:
+			data, ok := secrets[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			b, _ := json.Marshal(&api.Secret{Data: data})
+			_, _ = w.Write(b)
+		case r.Method == http.MethodPut:
+			b, _ := ioutil.ReadAll(r.Body)
+			var data map[string]interface{}
+			require.NoError(t, json.Unmarshal(b, &data))
+			secrets[name] = data
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete:
+			delete(secrets, name)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func cubbyholeAccountManager(t *testing.T, vaultURL, acctDir string) *accountManager {
+	a := reconcileAccountManager(t, vaultURL, acctDir)
+	a.kvEngineName = config.SecretsEngineCubbyhole
+	a.client.kvEngineName = config.SecretsEngineCubbyhole
+	a.client.accts = make(accountsByURL)
+	a.secrets = newSecretStore(a.client, config.VaultClient{SecretsEngine: config.SecretsEngineCubbyhole})
+	a.unlocked = make(map[string]*lockableKey)
+	return a
+}
+
+func TestCubbyholeStore(t *testing.T) {
+	vault := cubbyholeServer(t)
+	defer vault.Close()
+
+	s := cubbyholeAccountManager(t, vault.URL, "/path/to/dir").secrets
+	require.Equal(t, "cubbyhole/acct1", s.location("acct1"))
+
+	got, err := s.read(context.Background(), "acct1", 0)
+	require.NoError(t, err)
+	require.Nil(t, got)
+	names, err := s.list("")
+	require.NoError(t, err)
+	require.Nil(t, names)
+
+	notExists := int64(0)
+	version, err := s.write("acct1", map[string]interface{}{"key": "value"}, &notExists)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+
+	// the secret already exists
+	_, err = s.write("acct1", map[string]interface{}{"key": "other"}, &notExists)
+	require.Error(t, err)
+
+	got, err = s.read(context.Background(), "acct1", 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"key": "value"}, got.Data)
+
+	got, err = s.read(context.Background(), "acct1", 2)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	names, err = s.list("")
+	require.NoError(t, err)
+	require.Equal(t, []string{"acct1"}, names)
+
+	meta, err := s.metadata("acct1")
+	require.NoError(t, err)
+	require.Contains(t, meta["versions"], "1")
+
+	require.NoError(t, s.delete("acct1", []int64{1}))
+	got, err = s.read(context.Background(), "acct1", 1)
+	require.NoError(t, err)
+	require.Nil(t, got)
+}
+
+func TestCubbyhole_ImportAndUnlock(t *testing.T) {
+	vault := cubbyholeServer(t)
+	defer vault.Close()
+
+	dir, err := ioutil.TempDir("", "cubbyhole")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := cubbyholeAccountManager(t, vault.URL, dir)
+
+	key, err := account.NewKeyFromHexString(reconcileKey1)
+	require.NoError(t, err)
+	acct, err := a.ImportPrivateKey(key, config.NewAccount{SecretName: "acct1"})
+	require.NoError(t, err)
+	require.Contains(t, acct.URL.String(), "cubbyhole")
+
+	require.NoError(t, a.TimedUnlock(context.Background(), acct.Address, 0))
+}
@@ -0,0 +1,72 @@
+package hashicorp
+
+// DebugState is a snapshot of the account manager's internal state for troubleshooting.  It must never contain key
+// material.
+type DebugState struct {
+	Vault                     string
+	Accounts                  int
+	UnlockedAccounts          int
+	DegradedAccounts          int
+	FrozenAccounts            int
+	DroppedWallets            int
+	ReadCacheEntries          int
+	ReadCacheBytes            int64
+	Authentication            string
+	AuthState                 string // one of unauthenticated, authenticated, renewing, reauthenticating or degraded
+	StandbyTokens             int
+	ReadReplicas              []ReadReplicaState `json:",omitempty"`
+	DRSecondary               string             `json:",omitempty"`
+	DRSecondaryInUse          bool
+	DRSecondaryAuthentication string            `json:",omitempty"`
+	DRSecondaryAuthState      string            `json:",omitempty"`
+	StateDirectory            string            `json:",omitempty"`
+	PendingApprovals          []PendingApproval `json:",omitempty"`
+	Mirror                    string            `json:",omitempty"`
+	FIPS                      bool
+	AccountScan               *AccountScanState    `json:",omitempty"`
+	SigningLatency            *SigningLatencyState `json:",omitempty"`
+}
+
+func (a *accountManager) DebugState() DebugState {
+	a.mu.Lock()
+	s := DebugState{
+		Vault:            a.client.Address(),
+		Accounts:         len(a.client.accounts()),
+		UnlockedAccounts: len(a.unlocked),
+		DegradedAccounts: len(a.degraded),
+		ReadCacheEntries: a.cache.len(),
+		ReadCacheBytes:   a.cache.size(),
+		Authentication:   a.client.getAuthStatus(),
+		FIPS:             a.fips,
+		AccountScan:      a.client.scanState(),
+	}
+	a.mu.Unlock()
+
+	s.FrozenAccounts = len(frozenAccounts(a.client.accounts()))
+	s.DroppedWallets = a.droppedWallets()
+	s.SigningLatency = a.latency.state()
+	s.StandbyTokens = a.client.pool.len()
+	state, _ := a.client.getAuthState()
+	s.AuthState = state.String()
+	if pending := a.approvals.pending(); len(pending) != 0 {
+		s.PendingApprovals = pending
+	}
+	if a.state != nil {
+		s.StateDirectory = a.state.Path()
+	}
+
+	if a.client.replicas != nil {
+		s.ReadReplicas = a.client.replicas.state()
+	}
+	if dr := a.client.dr; dr != nil {
+		s.DRSecondary = dr.client.Address()
+		s.DRSecondaryInUse = dr.isFailedOver()
+		s.DRSecondaryAuthentication = dr.client.getAuthStatus()
+		drState, _ := dr.client.getAuthState()
+		s.DRSecondaryAuthState = drState.String()
+	}
+	if a.mirror != nil {
+		s.Mirror = a.mirror.status()
+	}
+	return s
+}
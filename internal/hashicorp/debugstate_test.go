@@ -0,0 +1,33 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugState(t *testing.T) {
+	a := reconcileAccountManager(t, "http://vault:8200", "/path/to/dir")
+	a.client.setAuthStatus(authStatic)
+	a.unlocked = map[string]*lockableKey{reconcileAddr1: {}}
+	a.cache = newReadCache(10, 0)
+	a.cache.add("kv/data/acct1", 1, map[string]interface{}{reconcileAddr1: reconcileKey1})
+
+	got := a.DebugState()
+
+	require.Equal(t, DebugState{
+		Vault:            "http://vault:8200",
+		Accounts:         3,
+		UnlockedAccounts: 1,
+		ReadCacheEntries: 1,
+		ReadCacheBytes:   int64(len("kv/data/acct1?version=1") + len(reconcileAddr1) + len(reconcileKey1) + readCacheEntryOverhead),
+		Authentication:   "static token",
+		AuthState:        "unauthenticated",
+	}, got)
+
+	// key material is never included
+	b, err := json.Marshal(got)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), reconcileKey1)
+}
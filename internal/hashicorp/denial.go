@@ -0,0 +1,76 @@
+package hashicorp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	vaultRequestURL = regexp.MustCompile(`URL: ([A-Z]+) (\S+)`)
+	sentinelDenial  = regexp.MustCompile(`(egp|rgp) (?:[a-z-]+ )?policy "([^"]+)" evaluation resulted in denial`)
+)
+
+// PermissionDeniedError is a request denied by Vault, either because the token's ACL policies do not grant the
+// required capability on the path or because a Sentinel policy rejected the request
+type PermissionDeniedError struct {
+	Method     string // e.g. GET
+	Path       string // the Vault API path, e.g. kv/data/myacct
+	Capability string // the capability the token's policies must grant on Path
+	Sentinel   string // the Sentinel policy that denied the request, e.g. egp policy "root/business-hours"
+}
+
+func (e *PermissionDeniedError) Error() string {
+	if e.Sentinel != "" {
+		return fmt.Sprintf("Vault Sentinel %v denied %v %v", e.Sentinel, e.Method, e.Path)
+	}
+	return fmt.Sprintf("Vault permission denied for %v %v: the token's policies must grant %v capability on %v", e.Method, e.Path, e.Capability, e.Path)
+}
+
+// permissionDenied converts a Vault 403 response error to a *PermissionDeniedError, returning any other error unchanged
+func permissionDenied(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "Code: 403") {
+		return err
+	}
+
+	m := vaultRequestURL.FindStringSubmatch(msg)
+	if m == nil {
+		return err
+	}
+	u, parseErr := url.Parse(m[2])
+	if parseErr != nil {
+		return err
+	}
+
+	pd := &PermissionDeniedError{
+		Method:     m[1],
+		Path:       strings.TrimPrefix(u.Path, "/v1/"),
+		Capability: capability(m[1], u),
+	}
+	if s := sentinelDenial.FindStringSubmatch(msg); s != nil {
+		pd.Sentinel = fmt.Sprintf("%v policy %q", s[1], s[2])
+	}
+	return pd
+}
+
+// capability returns the Vault policy capability required for the request
+func capability(method string, u *url.URL) string {
+	switch method {
+	case "GET":
+		if u.Query().Get("list") == "true" {
+			return "list"
+		}
+		return "read"
+	case "LIST":
+		return "list"
+	case "DELETE":
+		return "delete"
+	default:
+		return "create/update"
+	}
+}
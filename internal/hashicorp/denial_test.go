@@ -0,0 +1,69 @@
+package hashicorp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionDenied_ACLPolicy(t *testing.T) {
+	err := errors.New("Error making API request.\n\nURL: GET https://vault:8200/v1/kv/data/myacct?version=2\nCode: 403. Errors:\n\n* 1 error occurred:\n\t* permission denied\n\n")
+
+	got, ok := permissionDenied(err).(*PermissionDeniedError)
+	require.True(t, ok)
+	require.Equal(t, "GET", got.Method)
+	require.Equal(t, "kv/data/myacct", got.Path)
+	require.Equal(t, "read", got.Capability)
+	require.Empty(t, got.Sentinel)
+	require.EqualError(t, got, "Vault permission denied for GET kv/data/myacct: the token's policies must grant read capability on kv/data/myacct")
+}
+
+func TestPermissionDenied_Write(t *testing.T) {
+	err := errors.New("Error making API request.\n\nURL: PUT https://vault:8200/v1/kv/data/myacct\nCode: 403. Errors:\n\n* 1 error occurred:\n\t* permission denied\n\n")
+
+	got, ok := permissionDenied(err).(*PermissionDeniedError)
+	require.True(t, ok)
+	require.Equal(t, "create/update", got.Capability)
+}
+
+func TestPermissionDenied_Sentinel(t *testing.T) {
+	err := errors.New("Error making API request.\n\nURL: GET https://vault:8200/v1/kv/data/myacct?version=1\nCode: 403. Errors:\n\n" +
+		"* 2 errors occurred:\n\t* egp standard policy \"root/business-hours\" evaluation resulted in denial.\n\nThe specific error was:\n<nil>\n\n" +
+		"A trace of the execution for policy \"root/business-hours\" is available:\n\nResult: false\n\n\t* permission denied\n\n")
+
+	got, ok := permissionDenied(err).(*PermissionDeniedError)
+	require.True(t, ok)
+	require.Equal(t, `egp policy "root/business-hours"`, got.Sentinel)
+	require.EqualError(t, got, `Vault Sentinel egp policy "root/business-hours" denied GET kv/data/myacct`)
+}
+
+func TestPermissionDenied_OtherErrorsUnchanged(t *testing.T) {
+	require.Nil(t, permissionDenied(nil))
+
+	err := errors.New("Error making API request.\n\nURL: GET https://vault:8200/v1/kv/data/myacct\nCode: 503. Errors:\n\n* Vault is sealed")
+	require.Equal(t, err, permissionDenied(err))
+}
+
+func TestTimedUnlock_PermissionDenied(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["1 error occurred:\n\t* permission denied\n\n"]}`))
+	}))
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	err := a.TimedUnlock(context.Background(), addr, 0)
+
+	got, ok := err.(*PermissionDeniedError)
+	require.True(t, ok, "unexpected error: %v", err)
+	require.Equal(t, "kv/data/acct1", got.Path)
+	require.Equal(t, "read", got.Capability)
+}
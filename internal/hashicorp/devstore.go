@@ -0,0 +1,161 @@
+package hashicorp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	// devEngineName is the secrets engine path used in account URLs in dev mode
+	devEngineName = "dev"
+	// devVaultAddress is the address used in account URLs in dev mode.  The .invalid TLD never resolves, so nothing can
+	// be sent to it by mistake.
+	devVaultAddress = "http://dev.invalid"
+)
+
+// memorySecretStore keeps versioned secrets in memory, mimicking a KV version 2 engine, so that the plugin can be run in
+// dev mode without Vault.  All secrets are lost when the plugin stops.
+type memorySecretStore struct {
+	mu      sync.Mutex
+	secrets map[string][]memoryVersion // secret name -> versions, where versions[0] is version 1
+	// custom is the custom metadata of each secret, keyed by secret name
+	custom map[string]map[string]interface{}
+}
+
+type memoryVersion struct {
+	data    map[string]interface{}
+	deleted time.Time
+}
+
+func newMemorySecretStore() *memorySecretStore {
+	return &memorySecretStore{secrets: make(map[string][]memoryVersion), custom: make(map[string]map[string]interface{})}
+}
+
+func (s *memorySecretStore) location(name string) string {
+	return fmt.Sprintf("%v/data/%v", devEngineName, name)
+}
+
+func (s *memorySecretStore) read(_ context.Context, name string, version int64) (*api.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.secrets[name]
+	if version == 0 {
+		version = int64(len(versions))
+	}
+	if version < 1 || version > int64(len(versions)) {
+		return nil, nil
+	}
+	v := versions[version-1]
+	if !v.deleted.IsZero() {
+		return nil, nil
+	}
+	return &api.Secret{Data: copyData(v.data)}, nil
+}
+
+func (s *memorySecretStore) write(name string, data map[string]interface{}, cas *int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := int64(len(s.secrets[name]))
+	if cas != nil && *cas != current {
+		return 0, fmt.Errorf("check-and-set parameter did not match the current version of secret %v", name)
+	}
+	s.secrets[name] = append(s.secrets[name], memoryVersion{data: copyData(data)})
+	return current + 1, nil
+}
+
+func (s *memorySecretStore) delete(name string, versions []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.secrets[name]
+	for _, v := range versions {
+		if v >= 1 && v <= int64(len(stored)) && stored[v-1].deleted.IsZero() {
+			stored[v-1].deleted = time.Now()
+		}
+	}
+	return nil
+}
+
+func (s *memorySecretStore) list(prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := make(map[string]bool)
+	for name := range s.secrets {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(name, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			// secrets in sub-folders are listed as the folder
+			rest = rest[:i+1]
+		}
+		found[rest] = true
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(found))
+	for n := range found {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// metadata returns the version history in the same form as the KV version 2 metadata endpoint
+func (s *memorySecretStore) metadata(name string) (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.secrets[name]
+	if !ok {
+		return nil, nil
+	}
+	versions := make(map[string]interface{}, len(stored))
+	for i, v := range stored {
+		var deletionTime string
+		if !v.deleted.IsZero() {
+			deletionTime = v.deleted.UTC().Format(time.RFC3339Nano)
+		}
+		versions[fmt.Sprint(i+1)] = map[string]interface{}{"deletion_time": deletionTime, "destroyed": false}
+	}
+	return map[string]interface{}{
+		"current_version": len(stored),
+		"custom_metadata": copyData(s.custom[name]),
+		"versions":        versions,
+	}, nil
+}
+
+func (s *memorySecretStore) writeCustomMetadata(name string, custom map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.secrets[name]; !ok {
+		return fmt.Errorf("secret %v does not exist", name)
+	}
+	if s.custom[name] == nil {
+		s.custom[name] = make(map[string]interface{}, len(custom))
+	}
+	for k, v := range custom {
+		s.custom[name][k] = v
+	}
+	return nil
+}
+
+// copyData returns a shallow copy of the secret data so that callers cannot modify the stored secret
+func copyData(data map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,129 @@
+package hashicorp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySecretStore_ReadWrite(t *testing.T) {
+	s := newMemorySecretStore()
+
+	got, err := s.read(context.Background(), "acct1", 0)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	cas := int64(0)
+	version, err := s.write("acct1", map[string]interface{}{reconcileAddr1: reconcileKey1}, &cas)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+
+	_, err = s.write("acct1", map[string]interface{}{reconcileAddr2: reconcileKey2}, &cas)
+	require.EqualError(t, err, "check-and-set parameter did not match the current version of secret acct1")
+
+	version, err = s.write("acct1", map[string]interface{}{reconcileAddr2: reconcileKey2}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), version)
+
+	got, err = s.read(context.Background(), "acct1", 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{reconcileAddr1: reconcileKey1}, got.Data)
+
+	got, err = s.read(context.Background(), "acct1", 0)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{reconcileAddr2: reconcileKey2}, got.Data)
+
+	// modifying the returned data does not change the stored secret
+	got.Data[reconcileAddr2] = "modified"
+	got, err = s.read(context.Background(), "acct1", 2)
+	require.NoError(t, err)
+	require.Equal(t, reconcileKey2, got.Data[reconcileAddr2])
+
+	got, err = s.read(context.Background(), "acct1", 3)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	require.Equal(t, "dev/data/acct1", s.location("acct1"))
+}
+
+func TestMemorySecretStore_DeleteAndMetadata(t *testing.T) {
+	s := newMemorySecretStore()
+	_, _ = s.write("acct1", map[string]interface{}{reconcileAddr1: reconcileKey1}, nil)
+	_, _ = s.write("acct1", map[string]interface{}{reconcileAddr2: reconcileKey2}, nil)
+
+	require.NoError(t, s.delete("acct1", []int64{1, 5}))
+
+	got, err := s.read(context.Background(), "acct1", 1)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	meta, err := s.metadata("acct1")
+	require.NoError(t, err)
+	versions := meta["versions"].(map[string]interface{})
+	require.Len(t, versions, 2)
+	require.NotEmpty(t, versions["1"].(map[string]interface{})["deletion_time"])
+	require.Empty(t, versions["2"].(map[string]interface{})["deletion_time"])
+
+	meta, err = s.metadata("doesnotexist")
+	require.NoError(t, err)
+	require.Nil(t, meta)
+}
+
+func TestMemorySecretStore_List(t *testing.T) {
+	s := newMemorySecretStore()
+	for _, name := range []string{"acct2", "acct1", "team/acct3", "team/sub/acct4"} {
+		_, _ = s.write(name, map[string]interface{}{"key": "value"}, nil)
+	}
+
+	names, err := s.list("")
+	require.NoError(t, err)
+	require.Equal(t, []string{"acct1", "acct2", "team/"}, names)
+
+	names, err = s.list("team/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"acct3", "sub/"}, names)
+
+	names, err = s.list("doesnotexist/")
+	require.NoError(t, err)
+	require.Nil(t, names)
+}
+
+func TestMemorySecretStore_WriteCustomMetadata(t *testing.T) {
+	s := newMemorySecretStore()
+	require.EqualError(t, s.writeCustomMetadata("acct1", map[string]string{"owner": "ops"}), "secret acct1 does not exist")
+
+	_, _ = s.write("acct1", map[string]interface{}{reconcileAddr1: reconcileKey1}, nil)
+	require.NoError(t, s.writeCustomMetadata("acct1", map[string]string{"owner": "ops", "address": "0xold"}))
+	require.NoError(t, s.writeCustomMetadata("acct1", map[string]string{"address": "0x" + reconcileAddr1}))
+
+	meta, err := s.metadata("acct1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"owner": "ops", "address": "0x" + reconcileAddr1}, meta["custom_metadata"])
+}
+
+func TestNewAccount_WritesSecretCustomMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "custommetadata")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dirURL, _ := url.Parse("file://" + dir + "/")
+
+	a, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL})
+	require.NoError(t, err)
+	acct, err := a.NewAccount(config.NewAccount{SecretName: "acct", CreatedBy: "node=node1 user=alice"})
+	require.NoError(t, err)
+
+	meta, err := a.(*accountManager).secrets.metadata("acct")
+	require.NoError(t, err)
+	custom := meta["custom_metadata"].(map[string]interface{})
+	require.Equal(t, "0x"+acct.Address.ToHexString(), custom["address"])
+	require.NotEmpty(t, custom["accountId"])
+	require.Equal(t, "node=node1 user=alice", custom["createdBy"])
+	_, err = time.Parse(time.RFC3339, custom["createdAt"].(string))
+	require.NoError(t, err)
+}
@@ -0,0 +1,97 @@
+package hashicorp
+
+import (
+	"log"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// lookupHost resolves a hostname, replaced in tests
+var lookupHost = net.LookupHost
+
+type idleConnectionCloser interface {
+	CloseIdleConnections()
+}
+
+// dnsWatcher recycles keep-alive connections when the addresses a hostname resolves to change, so that after a
+// DNS-based failover requests are sent to the new address rather than continuing over connections to the old one
+type dnsWatcher struct {
+	host    string
+	conns   idleConnectionCloser
+	addrs   []string
+	pending bool // connections that were in use at the time of the change must also be closed once they are idle
+}
+
+// watchDNS re-resolves the hostname of address every interval until stop is closed.  Nothing is done if interval is 0 or
+// the address is an IP literal.
+func watchDNS(conns idleConnectionCloser, address *url.URL, interval time.Duration, stop <-chan struct{}) {
+	host := address.Hostname()
+	if interval <= 0 || net.ParseIP(host) != nil {
+		return
+	}
+
+	w := &dnsWatcher{host: host, conns: conns}
+	w.addrs, _ = w.resolve()
+
+	supervise("dns watcher", stop, func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				w.check()
+			}
+		}
+	})
+}
+
+func (w *dnsWatcher) resolve() ([]string, error) {
+	addrs, err := lookupHost(w.host)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// check closes idle connections if the resolved addresses have changed since the last check, returning true if they
+// were closed
+func (w *dnsWatcher) check() bool {
+	addrs, err := w.resolve()
+	if err != nil {
+		log.Printf("[WARN] unable to re-resolve Vault host %v, continuing to use existing connections: %v", w.host, err)
+		return false
+	}
+
+	if equalStrings(addrs, w.addrs) {
+		if w.pending {
+			w.pending = false
+			w.conns.CloseIdleConnections()
+			return true
+		}
+		return false
+	}
+
+	log.Printf("[INFO] Vault host %v now resolves to %v (was %v), recycling connections", w.host, addrs, w.addrs)
+	w.addrs = addrs
+	w.pending = true
+	w.conns.CloseIdleConnections()
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package hashicorp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingCloser struct {
+	closed int
+}
+
+func (c *countingCloser) CloseIdleConnections() {
+	c.closed++
+}
+
+func TestDNSWatcher_Check(t *testing.T) {
+	defer func(f func(string) ([]string, error)) { lookupHost = f }(lookupHost)
+
+	var (
+		addrs     = []string{"10.0.0.2", "10.0.0.1"}
+		lookupErr error
+	)
+	lookupHost = func(host string) ([]string, error) {
+		require.Equal(t, "vault.example.com", host)
+		return addrs, lookupErr
+	}
+
+	conns := &countingCloser{}
+	w := &dnsWatcher{host: "vault.example.com", conns: conns}
+	w.addrs, _ = w.resolve()
+
+	// same addresses in a different order
+	addrs = []string{"10.0.0.1", "10.0.0.2"}
+	require.False(t, w.check())
+	require.Equal(t, 0, conns.closed)
+
+	// failover
+	addrs = []string{"10.0.1.1"}
+	require.True(t, w.check())
+	require.Equal(t, 1, conns.closed)
+
+	// connections in use during the failover are closed on the next check
+	require.True(t, w.check())
+	require.Equal(t, 2, conns.closed)
+	require.False(t, w.check())
+	require.Equal(t, 2, conns.closed)
+
+	// lookup failures keep the existing connections
+	lookupErr = errors.New("no such host")
+	require.False(t, w.check())
+	require.Equal(t, 2, conns.closed)
+	require.Equal(t, []string{"10.0.1.1"}, w.addrs)
+}
@@ -0,0 +1,171 @@
+package hashicorp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+// ReadOnlyErr is returned for operations that write to Vault while the plugin is failed over to the DR secondary
+var ReadOnlyErr = errors.New("Vault primary is unavailable: plugin is read-only while using the DR secondary")
+
+// primaryCheckInterval is how often the primary's health is checked while failed over
+var primaryCheckInterval = 30 * time.Second
+
+// drSecondary is the Disaster Recovery secondary cluster that reads are sent to when the primary is unavailable
+type drSecondary struct {
+	client     *vaultClient
+	auth       config.VaultClientAuthentication
+	mu         sync.Mutex
+	authed     bool
+	failedOver bool
+}
+
+// newDRSecondary creates a client for the DR secondary, sharing stop with the client of the primary
+func newDRSecondary(conf config.VaultClient, stop chan struct{}) (*drSecondary, error) {
+	c, err := newAPIClient(conf.DRSecondary, conf.TLS, conf.DNSRefreshInterval, stop)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Hashicorp Vault DR secondary client: %v", err)
+	}
+	setRequestHeaders(c, conf)
+
+	return &drSecondary{
+		client: &vaultClient{Client: c, kvEngineName: conf.KVEngineName, stop: stop},
+		auth:   conf.Authentication,
+	}, nil
+}
+
+// read performs the read against the primary, or against the DR secondary if the plugin has failed over.  If a read
+// from the primary fails and the primary is unhealthy, the plugin fails over and the read is retried on the DR secondary.
+// The read waits for a free request slot if the number of concurrent requests is limited.
+func (c *vaultClient) read(ctx context.Context, fn func(c *api.Client) (*api.Secret, error)) (*api.Secret, error) {
+	release, err := c.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if c.dr != nil && c.dr.isFailedOver() {
+		return fn(c.dr.client.Client)
+	}
+
+	resp, err := c.readFromPrimaryCluster(ctx, fn)
+	c.detectRevocation(err)
+	if c.dr == nil || err == nil || c.primaryHealthy() {
+		return resp, err
+	}
+
+	log.Printf("[WARN] read from Vault primary %v failed and primary is unhealthy, failing over to DR secondary %v: err = %v", c.Address(), c.dr.client.Address(), err)
+	if ferr := c.failover(); ferr != nil {
+		log.Printf("[ERROR] unable to fail over to DR secondary %v: %v", c.dr.client.Address(), ferr)
+		return resp, err
+	}
+	return fn(c.dr.client.Client)
+}
+
+// readFromPrimaryCluster sends the read to the healthiest read replica if configured, unless the active node is more
+// local or ctx requires the active node.  The read falls back to the active node if the read replica cannot be reached, or if the secret is not found
+// on the replica as it may not have been replicated yet.
+func (c *vaultClient) readFromPrimaryCluster(ctx context.Context, fn func(c *api.Client) (*api.Secret, error)) (*api.Secret, error) {
+	if c.replicas == nil || requiresActiveNode(ctx) {
+		return fn(c.Client)
+	}
+	replica := c.replicas.best()
+	if c.primaryTier < replica.tier {
+		return fn(c.Client)
+	}
+
+	// the read replica is part of the same cluster so uses the same (possibly renewed) token
+	replica.SetToken(c.Token())
+
+	start := time.Now()
+	resp, err := fn(replica.Client)
+	_, unreachable := err.(*url.Error)
+	c.replicas.record(replica, time.Since(start), unreachable)
+
+	if unreachable {
+		log.Printf("[DEBUG] unable to read from Vault read replica %v, reading from active node: err = %v", replica.Address(), err)
+		return fn(c.Client)
+	}
+	if err == nil && resp == nil {
+		log.Printf("[DEBUG] secret not found on Vault read replica %v, reading from active node in case of replication lag", replica.Address())
+		return fn(c.Client)
+	}
+	return resp, err
+}
+
+// writable returns ReadOnlyErr if the plugin has failed over to the DR secondary
+func (c *vaultClient) writable() error {
+	if c.dr != nil && c.dr.isFailedOver() {
+		return ReadOnlyErr
+	}
+	return nil
+}
+
+func (c *vaultClient) primaryHealthy() bool {
+	h, err := c.Sys().Health()
+	return err == nil && h.Initialized && !h.Sealed
+}
+
+func (c *vaultClient) failover() error {
+	d := c.dr
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.failedOver {
+		return nil
+	}
+	if !d.authed {
+		if err := d.client.authenticate(d.auth); err != nil {
+			return err
+		}
+		d.authed = true
+	}
+	d.failedOver = true
+	metrics.VaultFailedOver.Set(1)
+	event.Emit(event.VaultFailover, d.client.Address(), fmt.Sprintf("primary %v unavailable, using DR secondary in read-only mode", c.Address()))
+
+	supervise("dr primary check", c.stop, c.awaitPrimary)
+	return nil
+}
+
+// awaitPrimary periodically checks the primary's health, switching back to it once healthy or the client is closed
+func (c *vaultClient) awaitPrimary() {
+	t := time.NewTicker(primaryCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-t.C:
+		}
+		if !c.primaryHealthy() {
+			log.Printf("[DEBUG] Vault primary %v is still unavailable", c.Address())
+			continue
+		}
+		c.dr.mu.Lock()
+		c.dr.failedOver = false
+		c.dr.mu.Unlock()
+
+		log.Printf("[INFO] Vault primary %v is available, no longer using DR secondary %v", c.Address(), c.dr.client.Address())
+		metrics.VaultFailedOver.Set(0)
+		event.Emit(event.VaultRecovered, c.Address(), "primary available, writes re-enabled")
+		return
+	}
+}
+
+func (d *drSecondary) isFailedOver() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.failedOver
+}
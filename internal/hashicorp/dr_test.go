@@ -0,0 +1,64 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDRSecondary_FailoverOnPrimaryUnavailable(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+
+	dr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/kv/data/acct1", r.URL.Path)
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{
+			"data": map[string]interface{}{reconcileAddr1: reconcileKey1},
+		}})
+		_, _ = w.Write(b)
+	}))
+	defer dr.Close()
+
+	// primary is unreachable
+	primary := httptest.NewServer(http.NotFoundHandler())
+	primary.Close()
+
+	a := reconcileAccountManager(t, primary.URL, "/path/to/dir")
+
+	drURL, _ := url.Parse(dr.URL)
+	token, _ := url.Parse("env://" + testutil.MY_TOKEN)
+	tokenEnv := config.EnvironmentVariable(*token)
+	a.client.dr, _ = newDRSecondary(config.VaultClient{
+		KVEngineName:   "kv",
+		DRSecondary:    drURL,
+		Authentication: config.VaultClientAuthentication{Token: &tokenEnv},
+		TLS:            config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}},
+	}, nil)
+	a.client.dr.client.SetMaxRetries(0)
+	a.unlocked = make(map[string]*lockableKey)
+
+	require.NoError(t, a.client.writable())
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+
+	require.True(t, a.client.dr.isFailedOver())
+	require.Equal(t, ReadOnlyErr, a.client.writable())
+
+	status, err := a.Status()
+	require.NoError(t, err)
+	require.Contains(t, status, "using DR secondary "+dr.URL+" (read-only)")
+
+	key, _ := account.NewKeyFromHexString(reconcileKey2)
+	_, err = a.ImportPrivateKey(key, config.NewAccount{SecretName: "new"})
+	require.Equal(t, ReadOnlyErr, err)
+}
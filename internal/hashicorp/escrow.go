@@ -0,0 +1,111 @@
+package hashicorp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+const (
+	escrowAlgorithm    = "RSA-OAEP-SHA256"
+	escrowSecretSuffix = "-escrow"
+	minEscrowKeyBits   = 2048
+)
+
+// escrow encrypts new private keys to an offline RSA public key.  Only the custodian holding the corresponding private
+// key can decrypt them; the plugin never stores or exposes the plaintext outside of the account's own secret.
+type escrow struct {
+	publicKey   *rsa.PublicKey
+	fingerprint string // hex-encoded SHA-256 of the DER-encoded public key
+}
+
+// newEscrow returns nil if escrow is not configured
+func newEscrow(conf config.VaultClientEscrow) (*escrow, error) {
+	if conf.PublicKey == nil {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(config.FilePath(conf.PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read escrow publicKey: %v", err)
+	}
+	pub, der, err := parseRSAPublicKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid escrow publicKey: %v", err)
+	}
+	if pub.N.BitLen() < minEscrowKeyBits {
+		return nil, fmt.Errorf("invalid escrow publicKey: RSA key must be at least %v bits", minEscrowKeyBits)
+	}
+	sum := sha256.Sum256(der)
+	return &escrow{publicKey: pub, fingerprint: hex.EncodeToString(sum[:])}, nil
+}
+
+// parseRSAPublicKey parses a PEM-encoded PKIX ("PUBLIC KEY") or PKCS #1 ("RSA PUBLIC KEY") RSA public key, returning
+// the key and its PKIX DER encoding
+func parseRSAPublicKey(b []byte) (*rsa.PublicKey, []byte, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, nil, errors.New("no PEM data found")
+	}
+	var pub *rsa.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		var ok bool
+		if pub, ok = k.(*rsa.PublicKey); !ok {
+			return nil, nil, fmt.Errorf("unsupported key type %T, only RSA keys are supported", k)
+		}
+	case "RSA PUBLIC KEY":
+		var err error
+		if pub, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub, der, nil
+}
+
+// secretData returns the data of the escrow secret for the hex-encoded private key of the account
+func (e *escrow) secretData(addrHex, keyHex string, secretVersion int64) (map[string]interface{}, error) {
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, e.publicKey, []byte(keyHex), nil)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"address":         addrHex,
+		"secretVersion":   secretVersion,
+		"algorithm":       escrowAlgorithm,
+		"publicKeySHA256": e.fingerprint,
+		"ciphertext":      base64.StdEncoding.EncodeToString(ciphertext),
+	}, nil
+}
+
+// writeEscrow stores the new key, encrypted to the escrow public key, in a sibling secret of the account's secret.  Each
+// version of the escrow secret records the version of the account secret it escrows.
+func (a *accountManager) writeEscrow(addrHex, keyHex string, secretVersion int64, conf config.NewAccount) error {
+	if a.escrow == nil {
+		return nil
+	}
+	data, err := a.escrow.secretData(addrHex, keyHex, secretVersion)
+	if err != nil {
+		return err
+	}
+	_, err = a.secrets.write(conf.SecretName+escrowSecretSuffix, data, nil)
+	return err
+}
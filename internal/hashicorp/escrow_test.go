@@ -0,0 +1,147 @@
+package hashicorp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// writeEscrowPublicKey writes the PEM encoding of pub to a file in dir, returning its url
+func writeEscrowPublicKey(t *testing.T, dir string, pub interface{}) *url.URL {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "escrow.pem")
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	u, _ := url.Parse("file://" + path)
+	return u
+}
+
+func TestEscrow_EncryptsToPublicKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "escrow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	custodian, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	e, err := newEscrow(config.VaultClientEscrow{PublicKey: writeEscrowPublicKey(t, dir, &custodian.PublicKey)})
+	require.NoError(t, err)
+
+	data, err := e.secretData(reconcileAddr2, reconcileKey2, 3)
+	require.NoError(t, err)
+	require.Equal(t, reconcileAddr2, data["address"])
+	require.Equal(t, int64(3), data["secretVersion"])
+	require.Equal(t, "RSA-OAEP-SHA256", data["algorithm"])
+	require.Len(t, data["publicKeySHA256"], 64)
+
+	ciphertext, err := base64.StdEncoding.DecodeString(data["ciphertext"].(string))
+	require.NoError(t, err)
+	plaintext, err := rsa.DecryptOAEP(sha256.New(), nil, custodian, ciphertext, nil)
+	require.NoError(t, err)
+	require.Equal(t, reconcileKey2, string(plaintext))
+}
+
+func TestNewEscrow_InvalidPublicKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "escrow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = newEscrow(config.VaultClientEscrow{PublicKey: writeEscrowPublicKey(t, dir, &small.PublicKey)})
+	require.EqualError(t, err, "invalid escrow publicKey: RSA key must be at least 2048 bits")
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = newEscrow(config.VaultClientEscrow{PublicKey: writeEscrowPublicKey(t, dir, &ec.PublicKey)})
+	require.EqualError(t, err, "invalid escrow publicKey: unsupported key type *ecdsa.PublicKey, only RSA keys are supported")
+
+	path := filepath.Join(dir, "notpem")
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a key"), 0600))
+	_, err = newEscrow(config.VaultClientEscrow{PublicKey: &url.URL{Scheme: "file", Path: path}})
+	require.EqualError(t, err, "invalid escrow publicKey: no PEM data found")
+
+	e, err := newEscrow(config.VaultClientEscrow{})
+	require.NoError(t, err)
+	require.Nil(t, e)
+}
+
+func TestAccountManager_ImportPrivateKey_WritesEscrow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "escrow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	custodian, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var escrowed map[string]interface{}
+	escrowFails := false
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveSecretMetadata(w, r) {
+			return
+		}
+		require.Equal(t, http.MethodPut, r.Method)
+		switch r.URL.Path {
+		case "/v1/kv/data/new":
+		case "/v1/kv/data/new-escrow":
+			if escrowFails {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var body map[string]map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			escrowed = body["data"]
+		default:
+			t.Fatalf("unexpected request %v", r.URL.Path)
+		}
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"version": 1}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, dir)
+	a.escrow, err = newEscrow(config.VaultClientEscrow{PublicKey: writeEscrowPublicKey(t, dir, &custodian.PublicKey)})
+	require.NoError(t, err)
+
+	key, _ := account.NewKeyFromHexString(reconcileKey2)
+	_, err = a.ImportPrivateKey(key, config.NewAccount{SecretName: "new"})
+	require.NoError(t, err)
+
+	require.Equal(t, reconcileAddr2, escrowed["address"])
+	ciphertext, _ := base64.StdEncoding.DecodeString(escrowed["ciphertext"].(string))
+	plaintext, err := rsa.DecryptOAEP(sha256.New(), nil, custodian, ciphertext, nil)
+	require.NoError(t, err)
+	require.Equal(t, reconcileKey2, string(plaintext))
+
+	// the account is not created if its key cannot be escrowed
+	escrowFails = true
+	other := filepath.Join(dir, "other")
+	require.NoError(t, os.Mkdir(other, 0700))
+	a = reconcileAccountManager(t, vault.URL, other)
+	a.escrow, _ = newEscrow(config.VaultClientEscrow{PublicKey: writeEscrowPublicKey(t, dir, &custodian.PublicKey)})
+
+	key, _ = account.NewKeyFromHexString(reconcileKey2)
+	_, err = a.ImportPrivateKey(key, config.NewAccount{SecretName: "new"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to write escrow secret to Vault")
+	files, _ := ioutil.ReadDir(other)
+	require.Empty(t, files)
+}
@@ -0,0 +1,191 @@
+package hashicorp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// etcdStore keeps each account config as a key under the prefix in etcd, using the v3 API's JSON gateway.  The prefix
+// is polled for changes.
+type etcdStore struct {
+	client       *http.Client
+	address      *url.URL
+	prefix       string
+	token        *config.EnvironmentVariable
+	pollInterval time.Duration
+}
+
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end"`
+	KeysOnly bool   `json:"keys_only,omitempty"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []etcdKV `json:"kvs"`
+}
+
+type etcdKV struct {
+	Key         []byte `json:"key"`
+	Value       []byte `json:"value"`
+	ModRevision int64  `json:"mod_revision,string"`
+}
+
+type etcdTxnRequest struct {
+	Compare []etcdCompare   `json:"compare"`
+	Success []etcdOperation `json:"success"`
+}
+
+type etcdCompare struct {
+	Key            []byte `json:"key"`
+	Target         string `json:"target"`
+	CreateRevision int64  `json:"create_revision,string"`
+}
+
+type etcdOperation struct {
+	RequestPut etcdPutRequest `json:"request_put"`
+}
+
+type etcdPutRequest struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type etcdTxnResponse struct {
+	Succeeded bool `json:"succeeded"`
+}
+
+func newEtcdStore(conf config.VaultClientAccountStore) *etcdStore {
+	return &etcdStore{
+		client:       &http.Client{Timeout: storeRequestTimeout},
+		address:      conf.Address,
+		prefix:       strings.Trim(conf.Prefix, "/"),
+		token:        conf.Token,
+		pollInterval: storePollInterval(conf),
+	}
+}
+
+func (s *etcdStore) String() string {
+	return fmt.Sprintf("etcd://%v/%v", s.address.Host, s.prefix)
+}
+
+func (s *etcdStore) load() (storeContents, error) {
+	resp, err := s.rangePrefix(false)
+	if err != nil {
+		return storeContents{}, err
+	}
+	contents := storeContents{version: etcdVersion(resp)}
+	for _, kv := range resp.Kvs {
+		contents.accounts = append(contents.accounts, storedAccount{location: s.location(string(kv.Key)), contents: kv.Value})
+	}
+	return contents, nil
+}
+
+// create uses a transaction which only puts the key if it has not been created, so that an existing key is never
+// overwritten
+func (s *etcdStore) create(name string, contents []byte) (string, error) {
+	key := []byte(storeKey(s.prefix, name))
+	req := etcdTxnRequest{
+		Compare: []etcdCompare{{Key: key, Target: "CREATE", CreateRevision: 0}},
+		Success: []etcdOperation{{RequestPut: etcdPutRequest{Key: key, Value: contents}}},
+	}
+	var resp etcdTxnResponse
+	if err := s.post("/v3/kv/txn", req, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Succeeded {
+		return "", fmt.Errorf("account config %v already exists", s.location(string(key)))
+	}
+	return s.location(string(key)), nil
+}
+
+// wait polls the keys under the prefix after the poll interval
+func (s *etcdStore) wait(version uint64) (uint64, error) {
+	time.Sleep(s.pollInterval)
+	resp, err := s.rangePrefix(true)
+	if err != nil {
+		return 0, err
+	}
+	return etcdVersion(resp), nil
+}
+
+func (s *etcdStore) rangePrefix(keysOnly bool) (etcdRangeResponse, error) {
+	key := []byte(s.prefix + "/")
+	req := etcdRangeRequest{Key: key, RangeEnd: etcdPrefixEnd(key), KeysOnly: keysOnly}
+	var resp etcdRangeResponse
+	if err := s.post("/v3/kv/range", req, &resp); err != nil {
+		return etcdRangeResponse{}, err
+	}
+	return resp, nil
+}
+
+func (s *etcdStore) post(path string, body, result interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	u := *s.address
+	u.Path = path
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := storeToken(s.token); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response from etcd: %v: %v", resp.Status, strings.TrimSpace(string(b)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("unable to decode etcd response: %v", err)
+	}
+	return nil
+}
+
+func (s *etcdStore) location(key string) string {
+	return fmt.Sprintf("etcd://%v/%v", s.address.Host, key)
+}
+
+// etcdPrefixEnd returns the end of the range of keys with the given prefix
+func etcdPrefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// the prefix is all 0xff, so the range is every key after it
+	return []byte{0}
+}
+
+// etcdVersion hashes the keys under the prefix and their modification revisions, so that creations, updates and
+// deletions all change the version.  The cluster revision cannot be used as it also changes for keys outside the
+// prefix.
+func etcdVersion(resp etcdRangeResponse) uint64 {
+	h := fnv.New64a()
+	for _, kv := range resp.Kvs {
+		fmt.Fprintf(h, "%s=%d;", kv.Key, kv.ModRevision)
+	}
+	return h.Sum64()
+}
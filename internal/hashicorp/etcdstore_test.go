@@ -0,0 +1,136 @@
+package hashicorp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEtcdKV struct {
+	value       []byte
+	modRevision int64
+}
+
+// fakeEtcd implements the subset of the etcd v3 JSON gateway used by etcdStore
+type fakeEtcd struct {
+	mu       sync.Mutex
+	kvs      map[string]fakeEtcdKV
+	revision int64
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		var req etcdRangeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		var keys []string
+		for k := range f.kvs {
+			if bytes.Compare([]byte(k), req.Key) >= 0 && bytes.Compare([]byte(k), req.RangeEnd) < 0 {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var resp etcdRangeResponse
+		for _, k := range keys {
+			kv := f.kvs[k]
+			if req.KeysOnly {
+				kv.value = nil
+			}
+			resp.Kvs = append(resp.Kvs, etcdKV{Key: []byte(k), Value: kv.value, ModRevision: kv.modRevision})
+		}
+		json.NewEncoder(w).Encode(resp)
+	case "/v3/kv/txn":
+		var req etcdTxnRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if _, ok := f.kvs[string(req.Compare[0].Key)]; ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+			return
+		}
+		f.revision++
+		put := req.Success[0].RequestPut
+		f.kvs[string(put.Key)] = fakeEtcdKV{value: put.Value, modRevision: f.revision}
+		json.NewEncoder(w).Encode(map[string]interface{}{"succeeded": true})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeEtcdStore(t *testing.T) (*etcdStore, *fakeEtcd, func()) {
+	fake := &fakeEtcd{kvs: make(map[string]fakeEtcdKV), revision: 1}
+	server := httptest.NewServer(fake)
+	addr, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	s := newEtcdStore(config.VaultClientAccountStore{
+		Type:         config.AccountStoreEtcd,
+		Address:      addr,
+		Prefix:       "quorum/accounts",
+		PollInterval: 1,
+	})
+	return s, fake, server.Close
+}
+
+func TestEtcdStore_CreateAndLoad(t *testing.T) {
+	s, fake, stop := newFakeEtcdStore(t)
+	defer stop()
+
+	empty, err := s.load()
+	require.NoError(t, err)
+	require.Empty(t, empty.accounts)
+
+	location, err := s.create("UTC--acct1", []byte(`{"Address": "acct1"}`))
+	require.NoError(t, err)
+	require.Equal(t, "etcd://"+s.address.Host+"/quorum/accounts/UTC--acct1", location)
+	require.Contains(t, fake.kvs, "quorum/accounts/UTC--acct1")
+
+	got, err := s.load()
+	require.NoError(t, err)
+	require.Equal(t, []storedAccount{{location: location, contents: []byte(`{"Address": "acct1"}`)}}, got.accounts)
+	require.NotEqual(t, empty.version, got.version)
+
+	version, err := s.wait(empty.version)
+	require.NoError(t, err)
+	require.Equal(t, got.version, version)
+}
+
+func TestEtcdStore_Load_IgnoresKeysOutsidePrefix(t *testing.T) {
+	s, fake, stop := newFakeEtcdStore(t)
+	defer stop()
+
+	fake.kvs["quorum/accountsother"] = fakeEtcdKV{value: []byte("other"), modRevision: 1}
+	fake.kvs["quorum/accounts/acct1"] = fakeEtcdKV{value: []byte("acct1"), modRevision: 1}
+
+	got, err := s.load()
+	require.NoError(t, err)
+	require.Len(t, got.accounts, 1)
+	require.Equal(t, []byte("acct1"), got.accounts[0].contents)
+}
+
+func TestEtcdStore_Create_DoesNotOverwrite(t *testing.T) {
+	s, fake, stop := newFakeEtcdStore(t)
+	defer stop()
+
+	_, err := s.create("UTC--acct1", []byte("original"))
+	require.NoError(t, err)
+
+	_, err = s.create("UTC--acct1", []byte("overwritten"))
+	require.EqualError(t, err, "account config etcd://"+s.address.Host+"/quorum/accounts/UTC--acct1 already exists")
+	require.Equal(t, []byte("original"), fake.kvs["quorum/accounts/UTC--acct1"].value)
+}
+
+func TestEtcdPrefixEnd(t *testing.T) {
+	require.Equal(t, []byte("quorum/accounts0"), etcdPrefixEnd([]byte("quorum/accounts/")))
+	require.Equal(t, []byte("b"), etcdPrefixEnd([]byte{'a', 0xff}))
+	require.Equal(t, []byte{0}, etcdPrefixEnd([]byte{0xff}))
+}
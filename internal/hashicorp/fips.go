@@ -0,0 +1,148 @@
+package hashicorp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// fipsCipherSuites are the FIPS 140-2 approved cipher suites supported by crypto/tls.  TLS 1.3 is not used in FIPS mode
+// as its cipher suites cannot be restricted and include ChaCha20-Poly1305.
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// fipsCurves are the NIST curves approved for key exchange, excluding X25519
+var fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+var fipsSignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
+	x509.SHA256WithRSA:    true,
+	x509.SHA384WithRSA:    true,
+	x509.SHA512WithRSA:    true,
+	x509.SHA256WithRSAPSS: true,
+	x509.SHA384WithRSAPSS: true,
+	x509.SHA512WithRSAPSS: true,
+	x509.ECDSAWithSHA256:  true,
+	x509.ECDSAWithSHA384:  true,
+	x509.ECDSAWithSHA512:  true,
+}
+
+// fipsEnabled reports whether Vault connections are restricted to FIPS-approved algorithms, either because the plugin
+// was built with the fips tag or because it is enabled in the config
+func fipsEnabled(conf config.VaultClientTLS) bool {
+	return fipsBuild || conf.FIPS
+}
+
+// restrictToFIPS limits the TLS config to TLS 1.2 with FIPS-approved cipher suites and curves, and additionally
+// requires that the certificates presented by the Vault server use approved keys and signature algorithms
+func restrictToFIPS(tlsConf *tls.Config) {
+	tlsConf.MinVersion = tls.VersionTLS12
+	tlsConf.MaxVersion = tls.VersionTLS12
+	tlsConf.CipherSuites = fipsCipherSuites
+	tlsConf.CurvePreferences = fipsCurves
+
+	verify := tlsConf.VerifyPeerCertificate
+	tlsConf.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if verify != nil {
+			if err := verify(rawCerts, verifiedChains); err != nil {
+				return err
+			}
+		}
+		return checkFIPSCertificates(rawCerts)
+	}
+}
+
+// verifyFIPS checks at startup that connections to the Vault server at address can only use FIPS-approved algorithms
+func verifyFIPS(address *url.URL, tlsConf *tls.Config) error {
+	if address.Scheme != "https" {
+		return fmt.Errorf("%v must be an https url", address)
+	}
+	if tlsConf.MinVersion != tls.VersionTLS12 || tlsConf.MaxVersion != tls.VersionTLS12 {
+		return errors.New("TLS version is not restricted to TLS 1.2")
+	}
+	approvedSuites := make(map[uint16]bool)
+	for _, s := range fipsCipherSuites {
+		approvedSuites[s] = true
+	}
+	if len(tlsConf.CipherSuites) == 0 {
+		return errors.New("cipher suites are not restricted")
+	}
+	for _, s := range tlsConf.CipherSuites {
+		if !approvedSuites[s] {
+			return fmt.Errorf("cipher suite %#04x is not FIPS-approved", s)
+		}
+	}
+	if len(tlsConf.CurvePreferences) == 0 {
+		return errors.New("curves are not restricted")
+	}
+	for _, c := range tlsConf.CurvePreferences {
+		if c != tls.CurveP256 && c != tls.CurveP384 && c != tls.CurveP521 {
+			return fmt.Errorf("curve %v is not FIPS-approved", c)
+		}
+	}
+
+	certs := tlsConf.Certificates
+	if tlsConf.GetClientCertificate != nil {
+		c, err := tlsConf.GetClientCertificate(&tls.CertificateRequestInfo{})
+		if err != nil {
+			return err
+		}
+		if c != nil {
+			certs = append(certs, *c)
+		}
+	}
+	for _, c := range certs {
+		if len(c.Certificate) == 0 {
+			continue
+		}
+		if err := checkFIPSCertificates(c.Certificate[:1]); err != nil {
+			return fmt.Errorf("client certificate: %v", err)
+		}
+	}
+	return nil
+}
+
+// checkFIPSCertificates checks that each certificate uses a FIPS-approved public key and signature algorithm
+func checkFIPSCertificates(rawCerts [][]byte) error {
+	for _, raw := range rawCerts {
+		c, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		if !fipsSignatureAlgorithms[c.SignatureAlgorithm] {
+			return fmt.Errorf("certificate %q signature algorithm %v is not FIPS-approved", c.Subject.CommonName, c.SignatureAlgorithm)
+		}
+		if err := checkFIPSPublicKey(c.PublicKey); err != nil {
+			return fmt.Errorf("certificate %q: %v", c.Subject.CommonName, err)
+		}
+	}
+	return nil
+}
+
+func checkFIPSPublicKey(pub crypto.PublicKey) error {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return fmt.Errorf("%v-bit RSA key is not FIPS-approved", k.N.BitLen())
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+			return nil
+		}
+		return fmt.Errorf("ECDSA curve %v is not FIPS-approved", k.Curve.Params().Name)
+	}
+	return fmt.Errorf("%T key is not FIPS-approved", pub)
+}
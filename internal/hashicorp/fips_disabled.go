@@ -0,0 +1,5 @@
+// +build !fips
+
+package hashicorp
+
+const fipsBuild = false
@@ -0,0 +1,6 @@
+// +build fips
+
+package hashicorp
+
+// fipsBuild forces FIPS mode for all Vault connections, regardless of the tls fips config
+const fipsBuild = true
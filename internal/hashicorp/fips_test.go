@@ -0,0 +1,108 @@
+package hashicorp
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIPSEnabled(t *testing.T) {
+	require.True(t, fipsEnabled(config.VaultClientTLS{FIPS: true}))
+	require.Equal(t, fipsBuild, fipsEnabled(config.VaultClientTLS{}))
+}
+
+func TestVerifyFIPS(t *testing.T) {
+	u, _ := url.Parse("https://vault:8200")
+
+	tlsConf := &tls.Config{}
+	require.EqualError(t, verifyFIPS(u, tlsConf), "TLS version is not restricted to TLS 1.2")
+
+	restrictToFIPS(tlsConf)
+	require.NoError(t, verifyFIPS(u, tlsConf))
+
+	tlsConf.CipherSuites = append(tlsConf.CipherSuites, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305)
+	require.EqualError(t, verifyFIPS(u, tlsConf), "cipher suite 0xcca8 is not FIPS-approved")
+
+	restrictToFIPS(tlsConf)
+	tlsConf.CurvePreferences = []tls.CurveID{tls.X25519}
+	require.EqualError(t, verifyFIPS(u, tlsConf), "curve X25519 is not FIPS-approved")
+}
+
+func TestVerifyFIPS_NotHTTPS(t *testing.T) {
+	u, _ := url.Parse("http://vault:8200")
+	tlsConf := &tls.Config{}
+	restrictToFIPS(tlsConf)
+
+	require.EqualError(t, verifyFIPS(u, tlsConf), "http://vault:8200 must be an https url")
+}
+
+func TestNewAPIClient_FIPSRequiresHTTPS(t *testing.T) {
+	u, _ := url.Parse("http://vault:8200")
+	_, err := newAPIClient(u, config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}, FIPS: true}, 0, nil)
+
+	require.EqualError(t, err, "unable to use FIPS mode: http://vault:8200 must be an https url")
+}
+
+func TestCheckFIPSPublicKey(t *testing.T) {
+	rsa1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	require.EqualError(t, checkFIPSPublicKey(&rsa1024.PublicKey), "1024-bit RSA key is not FIPS-approved")
+
+	rsa2048, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	require.NoError(t, checkFIPSPublicKey(&rsa2048.PublicKey))
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, checkFIPSPublicKey(&p256.PublicKey))
+
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+	require.EqualError(t, checkFIPSPublicKey(&p224.PublicKey), "ECDSA curve P-224 is not FIPS-approved")
+
+	ed, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.EqualError(t, checkFIPSPublicKey(ed), "ed25519.PublicKey key is not FIPS-approved")
+}
+
+// fipsClient returns a client for the server with its TLS config restricted to FIPS mode
+func fipsClient(server *httptest.Server) *http.Client {
+	client := server.Client()
+	tlsConf := client.Transport.(*http.Transport).TLSClientConfig
+	restrictToFIPS(tlsConf)
+	return client
+}
+
+func TestRestrictToFIPS_ApprovedServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, uint16(tls.VersionTLS12), r.TLS.Version)
+	}))
+	defer server.Close()
+
+	resp, err := fipsClient(server).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestRestrictToFIPS_UnapprovedCipherSuite(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	_, err := fipsClient(server).Get(server.URL)
+	require.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+)
+
+// FrozenError is a request refused because the account has been frozen
+type FrozenError struct {
+	Address string
+	Reason  string
+}
+
+func (e *FrozenError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("account 0x%v is frozen", e.Address)
+	}
+	return fmt.Sprintf("account 0x%v is frozen: %v", e.Address, e.Reason)
+}
+
+func checkFrozen(acctFile config.AccountFile) error {
+	if f := acctFile.Contents.Frozen; f != nil {
+		return &FrozenError{Address: acctFile.Contents.Address, Reason: f.Reason}
+	}
+	return nil
+}
+
+// Freeze marks the account configs for the address as frozen, so that the account remains listed but cannot be
+// unlocked or sign until it is unfrozen.  The account is locked.
+func (a *accountManager) Freeze(acctAddr account.Address, reason string) error {
+	return a.setFrozen(acctAddr, &config.AccountFreeze{Reason: reason, Time: time.Now().UTC()})
+}
+
+// Unfreeze removes the freeze from the account configs for the address
+func (a *accountManager) Unfreeze(acctAddr account.Address) error {
+	return a.setFrozen(acctAddr, nil)
+}
+
+func (a *accountManager) setFrozen(acctAddr account.Address, freeze *config.AccountFreeze) error {
+	store, ok := a.client.store.(*dirStore)
+	if !ok {
+		return fmt.Errorf("account configs are stored in %v, not an accountDirectory", a.client.store)
+	}
+
+	addr := acctAddr.ToHexString()
+	found := false
+	// every config for the address is updated, so that the account stays frozen if duplicate configs are removed
+	for _, acct := range a.client.accounts() {
+		if acct.Contents.Address != addr {
+			continue
+		}
+		found = true
+		acct.Contents.Frozen = freeze
+		contents, err := json.Marshal(acct.Contents)
+		if err != nil {
+			return err
+		}
+		if err := store.replace(acct.Path, contents); err != nil {
+			return fmt.Errorf("unable to update account config %v: %v", acct.Path, err)
+		}
+	}
+	if !found {
+		return fmt.Errorf("no account config found for 0x%v", addr)
+	}
+	return a.ReloadAccounts()
+}
+
+// ReloadAccounts re-reads the account configs, e.g. after accounts have been frozen or unfrozen by an operator
+// command.  Accounts that are now frozen are locked.
+func (a *accountManager) ReloadAccounts() error {
+	prev := frozenAccounts(a.client.accounts())
+	result, err := a.client.loadAccounts()
+	if err != nil {
+		return fmt.Errorf("error reloading account configs from %v: %v", a.client.store, err)
+	}
+	a.client.setAccounts(result)
+	warnIfAmbiguous(result)
+	log.Printf("[INFO] reloaded %v account configs from %v", len(result), a.client.store)
+
+	next := frozenAccounts(result)
+	for addr, freeze := range next {
+		if _, ok := prev[addr]; ok {
+			continue
+		}
+		log.Printf("[WARN] account 0x%v has been frozen: %v", addr, freeze.Reason)
+		event.Emit(event.AccountFrozen, "0x"+addr, freeze.Reason)
+		if acctAddr, err := account.NewAddressFromHexString(addr); err == nil {
+			a.Lock(acctAddr)
+		}
+	}
+	for addr := range prev {
+		if _, ok := next[addr]; !ok {
+			log.Printf("[INFO] account 0x%v has been unfrozen", addr)
+			event.Emit(event.AccountUnfrozen, "0x"+addr, "")
+		}
+	}
+	return nil
+}
+
+// frozenAccounts returns the freeze of each frozen account, by lowercase hex address without 0x prefix
+func frozenAccounts(accts accountsByURL) map[string]config.AccountFreeze {
+	frozen := make(map[string]config.AccountFreeze)
+	for _, acct := range accts {
+		if f := acct.Contents.Frozen; f != nil {
+			frozen[acct.Contents.Address] = *f
+		}
+	}
+	return frozen
+}
@@ -0,0 +1,70 @@
+package hashicorp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountManager_FreezeAndUnfreeze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freeze")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dirURL, _ := url.Parse("file://" + dir + "/")
+
+	am, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL})
+	require.NoError(t, err)
+	a := am.(*accountManager)
+
+	key, _ := account.NewKeyFromHexString(reconcileKey1)
+	_, err = a.ImportPrivateKey(key, config.NewAccount{SecretName: "acct1"})
+	require.NoError(t, err)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+
+	events, unsubscribe := event.Subscribe(10)
+	defer unsubscribe()
+
+	require.NoError(t, a.Freeze(addr, "INC-1234"))
+
+	e := <-events
+	require.Equal(t, event.AccountFrozen, e.Kind)
+	require.Equal(t, "0x"+reconcileAddr1, e.Subject)
+	require.Equal(t, "INC-1234", e.Message)
+
+	// the account is locked and still listed, but cannot be used
+	require.Equal(t, 0, a.DebugState().UnlockedAccounts)
+	require.Equal(t, 1, a.DebugState().FrozenAccounts)
+	accts, err := a.Accounts()
+	require.NoError(t, err)
+	require.Len(t, accts, 1)
+
+	wantErr := "account 0x" + reconcileAddr1 + " is frozen: INC-1234"
+	require.EqualError(t, a.TimedUnlock(context.Background(), addr, 0), wantErr)
+	_, err = a.UnlockAndSign(context.Background(), addr, make([]byte, 32))
+	require.IsType(t, &FrozenError{}, err)
+	_, err = a.Sign(context.Background(), addr, make([]byte, 32))
+	require.EqualError(t, err, wantErr)
+
+	// the freeze is persisted in the account config, so applies after a restart
+	reloaded, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL})
+	require.NoError(t, err)
+	acctFile, err := reloaded.(*accountManager).client.getAccount(addr)
+	require.NoError(t, err)
+	require.Equal(t, "INC-1234", acctFile.Contents.Frozen.Reason)
+
+	require.NoError(t, a.Unfreeze(addr))
+	e = <-events
+	require.Equal(t, event.AccountUnfrozen, e.Kind)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+
+	unknown, _ := account.NewAddressFromHexString(reconcileAddr2)
+	require.EqualError(t, a.Freeze(unknown, ""), "no account config found for 0x"+reconcileAddr2)
+}
@@ -0,0 +1,156 @@
+package hashicorp
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GCReport describes the Vault secret versions under a prefix that are not referenced by any account config
+type GCReport struct {
+	Prefix   string
+	Orphaned []OrphanedSecret
+	Deleted  bool
+}
+
+type OrphanedSecret struct {
+	SecretName string
+	Versions   []int64
+}
+
+// GarbageCollect lists all secrets under prefix in the KV engine and reports the live (i.e. not deleted or destroyed)
+// versions that are not referenced by any of the loaded account configs.  If confirm is true the orphaned versions
+// are soft-deleted, so can still be recovered with Vault's undelete API.
+//
+// Account configs are only known for this node.  Care should be taken if other nodes share the same KV engine.
+func (a *accountManager) GarbageCollect(prefix string, confirm bool) (GCReport, error) {
+	referenced := make(map[string]map[int64]bool)
+	for _, acct := range a.client.accounts() {
+		v := acct.Contents.VaultAccount
+		if referenced[v.SecretName] == nil {
+			referenced[v.SecretName] = make(map[int64]bool)
+		}
+		referenced[v.SecretName][v.SecretVersion] = true
+	}
+
+	names, err := a.listSecrets(prefix)
+	if err != nil {
+		return GCReport{}, err
+	}
+
+	report := GCReport{Prefix: prefix}
+
+	for _, name := range names {
+		live, err := a.liveVersions(name)
+		if err != nil {
+			return GCReport{}, err
+		}
+		var orphaned []int64
+		for _, version := range live {
+			if !referenced[name][version] {
+				orphaned = append(orphaned, version)
+			}
+		}
+		if len(orphaned) > 0 {
+			report.Orphaned = append(report.Orphaned, OrphanedSecret{SecretName: name, Versions: orphaned})
+		}
+	}
+
+	if !confirm {
+		return report, nil
+	}
+
+	if err := a.client.writable(); err != nil {
+		return report, err
+	}
+	for _, o := range report.Orphaned {
+		log.Printf("[INFO] soft-deleting orphaned secret: name = %v, versions = %v", o.SecretName, o.Versions)
+		if err := a.secrets.delete(o.SecretName, o.Versions); err != nil {
+			return report, fmt.Errorf("unable to delete versions of secret %v: %v", o.SecretName, err)
+		}
+		for _, v := range o.Versions {
+			a.invalidateCachedKey(o.SecretName, v)
+		}
+	}
+	report.Deleted = true
+
+	return report, nil
+}
+
+// listSecrets recursively lists the names of all secrets under prefix
+func (a *accountManager) listSecrets(prefix string) ([]string, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix = prefix + "/"
+	}
+
+	keys, err := a.secrets.list(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			nested, err := a.listSecrets(prefix + key)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, nested...)
+			continue
+		}
+		names = append(names, prefix+key)
+	}
+	return names, nil
+}
+
+// liveVersions returns the versions of the secret that have not been deleted or destroyed, in ascending order.  A
+// version with a deletion_time in the future, e.g. on a KV engine with delete_version_after, has not yet been deleted.
+func (a *accountManager) liveVersions(secretName string) ([]int64, error) {
+	meta, err := a.secrets.metadata(secretName)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+	versions, ok := meta["versions"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no version metadata returned from Vault for secret %v", secretName)
+	}
+
+	now := time.Now()
+	var live []int64
+	for v, meta := range versions {
+		version, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version metadata returned from Vault for secret %v: %v", secretName, err)
+		}
+		m, ok := meta.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid version metadata returned from Vault for secret %v", secretName)
+		}
+		if deletionTime, _ := m["deletion_time"].(string); isDeleted(deletionTime, now) {
+			continue
+		}
+		if destroyed, _ := m["destroyed"].(bool); destroyed {
+			continue
+		}
+		live = append(live, version)
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i] < live[j] })
+	return live, nil
+}
+
+// isDeleted returns true if the deletion_time of a secret version is set and not in the future.  A deletion_time that
+// cannot be parsed is treated as deleted.
+func isDeleted(deletionTime string, now time.Time) bool {
+	if deletionTime == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339Nano, deletionTime)
+	return err != nil || !t.After(now)
+}
@@ -0,0 +1,127 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func gcVaultServer(t *testing.T, deleted map[string][]interface{}) *httptest.Server {
+	live := map[string]interface{}{"deletion_time": "", "destroyed": false}
+	softDeleted := map[string]interface{}{"deletion_time": "2020-07-20T10:00:00Z", "destroyed": false}
+	destroyed := map[string]interface{}{"deletion_time": "", "destroyed": true}
+
+	responses := map[string]map[string]interface{}{
+		"/v1/kv/metadata":           {"keys": []string{"acct1", "dir/"}},
+		"/v1/kv/metadata/dir":       {"keys": []string{"acct2"}},
+		"/v1/kv/metadata/acct1":     {"versions": map[string]interface{}{"1": live, "2": live, "3": softDeleted, "4": destroyed}},
+		"/v1/kv/metadata/dir/acct2": {"versions": map[string]interface{}{"1": live}},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut || r.Method == http.MethodPost {
+			b, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			body := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal(b, &body))
+			deleted[r.URL.Path] = body["versions"].([]interface{})
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		data, ok := responses[strings.TrimSuffix(r.URL.Path, "/")]
+		if !ok {
+			// mimic Vault's response when a secret does not exist
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		b, _ := json.Marshal(&api.Secret{Data: data})
+		_, _ = w.Write(b)
+	}))
+}
+
+func gcAccountManager(t *testing.T, vaultURL string) *accountManager {
+	u, _ := url.Parse("file:///path/to/acct1")
+	acct := config.AccountFile{}
+	acct.Contents.Address = "dc99ddec13457de6c0f6bb8e6cf3955c86f55526"
+	acct.Contents.VaultAccount.SecretName = "acct1"
+	acct.Contents.VaultAccount.SecretVersion = 2
+
+	return testKVAccountManager(t, vaultURL, accountsByURL{u: acct})
+}
+
+func TestGarbageCollect_ReportOnly(t *testing.T) {
+	deleted := make(map[string][]interface{})
+	vault := gcVaultServer(t, deleted)
+	defer vault.Close()
+
+	a := gcAccountManager(t, vault.URL)
+
+	got, err := a.GarbageCollect("", false)
+	require.NoError(t, err)
+
+	want := GCReport{
+		Orphaned: []OrphanedSecret{
+			{SecretName: "acct1", Versions: []int64{1}},
+			{SecretName: "dir/acct2", Versions: []int64{1}},
+		},
+	}
+	require.Equal(t, want, got)
+	require.Empty(t, deleted)
+}
+
+func TestGarbageCollect_Confirm(t *testing.T) {
+	deleted := make(map[string][]interface{})
+	vault := gcVaultServer(t, deleted)
+	defer vault.Close()
+
+	a := gcAccountManager(t, vault.URL)
+
+	got, err := a.GarbageCollect("", true)
+	require.NoError(t, err)
+	require.True(t, got.Deleted)
+
+	want := map[string][]interface{}{
+		"/v1/kv/delete/acct1":     {float64(1)},
+		"/v1/kv/delete/dir/acct2": {float64(1)},
+	}
+	require.Equal(t, want, deleted)
+}
+
+func TestGarbageCollect_Prefix(t *testing.T) {
+	deleted := make(map[string][]interface{})
+	vault := gcVaultServer(t, deleted)
+	defer vault.Close()
+
+	a := gcAccountManager(t, vault.URL)
+
+	got, err := a.GarbageCollect("/dir/", false)
+	require.NoError(t, err)
+
+	want := GCReport{
+		Prefix: "/dir/",
+		Orphaned: []OrphanedSecret{
+			{SecretName: "dir/acct2", Versions: []int64{1}},
+		},
+	}
+	require.Equal(t, want, got)
+}
+
+func TestIsDeleted(t *testing.T) {
+	now := time.Date(2020, 7, 20, 10, 0, 0, 0, time.UTC)
+	require.False(t, isDeleted("", now))
+	require.True(t, isDeleted("2020-07-20T09:59:59.123456Z", now))
+	require.True(t, isDeleted("2020-07-20T10:00:00Z", now))
+	// scheduled by delete_version_after
+	require.False(t, isDeleted("2020-07-20T10:00:00.5Z", now))
+	require.True(t, isDeleted("not a time", now))
+}
@@ -0,0 +1,121 @@
+package hashicorp
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+// AccountHealth describes whether the Vault secret referenced by an account config can still be used
+type AccountHealth struct {
+	Address       string
+	URL           string
+	SecretName    string
+	SecretVersion int64
+	SecretKey     string `json:",omitempty"` // the field of the secret holding the key, if set in the account config
+	Degraded      bool
+	Reason        string `json:",omitempty"`
+}
+
+// CheckAccounts probes Vault's metadata for the secret version referenced by each account config.  Accounts whose
+// secret version no longer exists, has been deleted/destroyed, or cannot be read are marked as degraded.  No key
+// material is read.
+func (a *accountManager) CheckAccounts() []AccountHealth {
+	var (
+		results = make([]AccountHealth, 0, len(a.client.accounts()))
+		live    = make(map[string]map[int64]bool) // secret name -> live versions
+		errs    = make(map[string]error)          // secret name -> error reading metadata
+		checked = make(map[string]bool)
+	)
+
+	for u, acct := range a.client.accounts() {
+		conf := acct.Contents.VaultAccount
+		h := AccountHealth{
+			Address:       fmt.Sprintf("0x%v", strings.TrimPrefix(acct.Contents.Address, "0x")),
+			URL:           u.String(),
+			SecretName:    conf.SecretName,
+			SecretVersion: conf.SecretVersion,
+			SecretKey:     conf.SecretKey,
+		}
+
+		if _, done := live[conf.SecretName]; !done && errs[conf.SecretName] == nil {
+			versions, err := a.liveVersions(conf.SecretName)
+			if err != nil {
+				errs[conf.SecretName] = err
+			} else {
+				live[conf.SecretName] = make(map[int64]bool)
+				for _, v := range versions {
+					live[conf.SecretName][v] = true
+				}
+			}
+		}
+
+		if err := errs[conf.SecretName]; err != nil {
+			h.Degraded = true
+			h.Reason = fmt.Sprintf("unable to read secret metadata: %v", err)
+		} else if !live[conf.SecretName][conf.SecretVersion] {
+			h.Degraded = true
+			h.Reason = fmt.Sprintf("secret version %v does not exist or has been deleted", conf.SecretVersion)
+		}
+
+		checked[strings.TrimPrefix(acct.Contents.Address, "0x")] = true
+		if h.Degraded {
+			a.markDegraded(acct.Contents.Address, h.Reason)
+		} else {
+			a.markHealthy(acct.Contents.Address)
+		}
+		results = append(results, h)
+	}
+
+	// forget about any accounts that are no longer configured
+	a.mu.Lock()
+	for addr := range a.degraded {
+		if !checked[addr] {
+			delete(a.degraded, addr)
+		}
+	}
+	metrics.AccountsDegraded.Set(int64(len(a.degraded)))
+	a.mu.Unlock()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].URL < results[j].URL })
+	return results
+}
+
+func (a *accountManager) markDegraded(addr, reason string) {
+	addr = strings.TrimPrefix(addr, "0x")
+
+	a.mu.Lock()
+	if a.degraded == nil {
+		a.degraded = make(map[string]string)
+	}
+	prev, already := a.degraded[addr]
+	a.degraded[addr] = reason
+	count := len(a.degraded)
+	a.mu.Unlock()
+
+	metrics.AccountsDegraded.Set(int64(count))
+	if !already || prev != reason {
+		log.Printf("[WARN] account 0x%v is degraded: %v", addr, reason)
+		event.Emit(event.AccountDegraded, "0x"+addr, reason)
+	}
+}
+
+func (a *accountManager) markHealthy(addr string) {
+	addr = strings.TrimPrefix(addr, "0x")
+
+	a.mu.Lock()
+	_, wasDegraded := a.degraded[addr]
+	delete(a.degraded, addr)
+	count := len(a.degraded)
+	a.mu.Unlock()
+
+	metrics.AccountsDegraded.Set(int64(count))
+	if wasDegraded {
+		log.Printf("[INFO] account 0x%v is no longer degraded", addr)
+		event.Emit(event.AccountRecovered, "0x"+addr, "")
+	}
+}
@@ -0,0 +1,73 @@
+package hashicorp
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAccounts_Healthy(t *testing.T) {
+	vault := gcVaultServer(t, nil)
+	defer vault.Close()
+
+	a := gcAccountManager(t, vault.URL)
+
+	got := a.CheckAccounts()
+	require.Len(t, got, 1)
+	require.False(t, got[0].Degraded)
+	require.Equal(t, "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", got[0].Address)
+
+	status, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s)", status)
+}
+
+func TestCheckAccounts_Degraded(t *testing.T) {
+	vault := gcVaultServer(t, nil)
+	defer vault.Close()
+
+	a := gcAccountManager(t, vault.URL)
+
+	softDeleted, _ := url.Parse("file:///path/to/softdeleted")
+	acct := config.AccountFile{}
+	acct.Contents.Address = "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5"
+	acct.Contents.VaultAccount.SecretName = "acct1"
+	acct.Contents.VaultAccount.SecretVersion = 3
+	a.client.accts[softDeleted] = acct
+
+	missing, _ := url.Parse("file:///path/to/missing")
+	acct = config.AccountFile{}
+	acct.Contents.Address = "0x1111111111111111111111111111111111111111"
+	acct.Contents.VaultAccount.SecretName = "doesnotexist"
+	acct.Contents.VaultAccount.SecretVersion = 1
+	a.client.accts[missing] = acct
+
+	got := a.CheckAccounts()
+	require.Len(t, got, 3)
+
+	require.Equal(t, "file:///path/to/acct1", got[0].URL)
+	require.False(t, got[0].Degraded)
+
+	require.Equal(t, "file:///path/to/missing", got[1].URL)
+	require.True(t, got[1].Degraded)
+	require.Equal(t, "secret version 1 does not exist or has been deleted", got[1].Reason)
+
+	require.Equal(t, "file:///path/to/softdeleted", got[2].URL)
+	require.True(t, got[2].Degraded)
+	require.Equal(t, "secret version 3 does not exist or has been deleted", got[2].Reason)
+
+	status, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s); 2 degraded account(s): [0x1111111111111111111111111111111111111111 0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5]", status)
+
+	// degraded accounts are forgotten once they are no longer configured
+	delete(a.client.accts, softDeleted)
+	delete(a.client.accts, missing)
+	a.CheckAccounts()
+
+	status, err = a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s)", status)
+}
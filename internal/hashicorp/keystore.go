@@ -0,0 +1,121 @@
+package hashicorp
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+const (
+	// keystoreField is the secret field holding the JSON contents of a geth keystore file, for accounts migrated by
+	// copying keystore files into Vault instead of a hex-encoded key
+	keystoreField = "keystore"
+	// keystorePassphraseField is the secret field holding an env:// or file:// URL referencing the keystore's passphrase
+	keystorePassphraseField = "passphrase"
+)
+
+// keyFromSecret returns the account's private key from the secret data, decrypting it if the secret holds a keystore or
+// unmasking it with pepper if the secret holds a peppered key
+func keyFromSecret(data map[string]interface{}, addr string, pepper *config.EnvironmentVariable) (*ecdsa.PrivateKey, error) {
+	if _, ok := data[keystoreField]; ok {
+		key, err := keystoreKey(data)
+		if err != nil {
+			return nil, err
+		}
+		keyAddr, err := account.PrivateKeyToAddress(key)
+		if err != nil {
+			zeroKey(key)
+			return nil, err
+		}
+		if keyAddr.ToHexString() != config.NormalizeAddress(addr) {
+			zeroKey(key)
+			return nil, fmt.Errorf("keystore does not contain the key for account address %v", addr)
+		}
+		return key, nil
+	}
+
+	privKey, ok := secretValue(data, addr)
+	if !ok {
+		return nil, fmt.Errorf("response does not contain data for account address %v", addr)
+	}
+	keyHex, ok := privKey.(string)
+	if !ok {
+		return nil, errors.New("secret value is not a hex-encoded private key")
+	}
+	if isPeppered(keyHex) {
+		return unpepperKey(keyHex, addr, pepper)
+	}
+	return account.NewKeyFromHexString(keyHex)
+}
+
+// onePairErr is returned for a secret holding more than one field, unless it holds a keystore or the account config
+// names the field holding the key
+var onePairErr = errors.New("only one key/value pair is allowed in each Hashicorp Vault secret, unless the account config sets SecretKey")
+
+// accountSecretKey returns the account's private key from the secret data, read from the field named by field if set.
+// addr is the account's address, used to unmask a peppered key.
+func accountSecretKey(data map[string]interface{}, addr, field string, pepper *config.EnvironmentVariable) (*ecdsa.PrivateKey, error) {
+	if field == "" {
+		if _, ok := data[keystoreField]; !ok && len(data) != 1 {
+			return nil, onePairErr
+		}
+		return keyFromSecret(data, addr, pepper)
+	}
+
+	v, ok := data[field]
+	if !ok {
+		return nil, fmt.Errorf("secret does not contain field %v holding the key for account address %v", field, addr)
+	}
+	keyHex, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("secret field %v is not a hex-encoded private key", field)
+	}
+	if isPeppered(keyHex) {
+		return unpepperKey(keyHex, addr, pepper)
+	}
+	return account.NewKeyFromHexString(keyHex)
+}
+
+// keystoreKey decrypts the keystore in the secret data using the passphrase it references
+func keystoreKey(data map[string]interface{}) (*ecdsa.PrivateKey, error) {
+	var keyJSON []byte
+	switch ks := data[keystoreField].(type) {
+	case string:
+		keyJSON = []byte(ks)
+	case map[string]interface{}:
+		// the keystore was written to Vault as a JSON object rather than a string
+		b, err := json.Marshal(ks)
+		if err != nil {
+			return nil, err
+		}
+		keyJSON = b
+	default:
+		return nil, errors.New("secret keystore is not a keystore file's JSON contents")
+	}
+
+	passphrase, err := keystorePassphrase(data)
+	if err != nil {
+		return nil, err
+	}
+	return account.DecryptKeystore(keyJSON, passphrase)
+}
+
+// keystorePassphrase resolves the env:// or file:// passphrase reference in the secret data.  The passphrase itself is
+// never stored in Vault alongside the keystore.
+func keystorePassphrase(data map[string]interface{}) (string, error) {
+	ref, _ := data[keystorePassphraseField].(string)
+	u, err := url.Parse(ref)
+	if err != nil || (u.Scheme != "env" && u.Scheme != "file") {
+		return "", errors.New("secret passphrase must be an env:// or file:// URL referencing the keystore passphrase")
+	}
+	env := config.EnvironmentVariable(*u)
+	if !env.IsSet() {
+		return "", fmt.Errorf("keystore passphrase %v is not set", ref)
+	}
+	return env.Get(), nil
+}
@@ -0,0 +1,111 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/stretchr/testify/require"
+)
+
+// pbkdf2 test vector from the Web3 Secret Storage Definition, with password "testpassword"
+const (
+	testKeystore     = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"6087dab2f9fdbbfaddc31a909735c1e6"},"ciphertext":"5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46","kdf":"pbkdf2","kdfparams":{"c":262144,"dklen":32,"prf":"hmac-sha256","salt":"ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},"mac":"517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+	testKeystoreAddr = "008aeeda4d805471df9b2a5b0f38a0c3bcba786b"
+	testKeystoreKey  = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+)
+
+func TestKeyFromSecret_Keystore(t *testing.T) {
+	os.Setenv("HASHICORP_TEST_KEYSTORE_PASSPHRASE", "testpassword")
+	defer os.Unsetenv("HASHICORP_TEST_KEYSTORE_PASSPHRASE")
+
+	var keystoreObject map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(testKeystore), &keystoreObject))
+
+	for name, keystore := range map[string]interface{}{"string": testKeystore, "object": keystoreObject} {
+		data := map[string]interface{}{
+			"keystore":   keystore,
+			"passphrase": "env://HASHICORP_TEST_KEYSTORE_PASSPHRASE",
+		}
+
+		key, err := keyFromSecret(data, "0x008AEEDA4D805471DF9B2A5B0F38A0C3BCBA786B", nil)
+		require.NoError(t, err, name)
+		got, err := account.PrivateKeyToHexString(key)
+		require.NoError(t, err)
+		require.Equal(t, testKeystoreKey, got, name)
+	}
+}
+
+func TestKeyFromSecret_KeystoreWrongAddress(t *testing.T) {
+	os.Setenv("HASHICORP_TEST_KEYSTORE_PASSPHRASE", "testpassword")
+	defer os.Unsetenv("HASHICORP_TEST_KEYSTORE_PASSPHRASE")
+
+	data := map[string]interface{}{
+		"keystore":   testKeystore,
+		"passphrase": "env://HASHICORP_TEST_KEYSTORE_PASSPHRASE",
+	}
+
+	_, err := keyFromSecret(data, "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", nil)
+	require.EqualError(t, err, "keystore does not contain the key for account address 4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+}
+
+func TestKeyFromSecret_KeystorePassphrase(t *testing.T) {
+	data := map[string]interface{}{"keystore": testKeystore}
+
+	_, err := keyFromSecret(data, testKeystoreAddr, nil)
+	require.EqualError(t, err, "secret passphrase must be an env:// or file:// URL referencing the keystore passphrase")
+
+	data["passphrase"] = "testpassword"
+	_, err = keyFromSecret(data, testKeystoreAddr, nil)
+	require.EqualError(t, err, "secret passphrase must be an env:// or file:// URL referencing the keystore passphrase")
+
+	data["passphrase"] = "env://HASHICORP_TEST_KEYSTORE_PASSPHRASE"
+	_, err = keyFromSecret(data, testKeystoreAddr, nil)
+	require.EqualError(t, err, "keystore passphrase env://HASHICORP_TEST_KEYSTORE_PASSPHRASE is not set")
+
+	os.Setenv("HASHICORP_TEST_KEYSTORE_PASSPHRASE", "wrongpassword")
+	defer os.Unsetenv("HASHICORP_TEST_KEYSTORE_PASSPHRASE")
+	_, err = keyFromSecret(data, testKeystoreAddr, nil)
+	require.Equal(t, account.KeystorePassphraseErr, err)
+}
+
+func TestKeyFromSecret_HexKey(t *testing.T) {
+	key, err := keyFromSecret(map[string]interface{}{"0x" + testKeystoreAddr: testKeystoreKey}, testKeystoreAddr, nil)
+	require.NoError(t, err)
+	got, err := account.PrivateKeyToHexString(key)
+	require.NoError(t, err)
+	require.Equal(t, testKeystoreKey, got)
+
+	_, err = keyFromSecret(map[string]interface{}{testKeystoreAddr: 1}, testKeystoreAddr, nil)
+	require.EqualError(t, err, "secret value is not a hex-encoded private key")
+
+	_, err = keyFromSecret(map[string]interface{}{}, testKeystoreAddr, nil)
+	require.EqualError(t, err, "response does not contain data for account address "+testKeystoreAddr)
+}
+
+func TestAccountSecretKey(t *testing.T) {
+	data := map[string]interface{}{
+		"privateKey": testKeystoreKey,
+		"publicKey":  "04abcdef",
+		"metadata":   map[string]interface{}{"owner": "treasury"},
+	}
+
+	key, err := accountSecretKey(data, testKeystoreAddr, "privateKey", nil)
+	require.NoError(t, err)
+	got, err := account.PrivateKeyToHexString(key)
+	require.NoError(t, err)
+	require.Equal(t, testKeystoreKey, got)
+
+	_, err = accountSecretKey(data, testKeystoreAddr, "missing", nil)
+	require.EqualError(t, err, "secret does not contain field missing holding the key for account address "+testKeystoreAddr)
+
+	_, err = accountSecretKey(data, testKeystoreAddr, "metadata", nil)
+	require.EqualError(t, err, "secret field metadata is not a hex-encoded private key")
+
+	// without a SecretKey the secret can only hold the key
+	_, err = accountSecretKey(data, testKeystoreAddr, "", nil)
+	require.Equal(t, onePairErr, err)
+	_, err = accountSecretKey(map[string]interface{}{testKeystoreAddr: testKeystoreKey}, testKeystoreAddr, "", nil)
+	require.NoError(t, err)
+}
@@ -0,0 +1,94 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// authenticateWithKubernetes logs in to Vault using the pod's service account token.  Projected service account tokens
+// are rotated by the kubelet, so the token file is read on every login rather than once at startup.
+func (c *vaultClient) authenticateWithKubernetes(conf config.VaultClientKubernetes) (*renewable, error) {
+	b, err := ioutil.ReadFile(config.FilePath(conf.ServiceAccountToken))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read service account token: %v", err)
+	}
+	jwt := strings.TrimSpace(string(b))
+	if jwt == "" {
+		return nil, errors.New("service account token is empty")
+	}
+
+	body := map[string]interface{}{"role": conf.Role, "jwt": jwt}
+
+	resp, err := c.Logical().Write(fmt.Sprintf("auth/%s/login", conf.Path), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := resp.TokenID(); err != nil {
+		return nil, err
+	}
+	return &renewable{Secret: resp}, nil
+}
+
+// login authenticates using whichever of the renewable auth methods is configured.  The new token is not used for
+// requests until it is installed by startAuthenticationRenewal.
+func (c *vaultClient) login(conf config.VaultClientAuthentication) (*renewable, error) {
+	if conf.Kubernetes.Role != "" {
+		return c.authenticateWithKubernetes(conf.Kubernetes)
+	}
+	if conf.Azure.Role != "" {
+		return c.authenticateWithAzure(conf.Azure)
+	}
+	if conf.Cert.Role != "" {
+		return c.authenticateWithCert(conf.Cert)
+	}
+	if _, up, ok := userpassAuth(conf); ok {
+		return c.authenticateWithUserpass(up)
+	}
+	return c.authenticateWithApprole(conf)
+}
+
+// authMethod describes the configured renewable auth method for logging
+func authMethod(conf config.VaultClientAuthentication) string {
+	if conf.Kubernetes.Role != "" {
+		return fmt.Sprintf("kubernetes = %v, role = %v", conf.Kubernetes.Path, conf.Kubernetes.Role)
+	}
+	if conf.Azure.Role != "" {
+		return fmt.Sprintf("azure = %v, role = %v", conf.Azure.Path, conf.Azure.Role)
+	}
+	if conf.Cert.Role != "" {
+		return fmt.Sprintf("cert = %v, role = %v", conf.Cert.Path, conf.Cert.Role)
+	}
+	if method, up, ok := userpassAuth(conf); ok {
+		return fmt.Sprintf("%v = %v, username = %v", method, up.Path, up.Username.Get())
+	}
+	return fmt.Sprintf("approle = %v", conf.ApprolePath)
+}
+
+// authID identifies the configured renewable auth method in events, e.g. approle/myapprole
+func authID(conf config.VaultClientAuthentication) string {
+	if conf.Kubernetes.Role != "" {
+		return "kubernetes/" + conf.Kubernetes.Path
+	}
+	if conf.Azure.Role != "" {
+		return "azure/" + conf.Azure.Path
+	}
+	if conf.Cert.Role != "" {
+		return "cert/" + conf.Cert.Path
+	}
+	if method, up, ok := userpassAuth(conf); ok {
+		return method + "/" + up.Path
+	}
+	return "approle/" + conf.ApprolePath
+}
+
+// reloginAfter is the point in a non-renewable token's lifetime at which a new login is made, leaving time for retries
+// before the token expires
+func reloginAfter(ttl time.Duration) time.Duration {
+	return ttl * 2 / 3
+}
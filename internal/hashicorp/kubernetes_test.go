@@ -0,0 +1,76 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultClient_AuthenticateWithKubernetes_RereadsRotatedToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviceaccount")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(path, []byte("firstjwt\n"), 0600))
+	tokenURL, _ := url.Parse("file://" + path)
+
+	var gotJWTs []string
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/auth/k8s/login", r.URL.Path)
+		body := make(map[string]string)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, "quorum", body["role"])
+		gotJWTs = append(gotJWTs, body["jwt"])
+
+		b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: "token-" + body["jwt"], LeaseDuration: 60}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	c := testVaultClient(t, vault.URL)
+
+	k8s := config.VaultClientKubernetes{Role: "quorum", Path: "k8s", ServiceAccountToken: tokenURL}
+
+	r, err := c.authenticateWithKubernetes(k8s)
+	require.NoError(t, err)
+	require.Equal(t, "token-firstjwt", r.Auth.ClientToken)
+	ttl, _ := r.TokenTTL()
+	require.Equal(t, time.Minute, ttl)
+
+	// the kubelet rotates the projected token
+	require.NoError(t, ioutil.WriteFile(path, []byte("secondjwt\n"), 0600))
+
+	r, err = c.login(config.VaultClientAuthentication{Kubernetes: k8s})
+	require.NoError(t, err)
+	require.Equal(t, "token-secondjwt", r.Auth.ClientToken)
+	require.Equal(t, []string{"firstjwt", "secondjwt"}, gotJWTs)
+}
+
+func TestVaultClient_AuthenticateWithKubernetes_EmptyToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviceaccount")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(path, nil, 0600))
+	tokenURL, _ := url.Parse("file://" + path)
+
+	c := &vaultClient{}
+	_, err = c.authenticateWithKubernetes(config.VaultClientKubernetes{Role: "quorum", Path: "kubernetes", ServiceAccountToken: tokenURL})
+	require.EqualError(t, err, "service account token is empty")
+}
+
+func TestReloginAfter(t *testing.T) {
+	require.Equal(t, 40*time.Minute, reloginAfter(time.Hour))
+}
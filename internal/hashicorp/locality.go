@@ -0,0 +1,40 @@
+package hashicorp
+
+import "strings"
+
+// locality tiers, lower is preferred
+const (
+	sameZone = iota
+	sameRegion
+	otherLocality
+)
+
+// localityTier ranks the locality of a Vault cluster relative to this node.  Localities are of the form region/zone.
+// Clusters with no locality, or if this node has no locality, are ranked with clusters in other regions.
+func localityTier(node, cluster string) int {
+	if node == "" || cluster == "" {
+		return otherLocality
+	}
+	if node == cluster {
+		return sameZone
+	}
+	if region(node) == region(cluster) {
+		return sameRegion
+	}
+	return otherLocality
+}
+
+func region(locality string) string {
+	return strings.SplitN(locality, "/", 2)[0]
+}
+
+// setLocalities ranks each replica by its locality relative to node
+func (s *replicaSet) setLocalities(node string, localities map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.replicas {
+		r.locality = localities[r.Address()]
+		r.tier = localityTier(node, r.locality)
+	}
+}
@@ -0,0 +1,101 @@
+package hashicorp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalityTier(t *testing.T) {
+	require.Equal(t, sameZone, localityTier("eu-west-1/eu-west-1a", "eu-west-1/eu-west-1a"))
+	require.Equal(t, sameRegion, localityTier("eu-west-1/eu-west-1a", "eu-west-1/eu-west-1b"))
+	require.Equal(t, sameRegion, localityTier("eu-west-1/eu-west-1a", "eu-west-1"))
+	require.Equal(t, otherLocality, localityTier("eu-west-1/eu-west-1a", "us-east-1/us-east-1a"))
+	require.Equal(t, otherLocality, localityTier("eu-west-1/eu-west-1a", ""))
+	require.Equal(t, otherLocality, localityTier("", "eu-west-1/eu-west-1a"))
+}
+
+func TestReplicaSet_BestPrefersLocal(t *testing.T) {
+	newClient := func(address string) *api.Client {
+		return testVaultClient(t, address).Client
+	}
+	s := newReplicaSet(newClient("http://us:8200"), newClient("http://eu-b:8200"), newClient("http://eu-a:8200"))
+	s.setLocalities("eu/eu-a", map[string]string{
+		"http://us:8200":   "us/us-a",
+		"http://eu-b:8200": "eu/eu-b",
+		"http://eu-a:8200": "eu/eu-a",
+	})
+	us, euB, euA := s.replicas[0], s.replicas[1], s.replicas[2]
+
+	// the local replica is preferred even if it is slower
+	s.record(us, time.Millisecond, false)
+	s.record(euB, 10*time.Millisecond, false)
+	s.record(euA, 50*time.Millisecond, false)
+	require.Equal(t, euA, s.best())
+
+	require.Equal(t, "eu/eu-a", s.state()[2].Locality)
+
+	// replicas in the same tier are scored against each other
+	euA.tier = sameRegion
+	require.Equal(t, euB, s.best())
+}
+
+func TestReadReplica_PrimaryPreferredIfMoreLocal(t *testing.T) {
+	var primaryHits, replicaHits int
+	primary := secretServer(t, &primaryHits)
+	defer primary.Close()
+	replica := secretServer(t, &replicaHits)
+	defer replica.Close()
+
+	a := reconcileAccountManager(t, primary.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.client.SetToken("mytoken")
+
+	replicaURL, _ := url.Parse(replica.URL)
+	replicaClient, err := newAPIClient(replicaURL, config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}}, 0, nil)
+	require.NoError(t, err)
+	a.client.replicas = newReplicaSet(replicaClient)
+	a.client.replicas.setLocalities("eu/eu-a", map[string]string{replica.URL: "us/us-a"})
+	a.client.primaryTier = localityTier("eu/eu-a", "eu/eu-b")
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+
+	require.Equal(t, 1, primaryHits)
+	require.Equal(t, 0, replicaHits)
+}
+
+func TestReadReplica_NotYetReplicatedReadFromPrimary(t *testing.T) {
+	var primaryHits, replicaHits int
+	primary := secretServer(t, &primaryHits)
+	defer primary.Close()
+	// the replica has not yet received the secret version
+	replica := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replicaHits++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer replica.Close()
+
+	a := reconcileAccountManager(t, primary.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.client.SetToken("mytoken")
+
+	replicaURL, _ := url.Parse(replica.URL)
+	replicaClient, err := newAPIClient(replicaURL, config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}}, 0, nil)
+	require.NoError(t, err)
+	a.client.replicas = newReplicaSet(replicaClient)
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+
+	require.Equal(t, 1, replicaHits)
+	require.Equal(t, 1, primaryHits)
+}
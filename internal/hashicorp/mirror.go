@@ -0,0 +1,187 @@
+package hashicorp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+)
+
+var NotPromotedErr = errors.New("standby mirror has not been promoted to signer")
+
+const (
+	defaultMirrorPollInterval = 5 * time.Second
+	promotionSignerKey        = "signer" // the key in the promotion secret holding the name of the promoted node
+)
+
+// mirror is a read-only standby for a primary signer.  Wallets are reported as normal but the mirror refuses to unlock
+// or sign until the promotion secret names this node, so that at most one node signs with the shared keys.
+type mirror struct {
+	node   string
+	secret string
+	unlock []string // accounts to unlock once promoted
+
+	mu       sync.Mutex
+	promoted bool
+	signer   string // the node named in the promotion secret when last read
+}
+
+// newMirror returns nil if mirror mode is not configured
+func newMirror(conf config.VaultClient) *mirror {
+	if conf.Mirror.Node == "" {
+		return nil
+	}
+	return &mirror{node: conf.Mirror.Node, secret: conf.Mirror.PromotionSecret, unlock: conf.Unlock}
+}
+
+func (m *mirror) isPromoted() bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.promoted
+}
+
+// checkPromoted returns NotPromotedErr if the plugin is an unpromoted mirror
+func (a *accountManager) checkPromoted() error {
+	if !a.mirror.isPromoted() {
+		return NotPromotedErr
+	}
+	return nil
+}
+
+// startMirror follows the promotion secret every interval until the account manager is closed
+func (a *accountManager) startMirror(interval time.Duration) {
+	if interval == 0 {
+		interval = defaultMirrorPollInterval
+	}
+	supervise("mirror", a.client.stop, func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-a.client.stop:
+				return
+			case <-t.C:
+				a.followPrimary()
+			}
+		}
+	})
+}
+
+// followPrimary reads the promotion secret, promoting or demoting this node if it has changed, and keeps the read
+// cache warm so that a promoted mirror can sign without waiting on Vault
+func (a *accountManager) followPrimary() {
+	signer, err := a.promotedSigner()
+	if err != nil {
+		// keep the current state rather than risk two nodes signing
+		log.Printf("[WARN] unable to read mirror promotion secret %v: %v", a.mirror.secret, err)
+	} else {
+		a.setSigner(signer)
+	}
+
+	if a.cache != nil {
+		a.warmCache()
+	}
+}
+
+func (a *accountManager) promotedSigner() (string, error) {
+	// read replicas may not yet have the latest promotion
+	resp, err := a.secrets.read(withActiveNode(context.Background()), a.mirror.secret, 0)
+	if err != nil {
+		return "", err
+	}
+	if resp == nil {
+		// no node has been promoted
+		return "", nil
+	}
+	signer, _ := resp.Data[promotionSignerKey].(string)
+	return signer, nil
+}
+
+func (a *accountManager) setSigner(signer string) {
+	m := a.mirror
+	m.mu.Lock()
+	wasPromoted := m.promoted
+	m.signer = signer
+	m.promoted = signer == m.node
+	promoted := m.promoted
+	m.mu.Unlock()
+
+	switch {
+	case promoted && !wasPromoted:
+		log.Printf("[INFO] mirror %v promoted to signer", m.node)
+		event.Emit(event.MirrorPromoted, m.node, "")
+		for _, toUnlock := range m.unlock {
+			addr, err := account.NewAddressFromHexString(toUnlock)
+			if err != nil {
+				log.Printf("[INFO] unable to unlock %v, err = %v", toUnlock, err)
+				continue
+			}
+			if err := a.TimedUnlock(context.Background(), addr, 0); err != nil {
+				log.Printf("[INFO] unable to unlock %v, err = %v", toUnlock, err)
+			}
+		}
+	case !promoted && wasPromoted:
+		log.Printf("[WARN] mirror %v demoted, signer is now %q", m.node, signer)
+		event.Emit(event.MirrorDemoted, m.node, fmt.Sprintf("signer is now %q", signer))
+		a.lockAll()
+	}
+}
+
+// lockAll locks all unlocked accounts
+func (a *accountManager) lockAll() {
+	a.mu.Lock()
+	var addrs []string
+	for addr := range a.unlocked {
+		addrs = append(addrs, addr)
+	}
+	a.mu.Unlock()
+
+	for _, addr := range addrs {
+		if acctAddr, err := account.NewAddressFromHexString(addr); err == nil {
+			a.relock(acctAddr)
+		}
+	}
+}
+
+// warmCache reads the secret of every account into the read cache
+func (a *accountManager) warmCache() {
+	for _, acct := range a.client.accounts() {
+		conf := acct.Contents.VaultAccount
+		if _, err := a.readSecret(context.Background(), conf.SecretName, conf.SecretVersion); err != nil {
+			log.Printf("[DEBUG] unable to warm read cache for 0x%v: %v", acct.Contents.Address, err)
+		}
+	}
+}
+
+// Promote writes the promotion secret, naming node as the signer.  Mirrors follow the secret, so the previous signer
+// stops signing and node starts once they next read it.
+func (a *accountManager) Promote(secret, node string) error {
+	if secret == "" || node == "" {
+		return errors.New("promotion secret and node must be set")
+	}
+	_, err := a.secrets.write(secret, map[string]interface{}{promotionSignerKey: node}, nil)
+	return permissionDenied(err)
+}
+
+// mirrorStatus describes the mirror state for the plugin status
+func (m *mirror) status() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.promoted {
+		return fmt.Sprintf("mirror %v promoted to signer", m.node)
+	}
+	if m.signer == "" {
+		return fmt.Sprintf("standby mirror %v, no signer promoted", m.node)
+	}
+	return fmt.Sprintf("standby mirror %v, following signer %v", m.node, m.signer)
+}
@@ -0,0 +1,133 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// promotionServer serves the promotion secret naming the current signer, and the key of reconcileAddr1
+type promotionServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	signer string
+}
+
+func newPromotionServer(t *testing.T) *promotionServer {
+	s := &promotionServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]interface{}
+		switch r.URL.Path {
+		case "/v1/kv/data/signer":
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if r.Method == http.MethodPut {
+				body := make(map[string]map[string]string)
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				s.signer = body["data"]["signer"]
+				_, _ = w.Write([]byte(`{"data": {"version": 1}}`))
+				return
+			}
+			if s.signer == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			data = map[string]interface{}{"signer": s.signer}
+		case "/v1/kv/data/acct1":
+			data = map[string]interface{}{reconcileAddr1: reconcileKey1}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"data": data}})
+		_, _ = w.Write(b)
+	}))
+	return s
+}
+
+func mirrorAccountManager(t *testing.T, vaultURL string) *accountManager {
+	a := reconcileAccountManager(t, vaultURL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.mirror = newMirror(config.VaultClient{
+		Unlock: []string{reconcileAddr1},
+		Mirror: config.VaultClientMirror{Node: "node2", PromotionSecret: "signer"},
+	})
+	return a
+}
+
+func TestMirror_RefusesToSignUntilPromoted(t *testing.T) {
+	vault := newPromotionServer(t)
+	defer vault.Close()
+
+	a := mirrorAccountManager(t, vault.URL)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	a.followPrimary()
+	require.Equal(t, NotPromotedErr, a.TimedUnlock(context.Background(), addr, 0))
+	_, err := a.UnlockAndSign(context.Background(), addr, make([]byte, 32))
+	require.Equal(t, NotPromotedErr, err)
+	_, err = a.Sign(context.Background(), addr, make([]byte, 32))
+	require.Equal(t, NotPromotedErr, err)
+
+	// wallets are still reported
+	require.True(t, a.Contains(addr))
+
+	status, err := a.Status()
+	require.NoError(t, err)
+	require.Contains(t, status, "standby mirror node2, no signer promoted")
+
+	require.NoError(t, a.Promote("signer", "node1"))
+	a.followPrimary()
+	require.Equal(t, NotPromotedErr, a.checkPromoted())
+	require.Equal(t, "standby mirror node2, following signer node1", a.DebugState().Mirror)
+}
+
+func TestMirror_PromoteAndDemote(t *testing.T) {
+	vault := newPromotionServer(t)
+	defer vault.Close()
+
+	a := mirrorAccountManager(t, vault.URL)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	require.NoError(t, a.Promote("signer", "node2"))
+	a.followPrimary()
+
+	// the configured accounts are unlocked on promotion
+	require.Equal(t, 1, a.DebugState().UnlockedAccounts)
+	_, err := a.Sign(context.Background(), addr, make([]byte, 32))
+	require.NoError(t, err)
+
+	require.NoError(t, a.Promote("signer", "node1"))
+	a.followPrimary()
+
+	require.Equal(t, 0, a.DebugState().UnlockedAccounts)
+	_, err = a.Sign(context.Background(), addr, make([]byte, 32))
+	require.Equal(t, NotPromotedErr, err)
+}
+
+func TestMirror_KeepsStateIfPromotionSecretUnreadable(t *testing.T) {
+	vault := newPromotionServer(t)
+
+	a := mirrorAccountManager(t, vault.URL)
+	require.NoError(t, a.Promote("signer", "node2"))
+	a.followPrimary()
+	require.NoError(t, a.checkPromoted())
+
+	vault.Close()
+	a.followPrimary()
+	require.NoError(t, a.checkPromoted())
+}
+
+func TestMirror_NotConfigured(t *testing.T) {
+	var m *mirror
+	require.True(t, m.isPromoted())
+	require.Nil(t, newMirror(config.VaultClient{}))
+}
@@ -0,0 +1,109 @@
+package hashicorp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// NamespaceKey is the gRPC metadata key a caller names the Vault namespace to read account secrets from in
+const NamespaceKey = "quorum-vault-namespace"
+
+// NamespaceError is a request refused because the Vault namespace named in its metadata cannot be used
+type NamespaceError struct {
+	Namespace string
+	Reason    string
+}
+
+func (e *NamespaceError) Error() string {
+	return fmt.Sprintf("Vault namespace %q refused: %v", e.Namespace, e.Reason)
+}
+
+type namespaceKey struct{}
+
+// requestNamespaces checks the Vault namespaces named in request metadata against the configured allowlist
+type requestNamespaces struct {
+	allowed map[string]bool
+	peers   []string // nil if any peer can name a namespace
+}
+
+// newRequestNamespaces returns nil if requestNamespaces is not configured
+func newRequestNamespaces(conf config.VaultClientRequestNamespaces) *requestNamespaces {
+	if len(conf.Allowed) == 0 {
+		return nil
+	}
+	n := &requestNamespaces{allowed: make(map[string]bool, len(conf.Allowed))}
+	for _, ns := range conf.Allowed {
+		n.allowed[ns] = true
+	}
+	if len(conf.Peers) != 0 {
+		n.peers = conf.Peers
+	}
+	return n
+}
+
+// namespaceContext returns a copy of ctx carrying the namespace named in the NamespaceKey gRPC metadata, so that the
+// Vault reads made with it are sent to that namespace.  ctx is returned unchanged if no namespace is named.  A
+// NamespaceError is returned if the namespace is not allowed, or the peer that made the request cannot name one.
+func (n *requestNamespaces) namespaceContext(ctx context.Context) (context.Context, error) {
+	ns := namespaceFromMetadata(ctx)
+	if ns == "" {
+		return ctx, nil
+	}
+	refuse := func(reason string) error {
+		log.Printf("[WARN] refused request for Vault namespace %q: %v", ns, reason)
+		return &NamespaceError{Namespace: ns, Reason: reason}
+	}
+	if n == nil {
+		return nil, refuse("requestNamespaces is not configured")
+	}
+	// Vault accepts namespaces with or without surrounding slashes
+	ns = strings.Trim(ns, "/")
+	if !n.allowed[ns] {
+		return nil, refuse("not in requestNamespaces allowed")
+	}
+	if n.peers != nil {
+		var addr net.Addr
+		if pr, ok := peer.FromContext(ctx); ok {
+			addr = pr.Addr
+		}
+		if !config.PeerAllowed(n.peers, addr) {
+			return nil, refuse(fmt.Sprintf("peer %v is not in requestNamespaces peers", addr))
+		}
+	}
+	return context.WithValue(ctx, namespaceKey{}, ns), nil
+}
+
+func namespaceFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	vals := md.Get(NamespaceKey)
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+// requestNamespace returns the namespace carried by ctx, or an empty string if there is none
+func requestNamespace(ctx context.Context) string {
+	ns, _ := ctx.Value(namespaceKey{}).(string)
+	return ns
+}
+
+// namespacedPath returns the Vault API path of path in the namespace carried by ctx, if any.  Vault Enterprise accepts
+// the namespace as a prefix of the path, relative to the namespace of the client, as an alternative to the
+// X-Vault-Namespace header, which the client sets for every request.
+func namespacedPath(ctx context.Context, path string) string {
+	if ns := requestNamespace(ctx); ns != "" {
+		return ns + "/" + path
+	}
+	return path
+}
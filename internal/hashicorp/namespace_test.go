@@ -0,0 +1,120 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func namespaceMetadataContext(namespace, nodeID string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(NamespaceKey, namespace, audit.NodeIDKey, nodeID))
+}
+
+func TestRequestNamespaces_NamespaceContext(t *testing.T) {
+	n := newRequestNamespaces(config.VaultClientRequestNamespaces{Allowed: []string{"tenant1", "tenant2/team"}})
+
+	ctx, err := n.namespaceContext(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, requestNamespace(ctx))
+
+	for _, ns := range []string{"tenant1", "/tenant2/team/"} {
+		ctx, err := n.namespaceContext(namespaceMetadataContext(ns, "node1"))
+		require.NoError(t, err, ns)
+		require.NotEmpty(t, requestNamespace(ctx), ns)
+	}
+
+	_, err = n.namespaceContext(namespaceMetadataContext("tenant3", "node1"))
+	require.IsType(t, &NamespaceError{}, err)
+	require.EqualError(t, err, `Vault namespace "tenant3" refused: not in requestNamespaces allowed`)
+
+	// only the listed peers can name a namespace, whatever caller metadata they send
+	n = newRequestNamespaces(config.VaultClientRequestNamespaces{Allowed: []string{"tenant1"}, Peers: []string{"10.0.0.0/8"}})
+	fromPeer := func(ip string) context.Context {
+		return peer.NewContext(namespaceMetadataContext("tenant1", "node1"), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}})
+	}
+	_, err = n.namespaceContext(fromPeer("10.1.2.3"))
+	require.NoError(t, err)
+	_, err = n.namespaceContext(fromPeer("203.0.113.1"))
+	require.EqualError(t, err, `Vault namespace "tenant1" refused: peer 203.0.113.1:1234 is not in requestNamespaces peers`)
+	_, err = n.namespaceContext(namespaceMetadataContext("tenant1", "node1"))
+	require.EqualError(t, err, `Vault namespace "tenant1" refused: peer <nil> is not in requestNamespaces peers`)
+
+	// a namespace is never silently ignored
+	n = newRequestNamespaces(config.VaultClientRequestNamespaces{})
+	require.Nil(t, n)
+	_, err = n.namespaceContext(namespaceMetadataContext("tenant1", "node1"))
+	require.EqualError(t, err, `Vault namespace "tenant1" refused: requestNamespaces is not configured`)
+}
+
+func TestTimedUnlock_RequestNamespace(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{
+			"data": map[string]interface{}{reconcileAddr1: reconcileKey1},
+		}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.cache = newReadCache(10, 0)
+	a.namespaces = newRequestNamespaces(config.VaultClientRequestNamespaces{Allowed: []string{"tenant1"}})
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	// secrets read from a namespace are not cached, as the cache does not distinguish namespaces
+	require.NoError(t, a.TimedUnlock(namespaceMetadataContext("tenant1", "node1"), addr, 0))
+	require.NoError(t, a.TimedUnlock(namespaceMetadataContext("tenant1", "node1"), addr, 0))
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+	require.Equal(t, []string{"/v1/tenant1/kv/data/acct1", "/v1/tenant1/kv/data/acct1", "/v1/kv/data/acct1"}, paths)
+
+	err := a.TimedUnlock(namespaceMetadataContext("tenant2", "node1"), addr, 0)
+	require.IsType(t, &NamespaceError{}, err)
+	_, err = a.Sign(namespaceMetadataContext("tenant2", "node1"), addr, make([]byte, 32))
+	require.IsType(t, &NamespaceError{}, err)
+	require.Len(t, paths, 3)
+}
+
+func TestUnlockAndSign_UnlockedAccountRefusesNamespace(t *testing.T) {
+	var reads int
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reads++
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{
+			"data": map[string]interface{}{reconcileAddr1: reconcileKey1},
+		}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.namespaces = newRequestNamespaces(config.VaultClientRequestNamespaces{Allowed: []string{"tenant1"}})
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+
+	_, err := a.UnlockAndSign(namespaceMetadataContext("tenant2", "node1"), addr, make([]byte, 32))
+	require.IsType(t, &NamespaceError{}, err)
+
+	_, err = a.UnlockAndSign(namespaceMetadataContext("tenant1", "node1"), addr, make([]byte, 32))
+	require.NoError(t, err)
+	require.Equal(t, 1, reads)
+}
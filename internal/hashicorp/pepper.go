@@ -0,0 +1,287 @@
+package hashicorp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// pepperedPrefix marks a secret value holding the account's key XORed with a mask derived from the pepper, rather
+	// than the key itself
+	pepperedPrefix = "peppered:"
+	// pepperInfo is the HKDF context, so that masks are not reused if the pepper is also used for something else
+	pepperInfo = "quorum-account-plugin-hashicorp-vault pepper"
+	// pepperedKeyLen is the length in bytes of a secp256k1 private key
+	pepperedKeyLen = 32
+)
+
+// PepperReport describes the accounts migrated from plain keys to peppered keys
+type PepperReport struct {
+	// Migrated are accounts whose key was written to a new, peppered, version of their secret, or would be if
+	// confirmed
+	Migrated []PepperedAccount
+	// Skipped are accounts that were not migrated, e.g. because they are already peppered or hold a keystore
+	Skipped []SkippedAccount
+}
+
+type PepperedAccount struct {
+	Address     string
+	URL         string
+	SecretName  string
+	FromVersion int64
+	ToVersion   int64 `json:",omitempty"` // 0 if the migration was not confirmed
+}
+
+type SkippedAccount struct {
+	Address       string
+	URL           string
+	SecretName    string
+	SecretVersion int64
+	Reason        string
+}
+
+func isPeppered(value string) bool {
+	return strings.HasPrefix(value, pepperedPrefix)
+}
+
+// pepperMask derives the mask for the account's key from the pepper using HKDF-SHA256.  The address is the salt, so each
+// account has a different mask.
+func pepperMask(pepper *config.EnvironmentVariable, addr string) ([]byte, error) {
+	if pepper == nil {
+		return nil, errors.New("secret holds a peppered key but no pepper is configured")
+	}
+	p := pepper.Get()
+	if p == "" {
+		return nil, fmt.Errorf("pepper %v is not set", pepper)
+	}
+	salt, err := hex.DecodeString(config.NormalizeAddress(addr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid account address %v: %v", addr, err)
+	}
+
+	mask := make([]byte, pepperedKeyLen)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(p), salt, []byte(pepperInfo)), mask); err != nil {
+		return nil, err
+	}
+	return mask, nil
+}
+
+// xorKeyHex returns the hex-encoded XOR of the hex-encoded key with mask
+func xorKeyHex(keyHex string, mask []byte) (string, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(keyHex, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid hex private key: %v", err)
+	}
+	defer zero(b)
+	if len(b) != len(mask) {
+		return "", fmt.Errorf("private key must have length %v bytes", len(mask))
+	}
+	for i := range b {
+		b[i] ^= mask[i]
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// pepperKeyHex returns the value to store in Vault for the account's hex-encoded key, which is the key itself if no
+// pepper is configured
+func pepperKeyHex(keyHex, addr string, pepper *config.EnvironmentVariable) (string, error) {
+	if pepper == nil {
+		return keyHex, nil
+	}
+	mask, err := pepperMask(pepper, addr)
+	if err != nil {
+		return "", err
+	}
+	defer zero(mask)
+
+	masked, err := xorKeyHex(keyHex, mask)
+	if err != nil {
+		return "", err
+	}
+	return pepperedPrefix + masked, nil
+}
+
+// unpepperKey returns the account's key from the peppered value stored in Vault.  A different pepper to the one the
+// key was stored with gives a different key, so the key is checked against the address.
+func unpepperKey(value, addr string, pepper *config.EnvironmentVariable) (*ecdsa.PrivateKey, error) {
+	mask, err := pepperMask(pepper, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(mask)
+
+	keyHex, err := xorKeyHex(strings.TrimPrefix(value, pepperedPrefix), mask)
+	if err != nil {
+		return nil, err
+	}
+	key, err := account.NewKeyFromHexString(keyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	keyAddr, err := account.PrivateKeyToAddress(key)
+	if err != nil {
+		zeroKey(key)
+		return nil, err
+	}
+	if keyAddr.ToHexString() != config.NormalizeAddress(addr) {
+		zeroKey(key)
+		return nil, fmt.Errorf("peppered key is not the key for account address %v, check the configured pepper", addr)
+	}
+	return key, nil
+}
+
+// PepperAccounts migrates accounts whose secrets hold a plain hex-encoded key to peppered keys.  The peppered key is
+// written as a new version of the account's secret and the account config is updated to reference it, so the previous
+// version remains in Vault until it is deleted (e.g. with gc).  Nothing is written unless confirm is true.
+func (a *accountManager) PepperAccounts(confirm bool) (PepperReport, error) {
+	if a.pepper == nil {
+		return PepperReport{}, errors.New("pepper must be configured")
+	}
+	store, ok := a.client.store.(*dirStore)
+	if !ok {
+		return PepperReport{}, fmt.Errorf("account configs are stored in %v, not an accountDirectory", a.client.store)
+	}
+
+	accts := a.client.accounts()
+	urls := make([]string, 0, len(accts))
+	byURL := make(map[string]config.AccountFile, len(accts))
+	for u, acct := range accts {
+		urls = append(urls, u.String())
+		byURL[u.String()] = acct
+	}
+	sort.Strings(urls)
+
+	var report PepperReport
+	for _, u := range urls {
+		acct := byURL[u]
+		conf := acct.Contents.VaultAccount
+		addr := config.NormalizeAddress(acct.Contents.Address)
+
+		keyHex, reason := a.plainKeyHex(acct)
+		if reason != "" {
+			report.Skipped = append(report.Skipped, SkippedAccount{
+				Address:       fmt.Sprintf("0x%v", addr),
+				URL:           u,
+				SecretName:    conf.SecretName,
+				SecretVersion: conf.SecretVersion,
+				Reason:        reason,
+			})
+			continue
+		}
+
+		migrated := PepperedAccount{
+			Address:     fmt.Sprintf("0x%v", addr),
+			URL:         u,
+			SecretName:  conf.SecretName,
+			FromVersion: conf.SecretVersion,
+		}
+		if confirm {
+			version, err := a.writePepperedKey(store, acct, keyHex)
+			if err != nil {
+				return report, fmt.Errorf("unable to migrate account %v: %v", migrated.Address, err)
+			}
+			migrated.ToVersion = version
+			log.Printf("[INFO] account %v migrated to peppered key in secret %v version %v", migrated.Address, conf.SecretName, version)
+		}
+		report.Migrated = append(report.Migrated, migrated)
+	}
+
+	if confirm && len(report.Migrated) != 0 {
+		result, err := a.client.loadAccounts()
+		if err != nil {
+			return report, fmt.Errorf("error reloading account configs from %v: %v", a.client.store, err)
+		}
+		a.client.setAccounts(result)
+	}
+	return report, nil
+}
+
+// plainKeyHex returns the hex-encoded key held in the account's secret, or the reason the account cannot be migrated
+func (a *accountManager) plainKeyHex(acct config.AccountFile) (string, string) {
+	conf := acct.Contents.VaultAccount
+
+	respData, err := a.readSecret(context.Background(), conf.SecretName, conf.SecretVersion)
+	if err != nil {
+		return "", fmt.Sprintf("unable to read secret: %v", err)
+	}
+	if _, ok := respData[keystoreField]; ok {
+		return "", "secret holds a keystore"
+	}
+	if conf.SecretKey != "" || len(respData) != 1 {
+		// writing a peppered key as a new version would drop the other fields of the secret
+		return "", "secret holds other fields alongside the key"
+	}
+	v, _ := secretValue(respData, acct.Contents.Address)
+	keyHex, ok := v.(string)
+	if !ok {
+		return "", "secret does not hold a hex-encoded key for the account"
+	}
+	if isPeppered(keyHex) {
+		return "", "already peppered"
+	}
+
+	key, err := account.NewKeyFromHexString(keyHex)
+	if err != nil {
+		return "", err.Error()
+	}
+	defer zeroKey(key)
+	keyAddr, err := account.PrivateKeyToAddress(key)
+	if err != nil {
+		return "", err.Error()
+	}
+	if keyAddr.ToHexString() != config.NormalizeAddress(acct.Contents.Address) {
+		return "", fmt.Sprintf("secret holds the key for a different address 0x%v", keyAddr.ToHexString())
+	}
+	return keyHex, ""
+}
+
+// writePepperedKey writes the peppered key as a new version of the account's secret and updates the account config to
+// reference it, returning the new version
+func (a *accountManager) writePepperedKey(store *dirStore, acct config.AccountFile, keyHex string) (int64, error) {
+	conf := acct.Contents.VaultAccount
+	addr := config.NormalizeAddress(acct.Contents.Address)
+
+	peppered, err := pepperKeyHex(keyHex, addr, a.pepper)
+	if err != nil {
+		return 0, err
+	}
+
+	// the write fails if another version has been written since the secret's versions were read
+	live, err := a.liveVersions(conf.SecretName)
+	if err != nil {
+		return 0, err
+	}
+	if len(live) == 0 {
+		return 0, fmt.Errorf("secret %v has no live versions", conf.SecretName)
+	}
+	cas := live[len(live)-1]
+	version, err := a.secrets.write(conf.SecretName, map[string]interface{}{addr: peppered}, &cas)
+	if err != nil {
+		return 0, permissionDenied(err)
+	}
+	a.cache.invalidateAll(a.secrets.location(conf.SecretName))
+
+	acct.Contents.VaultAccount.SecretVersion = version
+	contents, err := json.Marshal(acct.Contents)
+	if err != nil {
+		return 0, err
+	}
+	if err := store.replace(acct.Path, contents); err != nil {
+		return 0, fmt.Errorf("secret %v version %v written but unable to update account config %v: %v", conf.SecretName, version, acct.Path, err)
+	}
+	return version, nil
+}
@@ -0,0 +1,133 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testPepper(t *testing.T, env, value string) *config.EnvironmentVariable {
+	require.NoError(t, os.Setenv(env, value))
+	pepper := config.EnvironmentVariable(url.URL{Scheme: "env", Host: env})
+	return &pepper
+}
+
+func TestPepperKeyHex(t *testing.T) {
+	defer os.Unsetenv("PEPPER_TEST_PEPPER")
+	defer os.Unsetenv("PEPPER_TEST_OTHER")
+	pepper := testPepper(t, "PEPPER_TEST_PEPPER", "0123456789abcdef0123456789abcdef")
+
+	peppered, err := pepperKeyHex(reconcileKey1, reconcileAddr1, pepper)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(peppered, "peppered:"))
+	require.NotContains(t, peppered, reconcileKey1)
+
+	// each account has a different mask
+	other, err := pepperKeyHex(reconcileKey1, reconcileAddr2, pepper)
+	require.NoError(t, err)
+	require.NotEqual(t, peppered, other)
+
+	key, err := keyFromSecret(map[string]interface{}{reconcileAddr1: peppered}, reconcileAddr1, pepper)
+	require.NoError(t, err)
+	got, err := account.PrivateKeyToHexString(key)
+	require.NoError(t, err)
+	require.Equal(t, reconcileKey1, got)
+
+	// neither the secret nor the pepper alone is enough to recover the key
+	_, err = keyFromSecret(map[string]interface{}{reconcileAddr1: peppered}, reconcileAddr1, nil)
+	require.EqualError(t, err, "secret holds a peppered key but no pepper is configured")
+
+	wrongPepper := testPepper(t, "PEPPER_TEST_OTHER", "fedcba9876543210fedcba9876543210")
+	_, err = keyFromSecret(map[string]interface{}{reconcileAddr1: peppered}, reconcileAddr1, wrongPepper)
+	require.EqualError(t, err, "peppered key is not the key for account address "+reconcileAddr1+", check the configured pepper")
+
+	// keys are stored as-is if no pepper is configured
+	plain, err := pepperKeyHex(reconcileKey1, reconcileAddr1, nil)
+	require.NoError(t, err)
+	require.Equal(t, reconcileKey1, plain)
+}
+
+func TestAccountManager_PepperAccounts(t *testing.T) {
+	defer os.Unsetenv("PEPPER_TEST_PEPPER")
+
+	dir, err := ioutil.TempDir("", "pepper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dirURL, _ := url.Parse("file://" + dir + "/")
+
+	am, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL})
+	require.NoError(t, err)
+	a := am.(*accountManager)
+
+	key1, _ := account.NewKeyFromHexString(reconcileKey1)
+	acct1, err := a.ImportPrivateKey(key1, config.NewAccount{SecretName: "acct1"})
+	require.NoError(t, err)
+
+	_, err = a.PepperAccounts(false)
+	require.EqualError(t, err, "pepper must be configured")
+
+	// the pepper is configured after the account was created with a plain key
+	a.pepper = testPepper(t, "PEPPER_TEST_PEPPER", "0123456789abcdef0123456789abcdef")
+
+	report, err := a.PepperAccounts(false)
+	require.NoError(t, err)
+	require.Len(t, report.Migrated, 1)
+	require.Equal(t, PepperedAccount{Address: "0x" + reconcileAddr1, URL: acct1.URL.String(), SecretName: "acct1", FromVersion: 1}, report.Migrated[0])
+
+	// nothing is written unless confirmed
+	resp, err := a.secrets.read(context.Background(), "acct1", 0)
+	require.NoError(t, err)
+	require.Equal(t, reconcileKey1, resp.Data[reconcileAddr1])
+
+	report, err = a.PepperAccounts(true)
+	require.NoError(t, err)
+	require.Len(t, report.Migrated, 1)
+	require.Equal(t, int64(2), report.Migrated[0].ToVersion)
+
+	resp, err = a.secrets.read(context.Background(), "acct1", 2)
+	require.NoError(t, err)
+	require.True(t, isPeppered(resp.Data[reconcileAddr1].(string)))
+
+	// the account config is updated to reference the peppered version
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	b, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	var contents config.AccountFileJSON
+	require.NoError(t, json.Unmarshal(b, &contents))
+	require.Equal(t, int64(2), contents.VaultAccount.SecretVersion)
+
+	addr1, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr1, 0))
+	a.Lock(addr1)
+
+	// new accounts are peppered when created
+	key2, _ := account.NewKeyFromHexString(reconcileKey2)
+	_, err = a.ImportPrivateKey(key2, config.NewAccount{SecretName: "acct2"})
+	require.NoError(t, err)
+	resp, err = a.secrets.read(context.Background(), "acct2", 0)
+	require.NoError(t, err)
+	require.True(t, isPeppered(resp.Data[reconcileAddr2].(string)))
+
+	addr2, _ := account.NewAddressFromHexString(reconcileAddr2)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr2, 0))
+	a.Lock(addr2)
+
+	report, err = a.PepperAccounts(true)
+	require.NoError(t, err)
+	require.Empty(t, report.Migrated)
+	require.Len(t, report.Skipped, 2)
+	for _, s := range report.Skipped {
+		require.Equal(t, "already peppered", s.Reason)
+	}
+}
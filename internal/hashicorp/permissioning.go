@@ -0,0 +1,194 @@
+package hashicorp
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	defaultPermissioningCacheTTL = 10 * time.Second
+	permissioningRequestTimeout  = 10 * time.Second
+)
+
+// account statuses in the Quorum permissioning AccountManager contract that refuse signing
+var suspendedAccountStatuses = map[uint64]string{
+	4: "suspended",
+	5: "blacklisted",
+	6: "revoked",
+	7: "blacklisted pending recovery",
+}
+
+// getAccountStatusSelector is the function selector of AccountManager.getAccountStatus(address)
+var getAccountStatusSelector = func() []byte {
+	d := sha3.NewLegacyKeccak256()
+	d.Write([]byte("getAccountStatus(address)"))
+	return d.Sum(nil)[:4]
+}()
+
+// AccountSuspendedError is a signing request refused because the account has been suspended or blacklisted by the
+// Quorum network's permissioning contracts
+type AccountSuspendedError struct {
+	Address string
+	Status  string
+}
+
+func (e *AccountSuspendedError) Error() string {
+	return fmt.Sprintf("account 0x%v is %v by Quorum permissioning", e.Address, e.Status)
+}
+
+// quorumPermissioning checks the on-chain status of accounts before signing, so that the signer stays aligned with
+// network-level governance
+type quorumPermissioning struct {
+	client         *http.Client
+	rpc            string
+	accountManager string
+	ttl            time.Duration
+	mu             sync.Mutex
+	statuses       map[string]cachedAccountStatus
+}
+
+type cachedAccountStatus struct {
+	status  uint64
+	expires time.Time
+}
+
+// newQuorumPermissioning returns nil if quorumPermissioning is not configured
+func newQuorumPermissioning(conf config.VaultClientQuorumPermissioning) *quorumPermissioning {
+	if conf.RPC == nil {
+		return nil
+	}
+	ttl := conf.CacheTTL
+	if ttl == 0 {
+		ttl = defaultPermissioningCacheTTL
+	}
+	return &quorumPermissioning{
+		client:         &http.Client{Timeout: permissioningRequestTimeout},
+		rpc:            conf.RPC.String(),
+		accountManager: "0x" + strings.TrimPrefix(strings.ToLower(conf.AccountManager), "0x"),
+		ttl:            ttl,
+		statuses:       make(map[string]cachedAccountStatus),
+	}
+}
+
+// check returns an AccountSuspendedError if the account may not sign.  Signing is also refused if the status cannot be
+// determined, as the account may have been suspended.
+func (p *quorumPermissioning) check(ctx context.Context, addr account.Address) error {
+	if p == nil {
+		return nil
+	}
+	hexAddr := addr.ToHexString()
+	status, err := p.status(ctx, hexAddr)
+	if err != nil {
+		return fmt.Errorf("unable to check Quorum permissioning status of account 0x%v: %v", hexAddr, err)
+	}
+	if name, suspended := suspendedAccountStatuses[status]; suspended {
+		err := &AccountSuspendedError{Address: hexAddr, Status: name}
+		log.Printf("[WARN] refused signing request: %v", err)
+		return err
+	}
+	return nil
+}
+
+// status returns the account's status, from the cache if it has not expired
+func (p *quorumPermissioning) status(ctx context.Context, hexAddr string) (uint64, error) {
+	p.mu.Lock()
+	cached, ok := p.statuses[hexAddr]
+	p.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.status, nil
+	}
+
+	status, err := p.getAccountStatus(ctx, hexAddr)
+	if err != nil {
+		return 0, err
+	}
+	p.mu.Lock()
+	p.statuses[hexAddr] = cachedAccountStatus{status: status, expires: time.Now().Add(p.ttl)}
+	p.mu.Unlock()
+	return status, nil
+}
+
+type jsonRPCRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type jsonRPCResponse struct {
+	Result string `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type ethCallArgs struct {
+	To   string `json:"to"`
+	Data string `json:"data"`
+}
+
+// getAccountStatus calls AccountManager.getAccountStatus(address) on the Quorum node
+func (p *quorumPermissioning) getAccountStatus(ctx context.Context, hexAddr string) (uint64, error) {
+	// the address argument is left-padded to 32 bytes
+	data := "0x" + hex.EncodeToString(getAccountStatusSelector) + strings.Repeat("0", 24) + hexAddr
+
+	body, err := json.Marshal(jsonRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "eth_call",
+		Params:  []interface{}{ethCallArgs{To: p.accountManager, Data: data}, "latest"},
+	})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.rpc, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected response from %v: %v", p.rpc, resp.Status)
+	}
+
+	var rpcResp jsonRPCResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return 0, fmt.Errorf("unable to decode JSON-RPC response: %v", err)
+	}
+	if rpcResp.Error != nil {
+		return 0, fmt.Errorf("eth_call failed: %v", rpcResp.Error.Message)
+	}
+	return decodeUint256(rpcResp.Result)
+}
+
+// decodeUint256 decodes an ABI-encoded uint256 return value
+func decodeUint256(result string) (uint64, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil || len(b) != 32 {
+		return 0, fmt.Errorf("invalid uint256 result %q, is accountManager the address of the permissioning AccountManager contract?", result)
+	}
+	n := new(big.Int).SetBytes(b)
+	if !n.IsUint64() {
+		return 0, fmt.Errorf("uint256 result %v out of range", n)
+	}
+	return n.Uint64(), nil
+}
@@ -0,0 +1,116 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+const testAccountManager = "0x1932C48B2BF8102BA33B4A6B545C32236E342F34"
+
+// testQuorumNode serves eth_call requests for AccountManager.getAccountStatus, returning the status in statuses
+func testQuorumNode(t *testing.T, statuses map[string]uint64, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		var req jsonRPCRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "eth_call", req.Method)
+
+		args := req.Params[0].(map[string]interface{})
+		require.Equal(t, strings.ToLower(testAccountManager), args["to"])
+		data := args["data"].(string)
+		require.Equal(t, "0xfd4fa05a", data[:10])
+		require.Len(t, data, 2+8+64)
+
+		status, ok := statuses[data[len(data)-40:]]
+		if !ok {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"execution reverted"}}`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":"0x%064x"}`, status)
+	}))
+}
+
+func testPermissioning(t *testing.T, node *httptest.Server, ttl time.Duration) *quorumPermissioning {
+	rpc, err := url.Parse(node.URL)
+	require.NoError(t, err)
+	return newQuorumPermissioning(config.VaultClientQuorumPermissioning{RPC: rpc, AccountManager: testAccountManager, CacheTTL: ttl})
+}
+
+func TestQuorumPermissioning_Check(t *testing.T) {
+	active, _ := account.NewAddressFromHexString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	suspended, _ := account.NewAddressFromHexString("4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+	blacklisted, _ := account.NewAddressFromHexString("6038dc01869425004ca0b8370f6c81cf464213b3")
+	unknown, _ := account.NewAddressFromHexString("2fe95d1a3e1f9b6c6e7a1a3e1f9b6c6e7a1a3e1f")
+
+	var calls int
+	node := testQuorumNode(t, map[string]uint64{
+		active.ToHexString():      2,
+		suspended.ToHexString():   4,
+		blacklisted.ToHexString(): 5,
+	}, &calls)
+	defer node.Close()
+
+	p := testPermissioning(t, node, 0)
+
+	require.NoError(t, p.check(context.Background(), active))
+
+	err := p.check(context.Background(), suspended)
+	require.IsType(t, &AccountSuspendedError{}, err)
+	require.EqualError(t, err, "account 0x4d6d744b6da435b5bbdde2526dc20e9a41cb72e5 is suspended by Quorum permissioning")
+
+	err = p.check(context.Background(), blacklisted)
+	require.EqualError(t, err, "account 0x6038dc01869425004ca0b8370f6c81cf464213b3 is blacklisted by Quorum permissioning")
+
+	// signing is refused if the status cannot be determined
+	err = p.check(context.Background(), unknown)
+	require.EqualError(t, err, "unable to check Quorum permissioning status of account 0x2fe95d1a3e1f9b6c6e7a1a3e1f9b6c6e7a1a3e1f: eth_call failed: execution reverted")
+}
+
+func TestQuorumPermissioning_CachesStatus(t *testing.T) {
+	addr, _ := account.NewAddressFromHexString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+
+	var calls int
+	statuses := map[string]uint64{addr.ToHexString(): 2}
+	node := testQuorumNode(t, statuses, &calls)
+	defer node.Close()
+
+	p := testPermissioning(t, node, time.Hour)
+	require.NoError(t, p.check(context.Background(), addr))
+	require.NoError(t, p.check(context.Background(), addr))
+	require.Equal(t, 1, calls)
+
+	// the account is suspended on-chain, which is seen once the cached status expires
+	statuses[addr.ToHexString()] = 4
+	p.statuses[addr.ToHexString()] = cachedAccountStatus{status: 2, expires: time.Now().Add(-time.Second)}
+	require.IsType(t, &AccountSuspendedError{}, p.check(context.Background(), addr))
+	require.Equal(t, 2, calls)
+}
+
+func TestQuorumPermissioning_NotConfigured(t *testing.T) {
+	p := newQuorumPermissioning(config.VaultClientQuorumPermissioning{})
+	require.Nil(t, p)
+	require.NoError(t, p.check(context.Background(), account.Address{}))
+}
+
+func TestDecodeUint256(t *testing.T) {
+	got, err := decodeUint256("0x" + strings.Repeat("0", 63) + "7")
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), got)
+
+	_, err = decodeUint256("0x")
+	require.Error(t, err)
+
+	_, err = decodeUint256("0x" + strings.Repeat("f", 64))
+	require.Error(t, err)
+}
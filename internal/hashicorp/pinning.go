@@ -0,0 +1,66 @@
+package hashicorp
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+)
+
+var pinMismatchErr = errors.New("Vault server certificate does not match any pinned fingerprint")
+
+// chainVerifier verifies the certificates presented by the Vault server, returning the verified chains
+type chainVerifier func(rawCerts [][]byte) ([][]*x509.Certificate, error)
+
+// pinCertificates additionally requires that one of the certificates in the verified chain of the Vault server, or its
+// public key, matches one of the pinned SHA-256 fingerprints.  This is checked after the usual CA validation, so
+// protects against a compromised CA issuing a certificate for the Vault server.  Only the verified chains are checked,
+// as the server can present any certificates, e.g. the real Vault server's certificate appended to a forged chain.
+// verifyChains is the verification used instead of Go's default verification, if any (see tlsReloader).
+func pinCertificates(tlsConf *tls.Config, pins []string, verifyChains chainVerifier) {
+	if len(pins) == 0 {
+		return
+	}
+	pinned := make(map[string]bool)
+	for _, p := range pins {
+		pinned[p] = true
+	}
+
+	if verifyChains != nil {
+		tlsConf.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			chains, err := verifyChains(rawCerts)
+			if err != nil {
+				return err
+			}
+			return checkPins(chains, pinned)
+		}
+		return
+	}
+
+	verify := tlsConf.VerifyPeerCertificate
+	tlsConf.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if verify != nil {
+			if err := verify(rawCerts, verifiedChains); err != nil {
+				return err
+			}
+		}
+		return checkPins(verifiedChains, pinned)
+	}
+}
+
+func checkPins(verifiedChains [][]*x509.Certificate, pinned map[string]bool) error {
+	for _, chain := range verifiedChains {
+		for _, c := range chain {
+			certFingerprint := sha256.Sum256(c.Raw)
+			if pinned[hex.EncodeToString(certFingerprint[:])] {
+				return nil
+			}
+			keyFingerprint := sha256.Sum256(c.RawSubjectPublicKeyInfo)
+			if pinned[hex.EncodeToString(keyFingerprint[:])] {
+				return nil
+			}
+		}
+	}
+	return pinMismatchErr
+}
@@ -0,0 +1,135 @@
+package hashicorp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func serverCertChain(t *testing.T) [][]byte {
+	b, err := ioutil.ReadFile(testServerCert)
+	require.NoError(t, err)
+	var rawCerts [][]byte
+	for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+		rawCerts = append(rawCerts, block.Bytes)
+	}
+	return rawCerts
+}
+
+func pin(raw []byte) string {
+	fingerprint := sha256.Sum256(raw)
+	return hex.EncodeToString(fingerprint[:])
+}
+
+// forgedServerCert returns a certificate for localhost issued by a new CA, as a compromised CA could, and the CA
+func forgedServerCert(t *testing.T) (leaf []byte, ca *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "compromised CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caRaw, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err = x509.ParseCertificate(caRaw)
+	require.NoError(t, err)
+
+	leaf, err = x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, &key.PublicKey, key)
+	require.NoError(t, err)
+	return leaf, ca
+}
+
+func TestCheckPins(t *testing.T) {
+	r := &tlsReloader{caCert: testCACert, serverName: "localhost"}
+	_, err := r.reload()
+	require.NoError(t, err)
+	rawCerts := serverCertChain(t)
+	chains, err := r.verifyChains(rawCerts)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(rawCerts[0])
+	require.NoError(t, err)
+
+	for name, p := range map[string]string{"certificate": pin(rawCerts[0]), "public key": pin(leaf.RawSubjectPublicKeyInfo), "intermediate": pin(rawCerts[1])} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, checkPins(chains, map[string]bool{p: true}))
+		})
+	}
+
+	require.Equal(t, pinMismatchErr, checkPins(chains, map[string]bool{pin([]byte("other")): true}))
+	require.Equal(t, pinMismatchErr, checkPins(nil, map[string]bool{pin(rawCerts[0]): true}))
+}
+
+func TestPinCertificates_ForgedChainIncludingPinnedCert(t *testing.T) {
+	realCert := serverCertChain(t)[0]
+	forged, ca := forgedServerCert(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	// the forged chain verifies against the compromised CA, and also presents the pinned certificate
+	rawCerts := [][]byte{forged, realCert}
+
+	// the TLS files are reloaded
+	r := &tlsReloader{serverName: "localhost", roots: roots}
+	tlsConf := &tls.Config{}
+	pinCertificates(tlsConf, []string{pin(realCert)}, r.verifyChains)
+	require.Equal(t, pinMismatchErr, tlsConf.VerifyPeerCertificate(rawCerts, nil))
+
+	// Go verified the chain
+	verifiedChains, err := r.verifyChains(rawCerts)
+	require.NoError(t, err)
+	tlsConf = &tls.Config{}
+	pinCertificates(tlsConf, []string{pin(realCert)}, nil)
+	require.Equal(t, pinMismatchErr, tlsConf.VerifyPeerCertificate(rawCerts, verifiedChains))
+
+	// the real Vault server's chain is accepted
+	r = &tlsReloader{caCert: testCACert, serverName: "localhost"}
+	_, err = r.reload()
+	require.NoError(t, err)
+	tlsConf = &tls.Config{}
+	pinCertificates(tlsConf, []string{pin(realCert)}, r.verifyChains)
+	require.NoError(t, tlsConf.VerifyPeerCertificate(serverCertChain(t), nil))
+}
+
+func TestPinCertificates_RunsExistingVerificationFirst(t *testing.T) {
+	rawCerts := serverCertChain(t)
+
+	verifyErr := errors.New("untrusted")
+	tlsConf := &tls.Config{
+		VerifyPeerCertificate: func(_ [][]byte, _ [][]*x509.Certificate) error { return verifyErr },
+	}
+	pinCertificates(tlsConf, []string{pin(rawCerts[0])}, nil)
+
+	require.Equal(t, verifyErr, tlsConf.VerifyPeerCertificate(rawCerts, nil))
+}
+
+func TestPinCertificates_NoPins(t *testing.T) {
+	tlsConf := &tls.Config{}
+	pinCertificates(tlsConf, nil, nil)
+
+	require.Nil(t, tlsConf.VerifyPeerCertificate)
+}
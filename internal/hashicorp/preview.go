@@ -0,0 +1,15 @@
+package hashicorp
+
+import (
+	"log"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+)
+
+// previewSign is the result of a signing request made with the audit.SignPreviewKey metadata once every check has
+// passed and the key is available: an empty signature.  A preview is not counted as a signature in the account's signing
+// history, signing latency or usage, and does not use up a sign grant.
+func previewSign(acctAddr account.Address) []byte {
+	log.Printf("[INFO] signing preview for 0x%v: request would be signed", acctAddr.ToHexString())
+	return []byte{}
+}
@@ -0,0 +1,64 @@
+package hashicorp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func previewContext(kv ...string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(append([]string{audit.SignPreviewKey, "true"}, kv...)...))
+}
+
+func TestAccountManager_SignPreview(t *testing.T) {
+	defer os.Unsetenv("SIGN_GRANT_TEST_KEY")
+	dir, err := ioutil.TempDir("", "preview")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dirURL, _ := url.Parse("file://" + dir + "/")
+
+	am, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL, SignGrants: testSignGrantsConfig(t)})
+	require.NoError(t, err)
+	a := am.(*accountManager)
+
+	key, _ := account.NewKeyFromHexString(reconcileKey1)
+	_, err = a.ImportPrivateKey(key, config.NewAccount{SecretName: "acct1"})
+	require.NoError(t, err)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	toSign := make([]byte, 32)
+	grant, err := IssueSignGrant(testSignGrantKey, addr, toSign, time.Minute)
+	require.NoError(t, err)
+
+	// the checks are made as for a signing request
+	_, err = a.Sign(previewContext(), addr, toSign)
+	require.IsType(t, &SignGrantError{}, err)
+	_, err = a.Sign(previewContext(SignGrantKey, grant), addr, toSign)
+	require.EqualError(t, err, "account locked")
+
+	// the key is read from Vault, but nothing is signed and the account stays locked
+	sig, err := a.UnlockAndSign(previewContext(SignGrantKey, grant), addr, toSign)
+	require.NoError(t, err)
+	require.Empty(t, sig)
+	require.Equal(t, 0, a.DebugState().UnlockedAccounts)
+
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+	sig, err = a.Sign(previewContext(SignGrantKey, grant), addr, toSign)
+	require.NoError(t, err)
+	require.Empty(t, sig)
+
+	// previews do not use up the grant
+	sig, err = a.Sign(grantContext(grant), addr, toSign)
+	require.NoError(t, err)
+	require.Len(t, sig, 65)
+	_, err = a.Sign(previewContext(SignGrantKey, grant), addr, toSign)
+	require.EqualError(t, err, "signing with account 0x"+reconcileAddr1+" requires a sign grant: grant has already been used")
+}
@@ -0,0 +1,137 @@
+package hashicorp
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+// defaultProbeFailureThreshold is the number of consecutive failed probes before wallets are dropped, if not configured
+const defaultProbeFailureThreshold = 3
+
+// connectivityProbe tracks the result of periodic Vault health checks.  Locked accounts cannot be used for signing
+// while Vault is unreachable, so their wallets are dropped after a sustained failure and arrive again on recovery.
+// Dropped wallets are left out of the accounts reported to Quorum until they arrive again.
+type connectivityProbe struct {
+	threshold int
+
+	mu           sync.Mutex
+	failures     int             // consecutive failed probes
+	dropped      []string        // urls of the wallets dropped since Vault became unreachable
+	droppedAddrs map[string]bool // addresses of the dropped wallets, without the 0x prefix
+}
+
+func newConnectivityProbe(conf config.VaultClientHealthProbe) *connectivityProbe {
+	threshold := conf.FailureThreshold
+	if threshold == 0 {
+		threshold = defaultProbeFailureThreshold
+	}
+	return &connectivityProbe{threshold: threshold}
+}
+
+// startConnectivityProbe probes Vault every interval until the account manager is closed
+func (a *accountManager) startConnectivityProbe(interval time.Duration) {
+	metrics.VaultReachable.Set(1)
+	supervise("connectivity probe", a.client.stop, func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-a.client.stop:
+				return
+			case <-t.C:
+				a.probeVault()
+			}
+		}
+	})
+}
+
+// probeVault checks the health of the Vault cluster currently serving reads, dropping locked wallets once the
+// failure threshold is reached and restoring them when Vault is healthy again
+func (a *accountManager) probeVault() {
+	c := a.client
+	if c.dr != nil && c.dr.isFailedOver() {
+		c = c.dr.client
+	}
+	healthy := c.primaryHealthy()
+
+	p := a.probe
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if healthy {
+		if p.failures >= p.threshold {
+			log.Printf("[INFO] Vault %v is reachable again, %v wallet(s) arrived", c.Address(), len(p.dropped))
+			metrics.VaultReachable.Set(1)
+			for _, u := range p.dropped {
+				event.Emit(event.WalletArrived, u, fmt.Sprintf("Vault %v reachable", c.Address()))
+			}
+			p.dropped = nil
+			p.droppedAddrs = nil
+		}
+		p.failures = 0
+		return
+	}
+
+	p.failures++
+	log.Printf("[DEBUG] Vault %v health probe failed (%v consecutive)", c.Address(), p.failures)
+	if p.failures != p.threshold {
+		return
+	}
+
+	p.dropped, p.droppedAddrs = a.lockedWallets()
+	log.Printf("[WARN] Vault %v is unreachable, %v locked wallet(s) dropped", c.Address(), len(p.dropped))
+	metrics.VaultReachable.Set(0)
+	for _, u := range p.dropped {
+		event.Emit(event.WalletDropped, u, fmt.Sprintf("Vault %v unreachable", c.Address()))
+	}
+}
+
+// lockedWallets returns the urls and addresses of the accounts which need Vault to be reachable to sign
+func (a *accountManager) lockedWallets() ([]string, map[string]bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var (
+		urls  []string
+		addrs = make(map[string]bool)
+	)
+	for u, acct := range a.client.accounts() {
+		addr := strings.TrimPrefix(acct.Contents.Address, "0x")
+		if _, unlocked := a.unlocked[addr]; !unlocked {
+			urls = append(urls, u.String())
+			addrs[addr] = true
+		}
+	}
+	sort.Strings(urls)
+	return urls, addrs
+}
+
+// isDropped returns true if the wallet of the account with the given address, without the 0x prefix, has been dropped
+// because Vault is unreachable
+func (a *accountManager) isDropped(addr string) bool {
+	if a.probe == nil {
+		return false
+	}
+	a.probe.mu.Lock()
+	defer a.probe.mu.Unlock()
+	return a.probe.droppedAddrs[addr]
+}
+
+// droppedWallets returns the number of wallets dropped because Vault is unreachable
+func (a *accountManager) droppedWallets() int {
+	if a.probe == nil {
+		return 0
+	}
+	a.probe.mu.Lock()
+	defer a.probe.mu.Unlock()
+	return len(a.probe.dropped)
+}
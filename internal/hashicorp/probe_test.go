@@ -0,0 +1,120 @@
+package hashicorp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeVault_DropsAndRestoresLockedWallets(t *testing.T) {
+	healthy := true
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"initialized": true, "sealed": false}`))
+	}))
+	defer vault.Close()
+
+	a := gcAccountManager(t, vault.URL)
+	a.probe = newConnectivityProbe(config.VaultClientHealthProbe{FailureThreshold: 2})
+
+	events, unsubscribe := event.Subscribe(10)
+	defer unsubscribe()
+
+	healthy = false
+	a.probeVault()
+	require.Equal(t, 0, a.droppedWallets())
+
+	a.probeVault()
+	require.Equal(t, 1, a.droppedWallets())
+	e := <-events
+	require.Equal(t, event.WalletDropped, e.Kind)
+	require.Equal(t, "file:///path/to/acct1", e.Subject)
+
+	status, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s); Vault unreachable, 1 wallet(s) dropped", status)
+
+	// further failures do not drop the wallets again
+	a.probeVault()
+	require.Len(t, events, 0)
+
+	healthy = true
+	a.probeVault()
+	require.Equal(t, 0, a.droppedWallets())
+	e = <-events
+	require.Equal(t, event.WalletArrived, e.Kind)
+	require.Equal(t, "file:///path/to/acct1", e.Subject)
+}
+
+func TestProbeVault_UnlockedWalletsNotDropped(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer vault.Close()
+
+	a := gcAccountManager(t, vault.URL)
+	a.probe = newConnectivityProbe(config.VaultClientHealthProbe{FailureThreshold: 1})
+	a.unlocked = map[string]*lockableKey{"dc99ddec13457de6c0f6bb8e6cf3955c86f55526": {}}
+
+	a.probeVault()
+	require.Equal(t, 0, a.droppedWallets())
+}
+
+func TestProbeVault_DroppedWalletsNotReported(t *testing.T) {
+	healthy := false
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"initialized": true, "sealed": false}`))
+	}))
+	defer vault.Close()
+
+	lockedURL, _ := url.Parse("file:///path/to/locked")
+	locked := config.AccountFile{}
+	locked.Contents.Address = "dc99ddec13457de6c0f6bb8e6cf3955c86f55526"
+	unlockedURL, _ := url.Parse("file:///path/to/unlocked")
+	unlocked := config.AccountFile{}
+	unlocked.Contents.Address = reconcileAddr1
+
+	a := testKVAccountManager(t, vault.URL, accountsByURL{lockedURL: locked, unlockedURL: unlocked})
+	a.probe = newConnectivityProbe(config.VaultClientHealthProbe{FailureThreshold: 1})
+	a.unlocked = map[string]*lockableKey{reconcileAddr1: {}}
+
+	lockedAddr, _ := account.NewAddressFromHexString(locked.Contents.Address)
+	unlockedAddr, _ := account.NewAddressFromHexString(unlocked.Contents.Address)
+
+	a.probeVault()
+	require.Equal(t, 1, a.droppedWallets())
+
+	accts, err := a.Accounts()
+	require.NoError(t, err)
+	require.Equal(t, []account.Account{{Address: unlockedAddr, URL: unlockedURL}}, accts)
+	require.False(t, a.Contains(lockedAddr))
+	require.True(t, a.Contains(unlockedAddr))
+
+	healthy = true
+	a.probeVault()
+	require.Equal(t, 0, a.droppedWallets())
+
+	accts, err = a.Accounts()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []account.Account{{Address: lockedAddr, URL: lockedURL}, {Address: unlockedAddr, URL: unlockedURL}}, accts)
+	require.True(t, a.Contains(lockedAddr))
+	require.True(t, a.Contains(unlockedAddr))
+}
+
+func TestNewConnectivityProbe_DefaultThreshold(t *testing.T) {
+	p := newConnectivityProbe(config.VaultClientHealthProbe{})
+	require.Equal(t, defaultProbeFailureThreshold, p.threshold)
+}
@@ -0,0 +1,105 @@
+package hashicorp
+
+import (
+	"errors"
+	"expvar"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+var NewAccountQuotaExceededErr = errors.New("account creation quota exceeded")
+
+// creationQuota limits the number of accounts that can be created in a rolling hour and day so that runaway scripts
+// cannot generate an unbounded number of Vault secrets and account config files.  A limit of 0 is unlimited.
+type creationQuota struct {
+	perHour int
+	perDay  int
+	now     func() time.Time
+
+	mu      sync.Mutex
+	created []*creation // creations within the last day, oldest first
+}
+
+// creation is a creation counted towards the quota.  Each is a distinct pointer so that it can be released even if
+// another creation was made at the same time.
+type creation struct {
+	at time.Time
+}
+
+func newCreationQuota(conf config.VaultClientQuota) *creationQuota {
+	return &creationQuota{
+		perHour: conf.PerHour,
+		perDay:  conf.PerDay,
+		now:     time.Now,
+	}
+}
+
+// acquire reserves a creation in the quota.  If the creation subsequently fails, the returned release func should be
+// called to return the reservation.
+func (q *creationQuota) acquire() (release func(), err error) {
+	if q == nil || (q.perHour == 0 && q.perDay == 0) {
+		return func() {}, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.prune(now)
+
+	lastHour := 0
+	for _, c := range q.created {
+		if now.Sub(c.at) < time.Hour {
+			lastHour++
+		}
+	}
+
+	if (q.perHour != 0 && lastHour >= q.perHour) || (q.perDay != 0 && len(q.created) >= q.perDay) {
+		log.Printf("[WARN] account creation quota exceeded: created in last hour = %v (limit %v), created in last day = %v (limit %v)", lastHour, q.perHour, len(q.created), q.perDay)
+		metrics.AccountCreationQuotaRejected.Add(1)
+		return nil, NewAccountQuotaExceededErr
+	}
+
+	c := &creation{at: now}
+	q.created = append(q.created, c)
+	q.publish(lastHour + 1)
+
+	return func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i := range q.created {
+			if q.created[i] == c {
+				q.created = append(q.created[:i], q.created[i+1:]...)
+				break
+			}
+		}
+	}, nil
+}
+
+// prune removes creation times that no longer count towards any quota
+func (q *creationQuota) prune(now time.Time) {
+	i := 0
+	for ; i < len(q.created); i++ {
+		if now.Sub(q.created[i].at) < 24*time.Hour {
+			break
+		}
+	}
+	q.created = q.created[i:]
+}
+
+func (q *creationQuota) publish(lastHour int) {
+	if q.perHour != 0 {
+		v := new(expvar.Int)
+		v.Set(int64(q.perHour - lastHour))
+		metrics.AccountCreationQuotaRemaining.Set("hour", v)
+	}
+	if q.perDay != 0 {
+		v := new(expvar.Int)
+		v.Set(int64(q.perDay - len(q.created)))
+		metrics.AccountCreationQuotaRemaining.Set("day", v)
+	}
+}
@@ -0,0 +1,120 @@
+package hashicorp
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreationQuota_Unlimited(t *testing.T) {
+	q := newCreationQuota(config.VaultClientQuota{})
+
+	for i := 0; i < 100; i++ {
+		_, err := q.acquire()
+		require.NoError(t, err)
+	}
+}
+
+func TestCreationQuota_NilIsUnlimited(t *testing.T) {
+	var q *creationQuota
+
+	_, err := q.acquire()
+	require.NoError(t, err)
+}
+
+func TestCreationQuota_PerHour(t *testing.T) {
+	now := time.Date(2020, 7, 20, 10, 0, 0, 0, time.UTC)
+	q := newCreationQuota(config.VaultClientQuota{PerHour: 2})
+	q.now = func() time.Time { return now }
+
+	_, err := q.acquire()
+	require.NoError(t, err)
+	_, err = q.acquire()
+	require.NoError(t, err)
+	_, err = q.acquire()
+	require.EqualError(t, err, "account creation quota exceeded")
+
+	now = now.Add(time.Hour)
+	_, err = q.acquire()
+	require.NoError(t, err)
+}
+
+func TestCreationQuota_PerDay(t *testing.T) {
+	now := time.Date(2020, 7, 20, 10, 0, 0, 0, time.UTC)
+	q := newCreationQuota(config.VaultClientQuota{PerHour: 1, PerDay: 2})
+	q.now = func() time.Time { return now }
+
+	_, err := q.acquire()
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, err = q.acquire()
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, err = q.acquire()
+	require.Equal(t, NewAccountQuotaExceededErr, err)
+
+	now = now.Add(22 * time.Hour)
+	_, err = q.acquire()
+	require.NoError(t, err)
+}
+
+func TestCreationQuota_Release(t *testing.T) {
+	q := newCreationQuota(config.VaultClientQuota{PerHour: 1})
+
+	release, err := q.acquire()
+	require.NoError(t, err)
+	_, err = q.acquire()
+	require.Error(t, err)
+
+	release()
+
+	_, err = q.acquire()
+	require.NoError(t, err)
+}
+
+func TestCreationQuota_ReleaseSameTime(t *testing.T) {
+	now := time.Date(2020, 7, 20, 10, 0, 0, 0, time.UTC)
+	q := newCreationQuota(config.VaultClientQuota{PerHour: 2})
+	q.now = func() time.Time { return now }
+
+	release1, err := q.acquire()
+	require.NoError(t, err)
+	_, err = q.acquire()
+	require.NoError(t, err)
+
+	// each release only returns its own reservation, even if made at the same time as another
+	release1()
+	release1()
+	_, err = q.acquire()
+	require.NoError(t, err)
+	_, err = q.acquire()
+	require.Equal(t, NewAccountQuotaExceededErr, err)
+}
+
+func TestAccountManager_QuotaCountsSecretsWrittenByFailedCreations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quota")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	acctDir := filepath.Join(dir, "accts")
+	require.NoError(t, os.Mkdir(acctDir, 0700))
+	dirURL, _ := url.Parse("file://" + acctDir + "/")
+
+	a, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL, NewAccountQuota: config.VaultClientQuota{PerHour: 1}})
+	require.NoError(t, err)
+
+	// the secret is written to Vault, but the account config cannot be
+	require.NoError(t, os.RemoveAll(acctDir))
+	require.NoError(t, ioutil.WriteFile(acctDir, nil, 0600))
+	_, err = a.NewAccount(config.NewAccount{SecretName: "acct1"})
+	require.Error(t, err)
+	require.NotEqual(t, NewAccountQuotaExceededErr, err)
+
+	_, err = a.NewAccount(config.NewAccount{SecretName: "acct2"})
+	require.Equal(t, NewAccountQuotaExceededErr, err)
+}
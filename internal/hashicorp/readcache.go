@@ -0,0 +1,174 @@
+package hashicorp
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+// readCacheEntryOverhead is a rough estimate of the bytes used by each entry in addition to its key and data
+const readCacheEntryOverhead = 256
+
+// readCache is an LRU cache of secret data keyed by (path, version).  Versions of a KV v2 secret are immutable so
+// entries do not need to expire, only be evicted when the cache is full or explicitly invalidated.  The cache is full
+// when either the number of entries or the estimated memory used exceeds its limit (0 is unlimited).  A nil readCache
+// is valid and caches nothing.
+type readCache struct {
+	maxEntries int
+	maxBytes   int64
+	mu         sync.Mutex
+	ll         *list.List
+	entries    map[string]*list.Element
+	bytes      int64
+}
+
+type readCacheEntry struct {
+	key  string
+	data map[string]interface{}
+	size int64
+}
+
+func newReadCache(maxEntries int, maxBytes int64) *readCache {
+	if maxEntries <= 0 && maxBytes <= 0 {
+		return nil
+	}
+	return &readCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func readCacheKey(path string, version int64) string {
+	return fmt.Sprintf("%v?version=%v", path, version)
+}
+
+func (c *readCache) get(path string, version int64) (map[string]interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[readCacheKey(path, version)]
+	if !ok {
+		metrics.ReadCacheMisses.Add(1)
+		return nil, false
+	}
+	metrics.ReadCacheHits.Add(1)
+	c.ll.MoveToFront(el)
+	return el.Value.(*readCacheEntry).data, true
+}
+
+func (c *readCache) add(path string, version int64, data map[string]interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := readCacheKey(path, version)
+	if el, ok := c.entries[key]; ok {
+		c.removeElement(el)
+	}
+	e := &readCacheEntry{key: key, data: data, size: estimateSize(key, data)}
+	c.entries[key] = c.ll.PushFront(e)
+	c.bytes += e.size
+
+	for c.ll.Len() > 0 && ((c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes)) {
+		c.removeElement(c.ll.Back())
+		metrics.ReadCacheEvictions.Add(1)
+	}
+	c.publish()
+}
+
+// invalidate removes a single version of the secret at path
+func (c *readCache) invalidate(path string, version int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[readCacheKey(path, version)]; ok {
+		c.removeElement(el)
+	}
+	c.publish()
+}
+
+// invalidateAll removes all versions of the secret at path
+func (c *readCache) invalidateAll(path string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := path + "?version="
+	for key, el := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.removeElement(el)
+		}
+	}
+	c.publish()
+}
+
+// clear removes every entry, e.g. when the account manager is closed
+func (c *readCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.entries = make(map[string]*list.Element)
+	c.bytes = 0
+	c.publish()
+}
+
+func (c *readCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// size returns the estimated memory used by the cache in bytes
+func (c *readCache) size() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+func (c *readCache) removeElement(el *list.Element) {
+	e := el.Value.(*readCacheEntry)
+	c.ll.Remove(el)
+	delete(c.entries, e.key)
+	c.bytes -= e.size
+}
+
+func (c *readCache) publish() {
+	metrics.ReadCacheSize.Set(int64(c.ll.Len()))
+	metrics.ReadCacheBytes.Set(c.bytes)
+}
+
+func estimateSize(key string, data map[string]interface{}) int64 {
+	size := int64(len(key) + readCacheEntryOverhead)
+	for k, v := range data {
+		size += int64(len(k))
+		if s, ok := v.(string); ok {
+			size += int64(len(s))
+		}
+	}
+	return size
+}
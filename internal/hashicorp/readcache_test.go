@@ -0,0 +1,152 @@
+package hashicorp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCache_Disabled(t *testing.T) {
+	c := newReadCache(0, 0)
+	require.Nil(t, c)
+
+	c.add("kv/data/acct1", 1, map[string]interface{}{"addr": "key"})
+	_, ok := c.get("kv/data/acct1", 1)
+	require.False(t, ok)
+	require.Equal(t, 0, c.len())
+}
+
+func TestReadCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newReadCache(2, 0)
+
+	c.add("kv/data/acct1", 1, map[string]interface{}{"addr1": "key1"})
+	c.add("kv/data/acct2", 1, map[string]interface{}{"addr2": "key2"})
+
+	// use acct1 so that acct2 is the least recently used
+	_, ok := c.get("kv/data/acct1", 1)
+	require.True(t, ok)
+
+	c.add("kv/data/acct3", 1, map[string]interface{}{"addr3": "key3"})
+	require.Equal(t, 2, c.len())
+
+	_, ok = c.get("kv/data/acct2", 1)
+	require.False(t, ok)
+	got, ok := c.get("kv/data/acct1", 1)
+	require.True(t, ok)
+	require.Equal(t, map[string]interface{}{"addr1": "key1"}, got)
+}
+
+func TestReadCache_Invalidate(t *testing.T) {
+	c := newReadCache(10, 0)
+
+	c.add("kv/data/acct1", 1, map[string]interface{}{"addr": "key1"})
+	c.add("kv/data/acct1", 2, map[string]interface{}{"addr": "key2"})
+	c.add("kv/data/acct10", 1, map[string]interface{}{"addr": "key3"})
+
+	c.invalidate("kv/data/acct1", 1)
+	_, ok := c.get("kv/data/acct1", 1)
+	require.False(t, ok)
+	_, ok = c.get("kv/data/acct1", 2)
+	require.True(t, ok)
+
+	c.invalidateAll("kv/data/acct1")
+	_, ok = c.get("kv/data/acct1", 2)
+	require.False(t, ok)
+	_, ok = c.get("kv/data/acct10", 1)
+	require.True(t, ok)
+}
+
+func TestAccountManager_ReadCache(t *testing.T) {
+	var hits int
+	vault := secretServer(t, &hits)
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.client.SetToken("mytoken")
+	a.unlocked = make(map[string]*lockableKey)
+	a.cache = newReadCache(10, 0)
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	toSign := make([]byte, 32)
+
+	_, err := a.UnlockAndSign(context.Background(), addr, toSign)
+	require.NoError(t, err)
+	_, err = a.UnlockAndSign(context.Background(), addr, toSign)
+	require.NoError(t, err)
+	require.Equal(t, 1, hits)
+
+	// explicitly locking removes the key from the cache
+	a.Lock(addr)
+	_, err = a.UnlockAndSign(context.Background(), addr, toSign)
+	require.NoError(t, err)
+	require.Equal(t, 2, hits)
+}
+
+func TestAccountManager_ReadCacheEvictedWhenTimedUnlockExpires(t *testing.T) {
+	var hits int
+	vault := secretServer(t, &hits)
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.client.SetToken("mytoken")
+	a.unlocked = make(map[string]*lockableKey)
+	a.cache = newReadCache(10, 0)
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 10*time.Millisecond))
+	require.Equal(t, 1, a.cache.len())
+
+	require.Eventually(t, func() bool { return a.cache.len() == 0 }, time.Second, 10*time.Millisecond)
+	a.mu.Lock()
+	require.Empty(t, a.unlocked)
+	a.mu.Unlock()
+}
+
+func TestAccountManager_ReadCacheDisabledForAccount(t *testing.T) {
+	var hits int
+	vault := secretServer(t, &hits)
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.client.SetToken("mytoken")
+	a.unlocked = make(map[string]*lockableKey)
+	a.cache = newReadCache(10, 0)
+	no := false
+	a.settings.AccountOverrides = map[string]config.AccountSettings{reconcileAddr1: {ReadCache: &no}}
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	toSign := make([]byte, 32)
+
+	_, err := a.UnlockAndSign(context.Background(), addr, toSign)
+	require.NoError(t, err)
+	_, err = a.UnlockAndSign(context.Background(), addr, toSign)
+	require.NoError(t, err)
+	require.Equal(t, 2, hits)
+	require.Equal(t, 0, a.cache.len())
+}
+
+func TestReadCache_EvictsWhenMaxBytesExceeded(t *testing.T) {
+	data := map[string]interface{}{reconcileAddr1: reconcileKey1}
+	entrySize := estimateSize(readCacheKey("kv/data/acct1", 1), data)
+
+	c := newReadCache(0, 2*entrySize)
+
+	c.add("kv/data/acct1", 1, data)
+	c.add("kv/data/acct2", 1, data)
+	require.Equal(t, 2, c.len())
+	require.Equal(t, 2*entrySize, c.size())
+
+	c.add("kv/data/acct3", 1, data)
+	require.Equal(t, 2, c.len())
+	require.Equal(t, 2*entrySize, c.size())
+
+	_, ok := c.get("kv/data/acct1", 1)
+	require.False(t, ok)
+
+	c.invalidateAll("kv/data/acct2")
+	require.Equal(t, entrySize, c.size())
+}
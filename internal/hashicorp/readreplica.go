@@ -0,0 +1,124 @@
+package hashicorp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	// replicaScoreWeight is the weight given to the latest read when updating a replica's moving averages
+	replicaScoreWeight = 0.3
+	// replicaFailureLatency is the latency recorded for a failed read, so that failing replicas are avoided
+	replicaFailureLatency = 5 * time.Second
+)
+
+// readReplica is a Vault performance standby/secondary that reads can be sent to, scored by recent reads
+type readReplica struct {
+	*api.Client
+	latency   time.Duration // moving average of read latency, including replicaFailureLatency for failed reads
+	errorRate float64       // moving average of the proportion of reads that failed
+	reads     int
+	locality  string // region/zone, empty if not tagged
+	tier      int    // locality relative to this node, see localityTier
+}
+
+// replicaSet selects the healthiest of the configured read replicas for each read
+type replicaSet struct {
+	mu       sync.Mutex
+	replicas []*readReplica
+}
+
+func newReplicaSet(clients ...*api.Client) *replicaSet {
+	s := &replicaSet{}
+	for _, c := range clients {
+		s.replicas = append(s.replicas, &readReplica{Client: c})
+	}
+	return s
+}
+
+// best returns the replica with the lowest average latency of those in the most local tier.  Replicas that have not
+// yet been read from are preferred so that every replica is scored.  Ties go to the first configured replica.
+func (s *replicaSet) best() *readReplica {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	best := s.replicas[0]
+	for _, r := range s.replicas[1:] {
+		if r.tier != best.tier {
+			if r.tier < best.tier {
+				best = r
+			}
+			continue
+		}
+		if r.reads == 0 && best.reads != 0 {
+			best = r
+			continue
+		}
+		if (r.reads == 0) == (best.reads == 0) && r.latency < best.latency {
+			best = r
+		}
+	}
+	return best
+}
+
+// record updates the replica's score with the outcome of a read
+func (s *replicaSet) record(r *readReplica, latency time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var failure float64
+	if failed {
+		failure = 1
+		if latency < replicaFailureLatency {
+			latency = replicaFailureLatency
+		}
+	}
+
+	if r.reads == 0 {
+		r.latency = latency
+		r.errorRate = failure
+	} else {
+		r.latency = time.Duration(replicaScoreWeight*float64(latency) + (1-replicaScoreWeight)*float64(r.latency))
+		r.errorRate = replicaScoreWeight*failure + (1-replicaScoreWeight)*r.errorRate
+	}
+	r.reads++
+}
+
+func (s *replicaSet) clients() []*api.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients := make([]*api.Client, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		clients = append(clients, r.Client)
+	}
+	return clients
+}
+
+// ReadReplicaState is the score of a read replica, for troubleshooting
+type ReadReplicaState struct {
+	Address   string
+	Locality  string `json:",omitempty"`
+	LatencyMs int64
+	ErrorRate float64
+	Reads     int
+}
+
+func (s *replicaSet) state() []ReadReplicaState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make([]ReadReplicaState, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		states = append(states, ReadReplicaState{
+			Address:   r.Address(),
+			Locality:  r.locality,
+			LatencyMs: int64(r.latency / time.Millisecond),
+			ErrorRate: r.errorRate,
+			Reads:     r.reads,
+		})
+	}
+	return states
+}
@@ -0,0 +1,94 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func secretServer(t *testing.T, hits *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		require.Equal(t, "mytoken", r.Header.Get("X-Vault-Token"))
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{
+			"data": map[string]interface{}{reconcileAddr1: reconcileKey1},
+		}})
+		_, _ = w.Write(b)
+	}))
+}
+
+func TestReadReplica_ReadsSentToReplica(t *testing.T) {
+	var primaryHits, replicaHits int
+	primary := secretServer(t, &primaryHits)
+	defer primary.Close()
+	replica := secretServer(t, &replicaHits)
+	defer replica.Close()
+
+	a := reconcileAccountManager(t, primary.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.client.SetToken("mytoken")
+
+	replicaURL, _ := url.Parse(replica.URL)
+	replicaClient, err := newAPIClient(replicaURL, config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}}, 0, nil)
+	require.NoError(t, err)
+	replicaClient.SetMaxRetries(0)
+	a.client.replicas = newReplicaSet(replicaClient)
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+
+	require.Equal(t, 0, primaryHits)
+	require.Equal(t, 1, replicaHits)
+
+	// reads fall back to the active node if the replica is unreachable
+	replica.Close()
+	a.Lock(addr)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+
+	require.Equal(t, 1, primaryHits)
+}
+
+func TestReplicaSet_Best(t *testing.T) {
+	newClient := func(address string) *api.Client {
+		return testVaultClient(t, address).Client
+	}
+	s := newReplicaSet(newClient("http://replica1:8200"), newClient("http://replica2:8200"), newClient("http://replica3:8200"))
+	r1, r2, r3 := s.replicas[0], s.replicas[1], s.replicas[2]
+
+	// unscored replicas are tried first, in order
+	require.Equal(t, r1, s.best())
+	s.record(r1, 20*time.Millisecond, false)
+	require.Equal(t, r2, s.best())
+	s.record(r2, 10*time.Millisecond, false)
+	require.Equal(t, r3, s.best())
+	s.record(r3, 30*time.Millisecond, false)
+
+	// then the lowest latency
+	require.Equal(t, r2, s.best())
+
+	// failures are heavily penalised
+	s.record(r2, time.Millisecond, true)
+	require.Equal(t, r1, s.best())
+	require.Equal(t, replicaScoreWeight, r2.errorRate)
+
+	// and recover as reads succeed
+	for i := 0; i < 20; i++ {
+		s.record(r2, 10*time.Millisecond, false)
+	}
+	require.Equal(t, r2, s.best())
+
+	states := s.state()
+	require.Len(t, states, 3)
+	require.Equal(t, "http://replica1:8200", states[0].Address)
+	require.Equal(t, int64(20), states[0].LatencyMs)
+	require.Equal(t, 22, states[1].Reads)
+}
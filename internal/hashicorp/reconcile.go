@@ -0,0 +1,168 @@
+package hashicorp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// ReconcileReport describes the discrepancies between the account config files on disk and the secrets in Vault
+type ReconcileReport struct {
+	// ConfigsWithoutSecrets are accounts whose referenced secret version does not exist, has been deleted, or cannot be read
+	ConfigsWithoutSecrets []AccountHealth
+	// SecretsWithoutConfigs are live secret versions that are not referenced by any account config
+	SecretsWithoutConfigs []OrphanedSecret
+	// VersionMismatches are accounts that reference an older version than the latest live version of their secret
+	VersionMismatches []VersionMismatch
+	// AddressMismatches are accounts whose referenced secret contains the key for a different address
+	AddressMismatches []AddressMismatch
+	// Fixed describes the discrepancies that were resolved, if fixing was requested
+	Fixed []string
+}
+
+type VersionMismatch struct {
+	Address       string
+	URL           string
+	SecretName    string
+	ConfigVersion int64
+	LatestVersion int64
+}
+
+type AddressMismatch struct {
+	URL           string
+	SecretName    string
+	SecretVersion int64
+	ConfigAddress string
+	SecretAddress string
+}
+
+// Reconcile compares the loaded account configs with the secrets under prefix in the KV engine.  If fix is true, new
+// account configs are written for the latest version of any unreferenced secret that holds the key for an account
+// not already in the account directory.  All other discrepancies require an operator decision so are only reported.
+func (a *accountManager) Reconcile(prefix string, fix bool) (ReconcileReport, error) {
+	var report ReconcileReport
+
+	for _, h := range a.CheckAccounts() {
+		if h.Degraded {
+			report.ConfigsWithoutSecrets = append(report.ConfigsWithoutSecrets, h)
+			continue
+		}
+
+		live, err := a.liveVersions(h.SecretName)
+		if err != nil {
+			return ReconcileReport{}, err
+		}
+		if len(live) == 0 {
+			// deleted since the accounts were checked
+			h.Degraded = true
+			h.Reason = fmt.Sprintf("secret %v has no live versions", h.SecretName)
+			report.ConfigsWithoutSecrets = append(report.ConfigsWithoutSecrets, h)
+			continue
+		}
+		if latest := live[len(live)-1]; latest > h.SecretVersion {
+			report.VersionMismatches = append(report.VersionMismatches, VersionMismatch{
+				Address:       h.Address,
+				URL:           h.URL,
+				SecretName:    h.SecretName,
+				ConfigVersion: h.SecretVersion,
+				LatestVersion: latest,
+			})
+		}
+
+		secretAddr, err := a.secretAddress(h.SecretName, h.SecretVersion, h.SecretKey, h.Address)
+		if err != nil {
+			return ReconcileReport{}, fmt.Errorf("unable to read secret %v version %v: %v", h.SecretName, h.SecretVersion, err)
+		}
+		if !strings.EqualFold(secretAddr, h.Address) {
+			report.AddressMismatches = append(report.AddressMismatches, AddressMismatch{
+				URL:           h.URL,
+				SecretName:    h.SecretName,
+				SecretVersion: h.SecretVersion,
+				ConfigAddress: h.Address,
+				SecretAddress: secretAddr,
+			})
+		}
+	}
+
+	gc, err := a.GarbageCollect(prefix, false)
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+	report.SecretsWithoutConfigs = gc.Orphaned
+
+	if !fix {
+		return report, nil
+	}
+
+	for _, o := range report.SecretsWithoutConfigs {
+		fixed, err := a.restoreAccountConfig(o)
+		if err != nil {
+			return report, err
+		}
+		if fixed != "" {
+			report.Fixed = append(report.Fixed, fixed)
+		}
+	}
+	sort.Strings(report.Fixed)
+
+	return report, nil
+}
+
+// restoreAccountConfig writes an account config for the latest version of the orphaned secret, if the address it
+// holds the key for is not already in the account directory
+func (a *accountManager) restoreAccountConfig(o OrphanedSecret) (string, error) {
+	latest := o.Versions[len(o.Versions)-1]
+
+	secretAddr, err := a.secretAddress(o.SecretName, latest, "", "")
+	if err != nil {
+		log.Printf("[WARN] unable to restore account config for secret %v version %v: %v", o.SecretName, latest, err)
+		return "", nil
+	}
+
+	addr, err := account.NewAddressFromHexString(secretAddr)
+	if err != nil {
+		return "", err
+	}
+	if a.Contains(addr) {
+		return "", nil
+	}
+
+	fileData, err := a.writeToFile(addr.ToHexString(), latest, config.NewAccount{SecretName: o.SecretName})
+	if err != nil {
+		return "", fmt.Errorf("unable to write account config file for secret %v version %v: %v", o.SecretName, latest, err)
+	}
+	accountURL, err := fileData.Contents.AccountURL(a.client.Address(), a.kvEngineName)
+	if err != nil {
+		return "", err
+	}
+	a.client.addAccount(accountURL, fileData)
+
+	log.Printf("[INFO] restored account config for secret %v version %v: %v", o.SecretName, latest, fileData.Path)
+	return fmt.Sprintf("wrote account config %v for %v (secret %v version %v)", fileData.Path, secretAddr, o.SecretName, latest), nil
+}
+
+// secretAddress returns the address derived from the private key stored in a version of a secret.  field is the field
+// of the secret holding the key, if set in the account config, and addr the address in the account config, if any.
+func (a *accountManager) secretAddress(secretName string, secretVersion int64, field, addr string) (string, error) {
+	respData, err := a.readSecret(context.Background(), secretName, secretVersion)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := secretKey(respData, config.NormalizeAddress(addr), field, a.pepper)
+	if err != nil {
+		return "", err
+	}
+	defer zeroKey(key)
+
+	keyAddr, err := account.PrivateKeyToAddress(key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("0x%v", keyAddr.ToHexString()), nil
+}
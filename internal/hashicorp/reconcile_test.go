@@ -0,0 +1,177 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// private keys and their corresponding addresses
+	reconcileKey1  = "a0379af19f0b55b0f384f83c95f668ba600b78f487f6414f2d22339273891eec"
+	reconcileAddr1 = "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5"
+	reconcileKey2  = "1fe8f1ad4053326db20529257ac9401f2e6c769ef1d736b8c2f5aba5f787c72b"
+	reconcileAddr2 = "6038dc01869425004ca0b8370f6c81cf464213b3"
+)
+
+func reconcileVaultServer() *httptest.Server {
+	live := map[string]interface{}{"deletion_time": "", "destroyed": false}
+	// the engine has delete_version_after set, so the version will be deleted in the future
+	scheduled := map[string]interface{}{"deletion_time": time.Now().Add(time.Hour).Format(time.RFC3339Nano), "destroyed": false}
+
+	responses := map[string]map[string]interface{}{
+		"/v1/kv/metadata":             {"keys": []string{"acct1", "acct2"}},
+		"/v1/kv/metadata/acct1":       {"versions": map[string]interface{}{"1": live, "2": live}},
+		"/v1/kv/metadata/acct2":       {"versions": map[string]interface{}{"1": scheduled}},
+		"/v1/kv/data/acct1?version=1": {"data": map[string]interface{}{reconcileAddr1: reconcileKey1}},
+		"/v1/kv/data/acct1?version=2": {"data": map[string]interface{}{reconcileAddr2: reconcileKey2}},
+		"/v1/kv/data/acct2?version=1": {"data": map[string]interface{}{"dc99ddec13457de6c0f6bb8e6cf3955c86f55526": reconcileKey1}},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if v := r.URL.Query().Get("version"); v != "" {
+			path = path + "?version=" + v
+		}
+		data, ok := responses[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		b, _ := json.Marshal(&api.Secret{Data: data})
+		_, _ = w.Write(b)
+	}))
+}
+
+func reconcileAccountManager(t *testing.T, vaultURL, acctDir string) *accountManager {
+	accts := make(accountsByURL)
+	addAcct := func(u, addr, secretName string, secretVersion int64) {
+		acctURL, _ := url.Parse(u)
+		acct := config.AccountFile{}
+		acct.Contents.Address = addr
+		acct.Contents.VaultAccount.SecretName = secretName
+		acct.Contents.VaultAccount.SecretVersion = secretVersion
+		accts[acctURL] = acct
+	}
+	addAcct("file:///path/to/acct1", reconcileAddr1, "acct1", 1)
+	addAcct("file:///path/to/acct2", "dc99ddec13457de6c0f6bb8e6cf3955c86f55526", "acct2", 1)
+	addAcct("file:///path/to/missing", "1111111111111111111111111111111111111111", "doesnotexist", 1)
+
+	dir, _ := url.Parse("file://" + acctDir + "/")
+
+	a := testKVAccountManager(t, vaultURL, accts)
+	a.client.store = &dirStore{dir: dir}
+	return a
+}
+
+func TestReconcile_ReportOnly(t *testing.T) {
+	vault := reconcileVaultServer()
+	defer vault.Close()
+
+	dir, err := ioutil.TempDir("", "reconcile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := reconcileAccountManager(t, vault.URL, dir)
+
+	got, err := a.Reconcile("", false)
+	require.NoError(t, err)
+
+	require.Len(t, got.ConfigsWithoutSecrets, 1)
+	require.Equal(t, "file:///path/to/missing", got.ConfigsWithoutSecrets[0].URL)
+
+	require.Equal(t, []OrphanedSecret{{SecretName: "acct1", Versions: []int64{2}}}, got.SecretsWithoutConfigs)
+
+	require.Equal(t, []VersionMismatch{{
+		Address:       "0x" + reconcileAddr1,
+		URL:           "file:///path/to/acct1",
+		SecretName:    "acct1",
+		ConfigVersion: 1,
+		LatestVersion: 2,
+	}}, got.VersionMismatches)
+
+	require.Equal(t, []AddressMismatch{{
+		URL:           "file:///path/to/acct2",
+		SecretName:    "acct2",
+		SecretVersion: 1,
+		ConfigAddress: "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526",
+		SecretAddress: "0x" + reconcileAddr1,
+	}}, got.AddressMismatches)
+
+	require.Empty(t, got.Fixed)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestReconcile_SecretDeletedAfterCheck(t *testing.T) {
+	var metadataReads int
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/metadata/acct1" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		// the only version is deleted after the accounts have been checked
+		metadataReads++
+		version := map[string]interface{}{"deletion_time": "", "destroyed": metadataReads > 1}
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"versions": map[string]interface{}{"1": version}}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	u, _ := url.Parse("file:///path/to/acct1")
+	acct := config.AccountFile{}
+	acct.Contents.Address = reconcileAddr1
+	acct.Contents.VaultAccount.SecretName = "acct1"
+	acct.Contents.VaultAccount.SecretVersion = 1
+	a := testKVAccountManager(t, vault.URL, accountsByURL{u: acct})
+
+	got, err := a.Reconcile("", false)
+	require.NoError(t, err)
+	require.Len(t, got.ConfigsWithoutSecrets, 1)
+	require.Equal(t, "file:///path/to/acct1", got.ConfigsWithoutSecrets[0].URL)
+	require.Equal(t, "secret acct1 has no live versions", got.ConfigsWithoutSecrets[0].Reason)
+}
+
+func TestReconcile_Fix(t *testing.T) {
+	vault := reconcileVaultServer()
+	defer vault.Close()
+
+	dir, err := ioutil.TempDir("", "reconcile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := reconcileAccountManager(t, vault.URL, dir)
+
+	got, err := a.Reconcile("", true)
+	require.NoError(t, err)
+	require.Len(t, got.Fixed, 1)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	b, err := ioutil.ReadFile(dir + "/" + files[0].Name())
+	require.NoError(t, err)
+
+	var contents config.AccountFileJSON
+	require.NoError(t, json.Unmarshal(b, &contents))
+	require.Equal(t, reconcileAddr2, contents.Address)
+	require.Equal(t, "acct1", contents.VaultAccount.SecretName)
+	require.Equal(t, int64(2), contents.VaultAccount.SecretVersion)
+
+	// the restored account is now known to the plugin
+	require.Len(t, a.client.accts, 4)
+}
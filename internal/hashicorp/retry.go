@@ -0,0 +1,114 @@
+package hashicorp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+)
+
+const readRetries = 1
+
+// readRetryDelay is the time waited before retrying a failed read
+var readRetryDelay = 100 * time.Millisecond
+
+// readWithRetry reads the secret at path, retrying once if the read fails with a transient error.  Reads are
+// idempotent so are safe to retry.  The read is abandoned when ctx is done.
+func (c *vaultClient) readWithRetry(ctx context.Context, path string, data map[string][]string) (*api.Secret, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.read(ctx, func(client *api.Client) (*api.Secret, error) {
+			return readWithContext(ctx, client, path, data)
+		})
+		if err == nil || attempt >= readRetries || ctx.Err() != nil || !isTransient(err) {
+			return resp, err
+		}
+
+		log.Printf("[DEBUG] retrying read of %v after transient error: %v", path, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(readRetryDelay):
+		}
+	}
+}
+
+// readWithContext is equivalent to api.Logical.ReadWithData but the request is cancelled when ctx is done
+func readWithContext(ctx context.Context, c *api.Client, path string, data map[string][]string) (*api.Secret, error) {
+	r := c.NewRequest("GET", fmt.Sprintf("/v1/%v", namespacedPath(ctx, path)))
+	for k, v := range data {
+		r.Params[k] = v
+	}
+	setAuditRequestID(ctx, r)
+
+	resp, err := c.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == 404 {
+		secret, parseErr := api.ParseSecret(resp.Body)
+		switch parseErr {
+		case nil:
+		case io.EOF:
+			return nil, nil
+		default:
+			return nil, err
+		}
+		if secret != nil && (len(secret.Warnings) > 0 || len(secret.Data) > 0) {
+			audit.AddVaultRequestID(ctx, secret.RequestID)
+			return secret, nil
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := api.ParseSecret(resp.Body)
+	if secret != nil {
+		audit.AddVaultRequestID(ctx, secret.RequestID)
+	}
+	return secret, err
+}
+
+// setAuditRequestID sends the audit request ID of ctx, if any, on r.  The headers are copied as they may be shared
+// with the client.
+func setAuditRequestID(ctx context.Context, r *api.Request) {
+	id := audit.RequestID(ctx)
+	if id == "" {
+		return
+	}
+	h := make(http.Header)
+	for k, v := range r.Headers {
+		h[k] = v
+	}
+	h.Set(audit.RequestIDHeader, id)
+	r.Headers = h
+}
+
+// IsTransient returns true if err, returned by the AccountManager, is a connection error or a response status
+// indicating Vault is temporarily unable to handle the request, so the request may succeed if retried
+func IsTransient(err error) bool {
+	return err != nil && isTransient(err)
+}
+
+// isTransient returns true if err is a connection error or a response status indicating Vault is temporarily unable
+// to handle the request
+func isTransient(err error) bool {
+	if _, ok := err.(*url.Error); ok {
+		return true
+	}
+	msg := err.Error()
+	for _, code := range []string{"Code: 502", "Code: 503", "Code: 504"} {
+		if strings.Contains(msg, code) {
+			return true
+		}
+	}
+	return false
+}
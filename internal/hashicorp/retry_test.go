@@ -0,0 +1,99 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimedUnlock_RetriesTransientError(t *testing.T) {
+	var hits int
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors":["Vault is sealed"]}`))
+			return
+		}
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{
+			"data": map[string]interface{}{reconcileAddr1: reconcileKey1},
+		}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+	require.Equal(t, 2, hits)
+}
+
+func TestTimedUnlock_DoesNotRetryPermanentError(t *testing.T) {
+	var hits int
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+	}))
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.Error(t, a.TimedUnlock(context.Background(), addr, 0))
+	require.Equal(t, 1, hits)
+}
+
+func TestTimedUnlock_Deadline(t *testing.T) {
+	done := make(chan struct{})
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer vault.Close()
+	defer close(done)
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	start := time.Now()
+	require.Error(t, a.TimedUnlock(ctx, addr, 0))
+	require.True(t, time.Since(start) < time.Second)
+	require.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
+
+func TestTimedUnlock_CorrelatesVaultRequests(t *testing.T) {
+	var gotRequestID string
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequestID = r.Header.Get(audit.RequestIDHeader)
+		b, _ := json.Marshal(&api.Secret{RequestID: "vault-request-1", Data: map[string]interface{}{
+			"data": map[string]interface{}{reconcileAddr1: reconcileKey1},
+		}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+
+	ctx := audit.WithRequestID(context.Background())
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	require.NoError(t, a.TimedUnlock(ctx, addr, 0))
+
+	require.NotEmpty(t, gotRequestID)
+	record := audit.NewRecord(ctx, "TimedUnlock", reconcileAddr1, nil)
+	require.Equal(t, gotRequestID, record.RequestID)
+	require.Equal(t, []string{"vault-request-1"}, record.VaultRequestIDs)
+}
@@ -0,0 +1,61 @@
+package hashicorp
+
+import (
+	"log"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+)
+
+// isTokenRevoked reports whether err is Vault rejecting a request because the token is no longer valid, e.g. because it
+// was revoked, as opposed to the token's policies not granting access or a network error
+func isTokenRevoked(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "Code: 403") && strings.Contains(msg, "invalid token")
+}
+
+// detectRevocation handles err with tokenRevoked if it shows that the token has been revoked
+func (c *vaultClient) detectRevocation(err error) {
+	if isTokenRevoked(err) {
+		c.tokenRevoked(err)
+	}
+}
+
+// tokenRevoked logs in again immediately, rather than waiting for renewal to fail or the token to expire, and reports
+// the revocation as it may be a result of incident response.  A static token cannot be replaced by logging in, so
+// requests fail until a new token is provided.
+func (c *vaultClient) tokenRevoked(err error) {
+	c.tokenRevokedUnlessStopped(err, nil)
+}
+
+// tokenRevokedUnlessStopped is tokenRevoked for a revocation detected by the renewal of a token, which is ignored if stop
+// is closed as the token has already been replaced
+func (c *vaultClient) tokenRevokedUnlessStopped(err error, stop <-chan struct{}) {
+	c.authMu.Lock()
+	// revocation is only reported once, until the token has been replaced, and the token is not replaced once the plugin
+	// has revoked it on shutdown
+	handled := isStopped(stop) || c.authStatus == authRevoked || c.authStatus == authReauthenticating || c.authStatus == authShutdown
+	if !handled {
+		c.authStatus = authRevoked
+	}
+	c.authMu.Unlock()
+	if handled {
+		return
+	}
+
+	conf := c.auth
+	if conf.Token != nil && conf.Token.IsSet() {
+		log.Printf("[ERROR] Vault auth token has been revoked, provide a new token: err = %v", err)
+		event.Emit(event.AuthTokenRevoked, "token", err.Error())
+		return
+	}
+
+	log.Printf("[WARN] Vault auth token has been revoked, attempting re-authentication: %v, err = %v", authMethod(conf), err)
+	event.Emit(event.AuthTokenRevoked, authID(conf), err.Error())
+
+	reauth := c.supersedeRenewal()
+	supervise("auth renewal", reauth, func() { c.reauthenticate(conf, reauth) })
+}
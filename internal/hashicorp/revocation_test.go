@@ -0,0 +1,117 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/stretchr/testify/require"
+)
+
+// revocationServer issues a new token for each approle login and rejects reads made with a token in revoked
+func revocationServer(t *testing.T, revoked map[string]bool) *httptest.Server {
+	var mu sync.Mutex
+	logins := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/auth/myapprole/login":
+			logins++
+			b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: fmt.Sprintf("token-%v", logins)}})
+			_, _ = w.Write(b)
+		case "/v1/kv/data/acct1":
+			if revoked[r.Header.Get("X-Vault-Token")] {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied","invalid token"]}`))
+				return
+			}
+			b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"data": map[string]interface{}{reconcileAddr1: reconcileKey1}}})
+			_, _ = w.Write(b)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		}
+	}))
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	require.False(t, isTokenRevoked(nil))
+	require.False(t, isTokenRevoked(errors.New("dial tcp 127.0.0.1:8200: connect: connection refused")))
+	require.False(t, isTokenRevoked(errors.New("Error making API request.\n\nURL: GET http://vault:8200/v1/kv/data/acct1\nCode: 403. Errors:\n\n* 1 error occurred:\n\t* permission denied\n\n")))
+	require.True(t, isTokenRevoked(errors.New("Error making API request.\n\nURL: GET http://vault:8200/v1/kv/data/acct1\nCode: 403. Errors:\n\n* permission denied\n* invalid token")))
+}
+
+func TestVaultClient_Read_ReauthenticatesAfterRevocation(t *testing.T) {
+	vault := revocationServer(t, map[string]bool{"token-1": true})
+	defer vault.Close()
+
+	require.NoError(t, os.Setenv("REVOCATION_TEST_SECRET_ID", "mysecret"))
+	defer os.Unsetenv("REVOCATION_TEST_SECRET_ID")
+
+	c := testVaultClient(t, vault.URL)
+
+	roleID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "UNSET_ROLE_ID"})
+	secretID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "REVOCATION_TEST_SECRET_ID"})
+	auth := config.VaultClientAuthentication{Token: &config.EnvironmentVariable{}, RoleId: &roleID, SecretId: &secretID, ApprolePath: "myapprole"}
+	require.NoError(t, c.authenticate(auth))
+	require.Equal(t, "token-1", c.Token())
+
+	events, unsubscribe := event.Subscribe(10)
+	defer unsubscribe()
+
+	// the token is revoked, so the read fails and the plugin logs in again without waiting for renewal to fail
+	_, err := c.readWithRetry(context.Background(), "kv/data/acct1", nil)
+	require.Error(t, err)
+
+	e := <-events
+	require.Equal(t, event.AuthTokenRevoked, e.Kind)
+	require.Equal(t, "approle/myapprole", e.Subject)
+
+	awaitCondition(t, func() bool { return c.Token() == "token-2" })
+	_, err = c.readWithRetry(context.Background(), "kv/data/acct1", nil)
+	require.NoError(t, err)
+}
+
+func TestVaultClient_TokenRevoked_StaticToken(t *testing.T) {
+	require.NoError(t, os.Setenv("REVOCATION_TEST_TOKEN", "token-1"))
+	defer os.Unsetenv("REVOCATION_TEST_TOKEN")
+
+	vault := revocationServer(t, map[string]bool{"token-1": true})
+	defer vault.Close()
+
+	c := testVaultClient(t, vault.URL)
+
+	token := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "REVOCATION_TEST_TOKEN"})
+	require.NoError(t, c.authenticate(config.VaultClientAuthentication{Token: &token}))
+
+	events, unsubscribe := event.Subscribe(10)
+	defer unsubscribe()
+
+	for i := 0; i < 2; i++ {
+		_, err := c.readWithRetry(context.Background(), "kv/data/acct1", nil)
+		require.Error(t, err)
+	}
+
+	// a static token cannot be replaced by logging in, and the revocation is only reported once
+	e := <-events
+	require.Equal(t, event.AuthTokenRevoked, e.Kind)
+	require.Equal(t, "token", e.Subject)
+	require.Equal(t, authRevoked, c.getAuthStatus())
+	require.Len(t, events, 0)
+
+	require.NoError(t, os.Setenv("REVOCATION_TEST_TOKEN", "token-2"))
+	require.NoError(t, c.refreshCredentials())
+	require.Equal(t, authStatic, c.getAuthStatus())
+}
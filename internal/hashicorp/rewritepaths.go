@@ -0,0 +1,168 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/atomicfile"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// PathRewrite is an account config whose secret name is rewritten by RewritePaths
+type PathRewrite struct {
+	Address        string
+	URL            string
+	FromSecretName string
+	ToSecretName   string
+	SecretVersion  int64
+	Backup         string `json:",omitempty"` // the file the original account config was copied to, if rewritten
+	Error          string `json:",omitempty"` // why the secret could not be verified at its new path
+}
+
+// RewritePaths rewrites the secret names of account configs starting with from to start with to instead, e.g. after
+// secrets are moved to a new path or the KV engine is re-mounted.  Each account's secret version is first read at its
+// new path in the configured kvEngineName and must hold the key for the account's address.  If any account cannot be
+// verified no account configs are rewritten.  If confirm is true each account config is copied to backupDir before it
+// is rewritten.  Running plugins apply the change when sent SIGHUP.
+func (a *accountManager) RewritePaths(from, to, backupDir string, confirm bool) ([]PathRewrite, error) {
+	var store *dirStore
+	if confirm {
+		s, ok := a.client.store.(*dirStore)
+		if !ok {
+			return nil, fmt.Errorf("account configs are stored in %v, not an accountDirectory", a.client.store)
+		}
+		store = s
+		if err := checkBackupDir(backupDir, config.FilePath(store.dir)); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		rewrites []PathRewrite
+		files    []config.AccountFile // the account config of each rewrite
+		failed   int
+	)
+	accts := a.client.accounts()
+	for _, u := range accts.sortedURLs(config.AccountOrderURL) {
+		acct := accts[u]
+		conf := acct.Contents.VaultAccount
+		if !strings.HasPrefix(conf.SecretName, from) {
+			continue
+		}
+		r := PathRewrite{
+			Address:        "0x" + config.NormalizeAddress(acct.Contents.Address),
+			URL:            u.String(),
+			FromSecretName: conf.SecretName,
+			ToSecretName:   to + strings.TrimPrefix(conf.SecretName, from),
+			SecretVersion:  conf.SecretVersion,
+		}
+		if err := a.verifyRewrite(r, conf.SecretKey); err != nil {
+			r.Error = err.Error()
+			failed++
+		}
+		rewrites = append(rewrites, r)
+		files = append(files, acct)
+	}
+
+	if failed > 0 {
+		return rewrites, fmt.Errorf("%v account(s) could not be verified at their new path, no account configs were rewritten", failed)
+	}
+	if !confirm {
+		return rewrites, nil
+	}
+
+	// back up every account config before any is rewritten, so that a failed backup leaves them all unchanged
+	var rewritten []int
+	for i, r := range rewrites {
+		if r.FromSecretName == r.ToSecretName {
+			continue
+		}
+		backup, err := backupAccountConfig(files[i].Path, config.FilePath(store.dir), backupDir)
+		if err != nil {
+			return rewrites, fmt.Errorf("unable to back up account config %v: %v", files[i].Path, err)
+		}
+		rewrites[i].Backup = backup
+		rewritten = append(rewritten, i)
+	}
+
+	for _, i := range rewritten {
+		r, acct := rewrites[i], files[i]
+		acct.Contents.VaultAccount.SecretName = r.ToSecretName
+		contents, err := json.Marshal(acct.Contents)
+		if err != nil {
+			return rewrites, err
+		}
+		if err := store.replace(acct.Path, contents); err != nil {
+			return rewrites, fmt.Errorf("unable to update account config %v: %v", acct.Path, err)
+		}
+		log.Printf("[INFO] rewrote account config %v: secret changed from %v to %v, original copied to %v", acct.Path, r.FromSecretName, r.ToSecretName, r.Backup)
+	}
+
+	if len(rewritten) != 0 {
+		return rewrites, a.ReloadAccounts()
+	}
+	return rewrites, nil
+}
+
+// verifyRewrite checks that the secret version at the new path holds the key for the account, in field if set
+func (a *accountManager) verifyRewrite(r PathRewrite, field string) error {
+	if r.ToSecretName == "" {
+		return errors.New("rewritten secret name is empty")
+	}
+	secretAddr, err := a.secretAddress(r.ToSecretName, r.SecretVersion, field, r.Address)
+	if err != nil {
+		return fmt.Errorf("unable to read secret %v version %v: %v", r.ToSecretName, r.SecretVersion, err)
+	}
+	if secretAddr != r.Address {
+		return fmt.Errorf("secret %v version %v holds the key for %v", r.ToSecretName, r.SecretVersion, secretAddr)
+	}
+	return nil
+}
+
+// checkBackupDir returns an error if backupDir is not set or is within the account directory, where the backups would
+// be loaded as account configs
+func checkBackupDir(backupDir, acctDir string) error {
+	if backupDir == "" {
+		return errors.New("a backup directory must be set to rewrite account configs")
+	}
+	backupDir, err := filepath.Abs(backupDir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(filepath.Clean(acctDir), backupDir)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("backup directory %v must not be within the accountDirectory", backupDir)
+	}
+	return nil
+}
+
+// backupAccountConfig copies the account config file at path to the same path relative to backupDir as it has to
+// acctDir, returning the backup's path.  Existing backups are not overwritten.
+func backupAccountConfig(path, acctDir, backupDir string) (string, error) {
+	rel, err := filepath.Rel(filepath.Clean(acctDir), path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+	backup := filepath.Join(backupDir, rel)
+	if _, err := os.Stat(backup); err == nil {
+		return "", fmt.Errorf("backup %v already exists", backup)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(backup), 0700); err != nil {
+		return "", err
+	}
+	if err := atomicfile.Write(backup, b, 0600); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
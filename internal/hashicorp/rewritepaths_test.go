@@ -0,0 +1,124 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// writeRewriteAccount writes an account config for the secret to the account directory
+func writeRewriteAccount(t *testing.T, dir, file, addr, secretName string, secretVersion int64) {
+	var acct config.AccountFileJSON
+	acct.Address = addr
+	acct.VaultAccount.SecretName = secretName
+	acct.VaultAccount.SecretVersion = secretVersion
+	acct.Version = 1
+	b, err := json.Marshal(acct)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), b, 0600))
+}
+
+func TestRewritePaths(t *testing.T) {
+	vault := reconcileVaultServer()
+	defer vault.Close()
+
+	dir, err := ioutil.TempDir("", "rewrite")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	acctDir := filepath.Join(dir, "accts")
+	backupDir := filepath.Join(dir, "backup")
+
+	// the secrets were moved from legacy/ to the root of the engine
+	writeRewriteAccount(t, acctDir, "acct1.json", reconcileAddr1, "legacy/acct1", 1)
+	writeRewriteAccount(t, acctDir, "sub/acct2.json", reconcileAddr2, "legacy/acct1", 2)
+	writeRewriteAccount(t, acctDir, "other.json", reconcileAddr1, "acct1", 1)
+
+	a := reconcileAccountManager(t, vault.URL, acctDir)
+	require.NoError(t, a.ReloadAccounts())
+
+	got, err := a.RewritePaths("legacy/", "", "", false)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "0x"+reconcileAddr1, got[0].Address)
+	require.Equal(t, "legacy/acct1", got[0].FromSecretName)
+	require.Equal(t, "acct1", got[0].ToSecretName)
+	require.Empty(t, got[0].Error)
+
+	// nothing is written without confirm
+	b, err := ioutil.ReadFile(filepath.Join(acctDir, "acct1.json"))
+	require.NoError(t, err)
+	require.Contains(t, string(b), "legacy/acct1")
+
+	// backups must not be loaded as account configs
+	_, err = a.RewritePaths("legacy/", "", "", true)
+	require.EqualError(t, err, "a backup directory must be set to rewrite account configs")
+	_, err = a.RewritePaths("legacy/", "", filepath.Join(acctDir, "backup"), true)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must not be within the accountDirectory")
+
+	got, err = a.RewritePaths("legacy/", "", backupDir, true)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(backupDir, "acct1.json"), got[0].Backup)
+	require.Equal(t, filepath.Join(backupDir, "sub", "acct2.json"), got[1].Backup)
+
+	for file, want := range map[string]string{"acct1.json": "acct1", "sub/acct2.json": "acct1", "other.json": "acct1"} {
+		b, err := ioutil.ReadFile(filepath.Join(acctDir, file))
+		require.NoError(t, err)
+		var rewritten config.AccountFileJSON
+		require.NoError(t, json.Unmarshal(b, &rewritten))
+		require.Equal(t, want, rewritten.VaultAccount.SecretName, file)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(backupDir, "sub", "acct2.json"))
+	require.NoError(t, err)
+	require.Contains(t, string(b), "legacy/acct1")
+
+	// the rewritten account configs are loaded
+	for _, acct := range a.client.accounts() {
+		require.Equal(t, "acct1", acct.Contents.VaultAccount.SecretName)
+	}
+}
+
+func TestRewritePaths_NotVerified(t *testing.T) {
+	vault := reconcileVaultServer()
+	defer vault.Close()
+
+	dir, err := ioutil.TempDir("", "rewrite")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	acctDir := filepath.Join(dir, "accts")
+	backupDir := filepath.Join(dir, "backup")
+
+	writeRewriteAccount(t, acctDir, "acct1.json", reconcileAddr1, "legacy/acct1", 1)
+	// the secret at the new path holds the key for a different address
+	writeRewriteAccount(t, acctDir, "acct2.json", reconcileAddr2, "legacy/acct1", 1)
+	// the secret does not exist at the new path
+	writeRewriteAccount(t, acctDir, "missing.json", reconcileAddr2, "legacy/doesnotexist", 1)
+
+	a := reconcileAccountManager(t, vault.URL, acctDir)
+	require.NoError(t, a.ReloadAccounts())
+
+	got, err := a.RewritePaths("legacy/", "", backupDir, true)
+	require.EqualError(t, err, "2 account(s) could not be verified at their new path, no account configs were rewritten")
+	require.Len(t, got, 3)
+
+	var errs []string
+	for _, r := range got {
+		if r.Error != "" {
+			errs = append(errs, r.Error)
+		}
+	}
+	require.Contains(t, errs, "secret acct1 version 1 holds the key for 0x"+reconcileAddr1)
+
+	// no account configs are rewritten or backed up
+	b, err := ioutil.ReadFile(filepath.Join(acctDir, "acct1.json"))
+	require.NoError(t, err)
+	require.Contains(t, string(b), "legacy/acct1")
+	_, err = os.Stat(backupDir)
+	require.True(t, os.IsNotExist(err))
+}
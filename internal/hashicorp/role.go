@@ -0,0 +1,53 @@
+package hashicorp
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// RoleError is a signing request that is not permitted for the role of the account
+type RoleError struct {
+	Role   string
+	Reason string
+}
+
+func (e *RoleError) Error() string {
+	return fmt.Sprintf("%v account cannot be used: %v", e.Role, e.Reason)
+}
+
+// checkRole enforces the account's role using the caller metadata of the request, if the host sends it.  Requests
+// triggered by an RPC call carry its origin, whereas node-internal requests (e.g. sealing a block) identify the node
+// without an origin.  Requests without caller metadata, as sent by stock Quorum, cannot be distinguished and are not
+// refused.  The metadata is not authenticated, so roles only guard against mistakes by a host that sends it.  A
+// digest domain declared by the caller is also enforced: validators only sign consensus digests, and other roles
+// never do.
+func checkRole(ctx context.Context, acctFile config.AccountFile) error {
+	role := acctFile.Contents.AccountRole()
+	caller := audit.CallerFromContext(ctx)
+	domain := audit.SignDomainFromContext(ctx)
+
+	var err *RoleError
+	switch role {
+	case config.AccountRoleValidator:
+		if caller.RPCOrigin != "" {
+			err = &RoleError{Role: role, Reason: fmt.Sprintf("validator keys can only sign consensus payloads, not %v requests", caller.RPCOrigin)}
+		} else if domain != "" && domain != audit.SignDomainConsensus {
+			err = &RoleError{Role: role, Reason: fmt.Sprintf("validator keys can only sign consensus payloads, not %v digests", domain)}
+		}
+	case config.AccountRoleTransaction, config.AccountRoleFaucet:
+		if caller.NodeID != "" && caller.RPCOrigin == "" {
+			err = &RoleError{Role: role, Reason: "only validator keys can sign node-internal requests such as sealing blocks"}
+		} else if domain == audit.SignDomainConsensus {
+			err = &RoleError{Role: role, Reason: "only validator keys can sign consensus payloads"}
+		}
+	}
+	if err != nil {
+		log.Printf("[WARN] refused signing request for 0x%v: %v", acctFile.Contents.Address, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package hashicorp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func roleAccount(role string) config.AccountFile {
+	acct := config.AccountFile{}
+	acct.Contents.Address = reconcileAddr1
+	acct.Contents.Role = role
+	return acct
+}
+
+func callerContext(nodeID, rpcOrigin string) context.Context {
+	md := metadata.MD{}
+	if nodeID != "" {
+		md.Set(audit.NodeIDKey, nodeID)
+	}
+	if rpcOrigin != "" {
+		md.Set(audit.RPCOriginKey, rpcOrigin)
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestCheckRole_Unrestricted(t *testing.T) {
+	require.NoError(t, checkRole(callerContext("node1", ""), roleAccount("")))
+	require.NoError(t, checkRole(callerContext("node1", "eth_sendTransaction"), roleAccount("")))
+}
+
+func TestCheckRole_Validator(t *testing.T) {
+	require.NoError(t, checkRole(context.Background(), roleAccount(config.AccountRoleValidator)))
+	require.NoError(t, checkRole(callerContext("node1", ""), roleAccount(config.AccountRoleValidator)))
+
+	err := checkRole(callerContext("node1", "eth_sendTransaction"), roleAccount(config.AccountRoleValidator))
+	require.IsType(t, &RoleError{}, err)
+	require.EqualError(t, err, "validator account cannot be used: validator keys can only sign consensus payloads, not eth_sendTransaction requests")
+}
+
+func TestCheckRole_SealerIsValidator(t *testing.T) {
+	acct := roleAccount("")
+	acct.Contents.Sealer = true
+
+	require.IsType(t, &RoleError{}, checkRole(callerContext("", "personal_sign"), acct))
+}
+
+func TestCheckRole_Transaction(t *testing.T) {
+	for _, role := range []string{config.AccountRoleTransaction, config.AccountRoleFaucet} {
+		require.NoError(t, checkRole(context.Background(), roleAccount(role)), role)
+		require.NoError(t, checkRole(callerContext("node1", "eth_sendTransaction"), roleAccount(role)), role)
+
+		err := checkRole(callerContext("node1", ""), roleAccount(role))
+		require.IsType(t, &RoleError{}, err, role)
+	}
+}
+
+func domainContext(ctx context.Context, domain string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = metadata.Join(md, metadata.Pairs(audit.SignDomainKey, domain))
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestCheckRole_SignDomain(t *testing.T) {
+	validator := roleAccount(config.AccountRoleValidator)
+	require.NoError(t, checkRole(domainContext(callerContext("node1", ""), audit.SignDomainConsensus), validator))
+
+	err := checkRole(domainContext(callerContext("node1", ""), audit.SignDomainPersonalMessage), validator)
+	require.EqualError(t, err, "validator account cannot be used: validator keys can only sign consensus payloads, not personal_message digests")
+
+	for _, role := range []string{config.AccountRoleTransaction, config.AccountRoleFaucet} {
+		require.NoError(t, checkRole(domainContext(callerContext("node1", "eth_signTypedData"), audit.SignDomainTypedData), roleAccount(role)), role)
+
+		err := checkRole(domainContext(context.Background(), audit.SignDomainConsensus), roleAccount(role))
+		require.EqualError(t, err, role+" account cannot be used: only validator keys can sign consensus payloads")
+	}
+}
@@ -0,0 +1,52 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+)
+
+// isSecretIDRejected reports whether err is Vault refusing an approle login because the secret_id is not valid, e.g.
+// because its TTL or number of uses is exhausted
+func isSecretIDRejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "Code: 400") && strings.Contains(strings.ToLower(msg), "secret id")
+}
+
+// rotateSecretID generates a new secret_id for the approle role using the secretIdRotator token, and uses it for all
+// later logins until a different secretId is provided
+func (c *vaultClient) rotateSecretID(conf config.VaultClientAuthentication) (string, error) {
+	rotator := conf.SecretIdRotator
+	token := rotator.Token.Get()
+	if token == "" {
+		return "", fmt.Errorf("%v is empty", rotator.Token.String())
+	}
+	configured := conf.SecretId.Get()
+
+	path := fmt.Sprintf("/v1/auth/%v/role/%v/secret-id", conf.ApprolePath, rotator.Role)
+	resp, err := c.requestWithToken(path, token, nil)
+	if err != nil {
+		return "", err
+	}
+	secretID, _ := resp.Data["secret_id"].(string)
+	if secretID == "" {
+		return "", errors.New("response does not contain a secret_id")
+	}
+
+	w := c.secretIDs
+	w.mu.Lock()
+	w.rotatedFrom, w.rotated = configured, secretID
+	w.mu.Unlock()
+
+	accessor, _ := resp.Data["secret_id_accessor"].(string)
+	log.Printf("[INFO] generated a new approle secret_id for role %v: secret_id_accessor = %v", rotator.Role, accessor)
+	event.Emit(event.AuthSecretIDRotated, authID(conf), "secret_id_accessor "+accessor)
+	return secretID, nil
+}
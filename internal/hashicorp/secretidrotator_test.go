@@ -0,0 +1,131 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/stretchr/testify/require"
+)
+
+// rotatorVaultServer serves approle logins, accepting only the secret_ids in valid, and generates secret_ids for the
+// quorum role when requested with the rotator token
+func rotatorVaultServer(t *testing.T, valid map[string]bool) (*httptest.Server, *int) {
+	var generated int
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var secret api.Secret
+		switch r.URL.Path {
+		case "/v1/auth/approle/role/quorum/secret-id":
+			if r.Header.Get("X-Vault-Token") != "rotator-token" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			generated++
+			secretID := fmt.Sprintf("generated-%v", generated)
+			valid[secretID] = true
+			secret.Data = map[string]interface{}{"secret_id": secretID, "secret_id_accessor": "accessor-" + secretID}
+		case "/v1/auth/approle/login":
+			body := make(map[string]string)
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if !valid[body["secret_id"]] {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["invalid role or secret ID"]}`))
+				return
+			}
+			secret.Auth = &api.SecretAuth{ClientToken: "token-" + body["secret_id"]}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b, _ := json.Marshal(&secret)
+		_, _ = w.Write(b)
+	})), &generated
+}
+
+func rotatorAuth(t *testing.T, rotatorToken string) config.VaultClientAuthentication {
+	require.NoError(t, os.Setenv("ROTATOR_TEST_ROLE_ID", "role-id"))
+	require.NoError(t, os.Setenv("ROTATOR_TEST_TOKEN", rotatorToken))
+	roleID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "ROTATOR_TEST_ROLE_ID"})
+	secretID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "ROTATOR_TEST_SECRET_ID"})
+	token := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "ROTATOR_TEST_TOKEN"})
+	return config.VaultClientAuthentication{
+		RoleId:          &roleID,
+		SecretId:        &secretID,
+		ApprolePath:     "approle",
+		SecretIdRotator: config.VaultClientSecretIdRotator{Token: &token, Role: "quorum"},
+	}
+}
+
+func TestIsSecretIDRejected(t *testing.T) {
+	require.True(t, isSecretIDRejected(fmt.Errorf("Error making API request.\n\nCode: 400. Errors:\n\n* invalid secret id")))
+	require.True(t, isSecretIDRejected(fmt.Errorf("Error making API request.\n\nCode: 400. Errors:\n\n* invalid role or secret ID")))
+	require.False(t, isSecretIDRejected(fmt.Errorf("Error making API request.\n\nCode: 403. Errors:\n\n* permission denied")))
+	require.False(t, isSecretIDRejected(nil))
+}
+
+func TestVaultClient_AuthenticateWithApprole_RotatesRejectedSecretID(t *testing.T) {
+	defer os.Unsetenv("ROTATOR_TEST_ROLE_ID")
+	defer os.Unsetenv("ROTATOR_TEST_SECRET_ID")
+	defer os.Unsetenv("ROTATOR_TEST_TOKEN")
+
+	vault, generated := rotatorVaultServer(t, map[string]bool{"provisioned": true})
+	defer vault.Close()
+	c := testVaultClient(t, vault.URL)
+	auth := rotatorAuth(t, "rotator-token")
+
+	events, unsubscribe := event.Subscribe(10)
+	defer unsubscribe()
+
+	// the provisioned secret_id has been used up
+	require.NoError(t, os.Setenv("ROTATOR_TEST_SECRET_ID", "exhausted"))
+	r, err := c.authenticateWithApprole(auth)
+	require.NoError(t, err)
+	require.Equal(t, "token-generated-1", r.Auth.ClientToken)
+	e := <-events
+	require.Equal(t, event.AuthSecretIDRotated, e.Kind)
+	require.Equal(t, "approle/approle", e.Subject)
+
+	// the generated secret_id is used for later logins
+	r, err = c.authenticateWithApprole(auth)
+	require.NoError(t, err)
+	require.Equal(t, "token-generated-1", r.Auth.ClientToken)
+	require.Equal(t, 1, *generated)
+
+	// a secret_id provided by the operator replaces the generated secret_id
+	require.NoError(t, os.Setenv("ROTATOR_TEST_SECRET_ID", "provisioned"))
+	r, err = c.authenticateWithApprole(auth)
+	require.NoError(t, err)
+	require.Equal(t, "token-provisioned", r.Auth.ClientToken)
+}
+
+func TestVaultClient_AuthenticateWithApprole_RotatorRefused(t *testing.T) {
+	defer os.Unsetenv("ROTATOR_TEST_ROLE_ID")
+	defer os.Unsetenv("ROTATOR_TEST_SECRET_ID")
+	defer os.Unsetenv("ROTATOR_TEST_TOKEN")
+
+	vault, _ := rotatorVaultServer(t, map[string]bool{})
+	defer vault.Close()
+	c := testVaultClient(t, vault.URL)
+	require.NoError(t, os.Setenv("ROTATOR_TEST_SECRET_ID", "exhausted"))
+
+	auth := rotatorAuth(t, "revoked-rotator-token")
+	_, err := c.authenticateWithApprole(auth)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid role or secret ID")
+	require.Contains(t, err.Error(), "unable to generate a new secret_id")
+	require.Contains(t, err.Error(), "permission denied")
+
+	// without a rotator the rejection is returned
+	auth.SecretIdRotator = config.VaultClientSecretIdRotator{}
+	_, err = c.authenticateWithApprole(auth)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "unable to generate a new secret_id")
+}
@@ -0,0 +1,32 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SecretMetadata returns the Vault metadata (e.g. version history, timestamps) of each secret referenced by the loaded
+// account configs, keyed by secret name.  Metadata does not include secret data.
+func (a *accountManager) SecretMetadata() (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	for _, acct := range a.client.accounts() {
+		name := acct.Contents.VaultAccount.SecretName
+		if _, done := result[name]; done {
+			continue
+		}
+		meta, err := a.secrets.metadata(name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read metadata for secret %v: %v", name, err)
+		}
+		if meta == nil {
+			// secret does not exist
+			continue
+		}
+		b, err := json.Marshal(meta)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = b
+	}
+	return result, nil
+}
@@ -0,0 +1,180 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// secretStore is the Vault secrets engine that account keys are kept in.  Secrets are versioned and each account config
+// references a specific version of a secret.  Keys are kept in a KV version 2 engine (kvV2Store) unless the cubbyhole
+// engine is configured (cubbyholeStore), or in memory in dev mode (memorySecretStore).
+type secretStore interface {
+	// read returns the data of a version of the secret, where version 0 is the latest.  It returns nil if the version
+	// does not exist, or a secret with only WrapInfo if the read must first be approved by a control group.
+	read(ctx context.Context, name string, version int64) (*api.Secret, error)
+	// write stores data as a new version of the secret and returns the new version.  If cas is not nil the write fails
+	// unless *cas is the current version of the secret, where 0 means the secret must not exist.
+	write(name string, data map[string]interface{}, cas *int64) (int64, error)
+	// delete soft-deletes versions of the secret, so they can still be recovered
+	delete(name string, versions []int64) error
+	// list returns the names of the secrets and folders (ending in /) directly under prefix, or nil if there are none
+	list(prefix string) ([]string, error)
+	// metadata returns the version history of the secret, or nil if it does not exist
+	metadata(name string) (map[string]interface{}, error)
+	// writeCustomMetadata adds custom to the custom metadata of the secret, keeping any other keys.  It does nothing
+	// for engines without custom metadata.
+	writeCustomMetadata(name string, custom map[string]string) error
+	// location identifies the secret, e.g. in the read cache
+	location(name string) string
+}
+
+func newSecretStore(client *vaultClient, conf config.VaultClient) secretStore {
+	if conf.Dev {
+		return newMemorySecretStore()
+	}
+	if conf.SecretsEngine == config.SecretsEngineCubbyhole {
+		return &cubbyholeStore{client: client}
+	}
+	return newKVv2Store(client, conf.KVEngineName)
+}
+
+// secretsEngineName is the path of the secrets engine used in account URLs
+func secretsEngineName(conf config.VaultClient) string {
+	if conf.Dev {
+		return devEngineName
+	}
+	if conf.SecretsEngine == config.SecretsEngineCubbyhole {
+		return config.SecretsEngineCubbyhole
+	}
+	return conf.KVEngineName
+}
+
+// kvV2Store keeps secrets in a KV version 2 secrets engine
+type kvV2Store struct {
+	client *vaultClient
+	engine string
+}
+
+func newKVv2Store(client *vaultClient, engine string) *kvV2Store {
+	return &kvV2Store{client: client, engine: engine}
+}
+
+func (s *kvV2Store) location(name string) string {
+	return fmt.Sprintf("%v/data/%v", s.engine, name)
+}
+
+func (s *kvV2Store) read(ctx context.Context, name string, version int64) (*api.Secret, error) {
+	params := map[string][]string{"version": {strconv.FormatInt(version, 10)}}
+
+	resp, err := s.client.readWithRetry(ctx, s.location(name), params)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	if resp.WrapInfo != nil && resp.Data == nil {
+		return resp, nil
+	}
+	// the secret's data is returned alongside its metadata
+	rawData, ok := resp.Data["data"]
+	if ok && rawData == nil {
+		// the version has been deleted or destroyed, only its metadata is returned
+		return nil, nil
+	}
+	data, ok := rawData.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("no secret information returned from Vault")
+	}
+	resp.Data = data
+	return resp, nil
+}
+
+func (s *kvV2Store) write(name string, data map[string]interface{}, cas *int64) (int64, error) {
+	body := map[string]interface{}{"data": data}
+	if cas != nil {
+		body["options"] = map[string]interface{}{"cas": *cas}
+	}
+	resp, err := s.client.write(s.location(name), body)
+	if err != nil || resp == nil {
+		return 0, err
+	}
+	v, ok := resp.Data["version"]
+	if !ok {
+		return 0, nil
+	}
+	vJson, ok := v.(json.Number)
+	if !ok {
+		return 0, errors.New("invalid version information returned from Vault")
+	}
+	version, err := vJson.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("invalid version information returned from Vault, %v", err)
+	}
+	return version, nil
+}
+
+func (s *kvV2Store) delete(name string, versions []int64) error {
+	_, err := s.client.Logical().Write(fmt.Sprintf("%v/delete/%v", s.engine, name), map[string]interface{}{"versions": versions})
+	return err
+}
+
+func (s *kvV2Store) list(prefix string) ([]string, error) {
+	resp, err := s.client.read(context.Background(), func(c *api.Client) (*api.Secret, error) {
+		return c.Logical().List(fmt.Sprintf("%v/metadata/%v", s.engine, prefix))
+	})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	return listKeys(resp)
+}
+
+func (s *kvV2Store) metadata(name string) (map[string]interface{}, error) {
+	resp, err := s.client.read(context.Background(), func(c *api.Client) (*api.Secret, error) {
+		return c.Logical().Read(fmt.Sprintf("%v/metadata/%v", s.engine, name))
+	})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// writeCustomMetadata merges custom into the secret's existing custom metadata, as writing the metadata replaces it.
+// Vault 1.9 and later store custom metadata, earlier versions ignore it.
+func (s *kvV2Store) writeCustomMetadata(name string, custom map[string]string) error {
+	existing, err := s.metadata(name)
+	if err != nil {
+		return err
+	}
+	merged := make(map[string]interface{}, len(custom))
+	if m, ok := existing["custom_metadata"].(map[string]interface{}); ok {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	for k, v := range custom {
+		merged[k] = v
+	}
+	_, err = s.client.write(fmt.Sprintf("%v/metadata/%v", s.engine, name), map[string]interface{}{"custom_metadata": merged})
+	return err
+}
+
+// listKeys returns the keys of a list response
+func listKeys(resp *api.Secret) ([]string, error) {
+	keys, ok := resp.Data["keys"].([]interface{})
+	if !ok {
+		return nil, errors.New("invalid list response from Vault")
+	}
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		key, ok := k.(string)
+		if !ok {
+			return nil, errors.New("invalid list response from Vault")
+		}
+		names = append(names, key)
+	}
+	return names, nil
+}
@@ -0,0 +1,121 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/require"
+)
+
+// serveSecretMetadata responds to the requests made to write the custom metadata of a new account's secret, returning
+// false for any other request
+func serveSecretMetadata(w http.ResponseWriter, r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, "/v1/kv/metadata/") {
+		return false
+	}
+	if r.Method == http.MethodGet {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return true
+}
+
+func TestKVv2Store_Read(t *testing.T) {
+	vault := reconcileVaultServer()
+	defer vault.Close()
+
+	s := reconcileAccountManager(t, vault.URL, "/path/to/dir").secrets
+
+	got, err := s.read(context.Background(), "acct1", 2)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{reconcileAddr2: reconcileKey2}, got.Data)
+
+	got, err = s.read(context.Background(), "acct1", 3)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	require.Equal(t, "kv/data/acct1", s.location("acct1"))
+}
+
+func TestKVv2Store_Write(t *testing.T) {
+	var gotBody map[string]interface{}
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/kv/data/acct1", r.URL.Path)
+		b, _ := ioutil.ReadAll(r.Body)
+		gotBody = nil
+		require.NoError(t, json.Unmarshal(b, &gotBody))
+		resp, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"version": 3}})
+		_, _ = w.Write(resp)
+	}))
+	defer vault.Close()
+
+	s := reconcileAccountManager(t, vault.URL, "/path/to/dir").secrets
+
+	cas := int64(2)
+	version, err := s.write("acct1", map[string]interface{}{"key": "value"}, &cas)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), version)
+	require.Equal(t, map[string]interface{}{"key": "value"}, gotBody["data"])
+	require.Equal(t, map[string]interface{}{"cas": float64(2)}, gotBody["options"])
+
+	_, err = s.write("acct1", map[string]interface{}{"key": "value"}, nil)
+	require.NoError(t, err)
+	require.NotContains(t, gotBody, "options")
+}
+
+func TestKVv2Store_ListAndMetadata(t *testing.T) {
+	vault := reconcileVaultServer()
+	defer vault.Close()
+
+	s := reconcileAccountManager(t, vault.URL, "/path/to/dir").secrets
+
+	names, err := s.list("")
+	require.NoError(t, err)
+	require.Equal(t, []string{"acct1", "acct2"}, names)
+
+	names, err = s.list("doesnotexist/")
+	require.NoError(t, err)
+	require.Nil(t, names)
+
+	meta, err := s.metadata("acct2")
+	require.NoError(t, err)
+	require.Contains(t, meta, "versions")
+
+	meta, err = s.metadata("doesnotexist")
+	require.NoError(t, err)
+	require.Nil(t, meta)
+}
+
+func TestKVv2Store_WriteCustomMetadata(t *testing.T) {
+	var gotBody map[string]interface{}
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/kv/metadata/acct1", r.URL.Path)
+		if r.Method == http.MethodGet {
+			resp, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{
+				"current_version": 1,
+				"custom_metadata": map[string]interface{}{"owner": "ops", "address": "0xold"},
+			}})
+			_, _ = w.Write(resp)
+			return
+		}
+		require.Equal(t, http.MethodPut, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer vault.Close()
+
+	s := reconcileAccountManager(t, vault.URL, "/path/to/dir").secrets
+	require.NoError(t, s.writeCustomMetadata("acct1", map[string]string{"address": "0x" + reconcileAddr1, "accountId": "id"}))
+
+	// the keys written by operators are kept
+	require.Equal(t, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"owner": "ops", "address": "0x" + reconcileAddr1, "accountId": "id"},
+	}, gotBody)
+}
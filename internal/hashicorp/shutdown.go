@@ -0,0 +1,63 @@
+package hashicorp
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// RevokeTokens revokes the Vault tokens the plugin obtained by logging in, including standby tokens and the token of the
+// DR secondary if it has been used, so that they do not remain valid in Vault after the plugin stops.  Tokens are no
+// longer renewed or replaced, so the account manager cannot be used afterwards.
+func (a *accountManager) RevokeTokens() error {
+	err := a.client.revokeOnShutdown()
+	if d := a.client.dr; d != nil {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.authed {
+			if drErr := d.client.revokeOnShutdown(); drErr != nil && err == nil {
+				err = fmt.Errorf("unable to revoke token for DR secondary %v: %v", d.client.Address(), drErr)
+			}
+		}
+	}
+	return err
+}
+
+// revokeOnShutdown stops renewal of the token and revokes it with auth/token/revoke-self.  A static token is not revoked
+// as it is owned by the operator, nor is a token persisted by the tokenSink, as it is resumed when the plugin restarts.
+func (c *vaultClient) revokeOnShutdown() error {
+	if c.dev {
+		return nil
+	}
+	// a login in progress installs its token before renewal is stopped, so that it is the token that is revoked
+	c.loginMu.Lock()
+	c.authMu.Lock()
+	c.supersedeRenewalLocked()
+	previous := c.authStatus
+	c.authStatus = authShutdown
+	c.authMu.Unlock()
+	c.loginMu.Unlock()
+
+	c.pool.close()
+
+	conf := c.auth
+	switch {
+	case previous == authStatic || (conf.Token != nil && conf.Token.IsSet()):
+		return nil
+	case c.sink != nil:
+		log.Printf("[INFO] Vault auth token not revoked on shutdown as it is persisted by the tokenSink: %v", authMethod(conf))
+		return nil
+	}
+
+	r := c.NewRequest(http.MethodPost, "/v1/auth/token/revoke-self")
+	resp, err := c.RawRequest(r)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("unable to revoke Vault auth token: %v", err)
+	}
+	c.ClearToken()
+	log.Printf("[INFO] revoked Vault auth token on shutdown: %v", authMethod(conf))
+	return nil
+}
@@ -0,0 +1,68 @@
+package hashicorp
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultClient_RevokeOnShutdown(t *testing.T) {
+	defer os.Unsetenv("STANDBY_TEST_ROLE_ID")
+	defer os.Unsetenv("STANDBY_TEST_SECRET_ID")
+	vault := newStandbyTokenVaultServer(t, 3600)
+	defer vault.Close()
+
+	c, auth := standbyTokenClient(t, vault.URL, 1)
+	c.auth = auth
+	require.NoError(t, c.authenticate(auth))
+	c.pool.fill(auth)
+	require.Equal(t, 2, vault.loginCount())
+
+	require.NoError(t, c.revokeOnShutdown())
+	require.Equal(t, []string{"token-2", "token-1"}, vault.revoked)
+	require.Equal(t, authShutdown, c.getAuthStatus())
+	require.Empty(t, c.Token())
+
+	// the token is not replaced once revoked, and the pool is not refilled
+	c.tokenRevoked(errors.New("Code: 403. Errors:\n\n* invalid token"))
+	c.pool.fill(auth)
+	require.Equal(t, 0, c.pool.len())
+	require.Equal(t, 2, vault.loginCount())
+}
+
+func TestVaultClient_RevokeOnShutdown_StaticToken(t *testing.T) {
+	vault := newStandbyTokenVaultServer(t, 3600)
+	defer vault.Close()
+
+	c, _ := standbyTokenClient(t, vault.URL, 0)
+	require.NoError(t, os.Setenv("SHUTDOWN_TEST_TOKEN", "operator-token"))
+	defer os.Unsetenv("SHUTDOWN_TEST_TOKEN")
+	token := config.EnvironmentVariable{Scheme: "env", Host: "SHUTDOWN_TEST_TOKEN"}
+	c.auth = config.VaultClientAuthentication{Token: &token}
+	c.SetToken("operator-token")
+	c.setAuthStatus(authStatic)
+
+	// the operator's token is left for the operator to revoke
+	require.NoError(t, c.revokeOnShutdown())
+	require.Empty(t, vault.revoked)
+	require.Equal(t, "operator-token", c.Token())
+}
+
+func TestVaultClient_RevokeOnShutdown_TokenSink(t *testing.T) {
+	defer os.Unsetenv("STANDBY_TEST_ROLE_ID")
+	defer os.Unsetenv("STANDBY_TEST_SECRET_ID")
+	vault := newStandbyTokenVaultServer(t, 3600)
+	defer vault.Close()
+
+	c, auth := standbyTokenClient(t, vault.URL, 0)
+	c.auth = auth
+	c.SetToken("sunk-token")
+	c.sink = &tokenSink{}
+
+	// the persisted token is resumed when the plugin restarts
+	require.NoError(t, c.revokeOnShutdown())
+	require.Empty(t, vault.revoked)
+}
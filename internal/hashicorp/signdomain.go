@@ -0,0 +1,40 @@
+package hashicorp
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// SignDomainError is a signing request refused because it did not declare a valid domain for its digest
+type SignDomainError struct {
+	Address string
+	Reason  string
+}
+
+func (e *SignDomainError) Error() string {
+	return fmt.Sprintf("signing with account 0x%v refused: %v", e.Address, e.Reason)
+}
+
+// checkSignDomain checks the digest domain the caller declared in the audit.SignDomainKey gRPC metadata.  A request
+// without a domain is only refused if strict is true.
+func checkSignDomain(ctx context.Context, acctFile config.AccountFile, strict bool) error {
+	var reason string
+	switch domain := audit.SignDomainFromContext(ctx); domain {
+	case audit.SignDomainTransaction, audit.SignDomainConsensus, audit.SignDomainPersonalMessage, audit.SignDomainTypedData:
+		return nil
+	case "":
+		if !strict {
+			return nil
+		}
+		reason = fmt.Sprintf("the digest domain must be declared in %v metadata", audit.SignDomainKey)
+	default:
+		reason = fmt.Sprintf("unknown digest domain %q", domain)
+	}
+	err := &SignDomainError{Address: acctFile.Contents.Address, Reason: reason}
+	log.Printf("[WARN] refused signing request for 0x%v: %v", acctFile.Contents.Address, reason)
+	return err
+}
@@ -0,0 +1,49 @@
+package hashicorp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSignDomain(t *testing.T) {
+	acct := roleAccount("")
+
+	for _, domain := range []string{audit.SignDomainTransaction, audit.SignDomainConsensus, audit.SignDomainPersonalMessage, audit.SignDomainTypedData} {
+		require.NoError(t, checkSignDomain(domainContext(context.Background(), domain), acct, true), domain)
+	}
+
+	// untyped requests are only refused in strict mode
+	require.NoError(t, checkSignDomain(context.Background(), acct, false))
+	err := checkSignDomain(context.Background(), acct, true)
+	require.IsType(t, &SignDomainError{}, err)
+	require.EqualError(t, err, "signing with account 0x"+reconcileAddr1+" refused: the digest domain must be declared in quorum-sign-domain metadata")
+
+	require.EqualError(t, checkSignDomain(domainContext(context.Background(), "raw"), acct, false), "signing with account 0x"+reconcileAddr1+" refused: unknown digest domain \"raw\"")
+}
+
+func TestAccountManager_StrictSignDomains(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signdomain")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dirURL, _ := url.Parse("file://" + dir + "/")
+
+	a, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL, StrictSignDomains: true})
+	require.NoError(t, err)
+
+	acct, err := a.NewAccount(config.NewAccount{SecretName: "acct"})
+	require.NoError(t, err)
+	toSign := make([]byte, 32)
+
+	_, err = a.UnlockAndSign(context.Background(), acct.Address, toSign)
+	require.IsType(t, &SignDomainError{}, err)
+
+	_, err = a.UnlockAndSign(domainContext(context.Background(), audit.SignDomainTransaction), acct.Address, toSign)
+	require.NoError(t, err)
+}
@@ -0,0 +1,247 @@
+package hashicorp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/atomicfile"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/state"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// SignGrantKey is the gRPC metadata key a sign grant is presented in
+	SignGrantKey = "quorum-sign-grant"
+	// MaxSignGrantTTL is the longest a grant can be valid for.  It limits how long redeemed grants must be remembered.
+	MaxSignGrantTTL = time.Hour
+	signGrantsFile  = "sign-grants"
+)
+
+// SignGrantError is a signing request refused because it did not present a valid, unused grant for the account and
+// payload
+type SignGrantError struct {
+	Address string
+	Reason  string
+}
+
+func (e *SignGrantError) Error() string {
+	return fmt.Sprintf("signing with account 0x%v requires a sign grant: %v", e.Address, e.Reason)
+}
+
+// SignGrant pre-authorizes a single signature of Hash by Account until Expiry
+type SignGrant struct {
+	Account string `json:"account"` // 0x-prefixed hex address
+	Hash    string `json:"hash"`    // 0x-prefixed hex of the payload to sign
+	Expiry  int64  `json:"expiry"`  // unix seconds
+	Nonce   string `json:"nonce"`   // random, so that each grant can only be redeemed once
+}
+
+// IssueSignGrant returns a grant for a single signature of toSign by addr, valid for ttl.  The grant is the
+// base64url-encoded JSON SignGrant and its base64url-encoded HMAC-SHA256 with key, joined by a '.'.
+func IssueSignGrant(key string, addr account.Address, toSign []byte, ttl time.Duration) (string, error) {
+	if ttl <= 0 || ttl > MaxSignGrantTTL {
+		return "", fmt.Errorf("grant ttl must be greater than 0 and at most %v", MaxSignGrantTTL)
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(SignGrant{
+		Account: "0x" + addr.ToHexString(),
+		Hash:    "0x" + hex.EncodeToString(toSign),
+		Expiry:  time.Now().Add(ttl).Unix(),
+		Nonce:   hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signGrantMAC(key, encoded)), nil
+}
+
+func signGrantMAC(key, encoded string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// parseSignGrant returns the grant if its HMAC is valid for key
+func parseSignGrant(key, grant string) (SignGrant, error) {
+	parts := strings.Split(grant, ".")
+	if len(parts) != 2 {
+		return SignGrant{}, errors.New("malformed grant")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, signGrantMAC(key, parts[0])) {
+		return SignGrant{}, errors.New("invalid grant signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return SignGrant{}, errors.New("malformed grant")
+	}
+	var g SignGrant
+	if err := json.Unmarshal(payload, &g); err != nil {
+		return SignGrant{}, errors.New("malformed grant")
+	}
+	return g, nil
+}
+
+func signGrantFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	vals := md.Get(SignGrantKey)
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+// signGrants enforces single-use grants for the configured accounts.  Redeemed grants are remembered until they expire,
+// in the state directory if configured so that a grant cannot be redeemed again after a restart.
+type signGrants struct {
+	key      *config.EnvironmentVariable
+	accounts map[string]bool // lowercase hex address without 0x prefix
+	path     string          // empty if no state directory is configured
+	mu       sync.Mutex
+	redeemed map[string]int64 // nonce -> expiry
+}
+
+// newSignGrants returns nil if signGrants is not configured
+func newSignGrants(stateDir *state.Dir, conf config.VaultClientSignGrants) *signGrants {
+	if conf.Key == nil {
+		return nil
+	}
+	g := &signGrants{
+		key:      conf.Key,
+		accounts: make(map[string]bool, len(conf.Accounts)),
+		redeemed: make(map[string]int64),
+	}
+	for _, addr := range conf.Accounts {
+		g.accounts[config.NormalizeAddress(addr)] = true
+	}
+	if stateDir == nil {
+		log.Println("[WARN] signGrants: no stateDirectory configured, grants redeemed before a restart can be redeemed again until they expire")
+		return g
+	}
+	g.path = stateDir.File(signGrantsFile)
+	b, err := ioutil.ReadFile(g.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[WARN] unable to read redeemed sign grants, err = %v", err)
+		}
+		return g
+	}
+	if err := json.Unmarshal(b, &g.redeemed); err != nil {
+		log.Printf("[WARN] unable to read redeemed sign grants, err = %v", err)
+		g.redeemed = make(map[string]int64)
+	}
+	return g
+}
+
+// verify checks that ctx presents a valid, unused grant to sign toSign if the account requires one, returning the grant
+// or nil if the account does not require one.  The grant is not used up until it is redeemed, so that a request that
+// fails before it is signed (e.g. as the account is locked) does not use it up.
+func (g *signGrants) verify(ctx context.Context, acctAddr account.Address, toSign []byte) (*SignGrant, error) {
+	if g == nil || !g.accounts[acctAddr.ToHexString()] {
+		return nil, nil
+	}
+
+	presented := signGrantFromContext(ctx)
+	if presented == "" {
+		return nil, refuseSignGrant(acctAddr, "no grant presented")
+	}
+	grant, err := parseSignGrant(g.key.Get(), presented)
+	if err != nil {
+		return nil, refuseSignGrant(acctAddr, err.Error())
+	}
+	if config.NormalizeAddress(grant.Account) != acctAddr.ToHexString() {
+		return nil, refuseSignGrant(acctAddr, "grant is for a different account")
+	}
+	if config.NormalizeAddress(grant.Hash) != hex.EncodeToString(toSign) {
+		return nil, refuseSignGrant(acctAddr, "grant is for a different payload")
+	}
+	now := time.Now()
+	if grant.Expiry <= now.Unix() {
+		return nil, refuseSignGrant(acctAddr, "grant has expired")
+	}
+	if grant.Expiry > now.Add(MaxSignGrantTTL).Unix() {
+		return nil, refuseSignGrant(acctAddr, fmt.Sprintf("grant expires more than %v from now", MaxSignGrantTTL))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.isRedeemed(grant.Nonce, now) {
+		return nil, refuseSignGrant(acctAddr, "grant has already been used")
+	}
+	return &grant, nil
+}
+
+// redeem marks a grant returned by verify as used, once the request has the key to sign with.  It is refused if a
+// concurrent request has redeemed the grant since it was verified.  A preview does not use up the grant.
+func (g *signGrants) redeem(ctx context.Context, acctAddr account.Address, grant *SignGrant) error {
+	if grant == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.isRedeemed(grant.Nonce, time.Now()) {
+		return refuseSignGrant(acctAddr, "grant has already been used")
+	}
+	if audit.SignPreviewFromContext(ctx) {
+		return nil
+	}
+	g.redeemed[grant.Nonce] = grant.Expiry
+	if err := g.persist(); err != nil {
+		// the grant stays redeemed in memory, but is refused as it could be redeemed again after a restart
+		return refuseSignGrant(acctAddr, fmt.Sprintf("unable to record redeemed grant: %v", err))
+	}
+	log.Printf("[INFO] redeemed sign grant for 0x%v", acctAddr.ToHexString())
+	return nil
+}
+
+// isRedeemed returns true if the grant with nonce has been redeemed, forgetting grants that have expired.  g.mu must be
+// held.
+func (g *signGrants) isRedeemed(nonce string, now time.Time) bool {
+	for n, expiry := range g.redeemed {
+		if expiry <= now.Unix() {
+			delete(g.redeemed, n)
+		}
+	}
+	_, ok := g.redeemed[nonce]
+	return ok
+}
+
+func refuseSignGrant(acctAddr account.Address, reason string) error {
+	log.Printf("[WARN] refused signing request for 0x%v: %v", acctAddr.ToHexString(), reason)
+	return &SignGrantError{Address: acctAddr.ToHexString(), Reason: reason}
+}
+
+func (g *signGrants) persist() error {
+	if g.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(g.redeemed)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(g.path, b, 0600)
+}
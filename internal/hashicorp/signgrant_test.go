@@ -0,0 +1,149 @@
+package hashicorp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/state"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+const testSignGrantKey = "0123456789abcdef0123456789abcdef"
+
+func grantContext(grant string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(SignGrantKey, grant))
+}
+
+func testSignGrantsConfig(t *testing.T) config.VaultClientSignGrants {
+	require.NoError(t, os.Setenv("SIGN_GRANT_TEST_KEY", testSignGrantKey))
+	key := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "SIGN_GRANT_TEST_KEY"})
+	return config.VaultClientSignGrants{Key: &key, Accounts: []string{"0x" + reconcileAddr1}}
+}
+
+// verifyAndRedeem verifies and redeems the grant ctx presents, as a signing request does
+func verifyAndRedeem(ctx context.Context, g *signGrants, acctAddr account.Address, toSign []byte) error {
+	grant, err := g.verify(ctx, acctAddr, toSign)
+	if err != nil {
+		return err
+	}
+	return g.redeem(ctx, acctAddr, grant)
+}
+
+func TestSignGrants_Redeem(t *testing.T) {
+	defer os.Unsetenv("SIGN_GRANT_TEST_KEY")
+	g := newSignGrants(nil, testSignGrantsConfig(t))
+
+	addr1, _ := account.NewAddressFromHexString(reconcileAddr1)
+	addr2, _ := account.NewAddressFromHexString(reconcileAddr2)
+	toSign := []byte{1, 2, 3}
+
+	// accounts not listed do not need a grant
+	require.NoError(t, verifyAndRedeem(context.Background(), g, addr2, toSign))
+	var disabled *signGrants
+	require.NoError(t, verifyAndRedeem(context.Background(), disabled, addr1, toSign))
+
+	err := verifyAndRedeem(context.Background(), g, addr1, toSign)
+	require.IsType(t, &SignGrantError{}, err)
+	require.EqualError(t, err, "signing with account 0x"+reconcileAddr1+" requires a sign grant: no grant presented")
+
+	grant, err := IssueSignGrant(testSignGrantKey, addr1, toSign, time.Minute)
+	require.NoError(t, err)
+
+	require.EqualError(t, verifyAndRedeem(grantContext(grant), g, addr1, []byte{4, 5, 6}), "signing with account 0x"+reconcileAddr1+" requires a sign grant: grant is for a different payload")
+
+	require.NoError(t, verifyAndRedeem(grantContext(grant), g, addr1, toSign))
+	require.EqualError(t, verifyAndRedeem(grantContext(grant), g, addr1, toSign), "signing with account 0x"+reconcileAddr1+" requires a sign grant: grant has already been used")
+
+	forged, err := IssueSignGrant("fedcba9876543210fedcba9876543210", addr1, toSign, time.Minute)
+	require.NoError(t, err)
+	require.EqualError(t, verifyAndRedeem(grantContext(forged), g, addr1, toSign), "signing with account 0x"+reconcileAddr1+" requires a sign grant: invalid grant signature")
+
+	_, err = IssueSignGrant(testSignGrantKey, addr1, toSign, 2*time.Hour)
+	require.EqualError(t, err, "grant ttl must be greater than 0 and at most 1h0m0s")
+}
+
+func TestSignGrants_RedeemedGrantsPersisted(t *testing.T) {
+	defer os.Unsetenv("SIGN_GRANT_TEST_KEY")
+
+	dir, err := ioutil.TempDir("", "signgrants")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	addr1, _ := account.NewAddressFromHexString(reconcileAddr1)
+	grant, err := IssueSignGrant(testSignGrantKey, addr1, []byte{1}, time.Minute)
+	require.NoError(t, err)
+
+	stateDir, err := state.Open(dir)
+	require.NoError(t, err)
+	g := newSignGrants(stateDir, testSignGrantsConfig(t))
+	require.NoError(t, verifyAndRedeem(grantContext(grant), g, addr1, []byte{1}))
+	require.NoError(t, stateDir.Close())
+
+	// the grant cannot be redeemed again after a restart
+	stateDir, err = state.Open(dir)
+	require.NoError(t, err)
+	defer stateDir.Close()
+	g = newSignGrants(stateDir, testSignGrantsConfig(t))
+	require.EqualError(t, verifyAndRedeem(grantContext(grant), g, addr1, []byte{1}), "signing with account 0x"+reconcileAddr1+" requires a sign grant: grant has already been used")
+}
+
+func TestSignGrants_VerifyDoesNotUseUpGrant(t *testing.T) {
+	defer os.Unsetenv("SIGN_GRANT_TEST_KEY")
+	g := newSignGrants(nil, testSignGrantsConfig(t))
+	addr1, _ := account.NewAddressFromHexString(reconcileAddr1)
+	grant, err := IssueSignGrant(testSignGrantKey, addr1, []byte{1}, time.Minute)
+	require.NoError(t, err)
+
+	verified, err := g.verify(grantContext(grant), addr1, []byte{1})
+	require.NoError(t, err)
+	again, err := g.verify(grantContext(grant), addr1, []byte{1})
+	require.NoError(t, err)
+
+	// only one of the requests that verified the grant can redeem it
+	require.NoError(t, g.redeem(grantContext(grant), addr1, verified))
+	require.EqualError(t, g.redeem(grantContext(grant), addr1, again), "signing with account 0x"+reconcileAddr1+" requires a sign grant: grant has already been used")
+}
+
+func TestAccountManager_SignGrantNotUsedUpByFailedRequest(t *testing.T) {
+	defer os.Unsetenv("SIGN_GRANT_TEST_KEY")
+	dir, err := ioutil.TempDir("", "signgrants")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dirURL, _ := url.Parse("file://" + dir + "/")
+
+	am, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL, SignGrants: testSignGrantsConfig(t)})
+	require.NoError(t, err)
+	a := am.(*accountManager)
+	key, _ := account.NewKeyFromHexString(reconcileKey1)
+	_, err = a.ImportPrivateKey(key, config.NewAccount{SecretName: "acct1"})
+	require.NoError(t, err)
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	toSign := make([]byte, 32)
+	grant, err := IssueSignGrant(testSignGrantKey, addr, toSign, time.Minute)
+	require.NoError(t, err)
+
+	// the account is locked, so nothing is signed and the grant can still be used
+	_, err = a.Sign(grantContext(grant), addr, toSign)
+	require.EqualError(t, err, "account locked")
+
+	// the secret cannot be read, so nothing is signed and the grant can still be used
+	secrets := a.secrets
+	a.secrets = newMemorySecretStore()
+	_, err = a.UnlockAndSign(grantContext(grant), addr, toSign)
+	require.Error(t, err)
+	a.secrets = secrets
+
+	require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+	sig, err := a.Sign(grantContext(grant), addr, toSign)
+	require.NoError(t, err)
+	require.Len(t, sig, 65)
+	_, err = a.Sign(grantContext(grant), addr, toSign)
+	require.IsType(t, &SignGrantError{}, err)
+}
@@ -0,0 +1,74 @@
+package hashicorp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+// signHistory remembers the most recent hashes signed by each account, so that the same hash being signed again can be
+// reported.  Signing a hash twice is not refused, as it can be legitimate (e.g. a client resubmitting a transaction), but
+// can also be an early sign of a client bug or a replay attempt against the signer.
+type signHistory struct {
+	size  int
+	mu    sync.Mutex
+	accts map[string]*hashWindow
+}
+
+// hashWindow is a ring buffer of the last size hashes signed by an account
+type hashWindow struct {
+	ring   []string
+	next   int
+	counts map[string]int
+}
+
+// newSignHistory returns nil if size is 0
+func newSignHistory(size int) *signHistory {
+	if size == 0 {
+		return nil
+	}
+	return &signHistory{size: size, accts: make(map[string]*hashWindow)}
+}
+
+// record adds the signed hash to the account's history, reporting it if it was already in the history
+func (h *signHistory) record(addrHex string, hash []byte) {
+	if h == nil {
+		return
+	}
+	hashHex := hex.EncodeToString(hash)
+
+	h.mu.Lock()
+	w, ok := h.accts[addrHex]
+	if !ok {
+		w = &hashWindow{ring: make([]string, h.size), counts: make(map[string]int)}
+		h.accts[addrHex] = w
+	}
+	duplicate := w.add(hashHex)
+	h.mu.Unlock()
+
+	if duplicate {
+		log.Printf("[WARN] account 0x%v signed hash 0x%v more than once", addrHex, hashHex)
+		metrics.DuplicateSigns.Add(1)
+		event.Emit(event.DuplicateSign, "0x"+addrHex, fmt.Sprintf("hash 0x%v already signed within the last %v signatures", hashHex, h.size))
+	}
+}
+
+// add returns true if the hash is already in the window
+func (w *hashWindow) add(hashHex string) bool {
+	duplicate := w.counts[hashHex] > 0
+
+	if evicted := w.ring[w.next]; evicted != "" {
+		if w.counts[evicted]--; w.counts[evicted] == 0 {
+			delete(w.counts, evicted)
+		}
+	}
+	w.ring[w.next] = hashHex
+	w.counts[hashHex]++
+	w.next = (w.next + 1) % len(w.ring)
+
+	return duplicate
+}
@@ -0,0 +1,56 @@
+package hashicorp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignHistory_ReportsDuplicateWithinWindow(t *testing.T) {
+	h := newSignHistory(2)
+
+	events, unsubscribe := event.Subscribe(10)
+	defer unsubscribe()
+
+	hash1 := bytes.Repeat([]byte{1}, 32)
+	hash2 := bytes.Repeat([]byte{2}, 32)
+	hash3 := bytes.Repeat([]byte{3}, 32)
+
+	h.record(reconcileAddr1, hash1)
+	h.record(reconcileAddr1, hash2)
+	// a different account signing the same hash is not a duplicate
+	h.record(reconcileAddr2, hash1)
+	require.Len(t, events, 0)
+
+	h.record(reconcileAddr1, hash1)
+	e := <-events
+	require.Equal(t, event.DuplicateSign, e.Kind)
+	require.Equal(t, "0x"+reconcileAddr1, e.Subject)
+	require.Equal(t, "hash 0x0101010101010101010101010101010101010101010101010101010101010101 already signed within the last 2 signatures", e.Message)
+
+	// hash2 has been pushed out of the window by hash1 and hash3
+	h.record(reconcileAddr1, hash3)
+	h.record(reconcileAddr1, hash2)
+	require.Len(t, events, 0)
+}
+
+func TestHashWindow_Add(t *testing.T) {
+	w := &hashWindow{ring: make([]string, 2), counts: make(map[string]int)}
+
+	require.False(t, w.add("a"))
+	require.True(t, w.add("a"))
+	// both entries are a, so a remains in the window until both are evicted
+	require.False(t, w.add("b"))
+	require.True(t, w.add("a"))
+	require.False(t, w.add("c"))
+	require.False(t, w.add("d"))
+	require.Equal(t, map[string]int{"c": 1, "d": 1}, w.counts)
+}
+
+func TestSignHistory_NotConfigured(t *testing.T) {
+	h := newSignHistory(0)
+	require.Nil(t, h)
+	h.record(reconcileAddr1, []byte{1})
+}
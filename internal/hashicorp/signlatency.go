@@ -0,0 +1,215 @@
+package hashicorp
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+const (
+	defaultSLOPercentile = 99
+	defaultSLOWindow     = 1000
+	// minSLOSamples is the number of requests needed before the SLO is evaluated, so that a single slow request after
+	// startup (e.g. while the TLS connection to Vault is established) is not reported
+	minSLOSamples = 10
+)
+
+// signPhase is a part of the signing path that contributes to its latency
+type signPhase int
+
+const (
+	phaseLockWait signPhase = iota
+	phaseVaultRead
+	phaseCrypto
+	numSignPhases
+)
+
+var signPhaseNames = [...]string{
+	phaseLockWait:  "lock contention",
+	phaseVaultRead: "vault read",
+	phaseCrypto:    "crypto",
+}
+
+// signTimer measures the latency of a single signing request and the time spent in each phase
+type signTimer struct {
+	start  time.Time
+	phases [numSignPhases]time.Duration
+}
+
+func startSignTimer() *signTimer {
+	return &signTimer{start: time.Now()}
+}
+
+// phase starts timing a phase, returning a func to call when the phase is complete
+func (t *signTimer) phase(p signPhase) func() {
+	start := time.Now()
+	return func() {
+		t.phases[p] += time.Since(start)
+	}
+}
+
+type signSample struct {
+	total  time.Duration
+	phases [numSignPhases]time.Duration
+}
+
+// signingSLO keeps the latency of the most recent signing requests, and reports when the latency at the configured
+// percentile exceeds the threshold, along with the phase that contributed most to the slow requests
+type signingSLO struct {
+	threshold  time.Duration
+	percentile float64
+	mu         sync.Mutex
+	samples    []signSample // ring buffer
+	next       int
+	full       bool
+	breached   bool
+}
+
+// SigningLatencyState is the current signing latency, as reported in the debug state
+type SigningLatencyState struct {
+	Samples   int
+	P50       string
+	P90       string
+	P99       string
+	Threshold string
+	Breached  bool
+}
+
+// newSigningSLO returns nil if the SLO threshold is not configured
+func newSigningSLO(conf config.VaultClientSigningLatencySLO) *signingSLO {
+	if conf.Threshold == 0 {
+		return nil
+	}
+	s := &signingSLO{threshold: conf.Threshold, percentile: conf.Percentile}
+	if s.percentile == 0 {
+		s.percentile = defaultSLOPercentile
+	}
+	window := conf.Window
+	if window == 0 {
+		window = defaultSLOWindow
+	}
+	s.samples = make([]signSample, window)
+	return s
+}
+
+// record adds the latency of a completed signing request, reporting if the SLO is breached or no longer breached as a
+// result
+func (s *signingSLO) record(t *signTimer) {
+	if s == nil {
+		return
+	}
+	sample := signSample{total: time.Since(t.start), phases: t.phases}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % len(s.samples)
+	if s.next == 0 {
+		s.full = true
+	}
+
+	current := s.window()
+	if len(current) < minSLOSamples {
+		return
+	}
+	latency := percentile(current, s.percentile)
+
+	switch {
+	case latency > s.threshold && !s.breached:
+		s.breached = true
+		metrics.SigningLatencySLOBreached.Set(1)
+		msg := fmt.Sprintf("p%v signing latency %v exceeds SLO of %v, mostly %v", s.percentile, latency.Round(time.Millisecond), s.threshold, dominantPhase(current, latency))
+		log.Printf("[WARN] %v", msg)
+		event.Emit(event.SigningDegraded, "signing", msg)
+	case latency <= s.threshold && s.breached:
+		s.breached = false
+		metrics.SigningLatencySLOBreached.Set(0)
+		msg := fmt.Sprintf("p%v signing latency %v within SLO of %v", s.percentile, latency.Round(time.Millisecond), s.threshold)
+		log.Printf("[INFO] %v", msg)
+		event.Emit(event.SigningRecovered, "signing", msg)
+	}
+}
+
+// window returns the recorded samples, the caller must hold mu
+func (s *signingSLO) window() []signSample {
+	if s.full {
+		return s.samples
+	}
+	return s.samples[:s.next]
+}
+
+func (s *signingSLO) state() *SigningLatencyState {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.window()
+	state := &SigningLatencyState{
+		Samples:   len(current),
+		Threshold: fmt.Sprintf("p%v < %v", s.percentile, s.threshold),
+		Breached:  s.breached,
+	}
+	if len(current) != 0 {
+		state.P50 = percentile(current, 50).String()
+		state.P90 = percentile(current, 90).String()
+		state.P99 = percentile(current, 99).String()
+	}
+	return state
+}
+
+// percentile returns the nearest-rank percentile of the total latency of the samples
+func percentile(samples []signSample, p float64) time.Duration {
+	totals := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		totals[i] = s.total
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i] < totals[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(totals))))
+	if rank < 1 {
+		rank = 1
+	}
+	return totals[rank-1]
+}
+
+// dominantPhase describes the phase that contributed most to the latency of the samples at least as slow as latency.
+// Time not spent in any measured phase (e.g. checking the account's role) is reported as other.
+func dominantPhase(samples []signSample, latency time.Duration) string {
+	var (
+		sums  [numSignPhases]time.Duration
+		total time.Duration
+	)
+	for _, s := range samples {
+		if s.total < latency {
+			continue
+		}
+		total += s.total
+		for p, d := range s.phases {
+			sums[p] += d
+		}
+	}
+	if total == 0 {
+		return "other"
+	}
+
+	name, max := "other", total
+	for _, d := range sums {
+		max -= d
+	}
+	for p, d := range sums {
+		if d > max {
+			name, max = signPhaseNames[p], d
+		}
+	}
+	return fmt.Sprintf("%v (%.0f%%)", name, 100*float64(max)/float64(total))
+}
@@ -0,0 +1,96 @@
+package hashicorp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/stretchr/testify/require"
+)
+
+// testSignTimer returns a timer for a completed request that took total, of which vaultRead was spent reading from Vault
+func testSignTimer(total, vaultRead time.Duration) *signTimer {
+	t := &signTimer{start: time.Now().Add(-total)}
+	t.phases[phaseVaultRead] = vaultRead
+	return t
+}
+
+func TestSigningSLO_BreachAndRecovery(t *testing.T) {
+	s := newSigningSLO(config.VaultClientSigningLatencySLO{Threshold: time.Second, Percentile: 90, Window: 10})
+
+	events, unsubscribe := event.Subscribe(10)
+	defer unsubscribe()
+
+	for i := 0; i < 10; i++ {
+		s.record(testSignTimer(time.Millisecond, 0))
+	}
+	require.Len(t, events, 0)
+
+	// 2 of the last 10 requests are slow, so the p90 exceeds the threshold
+	s.record(testSignTimer(2*time.Second, 1800*time.Millisecond))
+	require.Len(t, events, 0)
+	s.record(testSignTimer(2*time.Second, 1800*time.Millisecond))
+	e := <-events
+	require.Equal(t, event.SigningDegraded, e.Kind)
+	require.Equal(t, "signing", e.Subject)
+	require.Contains(t, e.Message, "p90 signing latency 2s")
+	require.Contains(t, e.Message, "exceeds SLO of 1s, mostly vault read (90%)")
+
+	state := s.state()
+	require.True(t, state.Breached)
+	require.Equal(t, 10, state.Samples)
+	require.Equal(t, "p90 < 1s", state.Threshold)
+
+	// the slow requests leave the window
+	for i := 0; i < 9; i++ {
+		s.record(testSignTimer(time.Millisecond, 0))
+	}
+	e = <-events
+	require.Equal(t, event.SigningRecovered, e.Kind)
+	require.False(t, s.state().Breached)
+	require.Len(t, events, 0)
+}
+
+func TestSigningSLO_MinimumSamples(t *testing.T) {
+	s := newSigningSLO(config.VaultClientSigningLatencySLO{Threshold: time.Second})
+	require.Equal(t, float64(99), s.percentile)
+	require.Len(t, s.samples, 1000)
+
+	for i := 0; i < minSLOSamples-1; i++ {
+		s.record(testSignTimer(2*time.Second, 0))
+	}
+	require.False(t, s.state().Breached)
+
+	s.record(testSignTimer(2*time.Second, 0))
+	require.True(t, s.state().Breached)
+}
+
+func TestPercentile(t *testing.T) {
+	var samples []signSample
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, signSample{total: time.Duration(i)})
+	}
+	require.Equal(t, time.Duration(50), percentile(samples, 50))
+	require.Equal(t, time.Duration(99), percentile(samples, 99))
+	require.Equal(t, time.Duration(100), percentile(samples, 100))
+	require.Equal(t, time.Duration(1), percentile(samples, 0))
+}
+
+func TestDominantPhase(t *testing.T) {
+	samples := []signSample{
+		{total: 10 * time.Millisecond, phases: [numSignPhases]time.Duration{phaseCrypto: 9 * time.Millisecond}},
+		{total: time.Second, phases: [numSignPhases]time.Duration{phaseLockWait: 700 * time.Millisecond, phaseVaultRead: 100 * time.Millisecond}},
+		{total: time.Second, phases: [numSignPhases]time.Duration{phaseLockWait: 100 * time.Millisecond, phaseVaultRead: 100 * time.Millisecond}},
+	}
+	require.Equal(t, "lock contention (70%)", dominantPhase(samples[:2], time.Second))
+	// time outside the measured phases
+	require.Equal(t, "other (80%)", dominantPhase(samples[2:], time.Second))
+}
+
+func TestSigningSLO_NotConfigured(t *testing.T) {
+	s := newSigningSLO(config.VaultClientSigningLatencySLO{})
+	require.Nil(t, s)
+	s.record(startSignTimer())
+	require.Nil(t, s.state())
+}
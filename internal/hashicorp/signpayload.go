@@ -0,0 +1,273 @@
+package hashicorp
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"golang.org/x/crypto/sha3"
+	"google.golang.org/grpc/metadata"
+)
+
+// SignPayloadKey is the binary gRPC metadata key a caller declares the unsigned transaction in, i.e. the bytes the
+// digest of a signing request is the Keccak-256 hash of
+const SignPayloadKey = "quorum-sign-payload-bin"
+
+// SignPayloadError is a signing request refused because the transaction it declared is invalid or exceeds the
+// signPayloads limits
+type SignPayloadError struct {
+	Address string
+	Reason  string
+}
+
+func (e *SignPayloadError) Error() string {
+	return fmt.Sprintf("signing with account 0x%v refused: %v", e.Address, e.Reason)
+}
+
+// SelectorError is a signing request refused because the account can only sign transactions calling the functions in
+// its signPayloads selectors
+type SelectorError struct {
+	Address string
+	Reason  string
+}
+
+func (e *SelectorError) Error() string {
+	return fmt.Sprintf("signing with account 0x%v refused: %v", e.Address, e.Reason)
+}
+
+// signPayloads checks declared transactions against the configured limits and function selector allowlists
+type signPayloads struct {
+	maxBytes         int
+	maxCalldataBytes int
+	selectors        map[string]map[string]bool // lowercase hex address without 0x prefix -> lowercase 0x-prefixed selector
+}
+
+// newSignPayloads returns nil if signPayloads is not configured
+func newSignPayloads(conf config.VaultClientSignPayloads) *signPayloads {
+	if conf.MaxBytes == 0 && conf.MaxCalldataBytes == 0 && len(conf.Selectors) == 0 {
+		return nil
+	}
+	p := &signPayloads{
+		maxBytes:         conf.MaxBytes,
+		maxCalldataBytes: conf.MaxCalldataBytes,
+		selectors:        make(map[string]map[string]bool, len(conf.Selectors)),
+	}
+	for addr, selectors := range conf.Selectors {
+		allowed := make(map[string]bool, len(selectors))
+		for _, s := range selectors {
+			allowed[strings.ToLower(s)] = true
+		}
+		p.selectors[config.NormalizeAddress(addr)] = allowed
+	}
+	return p
+}
+
+// check checks the transaction declared in the SignPayloadKey gRPC metadata of ctx, which must hash to toSign.  A
+// request that does not declare a transaction is only refused if the account is restricted to selectors.
+func (p *signPayloads) check(ctx context.Context, acctAddr account.Address, toSign []byte) error {
+	if p == nil {
+		return nil
+	}
+	addr := acctAddr.ToHexString()
+	refuse := func(err error, reason string) error {
+		log.Printf("[WARN] refused signing request for 0x%v: %v", addr, reason)
+		return err
+	}
+	invalid := func(format string, args ...interface{}) error {
+		reason := fmt.Sprintf(format, args...)
+		return refuse(&SignPayloadError{Address: addr, Reason: reason}, reason)
+	}
+	notAllowed := func(format string, args ...interface{}) error {
+		reason := fmt.Sprintf(format, args...)
+		return refuse(&SelectorError{Address: addr, Reason: reason}, reason)
+	}
+
+	allowed, restricted := p.selectors[addr]
+	payload, declared := signPayloadFromContext(ctx)
+	if !declared {
+		if restricted {
+			return notAllowed("the account can only sign transactions declared in %v metadata", SignPayloadKey)
+		}
+		return nil
+	}
+	if domain := audit.SignDomainFromContext(ctx); domain != "" && domain != audit.SignDomainTransaction {
+		return invalid("a transaction cannot be declared for the %v domain", domain)
+	}
+	if p.maxBytes > 0 && len(payload) > p.maxBytes {
+		return invalid("declared transaction of %v bytes exceeds signPayloads maxBytes %v", len(payload), p.maxBytes)
+	}
+	if !bytes.Equal(keccak256(payload), toSign) {
+		return invalid("declared transaction does not hash to the digest being signed")
+	}
+	to, data, err := decodeUnsignedTransaction(payload)
+	if err != nil {
+		return invalid("declared transaction is invalid: %v", err)
+	}
+	if p.maxCalldataBytes > 0 && len(data) > p.maxCalldataBytes {
+		return invalid("calldata of %v bytes exceeds signPayloads maxCalldataBytes %v", len(data), p.maxCalldataBytes)
+	}
+
+	if !restricted {
+		return nil
+	}
+	if len(to) == 0 {
+		return notAllowed("the account cannot sign contract creations")
+	}
+	if len(data) < 4 {
+		return notAllowed("the transaction does not call a function")
+	}
+	if selector := "0x" + hex.EncodeToString(data[:4]); !allowed[selector] {
+		return notAllowed("function selector %v is not in the account's signPayloads selectors", selector)
+	}
+	return nil
+}
+
+// signPayloadFromContext returns the transaction declared in the incoming gRPC metadata of ctx, and whether one was
+// declared
+func signPayloadFromContext(ctx context.Context) ([]byte, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	vals := md.Get(SignPayloadKey)
+	if len(vals) == 0 {
+		return nil, false
+	}
+	return []byte(vals[0]), true
+}
+
+func keccak256(b []byte) []byte {
+	d := sha3.NewLegacyKeccak256()
+	d.Write(b)
+	return d.Sum(nil)
+}
+
+// Types of typed transactions (EIP-2718), whose signing payload is the type followed by the RLP list of their fields
+const (
+	accessListTxType = 0x01 // EIP-2930
+	dynamicFeeTxType = 0x02 // EIP-1559
+)
+
+// decodeUnsignedTransaction returns the recipient and calldata of the signing payload of a transaction: the RLP list
+// of a legacy transaction's fields, with or without the EIP-155 chain ID, or a typed transaction.  to is empty for a
+// contract creation.
+func decodeUnsignedTransaction(payload []byte) (to, data []byte, err error) {
+	if len(payload) == 0 {
+		return nil, nil, errors.New("empty transaction")
+	}
+	// the index of the recipient in the transaction's fields, which is followed by the value and calldata
+	toIndex, wantFields := 3, []int{6, 9}
+	switch payload[0] {
+	case accessListTxType:
+		toIndex, wantFields, payload = 4, []int{8}, payload[1:]
+	case dynamicFeeTxType:
+		toIndex, wantFields, payload = 5, []int{9}, payload[1:]
+	default:
+		if payload[0] < 0xc0 {
+			return nil, nil, fmt.Errorf("unsupported transaction type %#x", payload[0])
+		}
+	}
+
+	fields, err := rlpList(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !containsInt(wantFields, len(fields)) {
+		return nil, nil, fmt.Errorf("transaction has %v fields", len(fields))
+	}
+	for _, f := range fields[:toIndex+3] {
+		if f.list {
+			return nil, nil, errors.New("transaction field is a list")
+		}
+	}
+	to, data = fields[toIndex].content, fields[toIndex+2].content
+	if len(to) != 0 && len(to) != 20 {
+		return nil, nil, fmt.Errorf("recipient has %v bytes", len(to))
+	}
+	return to, data, nil
+}
+
+func containsInt(vals []int, v int) bool {
+	for _, val := range vals {
+		if val == v {
+			return true
+		}
+	}
+	return false
+}
+
+// rlpItem is a decoded RLP string, or the encoded items of an RLP list
+type rlpItem struct {
+	list    bool
+	content []byte
+}
+
+// rlpList decodes b, which must be exactly one RLP list, into its items
+func rlpList(b []byte) ([]rlpItem, error) {
+	list, rest, err := rlpSplit(b)
+	if err != nil {
+		return nil, err
+	}
+	if !list.list || len(rest) != 0 {
+		return nil, errors.New("not a single RLP list")
+	}
+	var items []rlpItem
+	for content := list.content; len(content) != 0; {
+		var item rlpItem
+		if item, content, err = rlpSplit(content); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// rlpSplit decodes the first RLP item of b, returning the bytes that follow it
+func rlpSplit(b []byte) (item rlpItem, rest []byte, err error) {
+	if len(b) == 0 {
+		return rlpItem{}, nil, errors.New("unexpected end of RLP")
+	}
+	var offset, size uint64
+	switch prefix := b[0]; {
+	case prefix < 0x80:
+		return rlpItem{content: b[:1]}, b[1:], nil
+	case prefix < 0xb8:
+		offset, size = 1, uint64(prefix-0x80)
+	case prefix < 0xc0:
+		offset, size, err = rlpLongSize(b, prefix-0xb7)
+	case prefix < 0xf8:
+		item.list = true
+		offset, size = 1, uint64(prefix-0xc0)
+	default:
+		item.list = true
+		offset, size, err = rlpLongSize(b, prefix-0xf7)
+	}
+	if err != nil {
+		return rlpItem{}, nil, err
+	}
+	if size > uint64(len(b))-offset {
+		return rlpItem{}, nil, errors.New("RLP item exceeds its input")
+	}
+	item.content = b[offset : offset+size]
+	return item, b[offset+size:], nil
+}
+
+// rlpLongSize decodes the size of an RLP item of 56 or more bytes, whose size is encoded in the n bytes following its
+// prefix
+func rlpLongSize(b []byte, n byte) (offset, size uint64, err error) {
+	offset = 1 + uint64(n)
+	if uint64(len(b)) < offset {
+		return 0, 0, errors.New("unexpected end of RLP")
+	}
+	for _, c := range b[1:offset] {
+		size = size<<8 | uint64(c)
+	}
+	return offset, size, nil
+}
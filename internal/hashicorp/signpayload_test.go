@@ -0,0 +1,189 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+// rlpEncode encodes a []byte as an RLP string and a []interface{} as an RLP list
+func rlpEncode(v interface{}) []byte {
+	var prefix byte
+	var content []byte
+	switch v := v.(type) {
+	case []byte:
+		if len(v) == 1 && v[0] < 0x80 {
+			return v
+		}
+		prefix, content = 0x80, v
+	case []interface{}:
+		prefix = 0xc0
+		for _, item := range v {
+			content = append(content, rlpEncode(item)...)
+		}
+	}
+	if len(content) < 56 {
+		return append([]byte{prefix + byte(len(content))}, content...)
+	}
+	var size []byte
+	for n := len(content); n > 0; n >>= 8 {
+		size = append([]byte{byte(n)}, size...)
+	}
+	return append(append([]byte{prefix + 55 + byte(len(size))}, size...), content...)
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// legacyTx returns the EIP-155 signing payload of a legacy transaction
+func legacyTx(to, data []byte) []byte {
+	return rlpEncode([]interface{}{[]byte{0x01}, []byte{0x3b, 0x9a, 0xca, 0x00}, []byte{0x52, 0x08}, to, []byte{}, data, []byte{0x0a}, []byte{}, []byte{}})
+}
+
+func payloadContext(domain string, payload []byte) context.Context {
+	md := metadata.Pairs(SignPayloadKey, string(payload))
+	if domain != "" {
+		md = metadata.Join(md, metadata.Pairs(audit.SignDomainKey, domain))
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestDecodeUnsignedTransaction(t *testing.T) {
+	to := mustDecodeHex(t, "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+	data := mustDecodeHex(t, "a9059cbb0000000000000000000000004d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+
+	gotTo, gotData, err := decodeUnsignedTransaction(legacyTx(to, data))
+	require.NoError(t, err)
+	require.Equal(t, to, gotTo)
+	require.Equal(t, data, gotData)
+
+	// the signing payload and hash of the EIP-155 example transaction
+	example := mustDecodeHex(t, "ec098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a764000080018080")
+	require.Equal(t, "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53", hex.EncodeToString(keccak256(example)))
+	gotTo, gotData, err = decodeUnsignedTransaction(example)
+	require.NoError(t, err)
+	require.Equal(t, "3535353535353535353535353535353535353535", hex.EncodeToString(gotTo))
+	require.Empty(t, gotData)
+
+	// a pre-EIP-155 transaction has no chain ID
+	homestead := rlpEncode([]interface{}{[]byte{0x01}, []byte{0x01}, []byte{0x52, 0x08}, []byte{}, []byte{}, data})
+	gotTo, gotData, err = decodeUnsignedTransaction(homestead)
+	require.NoError(t, err)
+	require.Empty(t, gotTo)
+	require.Equal(t, data, gotData)
+
+	dynamicFee := append([]byte{dynamicFeeTxType}, rlpEncode([]interface{}{
+		[]byte{0x0a}, []byte{0x01}, []byte{0x01}, []byte{0x02}, []byte{0x52, 0x08}, to, []byte{}, data, []interface{}{},
+	})...)
+	gotTo, gotData, err = decodeUnsignedTransaction(dynamicFee)
+	require.NoError(t, err)
+	require.Equal(t, to, gotTo)
+	require.Equal(t, data, gotData)
+
+	accessList := append([]byte{accessListTxType}, rlpEncode([]interface{}{
+		[]byte{0x0a}, []byte{0x01}, []byte{0x01}, []byte{0x52, 0x08}, to, []byte{}, data, []interface{}{},
+	})...)
+	gotTo, gotData, err = decodeUnsignedTransaction(accessList)
+	require.NoError(t, err)
+	require.Equal(t, to, gotTo)
+	require.Equal(t, data, gotData)
+
+	invalid := map[string][]byte{
+		"empty":            {},
+		"unsupported type": {0x03, 0xc0},
+		"not a list":       rlpEncode([]byte("hello")),
+		"trailing bytes":   append(legacyTx(to, data), 0x00),
+		"truncated":        legacyTx(to, data)[:20],
+		"too few fields":   rlpEncode([]interface{}{[]byte{0x01}, to, data}),
+		"list field":       rlpEncode([]interface{}{[]byte{0x01}, []byte{0x01}, []byte{0x52, 0x08}, []interface{}{}, []byte{}, data}),
+		"short recipient":  legacyTx(to[:19], data),
+		"oversized list":   {0xf9, 0xff, 0xff, 0xc0},
+	}
+	for name, payload := range invalid {
+		_, _, err := decodeUnsignedTransaction(payload)
+		require.Error(t, err, name)
+	}
+}
+
+func TestSignPayloads_Check(t *testing.T) {
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	to := mustDecodeHex(t, "dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	transfer := legacyTx(to, mustDecodeHex(t, "a9059cbb00000000"))
+	approve := legacyTx(to, mustDecodeHex(t, "095ea7b300000000"))
+
+	p := newSignPayloads(config.VaultClientSignPayloads{
+		MaxBytes:         200,
+		MaxCalldataBytes: 100,
+		Selectors:        map[string][]string{"0x" + reconcileAddr1: {"0xA9059CBB"}},
+	})
+
+	require.NoError(t, p.check(payloadContext("", transfer), addr, keccak256(transfer)))
+	require.NoError(t, p.check(payloadContext(audit.SignDomainTransaction, transfer), addr, keccak256(transfer)))
+
+	refused := map[string]struct {
+		ctx     context.Context
+		toSign  []byte
+		wantErr string
+	}{
+		"no payload":         {context.Background(), keccak256(transfer), "the account can only sign transactions declared in quorum-sign-payload-bin metadata"},
+		"selector":           {payloadContext("", approve), keccak256(approve), "function selector 0x095ea7b3 is not in the account's signPayloads selectors"},
+		"hash mismatch":      {payloadContext("", transfer), keccak256(approve), "declared transaction does not hash to the digest being signed"},
+		"domain":             {payloadContext(audit.SignDomainConsensus, transfer), keccak256(transfer), "a transaction cannot be declared for the consensus domain"},
+		"contract creation":  {payloadContext("", legacyTx(nil, nil)), keccak256(legacyTx(nil, nil)), "the account cannot sign contract creations"},
+		"no function called": {payloadContext("", legacyTx(to, nil)), keccak256(legacyTx(to, nil)), "the transaction does not call a function"},
+	}
+	for name, tt := range refused {
+		err := p.check(tt.ctx, addr, tt.toSign)
+		require.EqualError(t, err, "signing with account 0x"+reconcileAddr1+" refused: "+tt.wantErr, name)
+	}
+	require.IsType(t, &SelectorError{}, p.check(context.Background(), addr, keccak256(transfer)))
+	require.IsType(t, &SignPayloadError{}, p.check(payloadContext("", transfer), addr, keccak256(approve)))
+
+	// the limits apply to accounts without selectors
+	other, _ := account.NewAddressFromHexString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	require.NoError(t, p.check(context.Background(), other, keccak256(approve)))
+	require.NoError(t, p.check(payloadContext("", approve), other, keccak256(approve)))
+
+	bigCalldata := legacyTx(to, make([]byte, 101))
+	err := p.check(payloadContext("", bigCalldata), other, keccak256(bigCalldata))
+	require.EqualError(t, err, "signing with account 0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526 refused: calldata of 101 bytes exceeds signPayloads maxCalldataBytes 100")
+
+	big := legacyTx(to, make([]byte, 200))
+	err = p.check(payloadContext("", big), other, keccak256(big))
+	require.IsType(t, &SignPayloadError{}, err)
+	require.Contains(t, err.Error(), "exceeds signPayloads maxBytes 200")
+}
+
+func TestAccountManager_SignPayloads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signpayload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dirURL, _ := url.Parse("file://" + dir + "/")
+
+	a, err := NewAccountManager(config.VaultClient{Dev: true, AccountDirectory: dirURL})
+	require.NoError(t, err)
+	acct, err := a.NewAccount(config.NewAccount{SecretName: "acct"})
+	require.NoError(t, err)
+	a.(*accountManager).payloads = newSignPayloads(config.VaultClientSignPayloads{
+		Selectors: map[string][]string{acct.Address.ToHexString(): {"0xa9059cbb"}},
+	})
+
+	tx := legacyTx(mustDecodeHex(t, "dc99ddec13457de6c0f6bb8e6cf3955c86f55526"), mustDecodeHex(t, "a9059cbb"))
+	_, err = a.UnlockAndSign(context.Background(), acct.Address, keccak256(tx))
+	require.IsType(t, &SelectorError{}, err)
+
+	_, err = a.UnlockAndSign(payloadContext(audit.SignDomainTransaction, tx), acct.Address, keccak256(tx))
+	require.NoError(t, err)
+}
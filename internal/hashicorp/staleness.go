@@ -0,0 +1,57 @@
+package hashicorp
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+type activeNodeKey struct{}
+
+// withActiveNode returns a context for reads that must not be served by a read replica
+func withActiveNode(ctx context.Context) context.Context {
+	return context.WithValue(ctx, activeNodeKey{}, true)
+}
+
+func requiresActiveNode(ctx context.Context) bool {
+	active, _ := ctx.Value(activeNodeKey{}).(bool)
+	return active
+}
+
+// stalenessContext returns a context requiring the account's secret to be read from the active node if its account
+// config changed within maxStaleness, as the secret version it references may not yet have been replicated
+func (a *accountManager) stalenessContext(ctx context.Context, acctFile config.AccountFile) context.Context {
+	if a.maxStaleness == nil {
+		return ctx
+	}
+	if *a.maxStaleness == 0 {
+		return withActiveNode(ctx)
+	}
+	modified, err := accountFileModTime(acctFile)
+	if err != nil || time.Since(modified) < *a.maxStaleness {
+		return withActiveNode(ctx)
+	}
+	return ctx
+}
+
+// accountFileModTime returns the modification time of the account config file.  The path of account files written by
+// the plugin is a file URL.
+func accountFileModTime(acctFile config.AccountFile) (time.Time, error) {
+	path := acctFile.Path
+	if strings.HasPrefix(path, "file:") {
+		u, err := url.Parse(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		path = config.FilePath(u)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
@@ -0,0 +1,84 @@
+package hashicorp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxStaleness(t *testing.T) {
+	var primaryHits, replicaHits int
+	primary := secretServer(t, &primaryHits)
+	defer primary.Close()
+	replica := secretServer(t, &replicaHits)
+	defer replica.Close()
+
+	f, err := ioutil.TempFile("", "acct")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	defer os.Remove(f.Name())
+
+	a := reconcileAccountManager(t, primary.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	a.client.SetToken("mytoken")
+	for u, acct := range a.client.accts {
+		acct.Path = f.Name()
+		a.client.accts[u] = acct
+	}
+
+	replicaURL, _ := url.Parse(replica.URL)
+	replicaClient, err := newAPIClient(replicaURL, config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}}, 0, nil)
+	require.NoError(t, err)
+	a.client.replicas = newReplicaSet(replicaClient)
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	unlock := func() {
+		require.NoError(t, a.TimedUnlock(context.Background(), addr, 0))
+		a.Lock(addr)
+	}
+
+	// not set, so reads are always served by the replica
+	unlock()
+	require.Equal(t, 0, primaryHits)
+	require.Equal(t, 1, replicaHits)
+
+	// the account config has only just been written, so may reference an unreplicated version
+	maxStaleness := time.Hour
+	a.maxStaleness = &maxStaleness
+	unlock()
+	require.Equal(t, 1, primaryHits)
+	require.Equal(t, 1, replicaHits)
+
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(f.Name(), old, old))
+	unlock()
+	require.Equal(t, 1, primaryHits)
+	require.Equal(t, 2, replicaHits)
+
+	// always read from the active node
+	maxStaleness = 0
+	unlock()
+	require.Equal(t, 2, primaryHits)
+	require.Equal(t, 2, replicaHits)
+}
+
+func TestAccountFileModTime_FileURL(t *testing.T) {
+	f, err := ioutil.TempFile("", "acct")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	defer os.Remove(f.Name())
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(f.Name(), old, old))
+
+	got, err := accountFileModTime(config.AccountFile{Path: "file://" + f.Name()})
+	require.NoError(t, err)
+	require.True(t, old.Equal(got))
+}
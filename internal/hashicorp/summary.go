@@ -0,0 +1,102 @@
+package hashicorp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// StartupSummary describes the shape of the deployment in a single line of key=value pairs, logged once the plugin has
+// been initialized so that it can be identified from the Quorum logs alone.  It must never contain credentials or key
+// material.
+func StartupSummary(conf config.VaultClient, s DebugState) string {
+	fields := []string{
+		fmt.Sprintf("vault=%v", s.Vault),
+		fmt.Sprintf("auth=%v", authSummary(conf)),
+		fmt.Sprintf("secretsEngine=%v", secretsEngineName(conf)),
+	}
+	if len(s.ReadReplicas) != 0 {
+		replicas := make([]string, 0, len(s.ReadReplicas))
+		for _, r := range s.ReadReplicas {
+			replicas = append(replicas, r.Address)
+		}
+		fields = append(fields, fmt.Sprintf("readReplicas=%v", strings.Join(replicas, ",")))
+	}
+	if s.DRSecondary != "" {
+		fields = append(fields, fmt.Sprintf("drSecondary=%v", s.DRSecondary))
+	}
+	if conf.AccountStore.Type != "" {
+		fields = append(fields, fmt.Sprintf("accountStore=%v", conf.AccountStore.Type))
+	}
+	if s.Mirror != "" {
+		fields = append(fields, fmt.Sprintf("mirror=%q", s.Mirror))
+	}
+	fields = append(fields,
+		fmt.Sprintf("accounts=%v", s.Accounts),
+		fmt.Sprintf("unlocked=%v", s.UnlockedAccounts),
+		fmt.Sprintf("degraded=%v", s.DegradedAccounts),
+	)
+
+	features := enabledFeatures(conf)
+	if len(features) == 0 {
+		features = []string{"none"}
+	}
+	fields = append(fields, fmt.Sprintf("features=%v", strings.Join(features, ",")))
+
+	return strings.Join(fields, " ")
+}
+
+// authSummary names the auth method used to log in to Vault
+func authSummary(conf config.VaultClient) string {
+	auth := conf.Authentication
+	switch {
+	case conf.Dev:
+		return "none"
+	case auth.Token.IsSet() && auth.Token.IsFile():
+		return "tokenFile"
+	case auth.Token.IsSet():
+		return "token"
+	default:
+		return authID(auth)
+	}
+}
+
+// enabledFeatures lists the optional config fields that are set, in the order they are documented
+func enabledFeatures(conf config.VaultClient) []string {
+	var features []string
+	add := func(enabled bool, name string) {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+	add(conf.Dev, "dev")
+	add(conf.StandbyTokens > 0, "standbyTokens")
+	add(conf.TLS.FIPS, "fips")
+	add(len(conf.TLS.Pins) != 0, "tlsPins")
+	add(conf.NewAccountQuota.PerHour > 0 || conf.NewAccountQuota.PerDay > 0, "newAccountQuota")
+	add(len(conf.UnlockTOTP.Keys) != 0, "unlockTOTP")
+	add(conf.CheckAccountSecrets, "checkAccountSecrets")
+	add(conf.VersionFallback, "versionFallback")
+	add(conf.MaxStaleness != nil, "maxStaleness")
+	add(conf.RPCTimeout > 0, "rpcTimeout")
+	add(conf.ReadCacheSize > 0, "readCache")
+	add(conf.MaxConcurrentRequests > 0, "maxConcurrentRequests")
+	add(conf.HealthProbe.Interval > 0, "healthProbe")
+	add(conf.StateDirectory != nil, "stateDirectory")
+	add(conf.TokenSink.Key != nil && conf.StateDirectory != nil, "tokenSink")
+	add(conf.DNSRefreshInterval > 0, "dnsRefresh")
+	add(conf.QuorumPermissioning.RPC != nil, "quorumPermissioning")
+	add(conf.Escrow.PublicKey != nil, "escrow")
+	add(conf.Pepper != nil, "pepper")
+	add(conf.SignGrants.Key != nil, "signGrants")
+	add(conf.StrictSignDomains, "strictSignDomains")
+	add(conf.DuplicateSignWindow > 0, "duplicateSignWindow")
+	add(conf.SigningLatencySLO.Threshold > 0, "signingLatencySLO")
+	add(conf.UsageReport.Interval > 0, "usageReport")
+	add(conf.AccountDefaults != (config.AccountSettings{}) || len(conf.AccountOverrides) != 0, "accountSettings")
+	add(len(conf.RequestNamespaces.Allowed) != 0, "requestNamespaces")
+	add(conf.SignPayloads.MaxBytes > 0 || conf.SignPayloads.MaxCalldataBytes > 0 || len(conf.SignPayloads.Selectors) != 0, "signPayloads")
+	add(conf.Debug.Address != "", "debug")
+	return features
+}
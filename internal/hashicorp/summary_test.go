@@ -0,0 +1,49 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupSummary(t *testing.T) {
+	var conf config.VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"vault": "https://vault:8200",
+		"kvEngineName": "engine",
+		"authentication": {"roleId": "env://ROLE_ID", "secretId": "env://SECRET_ID", "approlePath": "myapprole"},
+		"readCacheSize": 10,
+		"versionFallback": true,
+		"debug": {"address": "localhost:6060"}
+	}`), &conf))
+
+	s := DebugState{
+		Vault:            "https://vault:8200",
+		Accounts:         3,
+		UnlockedAccounts: 1,
+		ReadReplicas:     []ReadReplicaState{{Address: "https://replica1:8200"}, {Address: "https://replica2:8200"}},
+	}
+
+	got := StartupSummary(conf, s)
+	require.Equal(t, "vault=https://vault:8200 auth=approle/myapprole secretsEngine=engine readReplicas=https://replica1:8200,https://replica2:8200 accounts=3 unlocked=1 degraded=0 features=versionFallback,readCache,debug", got)
+	require.NotContains(t, got, "SECRET_ID")
+}
+
+func TestStartupSummary_Token(t *testing.T) {
+	require.NoError(t, os.Setenv("SUMMARY_TEST_TOKEN", "sometoken"))
+	defer os.Unsetenv("SUMMARY_TEST_TOKEN")
+
+	var conf config.VaultClient
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"vault": "https://vault:8200",
+		"kvEngineName": "engine",
+		"authentication": {"token": "env://SUMMARY_TEST_TOKEN"}
+	}`), &conf))
+
+	got := StartupSummary(conf, DebugState{Vault: "https://vault:8200"})
+	require.Equal(t, "vault=https://vault:8200 auth=token secretsEngine=engine accounts=0 unlocked=0 degraded=0 features=none", got)
+	require.NotContains(t, got, "sometoken")
+}
@@ -0,0 +1,45 @@
+package hashicorp
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+)
+
+// workerRestartDelay is how long a background worker waits before being restarted after a panic, so that a worker
+// which panics immediately does not spin
+var workerRestartDelay = 5 * time.Second
+
+// supervise runs fn in a new goroutine.  A panic in fn is recovered and reported, and fn is run again after
+// workerRestartDelay, so that a bug in one background worker (e.g. the connectivity probe) stops only that worker
+// briefly rather than crashing the plugin and taking every wallet with it.  Supervision ends when fn returns, or when
+// stop is closed, in which case fn is not run again after a panic.
+func supervise(name string, stop <-chan struct{}, fn func()) {
+	delay := workerRestartDelay
+	go func() {
+		for !runRecovered(name, delay, fn) {
+			if !sleepUnlessStopped(delay, stop) || isStopped(stop) {
+				log.Printf("[INFO] not restarting %v as it has been stopped", name)
+				return
+			}
+			log.Printf("[INFO] restarting %v", name)
+		}
+	}()
+}
+
+// runRecovered runs fn, returning false if it panicked
+func runRecovered(name string, delay time.Duration, fn func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] %v panicked, restarting in %v: %v\n%s", name, delay, r, debug.Stack())
+			metrics.WorkerRestarts.Add(name, 1)
+			event.Emit(event.WorkerRestarted, name, fmt.Sprint(r))
+		}
+	}()
+	fn()
+	return true
+}
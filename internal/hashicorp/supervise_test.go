@@ -0,0 +1,77 @@
+package hashicorp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervise_RestartsAfterPanic(t *testing.T) {
+	defer func(d time.Duration) { workerRestartDelay = d }(workerRestartDelay)
+	workerRestartDelay = time.Millisecond
+
+	events, unsubscribe := event.Subscribe(10)
+	defer unsubscribe()
+
+	runs := make(chan int, 3)
+	n := 0
+	supervise("test worker", nil, func() {
+		n++
+		runs <- n
+		if n < 3 {
+			panic("boom")
+		}
+	})
+
+	for i := 1; i <= 3; i++ {
+		select {
+		case got := <-runs:
+			require.Equal(t, i, got)
+		case <-time.After(time.Second):
+			t.Fatalf("worker not restarted after panic %v", i-1)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		e := <-events
+		require.Equal(t, event.WorkerRestarted, e.Kind)
+		require.Equal(t, "test worker", e.Subject)
+		require.Equal(t, "boom", e.Message)
+	}
+	require.Equal(t, "2", metrics.WorkerRestarts.Get("test worker").String())
+
+	// returning normally ends supervision
+	select {
+	case <-runs:
+		t.Fatal("worker restarted after returning")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSupervise_NotRestartedAfterStop(t *testing.T) {
+	defer func(d time.Duration) { workerRestartDelay = d }(workerRestartDelay)
+	workerRestartDelay = 50 * time.Millisecond
+
+	stop := make(chan struct{})
+	runs := make(chan struct{}, 2)
+	supervise("stopped worker", stop, func() {
+		runs <- struct{}{}
+		panic("boom")
+	})
+
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatal("worker not started")
+	}
+	close(stop)
+
+	select {
+	case <-runs:
+		t.Fatal("worker restarted after stop")
+	case <-time.After(2 * workerRestartDelay):
+	}
+}
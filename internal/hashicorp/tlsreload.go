@@ -0,0 +1,283 @@
+package hashicorp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// tlsReloadInterval is how often the configured TLS files are checked for changes
+var tlsReloadInterval = 10 * time.Second
+
+// tlsReloader keeps the client certificate and CA used for Vault connections up to date with the configured files, so
+// that rotated certificates (e.g. by cert-manager) are used without restarting the plugin.  The certificate and CA are
+// read on each TLS handshake so the transport never has to be replaced.
+type tlsReloader struct {
+	caCert, clientCert, clientKey string // file paths, empty if not configured
+	caCertDir                     string // directory of PEM-encoded CA certificates, empty if not configured
+	appendSystemRoots             bool   // whether the CA certificates are added to the system roots
+	serverName                    string // the hostname the server's certificate is verified against
+	transport                     *http.Transport
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+// watchTLSFiles configures the transport to use the latest contents of the TLS files, checking the files for changes
+// every tlsReloadInterval until stop is closed, and returns the reloader.  It is a no-op returning nil if no TLS files
+// are configured.
+func watchTLSFiles(transport *http.Transport, address *url.URL, conf config.VaultClientTLS, stop <-chan struct{}) (*tlsReloader, error) {
+	files := convertTLSConfig(conf)
+	var caCertDir string
+	if conf.CaCertDir != nil {
+		caCertDir = config.FilePath(conf.CaCertDir)
+	}
+	if files.CACert == "" && caCertDir == "" && files.ClientCert == "" {
+		return nil, nil
+	}
+
+	r := &tlsReloader{
+		caCert:            files.CACert,
+		clientCert:        files.ClientCert,
+		clientKey:         files.ClientKey,
+		caCertDir:         caCertDir,
+		appendSystemRoots: conf.AppendSystemRoots,
+		serverName:        serverName(address, conf),
+		transport:         transport,
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	tlsConf := transport.TLSClientConfig
+	if r.clientCert != "" {
+		tlsConf.Certificates = nil
+		tlsConf.GetClientCertificate = r.getClientCertificate
+	}
+	if r.hasCA() {
+		// the default verification can only use a fixed pool of roots, so the equivalent verification against the
+		// latest roots is done in verifyPeerCertificate instead
+		tlsConf.InsecureSkipVerify = true
+		tlsConf.VerifyPeerCertificate = r.verifyPeerCertificate
+	}
+
+	supervise("tls reloader", stop, func() {
+		t := time.NewTicker(tlsReloadInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
+			if reloaded, err := r.reload(); err != nil {
+				log.Printf("[WARN] unable to reload Vault TLS files, continuing to use previous files: %v", err)
+			} else if reloaded {
+				log.Printf("[INFO] reloaded Vault TLS files for %v", address)
+				// existing connections continue to use the previous certificate, so make sure new ones are used
+				transport.CloseIdleConnections()
+			}
+		}
+	})
+	return r, nil
+}
+
+// serverName returns the hostname the Vault server's certificate should be verified against
+func serverName(address *url.URL, conf config.VaultClientTLS) string {
+	if conf.ServerName != "" {
+		return conf.ServerName
+	}
+	host := address.Hostname()
+	// the zone of a link-local IPv6 address (e.g. fe80::1%eth0) is not part of the address in the certificate
+	if i := strings.Index(host, "%"); i >= 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+func (r *tlsReloader) hasCA() bool {
+	return r.caCert != "" || r.caCertDir != ""
+}
+
+// files returns the paths of the TLS files, including the contents of the CA directory.  The client certificate and key
+// may be in the CA directory (e.g. when mounted from the same Kubernetes secret) and are not treated as CAs.
+func (r *tlsReloader) files() ([]string, error) {
+	var files []string
+	for _, f := range []string{r.caCert, r.clientCert, r.clientKey} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	if r.caCertDir == "" {
+		return files, nil
+	}
+	entries, err := ioutil.ReadDir(r.caCertDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		f := filepath.Join(r.caCertDir, e.Name())
+		// follow symlinks, e.g. to the files of a mounted Kubernetes secret
+		if info, err := os.Stat(f); err == nil && info.Mode().IsRegular() && !r.isClientFile(f) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// changed returns the current modification times of the TLS files, and whether any have been added, removed or
+// modified since they were last read
+func (r *tlsReloader) changed() (map[string]time.Time, bool, error) {
+	files, err := r.files()
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	modTimes := make(map[string]time.Time)
+	changed := len(files) != len(r.modTimes)
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, false, err
+		}
+		modTimes[f] = info.ModTime()
+
+		if prev, ok := r.modTimes[f]; !ok || !prev.Equal(info.ModTime()) {
+			changed = true
+		}
+	}
+	return modTimes, changed, nil
+}
+
+// reload reads the TLS files if any have changed since they were last read
+func (r *tlsReloader) reload() (bool, error) {
+	modTimes, changed, err := r.changed()
+	if err != nil {
+		return false, err
+	}
+	if !changed {
+		return false, nil
+	}
+
+	var (
+		cert  *tls.Certificate
+		roots *x509.CertPool
+	)
+	if r.clientCert != "" {
+		c, err := tls.LoadX509KeyPair(r.clientCert, r.clientKey)
+		if err != nil {
+			return false, fmt.Errorf("unable to load client certificate: %v", err)
+		}
+		cert = &c
+	}
+	if r.hasCA() {
+		if roots, err = r.loadRoots(modTimes); err != nil {
+			return false, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = cert
+	r.roots = roots
+	r.modTimes = modTimes
+	return true, nil
+}
+
+func (r *tlsReloader) isClientFile(f string) bool {
+	return f == r.clientCert || f == r.clientKey
+}
+
+// loadRoots creates a pool of the configured CA certificates.  Files in the CA directory that do not contain any
+// certificates are ignored.
+func (r *tlsReloader) loadRoots(files map[string]time.Time) (*x509.CertPool, error) {
+	roots := x509.NewCertPool()
+	if r.appendSystemRoots {
+		system, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("unable to load system root certificates: %v", err)
+		}
+		roots = system
+	}
+
+	found := false
+	for f := range files {
+		if r.isClientFile(f) {
+			continue
+		}
+		pem, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if roots.AppendCertsFromPEM(pem) {
+			found = true
+		} else if f == r.caCert {
+			return nil, fmt.Errorf("no valid certificates found in %v", f)
+		}
+	}
+	if !found {
+		return nil, errors.New("no valid CA certificates found")
+	}
+	return roots, nil
+}
+
+func (r *tlsReloader) getClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// verifyPeerCertificate verifies the server's certificate chain and hostname against the latest CA
+func (r *tlsReloader) verifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	_, err := r.verifyChains(rawCerts)
+	return err
+}
+
+// verifyChains verifies the server's certificate chain and hostname against the latest CA, returning the verified
+// chains
+func (r *tlsReloader) verifyChains(rawCerts [][]byte) ([][]*x509.Certificate, error) {
+	if len(rawCerts) == 0 {
+		return nil, errors.New("no certificate presented by Vault server")
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		c, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	r.mu.RLock()
+	roots := r.roots
+	r.mu.RUnlock()
+
+	return certs[0].Verify(x509.VerifyOptions{
+		DNSName:       r.serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+}
@@ -0,0 +1,142 @@
+package hashicorp
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCACert     = "../test/testdata/tls/ca-root.cert.pem"
+	testClientCert = "../test/testdata/tls/client-ca-chain.cert.pem"
+	testClientKey  = "../test/testdata/tls/client.key.pem"
+	testServerCert = "../test/testdata/tls/server-localhost-with-san-ca-chain.cert.pem"
+)
+
+func copyFile(t *testing.T, src, dst string) {
+	b, err := ioutil.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(dst, b, 0600))
+}
+
+func TestTLSReloader_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsreload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r := &tlsReloader{
+		caCert:     filepath.Join(dir, "ca.pem"),
+		clientCert: filepath.Join(dir, "client.pem"),
+		clientKey:  filepath.Join(dir, "client.key"),
+	}
+	copyFile(t, testCACert, r.caCert)
+	copyFile(t, testClientCert, r.clientCert)
+	copyFile(t, testClientKey, r.clientKey)
+
+	reloaded, err := r.reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	first, _ := r.getClientCertificate(nil)
+	require.NotNil(t, first)
+
+	reloaded, err = r.reload()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	// a partially written file is not used
+	require.NoError(t, ioutil.WriteFile(r.clientKey, []byte("rotating"), 0600))
+	_, err = r.reload()
+	require.Error(t, err)
+	got, _ := r.getClientCertificate(nil)
+	require.True(t, first == got)
+
+	copyFile(t, testClientKey, r.clientKey)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(r.clientKey, later, later))
+	reloaded, err = r.reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	got, _ = r.getClientCertificate(nil)
+	require.False(t, first == got)
+}
+
+func TestTLSReloader_VerifyPeerCertificate(t *testing.T) {
+	r := &tlsReloader{caCert: testCACert, serverName: "localhost"}
+	_, err := r.reload()
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadFile(testServerCert)
+	require.NoError(t, err)
+	var rawCerts [][]byte
+	for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+		rawCerts = append(rawCerts, block.Bytes)
+	}
+
+	require.NoError(t, r.verifyPeerCertificate(rawCerts, nil))
+
+	r.serverName = "vault.example.com"
+	require.Error(t, r.verifyPeerCertificate(rawCerts, nil))
+
+	require.EqualError(t, r.verifyPeerCertificate(nil, nil), "no certificate presented by Vault server")
+}
+
+func TestServerName(t *testing.T) {
+	address, _ := url.Parse("https://10.0.0.1:8200")
+
+	require.Equal(t, "10.0.0.1", serverName(address, config.VaultClientTLS{}))
+	require.Equal(t, "vault.example.com", serverName(address, config.VaultClientTLS{ServerName: "vault.example.com"}))
+
+	address, _ = url.Parse("https://[fe80::1%25eth0]:8200")
+	require.Equal(t, "fe80::1", serverName(address, config.VaultClientTLS{}))
+}
+
+func TestTLSReloader_CACertDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsreload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the client certificate and key are mounted in the same directory as the CA
+	r := &tlsReloader{
+		caCertDir:  dir,
+		clientCert: filepath.Join(dir, "tls.crt"),
+		clientKey:  filepath.Join(dir, "tls.key"),
+		serverName: "localhost",
+	}
+	copyFile(t, testClientCert, r.clientCert)
+	copyFile(t, testClientKey, r.clientKey)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a certificate"), 0600))
+
+	_, err = r.reload()
+	require.EqualError(t, err, "no valid CA certificates found")
+
+	copyFile(t, testCACert, filepath.Join(dir, "ca.crt"))
+	reloaded, err := r.reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	require.NoError(t, r.verifyPeerCertificate(serverCertChain(t), nil))
+
+	reloaded, err = r.reload()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	// removing a CA is detected
+	require.NoError(t, os.Remove(filepath.Join(dir, "README")))
+	reloaded, err = r.reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+}
+
+func TestTLSReloader_AppendSystemRoots(t *testing.T) {
+	r := &tlsReloader{caCert: testCACert, appendSystemRoots: true, serverName: "localhost"}
+	_, err := r.reload()
+	require.NoError(t, err)
+
+	require.NoError(t, r.verifyPeerCertificate(serverCertChain(t), nil))
+}
@@ -0,0 +1,61 @@
+package hashicorp
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// tokenFileCheckInterval is how often a token read from a file is checked for changes
+var tokenFileCheckInterval = 10 * time.Second
+
+// watchTokenFile updates the client's token whenever the token file changes, e.g. when a mounted Docker or Kubernetes
+// secret is rotated, until the client is closed
+func (c *vaultClient) watchTokenFile(token config.EnvironmentVariable) {
+	u := url.URL(token)
+	path := config.FilePath(&u)
+
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+
+	supervise("token file watcher", c.stop, func() {
+		t := time.NewTicker(tokenFileCheckInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-t.C:
+				modTime = c.refreshToken(token, path, modTime)
+			}
+		}
+	})
+}
+
+// refreshToken sets the client's token from the file if it has been modified since lastModified, returning the
+// modification time of the token that is now in use
+func (c *vaultClient) refreshToken(token config.EnvironmentVariable, path string, lastModified time.Time) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Printf("[WARN] unable to check Vault token file %v, continuing to use current token: %v", path, err)
+		return lastModified
+	}
+	if info.ModTime().Equal(lastModified) {
+		return lastModified
+	}
+
+	t := token.Get()
+	if t == "" {
+		// the file may be mid-rotation, so try again next time
+		return lastModified
+	}
+	c.installToken(t, authStateAuthenticated, authStatic, nil)
+	log.Printf("[INFO] Vault token file %v changed, using new token", path)
+	return info.ModTime()
+}
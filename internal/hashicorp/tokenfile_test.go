@@ -0,0 +1,47 @@
+package hashicorp
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tokenfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(path, []byte("first\n"), 0600))
+	u, _ := url.Parse("file://" + path)
+	token := config.EnvironmentVariable(*u)
+
+	c := testVaultClient(t, "http://vault:8200")
+	c.SetToken(token.Get())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	modTime := c.refreshToken(token, path, info.ModTime())
+	require.Equal(t, info.ModTime(), modTime)
+	require.Equal(t, "first", c.Token())
+
+	// rotated but not yet written
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, ioutil.WriteFile(path, nil, 0600))
+	require.NoError(t, os.Chtimes(path, later, later))
+	modTime = c.refreshToken(token, path, modTime)
+	require.Equal(t, info.ModTime(), modTime)
+	require.Equal(t, "first", c.Token())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("second\n"), 0600))
+	require.NoError(t, os.Chtimes(path, later, later))
+	modTime = c.refreshToken(token, path, modTime)
+	require.True(t, later.Equal(modTime))
+	require.Equal(t, "second", c.Token())
+}
@@ -0,0 +1,221 @@
+package hashicorp
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+)
+
+// standbyTokenCheckInterval is how often standby tokens nearing expiry are replaced and the pool is refilled
+var standbyTokenCheckInterval = 30 * time.Second
+
+// tokenPool keeps a number of tokens logged in with the configured auth method, in addition to the token in use, so
+// that when the token in use is revoked or can no longer be renewed the plugin switches to a standby token immediately
+// rather than pausing signing while it logs in.  The pool is refilled in the background.
+type tokenPool struct {
+	size   int
+	login  *vaultClient // logs in without changing the token of the client the pool serves
+	fillMu sync.Mutex   // serialises fills so that the pool is not overfilled
+	mu     sync.Mutex
+	tokens []standbyToken
+	closed bool // the pool is no longer filled once its tokens have been revoked on shutdown
+}
+
+type standbyToken struct {
+	secret    *renewable
+	issued    time.Time
+	replaceAt time.Time // zero if the token does not expire
+}
+
+// newTokenPool returns nil if standbyTokens is not configured.  secretIDs and stop are shared with the client the pool
+// serves, as a wrapped secret_id can only be unwrapped once.
+func newTokenPool(conf config.VaultClient, secretIDs *approleSecretIDs, stop chan struct{}) (*tokenPool, error) {
+	if conf.StandbyTokens <= 0 {
+		return nil, nil
+	}
+	c, err := newAPIClient(conf.Vault, conf.TLS, conf.DNSRefreshInterval, stop)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Hashicorp Vault standby token client: %v", err)
+	}
+	setRequestHeaders(c, conf)
+
+	return &tokenPool{
+		size:  conf.StandbyTokens,
+		login: &vaultClient{Client: c, secretIDs: secretIDs, stop: stop},
+	}, nil
+}
+
+// start fills the pool and keeps it filled until it or the client it serves is closed
+func (p *tokenPool) start(conf config.VaultClientAuthentication) {
+	if p == nil {
+		return
+	}
+	supervise("standby token pool", p.login.stop, func() {
+		for !p.isClosed() {
+			p.fill(conf)
+			if !sleepUnlessStopped(standbyTokenCheckInterval, p.login.stop) {
+				return
+			}
+		}
+	})
+}
+
+// fill drops standby tokens that are nearing expiry and logs in until the pool is full.  Logins refused by Vault, e.g.
+// because the secret_id can only be used once, are logged and retried at the next check.
+func (p *tokenPool) fill(conf config.VaultClientAuthentication) {
+	if p == nil {
+		return
+	}
+	p.fillMu.Lock()
+	defer p.fillMu.Unlock()
+
+	now := time.Now()
+	p.mu.Lock()
+	usable := p.tokens[:0]
+	for _, t := range p.tokens {
+		if t.replaceAt.IsZero() || now.Before(t.replaceAt) {
+			usable = append(usable, t)
+		}
+	}
+	p.tokens = usable
+	missing := p.size - len(p.tokens)
+	p.mu.Unlock()
+
+	for i := 0; i < missing; i++ {
+		if p.isClosed() {
+			return
+		}
+		secret, err := p.login.login(conf)
+		if err != nil {
+			log.Printf("[WARN] unable to log in to Vault for a standby token: %v, err = %v", authMethod(conf), err)
+			return
+		}
+		t := standbyToken{secret: secret, issued: time.Now()}
+		if ttl, _ := secret.TokenTTL(); ttl > 0 {
+			t.replaceAt = t.issued.Add(reloginAfter(ttl))
+		}
+		p.mu.Lock()
+		p.tokens = append(p.tokens, t)
+		p.mu.Unlock()
+	}
+}
+
+// take removes a standby token from the pool, returning false if none is usable.  The token's lease is shortened by the
+// time it spent in the pool, so that it is renewed or replaced before it expires.
+func (p *tokenPool) take() (*renewable, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for len(p.tokens) != 0 {
+		t := p.tokens[0]
+		p.tokens = p.tokens[1:]
+		if !t.replaceAt.IsZero() && !now.Before(t.replaceAt) {
+			continue
+		}
+		if auth := t.secret.Auth; auth != nil && auth.LeaseDuration > 0 {
+			auth.LeaseDuration -= int(now.Sub(t.issued).Seconds())
+		}
+		return t.secret, true
+	}
+	return nil, false
+}
+
+// len is the number of tokens in the pool
+func (p *tokenPool) len() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.tokens)
+}
+
+func (p *tokenPool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// close revokes the standby tokens and stops the pool being refilled
+func (p *tokenPool) close() {
+	if p == nil {
+		return
+	}
+	p.fillMu.Lock()
+	defer p.fillMu.Unlock()
+	p.mu.Lock()
+	old := p.tokens
+	p.tokens, p.closed = nil, true
+	p.mu.Unlock()
+
+	for _, t := range old {
+		if err := p.revoke(t.secret.Auth.ClientToken); err != nil {
+			log.Printf("[WARN] unable to revoke standby token: %v", err)
+		}
+	}
+}
+
+// flush revokes the standby tokens and refills the pool, so that tokens issued to credentials that have since been
+// rotated are not used
+func (p *tokenPool) flush(conf config.VaultClientAuthentication) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	old := p.tokens
+	p.tokens = nil
+	p.mu.Unlock()
+
+	for _, t := range old {
+		if err := p.revoke(t.secret.Auth.ClientToken); err != nil {
+			log.Printf("[DEBUG] unable to revoke standby token: %v", err)
+		}
+	}
+	p.fill(conf)
+}
+
+// revoke revokes a standby token using the token itself, as the login client's token may be a different standby token
+func (p *tokenPool) revoke(token string) error {
+	r := p.login.NewRequest(http.MethodPost, "/v1/auth/token/revoke-self")
+	r.ClientToken = token
+	resp, err := p.login.RawRequest(r)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+// useStandbyToken switches to a token from the pool unless stop is closed, returning false if none is available
+func (c *vaultClient) useStandbyToken(conf config.VaultClientAuthentication, stop <-chan struct{}) bool {
+	secret, ok := c.pool.take()
+	if !ok {
+		return false
+	}
+	installed, err := secret.startRenewal(c, conf, stop)
+	if err != nil {
+		log.Printf("[ERROR] unable to start renewal of standby token: %v, err = %v", authMethod(conf), err)
+		return false
+	}
+	if !installed {
+		log.Printf("[DEBUG] discarding standby Vault token as the token was replaced during re-authentication: %v", authMethod(conf))
+		return true
+	}
+	if c.sink != nil {
+		if err := c.sink.write(secret.Auth.ClientToken); err != nil {
+			log.Printf("[WARN] unable to persist Vault token: %v", err)
+		}
+	}
+	log.Printf("[DEBUG] switched to a standby Vault token: %v", authMethod(conf))
+	event.Emit(event.AuthReauthenticated, authID(conf), "standby token")
+
+	go c.pool.fill(conf)
+	return true
+}
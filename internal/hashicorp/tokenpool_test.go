@@ -0,0 +1,153 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/stretchr/testify/require"
+)
+
+// standbyTokenVaultServer serves approle logins, issuing token-1, token-2, ... with the given TTL, and records revoked
+// tokens
+type standbyTokenVaultServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	logins  int
+	revoked []string
+}
+
+func newStandbyTokenVaultServer(t *testing.T, ttl int) *standbyTokenVaultServer {
+	s := &standbyTokenVaultServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			s.logins++
+			b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: fmt.Sprintf("token-%v", s.logins), LeaseDuration: ttl}})
+			_, _ = w.Write(b)
+		case "/v1/auth/token/revoke-self":
+			s.revoked = append(s.revoked, r.Header.Get("X-Vault-Token"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s
+}
+
+func (s *standbyTokenVaultServer) loginCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+func standbyTokenClient(t *testing.T, vaultURL string, size int) (*vaultClient, config.VaultClientAuthentication) {
+	require.NoError(t, os.Setenv("STANDBY_TEST_ROLE_ID", "role-id"))
+	require.NoError(t, os.Setenv("STANDBY_TEST_SECRET_ID", "secret-id"))
+	roleID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "STANDBY_TEST_ROLE_ID"})
+	secretID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "STANDBY_TEST_SECRET_ID"})
+	auth := config.VaultClientAuthentication{Token: &config.EnvironmentVariable{}, RoleId: &roleID, SecretId: &secretID, ApprolePath: "approle"}
+
+	u, err := url.Parse(vaultURL)
+	require.NoError(t, err)
+	conf := config.VaultClient{
+		Vault:          u,
+		StandbyTokens:  size,
+		Authentication: auth,
+		TLS:            config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}},
+	}
+
+	c := testVaultClient(t, vaultURL)
+	c.pool, err = newTokenPool(conf, c.secretIDs, nil)
+	require.NoError(t, err)
+	return c, auth
+}
+
+func TestNewTokenPool_NotConfigured(t *testing.T) {
+	p, err := newTokenPool(config.VaultClient{}, nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	_, ok := p.take()
+	require.False(t, ok)
+	require.Equal(t, 0, p.len())
+}
+
+func TestTokenPool_FailsOverWithoutLogin(t *testing.T) {
+	defer os.Unsetenv("STANDBY_TEST_ROLE_ID")
+	defer os.Unsetenv("STANDBY_TEST_SECRET_ID")
+	vault := newStandbyTokenVaultServer(t, 3600)
+	defer vault.Close()
+
+	c, auth := standbyTokenClient(t, vault.URL, 2)
+	require.NoError(t, c.authenticate(auth))
+	require.Equal(t, "token-1", c.Token())
+
+	c.pool.fill(auth)
+	require.Equal(t, 2, c.pool.len())
+	require.Equal(t, 3, vault.loginCount())
+
+	events, unsubscribe := event.Subscribe(10)
+	defer unsubscribe()
+
+	// the current token is revoked
+	stop := c.supersedeRenewal()
+	c.reauthenticate(auth, stop)
+	require.Equal(t, "token-2", c.Token())
+	require.Equal(t, authNotRenewable, c.getAuthStatus())
+	e := <-events
+	require.Equal(t, event.AuthReauthenticated, e.Kind)
+	require.Equal(t, "standby token", e.Message)
+
+	// the pool is refilled in the background
+	c.pool.fill(auth)
+	require.Equal(t, 2, c.pool.len())
+	require.Equal(t, 4, vault.loginCount())
+}
+
+func TestTokenPool_Take_SkipsTokensNearingExpiry(t *testing.T) {
+	p := &tokenPool{tokens: []standbyToken{
+		{secret: &renewable{&api.Secret{Auth: &api.SecretAuth{ClientToken: "expiring", LeaseDuration: 60}}}, issued: time.Now().Add(-time.Minute), replaceAt: time.Now().Add(-time.Second)},
+		{secret: &renewable{&api.Secret{Auth: &api.SecretAuth{ClientToken: "valid", LeaseDuration: 60}}}, issued: time.Now().Add(-10 * time.Second), replaceAt: time.Now().Add(30 * time.Second)},
+	}}
+
+	got, ok := p.take()
+	require.True(t, ok)
+	require.Equal(t, "valid", got.Auth.ClientToken)
+	// the lease is shortened by the time the token spent in the pool
+	require.InDelta(t, 50, got.Auth.LeaseDuration, 1)
+
+	_, ok = p.take()
+	require.False(t, ok)
+}
+
+func TestTokenPool_Flush(t *testing.T) {
+	defer os.Unsetenv("STANDBY_TEST_ROLE_ID")
+	defer os.Unsetenv("STANDBY_TEST_SECRET_ID")
+	vault := newStandbyTokenVaultServer(t, 0)
+	defer vault.Close()
+
+	c, auth := standbyTokenClient(t, vault.URL, 2)
+	c.pool.fill(auth)
+	require.Equal(t, 2, vault.loginCount())
+
+	c.pool.flush(auth)
+	require.Equal(t, 2, c.pool.len())
+	require.Equal(t, 4, vault.loginCount())
+	require.Equal(t, []string{"token-1", "token-2"}, vault.revoked)
+
+	got, ok := c.pool.take()
+	require.True(t, ok)
+	require.Equal(t, "token-3", got.Auth.ClientToken)
+}
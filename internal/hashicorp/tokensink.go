@@ -0,0 +1,152 @@
+package hashicorp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/atomicfile"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/state"
+)
+
+const tokenSinkFile = "vault-token"
+
+// tokenSink persists the current Vault token, encrypted with a local key, so that a quick plugin restart can resume
+// with the existing token instead of performing an AppRole login.  This matters where secret_ids are single-use.
+type tokenSink struct {
+	path        string
+	key         [32]byte
+	vault       string
+	approlePath string
+}
+
+// sunkToken is the encrypted content of the sink.  The vault and approle path are stored so that a token is not reused
+// after the plugin's authentication config has changed.
+type sunkToken struct {
+	Vault       string
+	ApprolePath string
+	Token       string
+}
+
+// newTokenSink returns nil if the token sink is not configured
+func newTokenSink(stateDir *state.Dir, conf config.VaultClient) *tokenSink {
+	if conf.TokenSink.Key == nil || stateDir == nil {
+		return nil
+	}
+	return &tokenSink{
+		path:        stateDir.File(tokenSinkFile),
+		key:         sha256.Sum256([]byte(conf.TokenSink.Key.Get())),
+		vault:       conf.Vault.String(),
+		approlePath: conf.Authentication.ApprolePath,
+	}
+}
+
+func (s *tokenSink) write(token string) error {
+	plaintext, err := json.Marshal(sunkToken{Vault: s.vault, ApprolePath: s.approlePath, Token: token})
+	if err != nil {
+		return err
+	}
+
+	gcm, err := s.aead()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
+
+	return atomicfile.Write(s.path, ciphertext, 0600)
+}
+
+// read returns the persisted token if it was written for the same vault and approle
+func (s *tokenSink) read() (string, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := s.aead()
+	if err != nil {
+		return "", err
+	}
+	if len(b) < gcm.NonceSize() {
+		return "", errors.New("persisted token is corrupt")
+	}
+	plaintext, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("unable to decrypt persisted token: the file is corrupt or a different key was used")
+	}
+
+	var t sunkToken
+	if err := json.Unmarshal(plaintext, &t); err != nil {
+		return "", err
+	}
+	if t.Vault != s.vault || t.ApprolePath != s.approlePath {
+		return "", errors.New("persisted token was issued for a different vault or approle")
+	}
+	return t.Token, nil
+}
+
+func (s *tokenSink) remove() {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("[WARN] unable to remove persisted Vault token %v: %v", s.path, err)
+	}
+}
+
+func (s *tokenSink) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// resumeToken uses the persisted token if it is still valid, returning false if an AppRole login is needed
+func (c *vaultClient) resumeToken() (*renewable, bool) {
+	if c.sink == nil {
+		return nil, false
+	}
+
+	t, err := c.sink.read()
+	if os.IsNotExist(err) {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("[WARN] unable to use persisted Vault token, logging in with approle: %v", err)
+		c.sink.remove()
+		return nil, false
+	}
+
+	c.SetToken(t)
+	self, err := c.Auth().Token().LookupSelf()
+	if err != nil {
+		log.Printf("[INFO] persisted Vault token is no longer valid, logging in with approle: %v", err)
+		c.ClearToken()
+		c.sink.remove()
+		return nil, false
+	}
+
+	isRenewable, _ := self.TokenIsRenewable()
+	ttl, _ := self.TokenTTL()
+	log.Printf("[INFO] resumed with persisted Vault token: ttl = %v, renewable = %v", ttl, isRenewable)
+
+	return &renewable{Secret: &api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   t,
+			Renewable:     isRenewable,
+			LeaseDuration: int(ttl / time.Second),
+		},
+	}}, true
+}
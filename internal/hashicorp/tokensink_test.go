@@ -0,0 +1,118 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/state"
+	"github.com/stretchr/testify/require"
+)
+
+func testTokenSink(t *testing.T, stateDir *state.Dir, key string) *tokenSink {
+	require.NoError(t, os.Setenv("TOKEN_SINK_KEY", key))
+	defer os.Unsetenv("TOKEN_SINK_KEY")
+
+	vault, _ := url.Parse("https://vault:8200")
+	keyURL, _ := url.Parse("env://TOKEN_SINK_KEY")
+	env := config.EnvironmentVariable(*keyURL)
+
+	return newTokenSink(stateDir, config.VaultClient{
+		Vault:          vault,
+		Authentication: config.VaultClientAuthentication{ApprolePath: "myapprole"},
+		TokenSink:      config.VaultClientTokenSink{Key: &env},
+	})
+}
+
+func testStateDir(t *testing.T) *state.Dir {
+	dir, err := ioutil.TempDir("", "tokensink")
+	require.NoError(t, err)
+	stateDir, err := state.Open(dir)
+	require.NoError(t, err)
+	return stateDir
+}
+
+func TestTokenSink_WriteRead(t *testing.T) {
+	stateDir := testStateDir(t)
+	defer os.RemoveAll(stateDir.Path())
+	defer stateDir.Close()
+
+	s := testTokenSink(t, stateDir, "mykey")
+	require.NoError(t, s.write("mytoken"))
+
+	b, err := ioutil.ReadFile(stateDir.File(tokenSinkFile))
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "mytoken")
+
+	got, err := s.read()
+	require.NoError(t, err)
+	require.Equal(t, "mytoken", got)
+
+	_, err = testTokenSink(t, stateDir, "otherkey").read()
+	require.EqualError(t, err, "unable to decrypt persisted token: the file is corrupt or a different key was used")
+
+	s.approlePath = "otherapprole"
+	_, err = s.read()
+	require.EqualError(t, err, "persisted token was issued for a different vault or approle")
+}
+
+func TestTokenSink_NotConfigured(t *testing.T) {
+	require.Nil(t, newTokenSink(nil, config.VaultClient{}))
+}
+
+func TestVaultClient_ResumesWithPersistedToken(t *testing.T) {
+	stateDir := testStateDir(t)
+	defer os.RemoveAll(stateDir.Path())
+	defer stateDir.Close()
+
+	var logins int
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/myapprole/login":
+			logins++
+			b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: "logintoken"}})
+			_, _ = w.Write(b)
+		case "/v1/auth/token/lookup-self":
+			if r.Header.Get("X-Vault-Token") != "logintoken" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"ttl": 3600, "renewable": false}})
+			_, _ = w.Write(b)
+		}
+	}))
+	defer vault.Close()
+
+	newClient := func() *vaultClient {
+		c := testVaultClient(t, vault.URL)
+		c.sink = testTokenSink(t, stateDir, "mykey")
+		return c
+	}
+	auth := config.VaultClientAuthentication{RoleId: &config.EnvironmentVariable{}, SecretId: &config.EnvironmentVariable{}, ApprolePath: "myapprole"}
+
+	// no persisted token so must login
+	c := newClient()
+	require.NoError(t, c.renewableApproleAuthentication(auth))
+	require.Equal(t, 1, logins)
+	require.Equal(t, "logintoken", c.Token())
+
+	// restart resumes with the persisted token
+	c = newClient()
+	require.NoError(t, c.renewableApproleAuthentication(auth))
+	require.Equal(t, 1, logins)
+	require.Equal(t, "logintoken", c.Token())
+
+	// a persisted token that is no longer valid is replaced
+	require.NoError(t, c.sink.write("expiredtoken"))
+	c = newClient()
+	require.NoError(t, c.renewableApproleAuthentication(auth))
+	require.Equal(t, 2, logins)
+	require.Equal(t, "logintoken", c.Token())
+}
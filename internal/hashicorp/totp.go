@@ -0,0 +1,77 @@
+package hashicorp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+var TOTPRequiredErr = errors.New("valid TOTP code required to unlock account")
+
+type totpCodeKey struct{}
+
+// WithTOTPCode returns a context carrying the TOTP code provided with an unlock request
+func WithTOTPCode(ctx context.Context, code string) context.Context {
+	return context.WithValue(ctx, totpCodeKey{}, code)
+}
+
+func totpCode(ctx context.Context) string {
+	code, _ := ctx.Value(totpCodeKey{}).(string)
+	return code
+}
+
+// unlockTOTP verifies TOTP codes for accounts that require a second factor to be unlocked
+type unlockTOTP struct {
+	engine string
+	keys   map[string]string // lowercase hex address without 0x prefix -> TOTP key name
+}
+
+// newUnlockTOTP returns nil if no accounts require a TOTP code
+func newUnlockTOTP(conf config.VaultClientUnlockTOTP) *unlockTOTP {
+	if len(conf.Keys) == 0 {
+		return nil
+	}
+	u := &unlockTOTP{engine: conf.Engine, keys: make(map[string]string, len(conf.Keys))}
+	for addr, key := range conf.Keys {
+		u.keys[strings.ToLower(strings.TrimPrefix(addr, "0x"))] = key
+	}
+	return u
+}
+
+// verifyTOTP checks the TOTP code in ctx with Vault if the account requires one, in the namespace carried by ctx if any.
+// Vault rejects reuse of a code, so a code can only be used for a single unlock.
+func (a *accountManager) verifyTOTP(ctx context.Context, addr string) error {
+	if a.totp == nil {
+		return nil
+	}
+	key, ok := a.totp.keys[strings.ToLower(addr)]
+	if !ok {
+		return nil
+	}
+	code := totpCode(ctx)
+	if code == "" {
+		return TOTPRequiredErr
+	}
+
+	resp, err := a.client.writeWithContext(ctx, fmt.Sprintf("%s/code/%s", a.totp.engine, key), map[string]interface{}{"code": code})
+	if err != nil {
+		if pd, ok := permissionDenied(err).(*PermissionDeniedError); ok {
+			return pd
+		}
+		return fmt.Errorf("unable to verify TOTP code: %v", err)
+	}
+	if resp == nil || resp.Data == nil {
+		return errors.New("unable to verify TOTP code: empty response from Vault")
+	}
+	audit.AddVaultRequestID(ctx, resp.RequestID)
+	if valid, _ := resp.Data["valid"].(bool); !valid {
+		log.Printf("[WARN] invalid TOTP code provided to unlock account 0x%v", addr)
+		return TOTPRequiredErr
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package hashicorp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimedUnlock_TOTPRequired(t *testing.T) {
+	var usedCodes []string
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/totp/code/acct1":
+			body := make(map[string]string)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			valid := body["code"] == "123456"
+			for _, c := range usedCodes {
+				if c == body["code"] {
+					valid = false
+				}
+			}
+			usedCodes = append(usedCodes, body["code"])
+			b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"valid": valid}})
+			_, _ = w.Write(b)
+		case "/v1/kv/data/acct1":
+			b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{
+				"data": map[string]interface{}{reconcileAddr1: reconcileKey1},
+			}})
+			_, _ = w.Write(b)
+		case "/v1/kv/data/acct3":
+			b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{
+				"data": map[string]interface{}{reconcileAddr2: reconcileKey2},
+			}})
+			_, _ = w.Write(b)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	a := reconcileAccountManager(t, vault.URL, "/path/to/dir")
+	a.unlocked = make(map[string]*lockableKey)
+	acct3URL, _ := url.Parse("file:///path/to/acct3")
+	acct3 := config.AccountFile{}
+	acct3.Contents.Address = reconcileAddr2
+	acct3.Contents.VaultAccount.SecretName = "acct3"
+	acct3.Contents.VaultAccount.SecretVersion = 1
+	a.client.addAccount(acct3URL, acct3)
+	a.totp = newUnlockTOTP(config.VaultClientUnlockTOTP{
+		Engine: "totp",
+		Keys:   map[string]string{"0x" + reconcileAddr1: "acct1"},
+	})
+
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+
+	require.Equal(t, TOTPRequiredErr, a.TimedUnlock(context.Background(), addr, 0))
+	require.Equal(t, TOTPRequiredErr, a.TimedUnlock(WithTOTPCode(context.Background(), "000000"), addr, 0))
+
+	require.NoError(t, a.TimedUnlock(WithTOTPCode(context.Background(), "123456"), addr, 0))
+	a.Lock(addr)
+
+	// codes cannot be reused
+	require.Equal(t, TOTPRequiredErr, a.TimedUnlock(WithTOTPCode(context.Background(), "123456"), addr, 0))
+
+	// accounts without a TOTP key are unaffected
+	other, _ := account.NewAddressFromHexString(reconcileAddr2)
+	require.NoError(t, a.TimedUnlock(context.Background(), other, 0))
+}
+
+func totpAccountManager(t *testing.T, vaultURL string) (*accountManager, account.Address) {
+	a := reconcileAccountManager(t, vaultURL, "/path/to/dir")
+	a.totp = newUnlockTOTP(config.VaultClientUnlockTOTP{
+		Engine: "totp",
+		Keys:   map[string]string{reconcileAddr1: "acct1"},
+	})
+	addr, _ := account.NewAddressFromHexString(reconcileAddr1)
+	return a, addr
+}
+
+func TestTimedUnlock_TOTPDeadline(t *testing.T) {
+	done := make(chan struct{})
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer vault.Close()
+	defer close(done)
+
+	a, addr := totpAccountManager(t, vault.URL)
+
+	ctx, cancel := context.WithTimeout(WithTOTPCode(context.Background(), "123456"), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	require.Error(t, a.TimedUnlock(ctx, addr, 0))
+	require.True(t, time.Since(start) < time.Second)
+	require.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
+
+func TestTimedUnlock_TOTPPermissionDenied(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors": ["1 error occurred:\n\t* permission denied\n\n"]}`))
+	}))
+	defer vault.Close()
+
+	a, addr := totpAccountManager(t, vault.URL)
+
+	err := a.TimedUnlock(WithTOTPCode(context.Background(), "123456"), addr, 0)
+	pd, ok := err.(*PermissionDeniedError)
+	require.True(t, ok, "%v", err)
+	require.Equal(t, "PUT", pd.Method)
+	require.Equal(t, "totp/code/acct1", pd.Path)
+}
@@ -0,0 +1,356 @@
+package hashicorp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/atomicfile"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/state"
+)
+
+const (
+	usageFile              = "usage.json"
+	usageReportSendTimeout = 30 * time.Second
+)
+
+var usageCSVHeader = []string{"address", "url", "secretName", "secretVersion", "signatures", "lastSigned", "unlocks", "lastUnlocked", "unlockedSeconds", "unlocked"}
+
+// UsageReport is the usage of each configured account since usage was first recorded
+type UsageReport struct {
+	Generated time.Time
+	Since     time.Time
+	Accounts  []AccountUsage
+}
+
+// AccountUsage is the usage of a single account.  Accounts that have never been used are included with zero counts, as
+// they are the candidates for retirement.
+type AccountUsage struct {
+	Address         string
+	URL             string
+	SecretName      string
+	SecretVersion   int64
+	Signatures      int64
+	LastSigned      *time.Time `json:",omitempty"`
+	Unlocks         int64
+	LastUnlocked    *time.Time `json:",omitempty"`
+	UnlockedSeconds int64      // the total time the account's key has been held in memory
+	Unlocked        bool       // whether the key is currently held in memory
+}
+
+// accountUsage is the recorded usage of an account
+type accountUsage struct {
+	Signatures   int64
+	LastSigned   time.Time
+	Unlocks      int64
+	LastUnlocked time.Time
+	Unlocked     time.Duration // excluding the current unlock
+
+	unlockedSince time.Time // zero if locked
+}
+
+// usageTracker records how each account is used and periodically exports a UsageReport.  Usage is kept in the state
+// directory if configured, so that it accumulates across restarts.
+type usageTracker struct {
+	conf     config.VaultClientUsageReport
+	client   *http.Client
+	path     string // empty if no state directory is configured
+	mu       sync.Mutex
+	since    time.Time
+	accounts map[string]*accountUsage // lowercase hex address without 0x prefix
+	stop     chan struct{}            // closed when the account manager is closed
+}
+
+// persistedUsage is the contents of the usage file in the state directory
+type persistedUsage struct {
+	Since    time.Time
+	Accounts map[string]*accountUsage
+}
+
+// newUsageTracker returns nil if usageReport is not configured
+func newUsageTracker(stateDir *state.Dir, conf config.VaultClientUsageReport) *usageTracker {
+	if conf.Interval <= 0 {
+		return nil
+	}
+	u := &usageTracker{
+		conf:     conf,
+		client:   &http.Client{Timeout: usageReportSendTimeout},
+		since:    time.Now(),
+		accounts: make(map[string]*accountUsage),
+		stop:     make(chan struct{}),
+	}
+	if stateDir == nil {
+		log.Println("[WARN] usageReport: no stateDirectory configured, usage is only reported since the plugin was started")
+		return u
+	}
+	u.path = stateDir.File(usageFile)
+	b, err := ioutil.ReadFile(u.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[WARN] unable to read account usage, err = %v", err)
+		}
+		return u
+	}
+	var persisted persistedUsage
+	if err := json.Unmarshal(b, &persisted); err != nil {
+		log.Printf("[WARN] unable to read account usage, err = %v", err)
+		return u
+	}
+	if persisted.Accounts != nil {
+		u.since, u.accounts = persisted.Since, persisted.Accounts
+	}
+	return u
+}
+
+// get returns the recorded usage of the account, adding it if it has not been used.  It must be called with u.mu held.
+func (u *usageTracker) get(addr string) *accountUsage {
+	addr = config.NormalizeAddress(addr)
+	usage, ok := u.accounts[addr]
+	if !ok {
+		usage = &accountUsage{}
+		u.accounts[addr] = usage
+	}
+	return usage
+}
+
+func (u *usageTracker) signed(addr string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	usage := u.get(addr)
+	usage.Signatures++
+	usage.LastSigned = time.Now()
+}
+
+// unlocked records that the account's key has been read into memory.  Replacing the key of an account that is already
+// unlocked continues the current unlock.
+func (u *usageTracker) unlocked(addr string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := time.Now()
+	usage := u.get(addr)
+	usage.Unlocks++
+	usage.LastUnlocked = now
+	if usage.unlockedSince.IsZero() {
+		usage.unlockedSince = now
+	}
+}
+
+// locked records that the account's key has been removed from memory
+func (u *usageTracker) locked(addr string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	usage := u.get(addr)
+	if !usage.unlockedSince.IsZero() {
+		usage.Unlocked += time.Since(usage.unlockedSince)
+		usage.unlockedSince = time.Time{}
+	}
+}
+
+// report returns the usage of the accounts, in the order they are listed
+func (u *usageTracker) report(accts accountsByURL, order string) UsageReport {
+	now := time.Now()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	r := UsageReport{Generated: now, Since: u.since, Accounts: []AccountUsage{}}
+	for _, url := range accts.sortedURLs(order) {
+		conf := accts[url]
+		usage, ok := u.accounts[config.NormalizeAddress(conf.Contents.Address)]
+		if !ok {
+			usage = &accountUsage{}
+		}
+		unlocked := usage.Unlocked
+		if !usage.unlockedSince.IsZero() {
+			unlocked += now.Sub(usage.unlockedSince)
+		}
+		r.Accounts = append(r.Accounts, AccountUsage{
+			Address:         "0x" + config.NormalizeAddress(conf.Contents.Address),
+			URL:             url.String(),
+			SecretName:      conf.Contents.VaultAccount.SecretName,
+			SecretVersion:   conf.Contents.VaultAccount.SecretVersion,
+			Signatures:      usage.Signatures,
+			LastSigned:      optionalTime(usage.LastSigned),
+			Unlocks:         usage.Unlocks,
+			LastUnlocked:    optionalTime(usage.LastUnlocked),
+			UnlockedSeconds: int64(unlocked.Seconds()),
+			Unlocked:        !usage.unlockedSince.IsZero(),
+		})
+	}
+	return r
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// persist writes the recorded usage to the state directory.  Accounts that are unlocked are persisted as locked now, as
+// their keys are no longer in memory after a restart.
+func (u *usageTracker) persist() error {
+	if u == nil || u.path == "" {
+		return nil
+	}
+	u.mu.Lock()
+	accounts := make(map[string]*accountUsage, len(u.accounts))
+	for addr, usage := range u.accounts {
+		persisted := *usage
+		if !usage.unlockedSince.IsZero() {
+			persisted.Unlocked += time.Since(usage.unlockedSince)
+		}
+		accounts[addr] = &persisted
+	}
+	b, err := json.Marshal(persistedUsage{Since: u.since, Accounts: accounts})
+	u.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(u.path, b, 0600)
+}
+
+// close stops reporting and persists the recorded usage, so that it is not overwritten by this tracker once the state
+// directory is used by another account manager
+func (u *usageTracker) close() error {
+	if u == nil {
+		return nil
+	}
+	u.stopReporting()
+	return u.persist()
+}
+
+// stopReporting stops reporting without persisting the recorded usage, e.g. when the state directory has already been
+// handed over to another account manager
+func (u *usageTracker) stopReporting() {
+	if u == nil {
+		return
+	}
+	close(u.stop)
+}
+
+// export writes the report to the configured file and sends it to the configured endpoint
+func (u *usageTracker) export(r UsageReport) error {
+	if u.conf.File != nil {
+		b, err := encodeUsageReport(r, u.conf.Format)
+		if err != nil {
+			return err
+		}
+		if err := atomicfile.Write(config.FilePath(u.conf.File), b, 0640); err != nil {
+			return fmt.Errorf("unable to write usage report: %v", err)
+		}
+	}
+	if u.conf.Endpoint != nil {
+		if err := u.send(r); err != nil {
+			return fmt.Errorf("unable to send usage report to %v: %v", u.conf.Endpoint, err)
+		}
+	}
+	return nil
+}
+
+func (u *usageTracker) send(r UsageReport) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, u.conf.Endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if u.conf.Token != nil {
+		req.Header.Set("Authorization", "Bearer "+u.conf.Token.Get())
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response: %v", resp.Status)
+	}
+	return nil
+}
+
+// encodeUsageReport encodes the report as JSON, or as CSV with a header row and a row per account
+func encodeUsageReport(r UsageReport, format string) ([]byte, error) {
+	if format != config.UsageReportFormatCSV {
+		return json.MarshalIndent(r, "", "  ")
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(usageCSVHeader); err != nil {
+		return nil, err
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	for _, a := range r.Accounts {
+		row := []string{
+			a.Address,
+			a.URL,
+			a.SecretName,
+			strconv.FormatInt(a.SecretVersion, 10),
+			strconv.FormatInt(a.Signatures, 10),
+			formatTime(a.LastSigned),
+			strconv.FormatInt(a.Unlocks, 10),
+			formatTime(a.LastUnlocked),
+			strconv.FormatInt(a.UnlockedSeconds, 10),
+			strconv.FormatBool(a.Unlocked),
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// startUsageReport exports a usage report every interval until the account manager is closed
+func (a *accountManager) startUsageReport(interval time.Duration) {
+	supervise("usage report", a.usage.stop, func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-a.usage.stop:
+				return
+			case <-t.C:
+				if err := a.exportUsage(); err != nil {
+					log.Printf("[WARN] %v", err)
+				}
+			}
+		}
+	})
+}
+
+// exportUsage persists the recorded usage and exports a report of it
+func (a *accountManager) exportUsage() error {
+	u := a.usage
+	if err := u.persist(); err != nil {
+		log.Printf("[WARN] unable to persist account usage, err = %v", err)
+	}
+	return u.export(u.report(a.client.accounts(), a.order))
+}
@@ -0,0 +1,175 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/state"
+	"github.com/stretchr/testify/require"
+)
+
+func usageAccounts() accountsByURL {
+	u1, _ := url.Parse("file:///path/to/acct1")
+	u2, _ := url.Parse("file:///path/to/acct2")
+	f1 := config.AccountFile{Contents: config.AccountFileJSON{Address: reconcileAddr1}}
+	f1.Contents.VaultAccount.SecretName = "acct1"
+	f1.Contents.VaultAccount.SecretVersion = 1
+	f2 := config.AccountFile{Contents: config.AccountFileJSON{Address: reconcileAddr2}}
+	f2.Contents.VaultAccount.SecretName = "acct2"
+	f2.Contents.VaultAccount.SecretVersion = 3
+	return accountsByURL{u1: f1, u2: f2}
+}
+
+func TestNewUsageTracker_NotConfigured(t *testing.T) {
+	u := newUsageTracker(nil, config.VaultClientUsageReport{})
+	require.Nil(t, u)
+
+	u.signed(reconcileAddr1)
+	u.unlocked(reconcileAddr1)
+	u.locked(reconcileAddr1)
+	require.NoError(t, u.persist())
+}
+
+func TestUsageTracker_Report(t *testing.T) {
+	u := newUsageTracker(nil, config.VaultClientUsageReport{Interval: time.Hour})
+
+	u.unlocked(reconcileAddr1)
+	u.signed("0x" + strings.ToUpper(reconcileAddr1))
+	u.signed(reconcileAddr1)
+	// an unlock that has ended
+	u.accounts[reconcileAddr1].unlockedSince = time.Now().Add(-time.Minute)
+	u.locked(reconcileAddr1)
+	// an unlock that is ongoing
+	u.unlocked(reconcileAddr1)
+	u.accounts[reconcileAddr1].unlockedSince = time.Now().Add(-30 * time.Second)
+
+	r := u.report(usageAccounts(), config.AccountOrderURL)
+	require.Len(t, r.Accounts, 2)
+
+	got := r.Accounts[0]
+	require.Equal(t, "0x"+reconcileAddr1, got.Address)
+	require.Equal(t, "file:///path/to/acct1", got.URL)
+	require.Equal(t, "acct1", got.SecretName)
+	require.Equal(t, int64(1), got.SecretVersion)
+	require.Equal(t, int64(2), got.Signatures)
+	require.NotNil(t, got.LastSigned)
+	require.Equal(t, int64(2), got.Unlocks)
+	require.NotNil(t, got.LastUnlocked)
+	require.InDelta(t, 90, got.UnlockedSeconds, 1)
+	require.True(t, got.Unlocked)
+
+	// accounts that have never been used are reported
+	require.Equal(t, AccountUsage{
+		Address:       "0x" + reconcileAddr2,
+		URL:           "file:///path/to/acct2",
+		SecretName:    "acct2",
+		SecretVersion: 3,
+	}, r.Accounts[1])
+}
+
+func TestUsageTracker_Persisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	conf := config.VaultClientUsageReport{Interval: time.Hour}
+
+	stateDir, err := state.Open(dir)
+	require.NoError(t, err)
+	u := newUsageTracker(stateDir, conf)
+	u.unlocked(reconcileAddr1)
+	u.accounts[reconcileAddr1].unlockedSince = time.Now().Add(-time.Minute)
+	u.signed(reconcileAddr1)
+	require.NoError(t, u.persist())
+	require.NoError(t, stateDir.Close())
+
+	// usage accumulates across restarts, and the account is no longer unlocked
+	stateDir, err = state.Open(dir)
+	require.NoError(t, err)
+	defer stateDir.Close()
+	restarted := newUsageTracker(stateDir, conf)
+	require.Equal(t, u.since.Unix(), restarted.since.Unix())
+	restarted.signed(reconcileAddr1)
+
+	got := restarted.report(usageAccounts(), config.AccountOrderURL).Accounts[0]
+	require.Equal(t, int64(2), got.Signatures)
+	require.Equal(t, int64(1), got.Unlocks)
+	require.InDelta(t, 60, got.UnlockedSeconds, 1)
+	require.False(t, got.Unlocked)
+}
+
+func TestEncodeUsageReport_CSV(t *testing.T) {
+	lastSigned := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := UsageReport{Accounts: []AccountUsage{
+		{Address: "0x" + reconcileAddr1, URL: "file:///path/to/acct1", SecretName: "acct1", SecretVersion: 1, Signatures: 2, LastSigned: &lastSigned, Unlocks: 1, UnlockedSeconds: 60},
+		{Address: "0x" + reconcileAddr2, URL: "file:///path/to/acct2", SecretName: "acct2", SecretVersion: 3},
+	}}
+
+	b, err := encodeUsageReport(r, config.UsageReportFormatCSV)
+	require.NoError(t, err)
+	want := "address,url,secretName,secretVersion,signatures,lastSigned,unlocks,lastUnlocked,unlockedSeconds,unlocked\n" +
+		"0x" + reconcileAddr1 + ",file:///path/to/acct1,acct1,1,2,2020-03-01T12:00:00Z,1,,60,false\n" +
+		"0x" + reconcileAddr2 + ",file:///path/to/acct2,acct2,3,0,,0,,0,false\n"
+	require.Equal(t, want, string(b))
+
+	b, err = encodeUsageReport(r, "")
+	require.NoError(t, err)
+	var decoded UsageReport
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, r.Accounts[1], decoded.Accounts[1])
+}
+
+func TestUsageTracker_Export(t *testing.T) {
+	defer os.Unsetenv("USAGE_TEST_TOKEN")
+	require.NoError(t, os.Setenv("USAGE_TEST_TOKEN", "report-token"))
+
+	var received UsageReport
+	var auth string
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer endpoint.Close()
+
+	dir, err := ioutil.TempDir("", "usage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "usage.csv")
+
+	endpointURL, _ := url.Parse(endpoint.URL)
+	token := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "USAGE_TEST_TOKEN"})
+	u := newUsageTracker(nil, config.VaultClientUsageReport{
+		Interval: time.Hour,
+		File:     &url.URL{Scheme: "file", Path: filepath.ToSlash(file)},
+		Format:   config.UsageReportFormatCSV,
+		Endpoint: endpointURL,
+		Token:    &token,
+	})
+	u.signed(reconcileAddr2)
+
+	require.NoError(t, u.export(u.report(usageAccounts(), config.AccountOrderURL)))
+	require.Equal(t, "Bearer report-token", auth)
+	require.Len(t, received.Accounts, 2)
+	require.Equal(t, int64(1), received.Accounts[1].Signatures)
+
+	b, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	require.Len(t, strings.Split(strings.TrimSpace(string(b)), "\n"), 3)
+
+	// a refused report is an error
+	endpoint.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	err = u.export(u.report(usageAccounts(), config.AccountOrderURL))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "401 Unauthorized")
+}
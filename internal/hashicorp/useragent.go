@@ -0,0 +1,39 @@
+package hashicorp
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+const pluginName = "quorum-account-plugin-hashicorp-vault"
+
+// Version is the plugin version reported in the User-Agent of Vault requests.  It is set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp.Version=..."
+var Version = "dev"
+
+// userAgent identifies the plugin, and the node it is running with if nodeID is set, to Vault and any proxies
+func userAgent(nodeID string) string {
+	ua := fmt.Sprintf("%v/%v", pluginName, Version)
+	if nodeID != "" {
+		ua = fmt.Sprintf("%v (node %v)", ua, nodeID)
+	}
+	return ua
+}
+
+// setRequestHeaders sets the User-Agent and any configured headers on every request made by c.  A configured
+// User-Agent header replaces the default.
+func setRequestHeaders(c *api.Client, conf config.VaultClient) {
+	h := c.Headers()
+	if h == nil {
+		h = make(http.Header)
+	}
+	h.Set("User-Agent", userAgent(conf.NodeID))
+	for name, value := range conf.Headers {
+		h.Set(name, value)
+	}
+	c.SetHeaders(h)
+}
@@ -0,0 +1,47 @@
+package hashicorp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetRequestHeaders(t *testing.T) {
+	var got http.Header
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer vault.Close()
+
+	vaultURL, _ := url.Parse(vault.URL)
+	c, err := newAPIClient(vaultURL, config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}}, 0, nil)
+	require.NoError(t, err)
+	c.SetToken("mytoken")
+
+	setRequestHeaders(c, config.VaultClient{NodeID: "node1", Headers: map[string]string{"x-proxy-tenant": "quorum"}})
+
+	_, err = c.Logical().Read("kv/data/mysecret")
+	require.NoError(t, err)
+	require.Equal(t, "quorum-account-plugin-hashicorp-vault/dev (node node1)", got.Get("User-Agent"))
+	require.Equal(t, "quorum", got.Get("X-Proxy-Tenant"))
+	require.Equal(t, "mytoken", got.Get("X-Vault-Token"))
+}
+
+func TestSetRequestHeaders_UserAgentOverridden(t *testing.T) {
+	c, err := newAPIClient(&url.URL{Scheme: "http", Host: "localhost:8200"}, config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}}, 0, nil)
+	require.NoError(t, err)
+
+	setRequestHeaders(c, config.VaultClient{Headers: map[string]string{"User-Agent": "my-agent"}})
+
+	require.Equal(t, "my-agent", c.Headers().Get("User-Agent"))
+}
+
+func TestUserAgent(t *testing.T) {
+	require.Equal(t, "quorum-account-plugin-hashicorp-vault/dev", userAgent(""))
+	require.Equal(t, "quorum-account-plugin-hashicorp-vault/dev (node node1)", userAgent("node1"))
+}
@@ -0,0 +1,40 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// userpassAuth returns the name and config of the configured username/password auth method, if any.  The LDAP and
+// userpass auth methods have the same login API.
+func userpassAuth(conf config.VaultClientAuthentication) (string, config.VaultClientUserpass, bool) {
+	if conf.Ldap.Username != nil {
+		return "ldap", conf.Ldap, true
+	}
+	if conf.Userpass.Username != nil {
+		return "userpass", conf.Userpass, true
+	}
+	return "", config.VaultClientUserpass{}, false
+}
+
+// authenticateWithUserpass logs in to Vault using the LDAP or userpass auth method.  The credentials are read at every
+// login, so file credentials can be rotated without a restart.
+func (c *vaultClient) authenticateWithUserpass(conf config.VaultClientUserpass) (*renewable, error) {
+	username := conf.Username.Get()
+	if username == "" {
+		return nil, errors.New("username is not set")
+	}
+	body := map[string]interface{}{"password": conf.Password.Get()}
+
+	resp, err := c.Logical().Write(fmt.Sprintf("auth/%s/login/%s", conf.Path, username), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := resp.TokenID(); err != nil {
+		return nil, err
+	}
+	return &renewable{Secret: resp}, nil
+}
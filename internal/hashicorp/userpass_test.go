@@ -0,0 +1,83 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultClient_AuthenticateWithUserpass_RereadsRotatedPassword(t *testing.T) {
+	dir, err := ioutil.TempDir("", "userpass")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "password")
+	require.NoError(t, ioutil.WriteFile(path, []byte("firstpassword\n"), 0600))
+	passwordURL, _ := url.Parse("file://" + path)
+
+	require.NoError(t, os.Setenv("USERPASS_TEST_USERNAME", "quorum node"))
+	defer os.Unsetenv("USERPASS_TEST_USERNAME")
+
+	var gotPasswords []string
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/token/renew-self" {
+			b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: r.Header.Get("X-Vault-Token"), Renewable: true, LeaseDuration: 60}})
+			_, _ = w.Write(b)
+			return
+		}
+		require.Equal(t, "/v1/auth/corp-ldap/login/quorum node", r.URL.Path)
+		body := make(map[string]string)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		gotPasswords = append(gotPasswords, body["password"])
+
+		b, _ := json.Marshal(&api.Secret{Auth: &api.SecretAuth{ClientToken: "token-" + body["password"], Renewable: true, LeaseDuration: 60}})
+		_, _ = w.Write(b)
+	}))
+	defer vault.Close()
+
+	c := testVaultClient(t, vault.URL)
+
+	username := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "USERPASS_TEST_USERNAME"})
+	password := config.EnvironmentVariable(*passwordURL)
+	auth := config.VaultClientAuthentication{
+		Token: &config.EnvironmentVariable{},
+		Ldap:  config.VaultClientUserpass{Username: &username, Password: &password, Path: "corp-ldap"},
+	}
+	require.NoError(t, c.authenticate(auth))
+	defer c.supersedeRenewal()
+
+	require.Equal(t, "token-firstpassword", c.Token())
+	require.Equal(t, authRenewing, c.getAuthStatus())
+	require.Equal(t, "ldap/corp-ldap", authID(auth))
+
+	// the password is rotated in the mounted secret
+	require.NoError(t, ioutil.WriteFile(path, []byte("secondpassword\n"), 0600))
+
+	r, err := c.login(auth)
+	require.NoError(t, err)
+	require.Equal(t, "token-secondpassword", r.Auth.ClientToken)
+	require.Equal(t, []string{"firstpassword", "secondpassword"}, gotPasswords)
+}
+
+func TestUserpassAuth(t *testing.T) {
+	username := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "USERNAME"})
+	auth := config.VaultClientAuthentication{Userpass: config.VaultClientUserpass{Username: &username, Path: "userpass"}}
+
+	method, up, ok := userpassAuth(auth)
+	require.True(t, ok)
+	require.Equal(t, "userpass", method)
+	require.Equal(t, auth.Userpass, up)
+	require.Equal(t, "userpass/userpass", authID(auth))
+
+	_, _, ok = userpassAuth(config.VaultClientAuthentication{})
+	require.False(t, ok)
+}
@@ -3,71 +3,241 @@ package hashicorp
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/event"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/state"
 )
 
 const reauthRetryInterval = 5 * time.Second
 
 type vaultClient struct {
 	*api.Client
-	kvEngineName     string
-	accountDirectory *url.URL
-	accts            accountsByURL
+	kvEngineName string
+	store        accountStore
+	acctsMu      sync.RWMutex
+	accts        accountsByURL
+	replicas     *replicaSet // performance standbys/secondaries to send reads to, nil if not configured
+	primaryTier  int         // locality of the active node relative to this node, see localityTier
+	dr           *drSecondary
+	indexMu      sync.Mutex
+	limiter      requestLimiter
+	authMu       sync.Mutex
+	authStatus   string
+	renewalStop  chan struct{} // closed when the current token's renewal is superseded
+	auth         config.VaultClientAuthentication
+	sink         *tokenSink // persists the approle token, nil if not configured
+	secretIDs    *approleSecretIDs
+	scan         accountScan
+	dev          bool       // secrets are kept in memory and nothing is sent to Vault
+	pool         *tokenPool // standby tokens to fail over to, nil if not configured
+	authState    authState
+	// authGeneration is the number of tokens installed, so that a token can be told apart from the one it replaced
+	authGeneration uint64
+	// loginMu is held while logging in and installing the new token, so that logins cannot interleave
+	loginMu sync.Mutex
+	// onAuthTransition, if set, is called with c.authMu held for every change of authState
+	onAuthTransition func(authTransition)
+	// stop is closed when the client is closed, stopping its background workers and those of the clients created with
+	// it (e.g. for read replicas or the DR secondary), which share it
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // newVaultClient creates an authenticated Vault client using the credentials provided as environment variables
 // (either logging in using the AppRole or using a provided token directly).  Providing tls will configure the client
 // to use TLS for Vault communications.  If the AppRole token is renewable the client will be started with a renewer.
-func newVaultClient(conf config.VaultClient) (*vaultClient, error) {
-	clientConf := api.DefaultConfig()
-	clientConf.Address = conf.Vault.String()
+func newVaultClient(conf config.VaultClient, stateDir *state.Dir) (_ *vaultClient, err error) {
+	if conf.Dev {
+		return newDevClient(conf)
+	}
 
-	tlsConfig := convertTLSConfig(conf.TLS)
+	stop := make(chan struct{})
+	defer func() {
+		// stop the workers of the clients created so far
+		if err != nil {
+			close(stop)
+		}
+	}()
 
-	// passing an empty api.TLSConfig here is equivalent to not adding TLS config
-	if err := clientConf.ConfigureTLS(tlsConfig); err != nil {
+	c, err := newAPIClient(conf.Vault, conf.TLS, conf.DNSRefreshInterval, stop)
+	if err != nil {
 		return nil, fmt.Errorf("error creating Hashicorp Vault client: %v", err)
 	}
+	setRequestHeaders(c, conf)
+
+	vaultClient := &vaultClient{
+		Client:       c,
+		kvEngineName: secretsEngineName(conf),
+		store:        newAccountStore(conf),
+		limiter:      newRequestLimiter(conf.MaxConcurrentRequests),
+		sink:         newTokenSink(stateDir, conf),
+		secretIDs:    new(approleSecretIDs),
+		stop:         stop,
+	}
 
+	if err := vaultClient.authenticate(conf.Authentication); err != nil {
+		return nil, err
+	}
+	if vaultClient.pool, err = newTokenPool(conf, vaultClient.secretIDs, stop); err != nil {
+		return nil, err
+	}
+	vaultClient.pool.start(conf.Authentication)
+
+	warnIfSameEndpoint(conf)
+
+	if replicaURLs := readReplicaURLs(conf); len(replicaURLs) > 0 {
+		var replicas []*api.Client
+		for _, u := range replicaURLs {
+			r, err := newAPIClient(u, conf.TLS, conf.DNSRefreshInterval, stop)
+			if err != nil {
+				return nil, fmt.Errorf("error creating Hashicorp Vault read replica client: %v", err)
+			}
+			setRequestHeaders(r, conf)
+			replicas = append(replicas, r)
+		}
+		vaultClient.replicas = newReplicaSet(replicas...)
+		vaultClient.replicas.setLocalities(conf.Locality, conf.Localities)
+		vaultClient.primaryTier = localityTier(conf.Locality, conf.Localities[conf.Vault.String()])
+	}
+
+	if conf.DRSecondary != nil {
+		// the DR secondary is only authenticated with if it is needed
+		if vaultClient.dr, err = newDRSecondary(conf, stop); err != nil {
+			return nil, err
+		}
+		vaultClient.dr.client.secretIDs = vaultClient.secretIDs
+	}
+
+	if err := vaultClient.startAccounts(); err != nil {
+		return nil, err
+	}
+	return vaultClient, nil
+}
+
+// newDevClient creates a client that is never authenticated with Vault, for use with a memoryStore in dev mode
+func newDevClient(conf config.VaultClient) (*vaultClient, error) {
+	clientConf := api.DefaultConfig()
+	clientConf.Address = devVaultAddress
 	c, err := api.NewClient(clientConf)
 	if err != nil {
-		return nil, fmt.Errorf("error creating Hashicorp Vault client: %v", err)
+		return nil, fmt.Errorf("error creating dev mode client: %v", err)
 	}
 
 	vaultClient := &vaultClient{
-		Client:           c,
-		kvEngineName:     conf.KVEngineName,
-		accountDirectory: conf.AccountDirectory,
+		Client:       c,
+		kvEngineName: devEngineName,
+		store:        newAccountStore(conf),
+		limiter:      newRequestLimiter(conf.MaxConcurrentRequests),
+		dev:          true,
+		stop:         make(chan struct{}),
 	}
+	vaultClient.setAuthStatus(authDev)
+	log.Println("[WARN] running in dev mode: keys are kept in memory and will be lost when the plugin stops")
 
-	if err := vaultClient.authenticate(conf.Authentication); err != nil {
+	if err := vaultClient.startAccounts(); err != nil {
 		return nil, err
 	}
+	return vaultClient, nil
+}
 
-	result, err := vaultClient.loadAccounts()
+// close stops the background workers of the client, including the renewal of its token and that of the DR secondary
+func (c *vaultClient) close() {
+	if c.stop == nil {
+		return
+	}
+	c.stopOnce.Do(func() {
+		close(c.stop)
+		c.stopRenewal()
+		if c.dr != nil {
+			c.dr.client.stopRenewal()
+		}
+	})
+}
+
+// startAccounts loads the account configs and, if the account store supports it, watches it for changes
+func (c *vaultClient) startAccounts() error {
+	result, err := c.loadAccounts()
 	if err != nil {
-		return nil, fmt.Errorf("error loading account directory: %v", err)
+		return fmt.Errorf("error loading account configs from %v: %v", c.store, err)
 	}
-	vaultClient.accts = result
+	c.setAccounts(result)
+	warnIfAmbiguous(result)
 
-	return vaultClient, nil
+	if store, ok := c.store.(watchableStore); ok {
+		version := c.scan.version
+		supervise("account store watcher", c.stop, func() { c.watchAccountStore(store, version) })
+	}
+	return nil
+}
+
+// warnIfSameEndpoint logs a warning if the readReplica or drSecondary is the same server as the primary, after
+// normalization.  The config is still valid but it is unlikely to be what was intended.
+func warnIfSameEndpoint(conf config.VaultClient) {
+	vault := conf.Vault.String()
+	for _, r := range readReplicaURLs(conf) {
+		if r.String() == vault {
+			log.Printf("[WARN] readReplica %v is the same endpoint as vault, reads will not be offloaded", r)
+		}
+	}
+	if conf.DRSecondary != nil && conf.DRSecondary.String() == vault {
+		log.Printf("[WARN] drSecondary %v is the same endpoint as vault, failover will have no effect", conf.DRSecondary)
+	}
+}
+
+// readReplicaURLs returns the readReplica followed by any readReplicas
+func readReplicaURLs(conf config.VaultClient) []*url.URL {
+	var urls []*url.URL
+	if conf.ReadReplica != nil {
+		urls = append(urls, conf.ReadReplica)
+	}
+	return append(urls, conf.ReadReplicas...)
+}
+
+// newAPIClient creates a client for the Vault server at address.  Its background workers (e.g. reloading the TLS files)
+// run until stop is closed.
+func newAPIClient(address *url.URL, tls config.VaultClientTLS, dnsRefreshInterval time.Duration, stop <-chan struct{}) (*api.Client, error) {
+	clientConf := api.DefaultConfig()
+	clientConf.Address = address.String()
+
+	// passing an empty api.TLSConfig here is equivalent to not adding TLS config
+	if err := clientConf.ConfigureTLS(convertTLSConfig(tls)); err != nil {
+		return nil, err
+	}
+	transport := clientConf.HttpClient.Transport.(*http.Transport)
+	reloader, err := watchTLSFiles(transport, address, tls, stop)
+	if err != nil {
+		return nil, err
+	}
+	var verifyChains chainVerifier
+	if reloader != nil && reloader.hasCA() {
+		verifyChains = reloader.verifyChains
+	}
+	pinCertificates(transport.TLSClientConfig, tls.Pins, verifyChains)
+	if fipsEnabled(tls) {
+		restrictToFIPS(transport.TLSClientConfig)
+		if err := verifyFIPS(address, transport.TLSClientConfig); err != nil {
+			return nil, fmt.Errorf("unable to use FIPS mode: %v", err)
+		}
+	}
+	watchDNS(transport, address, dnsRefreshInterval, stop)
+
+	return api.NewClient(clientConf)
 }
 
 func convertTLSConfig(tls config.VaultClientTLS) *api.TLSConfig {
 	tlsConfig := &api.TLSConfig{}
 
-	caCert := tls.CaCert.Host + tls.CaCert.Path
-	clientCert := tls.ClientCert.Host + tls.ClientCert.Path
-	clientKey := tls.ClientKey.Host + tls.ClientKey.Path
+	caCert := config.FilePath(tls.CaCert)
+	clientCert := config.FilePath(tls.ClientCert)
+	clientKey := config.FilePath(tls.ClientKey)
 
 	if caCert != "/" {
 		tlsConfig.CACert = caCert
@@ -78,21 +248,58 @@ func convertTLSConfig(tls config.VaultClientTLS) *api.TLSConfig {
 	if clientKey != "/" {
 		tlsConfig.ClientKey = clientKey
 	}
+	tlsConfig.TLSServerName = tls.ServerName
 
 	return tlsConfig
 }
 
 func (c *vaultClient) authenticate(conf config.VaultClientAuthentication) error {
-	// authentication config has already been validated so only need to check if approle or token auth is being used
+	c.auth = conf
+	// authentication config has already been validated so only need to check if token, kubernetes, azure, cert, ldap, userpass or approle auth is being used
 	if conf.Token.IsSet() {
-		c.SetToken(conf.Token.Get())
+		c.installToken(conf.Token.Get(), authStateAuthenticated, authStatic, nil)
+		if conf.Token.IsFile() {
+			c.watchTokenFile(*conf.Token)
+		}
 		return nil
 	}
+	if conf.Kubernetes.Role != "" {
+		renewable, err := c.authenticateWithKubernetes(conf.Kubernetes)
+		if err != nil {
+			return err
+		}
+		return renewable.startAuthenticationRenewal(c, conf)
+	}
+	if conf.Azure.Role != "" {
+		renewable, err := c.authenticateWithAzure(conf.Azure)
+		if err != nil {
+			return err
+		}
+		return renewable.startAuthenticationRenewal(c, conf)
+	}
+	if conf.Cert.Role != "" {
+		renewable, err := c.authenticateWithCert(conf.Cert)
+		if err != nil {
+			return err
+		}
+		return renewable.startAuthenticationRenewal(c, conf)
+	}
+	if _, up, ok := userpassAuth(conf); ok {
+		renewable, err := c.authenticateWithUserpass(up)
+		if err != nil {
+			return err
+		}
+		return renewable.startAuthenticationRenewal(c, conf)
+	}
 
 	return c.renewableApproleAuthentication(conf)
 }
 
 func (c *vaultClient) renewableApproleAuthentication(conf config.VaultClientAuthentication) error {
+	if renewable, ok := c.resumeToken(); ok {
+		return renewable.startAuthenticationRenewal(c, conf)
+	}
+
 	renewable, err := c.authenticateWithApprole(conf)
 	if err != nil {
 		return err
@@ -101,9 +308,22 @@ func (c *vaultClient) renewableApproleAuthentication(conf config.VaultClientAuth
 }
 
 func (c *vaultClient) authenticateWithApprole(conf config.VaultClientAuthentication) (*renewable, error) {
-	body := map[string]interface{}{"role_id": conf.RoleId.Get(), "secret_id": conf.SecretId.Get()}
+	secretID, err := c.approleSecretID(conf)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{"role_id": conf.RoleId.Get(), "secret_id": secretID}
 
 	resp, err := c.Logical().Write(fmt.Sprintf("auth/%s/login", conf.ApprolePath), body)
+	if isSecretIDRejected(err) && conf.SecretIdRotator.Token != nil {
+		log.Printf("[WARN] approle secret_id rejected, generating a new secret_id: err = %v", err)
+		secretID, rotateErr := c.rotateSecretID(conf)
+		if rotateErr != nil {
+			return nil, fmt.Errorf("%v, and unable to generate a new secret_id: %v", err, rotateErr)
+		}
+		body["secret_id"] = secretID
+		resp, err = c.Logical().Write(fmt.Sprintf("auth/%s/login", conf.ApprolePath), body)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -112,62 +332,129 @@ func (c *vaultClient) authenticateWithApprole(conf config.VaultClientAuthenticat
 	if err != nil {
 		return nil, err
 	}
-	c.SetToken(t)
+
+	if c.sink != nil {
+		if err := c.sink.write(t); err != nil {
+			log.Printf("[WARN] unable to persist Vault token: %v", err)
+		}
+	}
 
 	return &renewable{Secret: resp}, nil
 }
 
 func (c *vaultClient) loadAccounts() (map[*url.URL]config.AccountFile, error) {
 	result := make(map[*url.URL]config.AccountFile)
+	start := time.Now()
 
-	walkFn := filepath.WalkFunc(func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			// do nothing with directories
-			return nil
-		}
-		log.Printf("[DEBUG] Loading %v", path)
-		b, err := ioutil.ReadFile(path)
+	contents, err := c.store.load()
+	if err != nil {
+		return nil, err
+	}
 
+	for _, stored := range contents.accounts {
 		conf := new(config.AccountFileJSON)
 
-		if err := json.Unmarshal(b, conf); err != nil {
-			return fmt.Errorf("unable to unmarshal contents of %v, err: %v", path, err)
+		if err := json.Unmarshal(stored.contents, conf); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal contents of %v, err: %v", stored.location, err)
 		}
 
 		acctURL, err := conf.AccountURL(c.Address(), c.kvEngineName)
 		if err != nil {
-			return fmt.Errorf("unable to parse account URL for %v, err: %v", path, err)
+			return nil, fmt.Errorf("unable to parse account URL for %v, err: %v", stored.location, err)
 		}
 
-		result[acctURL] = config.AccountFile{Path: path, Contents: *conf}
-		return nil
-	})
+		result[acctURL] = config.AccountFile{Path: stored.location, Contents: *conf}
+	}
 
-	root := c.accountDirectory.Host + "/" + c.accountDirectory.Path
+	c.acctsMu.Lock()
+	defer c.acctsMu.Unlock()
+	c.scan = accountScan{
+		time:     start,
+		duration: time.Since(start),
+		ignored:  contents.ignored,
+		version:  contents.version,
+	}
 
-	if _, err := os.Stat(root); os.IsNotExist(err) {
-		log.Printf("[DEBUG] Creating empty directory at %v", root)
-		if err := os.Mkdir(root, os.ModeDir+0755); err != nil {
-			return nil, err
+	return result, nil
+}
+
+// watchAccountStore reloads the account configs whenever a shared store changes, so that accounts created by other
+// nodes become available.  If a reload fails the previously loaded accounts continue to be used.
+func (c *vaultClient) watchAccountStore(store watchableStore, version uint64) {
+	for !isStopped(c.stop) {
+		latest, err := store.wait(version)
+		if err != nil {
+			log.Printf("[WARN] unable to watch account store %v for changes, err = %v", store, err)
+			sleepUnlessStopped(storeRetryInterval, c.stop)
+			continue
 		}
-		return result, nil
-	}
+		if latest == version {
+			continue
+		}
+		version = latest
 
-	log.Printf("[DEBUG] Loading accts from %v", root)
-	if err := filepath.Walk(root, walkFn); err != nil {
-		return nil, err
+		result, err := c.loadAccounts()
+		if err != nil {
+			log.Printf("[ERROR] unable to reload account configs from %v, err = %v", store, err)
+			continue
+		}
+		c.setAccounts(result)
+		warnIfAmbiguous(result)
+		log.Printf("[INFO] reloaded %v account configs from %v", len(result), store)
+		event.Emit(event.AccountsReloaded, store.String(), fmt.Sprintf("%v accounts", len(result)))
 	}
+}
 
-	return result, nil
+func warnIfAmbiguous(accts accountsByURL) {
+	if ambiguous := accts.ambiguousAddresses(); len(ambiguous) != 0 {
+		log.Printf("[WARN] multiple account configs found for addresses %v, these accounts cannot be used until the duplicate configs are removed", ambiguous)
+	}
 }
 
 func (c *vaultClient) hasAccount(acctAddr account.Address) bool {
+	c.acctsMu.RLock()
+	defer c.acctsMu.RUnlock()
 	return c.accts.HasAccountWithAddress(acctAddr)
 }
 
 func (c *vaultClient) getAccount(acctAddr account.Address) (config.AccountFile, error) {
+	c.acctsMu.RLock()
+	defer c.acctsMu.RUnlock()
 	return c.accts.GetAccountWithAddress(acctAddr)
 }
+
+// accounts returns a snapshot of the known accounts, which is not affected by accounts subsequently being added or
+// reloaded
+func (c *vaultClient) accounts() accountsByURL {
+	c.acctsMu.RLock()
+	defer c.acctsMu.RUnlock()
+	snapshot := make(accountsByURL, len(c.accts))
+	for u, f := range c.accts {
+		snapshot[u] = f
+	}
+	return snapshot
+}
+
+func (c *vaultClient) addAccount(u *url.URL, f config.AccountFile) {
+	c.acctsMu.Lock()
+	defer c.acctsMu.Unlock()
+	c.accts[u] = f
+}
+
+func (c *vaultClient) setAccounts(accts accountsByURL) {
+	c.acctsMu.Lock()
+	defer c.acctsMu.Unlock()
+	c.accts = accts
+}
+
+func (c *vaultClient) setAuthStatus(status string) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.authStatus = status
+}
+
+func (c *vaultClient) getAuthStatus() string {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return c.authStatus
+}
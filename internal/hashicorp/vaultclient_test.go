@@ -3,14 +3,42 @@ package hashicorp
 import (
 	"crypto/rand"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
 	"github.com/stretchr/testify/require"
 )
 
+// testVaultClient returns a client for the mock Vault server at vaultURL.  The client does not retry failed requests, so
+// that tests of failures don't wait for its retries.
+func testVaultClient(t *testing.T, vaultURL string) *vaultClient {
+	conf := api.DefaultConfig()
+	conf.Address = vaultURL
+	c, err := api.NewClient(conf)
+	require.NoError(t, err)
+	c.SetMaxRetries(0)
+	return &vaultClient{Client: c, secretIDs: new(approleSecretIDs)}
+}
+
+// testKVAccountManager returns an account manager for accts, whose secrets are in the kv engine of the mock Vault server
+// at vaultURL
+func testKVAccountManager(t *testing.T, vaultURL string, accts accountsByURL) *accountManager {
+	client := testVaultClient(t, vaultURL)
+	client.kvEngineName = "kv"
+	client.accts = accts
+	return &accountManager{
+		kvEngineName: "kv",
+		client:       client,
+		secrets:      newKVv2Store(client, "kv"),
+	}
+}
+
 func TestVaultClient_LoadAccounts_AccountDirectoryCreatedIfDoesntExist(t *testing.T) {
 	buf := make([]byte, 16)
 	_, err := rand.Read(buf)
@@ -27,7 +55,7 @@ func TestVaultClient_LoadAccounts_AccountDirectoryCreatedIfDoesntExist(t *testin
 	require.True(t, os.IsNotExist(err))
 
 	c := vaultClient{
-		accountDirectory: acctDir,
+		store: &dirStore{dir: acctDir},
 	}
 
 	result, err := c.loadAccounts()
@@ -47,6 +75,7 @@ func TestConvertTLSConfig(t *testing.T) {
 		CaCert:     caCert,
 		ClientCert: clientCert,
 		ClientKey:  clientKey,
+		ServerName: "vault.example.com",
 	}
 
 	got := convertTLSConfig(tls)
@@ -54,6 +83,7 @@ func TestConvertTLSConfig(t *testing.T) {
 	require.Equal(t, "/leading/slash/ca.cert", got.CACert)
 	require.Equal(t, "path/to/client.cert", got.ClientCert)
 	require.Equal(t, "path/to/client.key", got.ClientKey)
+	require.Equal(t, "vault.example.com", got.TLSServerName)
 }
 
 func TestConvertTLSConfigNoUrls(t *testing.T) {
@@ -71,3 +101,32 @@ func TestConvertTLSConfigNoUrls(t *testing.T) {
 	require.Equal(t, "", got.ClientCert)
 	require.Equal(t, "", got.ClientKey)
 }
+
+func TestVaultClient_LoadAccounts_IgnoresAndRemovesTemporaryFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// partially written account files left behind by an interrupted write
+	stale := filepath.Join(dir, ".UTC--2020-07-20T10-00-00.000000000Z--4d6d744b6da435b5bbdde2526dc20e9a41cb72e5123.tmp")
+	inProgress := filepath.Join(dir, ".UTC--2020-07-20T10-00-01.000000000Z--dc99ddec13457de6c0f6bb8e6cf3955c86f55526456.tmp")
+	for _, f := range []string{stale, inProgress} {
+		require.NoError(t, ioutil.WriteFile(f, []byte(`{"Address":`), 0600))
+	}
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(stale, old, old))
+
+	acctDir, err := url.Parse("file://" + dir + "/")
+	require.NoError(t, err)
+	c := vaultClient{
+		store: &dirStore{dir: acctDir},
+	}
+
+	result, err := c.loadAccounts()
+	require.NoError(t, err)
+	require.Len(t, result, 0)
+
+	_, err = os.Stat(stale)
+	require.True(t, os.IsNotExist(err))
+	require.FileExists(t, inProgress)
+}
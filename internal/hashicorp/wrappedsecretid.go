@@ -0,0 +1,110 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// approleSecretIDs holds the approle secret_id unwrapped from the most recent response-wrapping token, and the most
+// recent secret_id generated by the secretIdRotator.  A wrapping token can only be unwrapped once, so the secret_id is
+// kept for later logins until a different wrapping token is provided.  A generated secret_id is used until a different
+// secretId is provided.  It is shared by every client that logs in with the same credentials.
+type approleSecretIDs struct {
+	mu       sync.Mutex
+	token    string // the wrapping token secretID was unwrapped from
+	secretID string
+	// rotated replaces the configured secretId rotatedFrom
+	rotatedFrom string
+	rotated     string
+}
+
+// approleSecretID returns the secret_id to log in with: a secret_id generated by the secretIdRotator if secretId has not
+// changed since, otherwise secretId, unwrapped if it references a response-wrapping token
+func (c *vaultClient) approleSecretID(conf config.VaultClientAuthentication) (string, error) {
+	configured := conf.SecretId.Get()
+	if !conf.SecretIdWrapped && conf.SecretIdRotator.Token == nil {
+		return configured, nil
+	}
+
+	w := c.secretIDs
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.rotated != "" && configured == w.rotatedFrom {
+		return w.rotated, nil
+	}
+
+	if !conf.SecretIdWrapped {
+		return configured, nil
+	}
+	token := configured
+	if token == "" {
+		return "", fmt.Errorf("%v is empty", conf.SecretId.String())
+	}
+	if token == w.token {
+		return w.secretID, nil
+	}
+
+	secretID, err := c.unwrapSecretID(token, conf.ApprolePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to unwrap secret_id: %v", err)
+	}
+	w.token, w.secretID = token, secretID
+	log.Printf("[INFO] unwrapped approle secret_id from wrapping token %v", conf.SecretId.String())
+	return secretID, nil
+}
+
+// unwrapSecretID checks that the wrapping token was created by generating a secret_id for a role of the approle auth
+// engine, so that a token substituted by an attacker is not used, then unwraps it
+func (c *vaultClient) unwrapSecretID(token, approlePath string) (string, error) {
+	lookup, err := c.requestWithToken("/v1/sys/wrapping/lookup", token, map[string]interface{}{"token": token})
+	if err != nil {
+		return "", err
+	}
+	creationPath, _ := lookup.Data["creation_path"].(string)
+	prefix := fmt.Sprintf("auth/%v/role/", approlePath)
+	if !strings.HasPrefix(creationPath, prefix) || !strings.HasSuffix(creationPath, "/secret-id") {
+		return "", fmt.Errorf("wrapping token was created by %q, not %v<role>/secret-id", creationPath, prefix)
+	}
+
+	resp, err := c.requestWithToken("/v1/sys/wrapping/unwrap", token, nil)
+	if err != nil {
+		return "", err
+	}
+	secretID, _ := resp.Data["secret_id"].(string)
+	if secretID == "" {
+		return "", errors.New("wrapped response does not contain a secret_id")
+	}
+	return secretID, nil
+}
+
+// requestWithToken sends a request using token rather than the client's token, e.g. to a sys/wrapping endpoint with the
+// wrapping token, as the client may not have a token yet or its token may have expired
+func (c *vaultClient) requestWithToken(path, token string, body map[string]interface{}) (*api.Secret, error) {
+	r := c.NewRequest(http.MethodPut, path)
+	r.ClientToken = token
+	if err := r.SetJSONBody(body); err != nil {
+		return nil, err
+	}
+	resp, err := c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	secret, err := api.ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, emptyResponseErr
+	}
+	return secret, nil
+}
@@ -0,0 +1,119 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// wrappingVaultServer serves approle logins and the sys/wrapping endpoints for wrapping tokens created by the paths in
+// creationPaths.  Each wrapping token can only be unwrapped once.
+func wrappingVaultServer(t *testing.T, creationPaths map[string]string) (*httptest.Server, *int) {
+	unwrapped := make(map[string]bool)
+	var logins int
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string]string)
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		var secret api.Secret
+		switch r.URL.Path {
+		case "/v1/sys/wrapping/lookup":
+			path, ok := creationPaths[body["token"]]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["wrapping token is not valid or does not exist"]}`))
+				return
+			}
+			secret.Data = map[string]interface{}{"creation_path": path}
+		case "/v1/sys/wrapping/unwrap":
+			token := r.Header.Get("X-Vault-Token")
+			if _, ok := creationPaths[token]; !ok || unwrapped[token] {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["wrapping token is not valid or does not exist"]}`))
+				return
+			}
+			unwrapped[token] = true
+			secret.Data = map[string]interface{}{"secret_id": "secret-id-for-" + token}
+		case "/v1/auth/approle/login":
+			logins++
+			require.Equal(t, "role-id", body["role_id"])
+			secret.Auth = &api.SecretAuth{ClientToken: "token-" + body["secret_id"], Renewable: false}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b, _ := json.Marshal(&secret)
+		_, _ = w.Write(b)
+	})), &logins
+}
+
+func wrappedSecretIDAuth(t *testing.T) config.VaultClientAuthentication {
+	require.NoError(t, os.Setenv("WRAPPED_TEST_ROLE_ID", "role-id"))
+	roleID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "WRAPPED_TEST_ROLE_ID"})
+	secretID := config.EnvironmentVariable(url.URL{Scheme: "env", Host: "WRAPPED_TEST_SECRET_ID"})
+	return config.VaultClientAuthentication{
+		RoleId:          &roleID,
+		SecretId:        &secretID,
+		ApprolePath:     "approle",
+		SecretIdWrapped: true,
+	}
+}
+
+func TestVaultClient_AuthenticateWithApprole_WrappedSecretID(t *testing.T) {
+	defer os.Unsetenv("WRAPPED_TEST_ROLE_ID")
+	defer os.Unsetenv("WRAPPED_TEST_SECRET_ID")
+
+	vault, logins := wrappingVaultServer(t, map[string]string{
+		"wrap1": "auth/approle/role/quorum/secret-id",
+		"wrap2": "auth/approle/role/quorum/secret-id",
+	})
+	defer vault.Close()
+	c := testVaultClient(t, vault.URL)
+	auth := wrappedSecretIDAuth(t)
+
+	require.NoError(t, os.Setenv("WRAPPED_TEST_SECRET_ID", "wrap1"))
+	r, err := c.authenticateWithApprole(auth)
+	require.NoError(t, err)
+	require.Equal(t, "token-secret-id-for-wrap1", r.Auth.ClientToken)
+
+	// the wrapping token has been used, so later logins use the unwrapped secret_id
+	r, err = c.authenticateWithApprole(auth)
+	require.NoError(t, err)
+	require.Equal(t, "token-secret-id-for-wrap1", r.Auth.ClientToken)
+
+	// a new wrapping token is unwrapped when it is provided
+	require.NoError(t, os.Setenv("WRAPPED_TEST_SECRET_ID", "wrap2"))
+	r, err = c.authenticateWithApprole(auth)
+	require.NoError(t, err)
+	require.Equal(t, "token-secret-id-for-wrap2", r.Auth.ClientToken)
+	require.Equal(t, 3, *logins)
+}
+
+func TestVaultClient_AuthenticateWithApprole_WrappedSecretIDRefused(t *testing.T) {
+	defer os.Unsetenv("WRAPPED_TEST_ROLE_ID")
+	defer os.Unsetenv("WRAPPED_TEST_SECRET_ID")
+
+	vault, logins := wrappingVaultServer(t, map[string]string{
+		"wrapped-kv": "kv/data/other",
+	})
+	defer vault.Close()
+	c := testVaultClient(t, vault.URL)
+	auth := wrappedSecretIDAuth(t)
+
+	require.NoError(t, os.Setenv("WRAPPED_TEST_SECRET_ID", "wrapped-kv"))
+	_, err := c.authenticateWithApprole(auth)
+	require.EqualError(t, err, `unable to unwrap secret_id: wrapping token was created by "kv/data/other", not auth/approle/role/<role>/secret-id`)
+
+	require.NoError(t, os.Setenv("WRAPPED_TEST_SECRET_ID", "unknown"))
+	_, err = c.authenticateWithApprole(auth)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "wrapping token is not valid or does not exist")
+	require.Equal(t, 0, *logins)
+}
@@ -0,0 +1,25 @@
+// Package metrics contains the counters and gauges published by the plugin.  Metrics are registered with expvar so
+// that they can be served by any HTTP listener which mounts expvar.Handler.
+package metrics
+
+import "expvar"
+
+var (
+	AccountsCreated               = expvar.NewInt("hashicorp_accounts_created_total")
+	AccountCreationQuotaRejected  = expvar.NewInt("hashicorp_account_creation_quota_rejected_total")
+	AccountCreationQuotaRemaining = expvar.NewMap("hashicorp_account_creation_quota_remaining")
+	AccountsDegraded              = expvar.NewInt("hashicorp_accounts_degraded")
+	VaultFailedOver               = expvar.NewInt("hashicorp_vault_failed_over")
+	ReadCacheHits                 = expvar.NewInt("hashicorp_read_cache_hits_total")
+	ReadCacheMisses               = expvar.NewInt("hashicorp_read_cache_misses_total")
+	ReadCacheSize                 = expvar.NewInt("hashicorp_read_cache_size")
+	ReadCacheBytes                = expvar.NewInt("hashicorp_read_cache_bytes")
+	ReadCacheEvictions            = expvar.NewInt("hashicorp_read_cache_evictions_total")
+	VaultRequestsInFlight         = expvar.NewInt("hashicorp_vault_requests_in_flight")
+	VaultRequestsQueued           = expvar.NewInt("hashicorp_vault_requests_queued")
+	VaultReachable                = expvar.NewInt("hashicorp_vault_reachable")
+	DuplicateSigns                = expvar.NewInt("hashicorp_duplicate_signs_total")
+	SigningLatencySLOBreached     = expvar.NewInt("hashicorp_signing_latency_slo_breached")
+	WorkerRestarts                = expvar.NewMap("hashicorp_worker_restarts_total")
+	AuthStateTransitions          = expvar.NewMap("hashicorp_auth_state_transitions_total")
+)
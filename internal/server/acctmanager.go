@@ -3,24 +3,112 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/audit"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp"
 	"github.com/jpmorganchase/quorum-account-plugin-sdk-go/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func (p *HashicorpPlugin) isInitialized() bool {
-	return p.acctManager != nil
+// auditLog records the outcome of a key-usage or provisioning operation, along with the identity of the caller
+func auditLog(ctx context.Context, operation string, acct *account.Address, err error) {
+	var addr string
+	if acct != nil {
+		addr = "0x" + acct.ToHexString()
+	}
+	audit.Log(audit.NewRecord(ctx, operation, addr, err))
+}
+
+// withDeadline applies the configured deadline to requests on the signing path, so that they either complete in time
+// or fail crisply
+func (in *instance) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if in.rpcTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, in.rpcTimeout)
+}
+
+// vaultDenied converts a Vault policy or Sentinel denial to a PermissionDenied status, detailing the denied path and
+// the capability it requires
+func vaultDenied(err error) (error, bool) {
+	pd, ok := err.(*hashicorp.PermissionDeniedError)
+	if !ok {
+		return nil, false
+	}
+	desc := fmt.Sprintf("requires %v capability", pd.Capability)
+	if pd.Sentinel != "" {
+		desc = fmt.Sprintf("denied by Sentinel %v", pd.Sentinel)
+	}
+	d, _ := accountManagerErrorDetail(pd)
+	d.resource = &errdetails.ResourceInfo{
+		ResourceType: "hashicorp-vault-path",
+		ResourceName: pd.Path,
+		Description:  desc,
+	}
+	return detailedError(codes.PermissionDenied, pd.Error(), d), true
+}
+
+// signingError converts an error from the signing path to a gRPC status
+func signingError(ctx context.Context, err error) error {
+	if denied, ok := vaultDenied(err); ok {
+		return denied
+	}
+	d, ok := accountManagerErrorDetail(err)
+	if !ok {
+		if ctx.Err() == context.DeadlineExceeded {
+			return detailedError(codes.DeadlineExceeded, err.Error(), errorDetail{
+				kind:      kindDeadlineExceeded,
+				retryable: true,
+				action:    "retry, and check Vault latency or raise rpcTimeout if the error persists",
+			})
+		}
+		return internalError(err)
+	}
+	code := codes.Internal
+	switch d.kind {
+	case kindTOTPRequired, kindRoleRefused, kindAccountSuspended, kindSignGrantRefused, kindAccountFrozen, kindNamespaceRefused, kindSelectorRefused:
+		code = codes.PermissionDenied
+	case kindSignDomainRefused, kindSignPayloadInvalid:
+		code = codes.InvalidArgument
+	case kindApprovalPending, kindNotPromoted:
+		code = codes.FailedPrecondition
+	}
+	return detailedError(code, err.Error(), d)
+}
+
+// provisioningError converts an error from creating or importing an account to a gRPC status
+func provisioningError(err error) error {
+	if denied, ok := vaultDenied(err); ok {
+		return denied
+	}
+	d, ok := accountManagerErrorDetail(err)
+	if !ok {
+		return internalError(err)
+	}
+	code := codes.Internal
+	switch d.kind {
+	case kindQuotaExceeded:
+		code = codes.ResourceExhausted
+	case kindReadOnly:
+		code = codes.Unavailable
+	case kindNotPromoted:
+		code = codes.FailedPrecondition
+	}
+	return detailedError(code, err.Error(), d)
 }
 
 func (p *HashicorpPlugin) Status(_ context.Context, _ *proto.StatusRequest) (*proto.StatusResponse, error) {
-	if !p.isInitialized() {
-		return nil, status.Error(codes.Unavailable, "not configured")
+	in := p.instance()
+	if in == nil {
+		return nil, notConfigured()
 	}
-	s, err := p.acctManager.Status()
+	s, err := in.acctManager.Status()
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -28,7 +116,10 @@ func (p *HashicorpPlugin) Status(_ context.Context, _ *proto.StatusRequest) (*pr
 }
 
 // Open is a no-op for Vault-stored accounts
-func (p *HashicorpPlugin) Open(_ context.Context, _ *proto.OpenRequest) (*proto.OpenResponse, error) {
+func (p *HashicorpPlugin) Open(_ context.Context, req *proto.OpenRequest) (*proto.OpenResponse, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
 	return &proto.OpenResponse{}, nil
 }
 
@@ -38,10 +129,11 @@ func (p *HashicorpPlugin) Close(_ context.Context, _ *proto.CloseRequest) (*prot
 }
 
 func (p *HashicorpPlugin) Accounts(_ context.Context, _ *proto.AccountsRequest) (*proto.AccountsResponse, error) {
-	if !p.isInitialized() {
-		return nil, status.Error(codes.Unavailable, "not configured")
+	in := p.instance()
+	if in == nil {
+		return nil, notConfigured()
 	}
-	accts, err := p.acctManager.Accounts()
+	accts, err := in.acctManager.Accounts()
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -54,97 +146,164 @@ func (p *HashicorpPlugin) Accounts(_ context.Context, _ *proto.AccountsRequest)
 }
 
 func (p *HashicorpPlugin) Contains(_ context.Context, req *proto.ContainsRequest) (*proto.ContainsResponse, error) {
-	if !p.isInitialized() {
-		return nil, status.Error(codes.Unavailable, "not configured")
+	in := p.instance()
+	if in == nil {
+		return nil, notConfigured()
+	}
+	if err := validateRequest(req); err != nil {
+		return nil, err
 	}
 	addr, err := account.NewAddress(req.Address)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	isContained := p.acctManager.Contains(addr)
+	isContained := in.acctManager.Contains(addr)
 
 	return &proto.ContainsResponse{IsContained: isContained}, nil
 }
 
-func (p *HashicorpPlugin) Sign(_ context.Context, req *proto.SignRequest) (*proto.SignResponse, error) {
-	if !p.isInitialized() {
-		return nil, status.Error(codes.Unavailable, "not configured")
+func (p *HashicorpPlugin) Sign(ctx context.Context, req *proto.SignRequest) (*proto.SignResponse, error) {
+	in := p.instance()
+	if in == nil {
+		return nil, notConfigured()
+	}
+	ctx = audit.WithRequestID(ctx)
+	if err := in.checkPeer(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateRequest(req); err != nil {
+		return nil, err
 	}
 	addr, err := account.NewAddress(req.Address)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	result, err := p.acctManager.Sign(addr, req.ToSign)
+	result, err := in.acctManager.Sign(ctx, addr, req.ToSign)
+	auditLog(ctx, "Sign", &addr, err)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, signingError(ctx, err)
 	}
 	return &proto.SignResponse{Sig: result}, nil
 }
 
-func (p *HashicorpPlugin) UnlockAndSign(_ context.Context, req *proto.UnlockAndSignRequest) (*proto.SignResponse, error) {
-	if !p.isInitialized() {
-		return nil, status.Error(codes.Unavailable, "not configured")
+func (p *HashicorpPlugin) UnlockAndSign(ctx context.Context, req *proto.UnlockAndSignRequest) (*proto.SignResponse, error) {
+	in := p.instance()
+	if in == nil {
+		return nil, notConfigured()
+	}
+	ctx = audit.WithRequestID(ctx)
+	if err := in.checkPeer(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateRequest(req); err != nil {
+		return nil, err
 	}
 	addr, err := account.NewAddress(req.Address)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	result, err := p.acctManager.UnlockAndSign(addr, req.ToSign)
+	dctx, cancel := in.withDeadline(hashicorp.WithTOTPCode(ctx, req.Passphrase))
+	defer cancel()
+	result, err := in.acctManager.UnlockAndSign(dctx, addr, req.ToSign)
+	auditLog(ctx, "UnlockAndSign", &addr, err)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, signingError(dctx, err)
 	}
 	return &proto.SignResponse{Sig: result}, nil
 }
 
-func (p *HashicorpPlugin) TimedUnlock(_ context.Context, req *proto.TimedUnlockRequest) (*proto.TimedUnlockResponse, error) {
-	if !p.isInitialized() {
-		return nil, status.Error(codes.Unavailable, "not configured")
+func (p *HashicorpPlugin) TimedUnlock(ctx context.Context, req *proto.TimedUnlockRequest) (*proto.TimedUnlockResponse, error) {
+	in := p.instance()
+	if in == nil {
+		return nil, notConfigured()
+	}
+	ctx = audit.WithRequestID(ctx)
+	if err := in.checkPeer(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateRequest(req); err != nil {
+		return nil, err
 	}
 	addr, err := account.NewAddress(req.Address)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	if err := p.acctManager.TimedUnlock(addr, time.Duration(req.Duration)); err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+	dctx, cancel := in.withDeadline(hashicorp.WithTOTPCode(ctx, req.Password))
+	defer cancel()
+	err = in.acctManager.TimedUnlock(dctx, addr, time.Duration(req.Duration))
+	auditLog(ctx, "TimedUnlock", &addr, err)
+	if err != nil {
+		return nil, signingError(dctx, err)
 	}
 	return &proto.TimedUnlockResponse{}, nil
 }
 
-func (p *HashicorpPlugin) Lock(_ context.Context, req *proto.LockRequest) (*proto.LockResponse, error) {
-	if !p.isInitialized() {
-		return nil, status.Error(codes.Unavailable, "not configured")
+func (p *HashicorpPlugin) Lock(ctx context.Context, req *proto.LockRequest) (*proto.LockResponse, error) {
+	in := p.instance()
+	if in == nil {
+		return nil, notConfigured()
+	}
+	ctx = audit.WithRequestID(ctx)
+	if err := validateRequest(req); err != nil {
+		return nil, err
 	}
 	addr, err := account.NewAddress(req.Address)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	p.acctManager.Lock(addr)
+	in.acctManager.Lock(addr)
+	auditLog(ctx, "Lock", &addr, nil)
 	return &proto.LockResponse{}, nil
 }
 
-func (p *HashicorpPlugin) NewAccount(_ context.Context, req *proto.NewAccountRequest) (*proto.NewAccountResponse, error) {
-	if !p.isInitialized() {
-		return nil, status.Error(codes.Unavailable, "not configured")
+func (p *HashicorpPlugin) NewAccount(ctx context.Context, req *proto.NewAccountRequest) (*proto.NewAccountResponse, error) {
+	in := p.instance()
+	if in == nil {
+		return nil, notConfigured()
+	}
+	ctx = audit.WithRequestID(ctx)
+	if err := in.checkPeer(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+	if !in.permissions.NewAccounts {
+		return nil, status.Error(codes.PermissionDenied, "account creation disabled by plugin config")
 	}
 	conf := new(config.NewAccount)
 	if err := json.Unmarshal(req.NewAccountConfig, conf); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 	if err := conf.Validate(); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	acct, err := p.acctManager.NewAccount(*conf)
+	conf.CreatedBy = audit.CallerFromContext(ctx).String()
+	acct, err := in.acctManager.NewAccount(*conf)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, err.Error())
+		auditLog(ctx, "NewAccount", nil, err)
+		return nil, provisioningError(err)
 	}
+	auditLog(ctx, "NewAccount", &acct.Address, nil)
 	return &proto.NewAccountResponse{
 		Account: acct.ToProtoAccount(),
 	}, nil
 }
 
-func (p *HashicorpPlugin) ImportRawKey(_ context.Context, req *proto.ImportRawKeyRequest) (*proto.ImportRawKeyResponse, error) {
-	if !p.isInitialized() {
-		return nil, status.Error(codes.Unavailable, "not configured")
+func (p *HashicorpPlugin) ImportRawKey(ctx context.Context, req *proto.ImportRawKeyRequest) (*proto.ImportRawKeyResponse, error) {
+	in := p.instance()
+	if in == nil {
+		return nil, notConfigured()
+	}
+	ctx = audit.WithRequestID(ctx)
+	if err := in.checkPeer(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+	if !in.permissions.ImportKeys {
+		return nil, status.Error(codes.PermissionDenied, "key import disabled by plugin config")
 	}
 	conf := new(config.NewAccount)
 	if err := json.Unmarshal(req.NewAccountConfig, conf); err != nil {
@@ -157,10 +316,13 @@ func (p *HashicorpPlugin) ImportRawKey(_ context.Context, req *proto.ImportRawKe
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	acct, err := p.acctManager.ImportPrivateKey(privateKey, *conf)
+	conf.CreatedBy = audit.CallerFromContext(ctx).String()
+	acct, err := in.acctManager.ImportPrivateKey(privateKey, *conf)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		auditLog(ctx, "ImportRawKey", nil, err)
+		return nil, provisioningError(err)
 	}
+	auditLog(ctx, "ImportRawKey", &acct.Address, nil)
 	return &proto.ImportRawKeyResponse{
 		Account: acct.ToProtoAccount(),
 	}, nil
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error kinds are the Type of the PreconditionFailure violation attached to returned statuses, so that callers can
+// branch on the kind of error rather than parsing the message
+const (
+	kindNotConfigured         = "NOT_CONFIGURED"
+	kindVaultPermissionDenied = "VAULT_PERMISSION_DENIED"
+	kindVaultUnavailable      = "VAULT_UNAVAILABLE"
+	kindTOTPRequired          = "TOTP_REQUIRED"
+	kindRoleRefused           = "ROLE_REFUSED"
+	kindAccountSuspended      = "ACCOUNT_SUSPENDED"
+	kindSignGrantRefused      = "SIGN_GRANT_REFUSED"
+	kindAccountFrozen         = "ACCOUNT_FROZEN"
+	kindSignDomainRefused     = "SIGN_DOMAIN_REFUSED"
+	kindNamespaceRefused      = "NAMESPACE_REFUSED"
+	kindSignPayloadInvalid    = "SIGN_PAYLOAD_INVALID"
+	kindSelectorRefused       = "SELECTOR_REFUSED"
+	kindAddressMismatch       = "ADDRESS_MISMATCH"
+	kindApprovalPending       = "APPROVAL_PENDING"
+	kindNotPromoted           = "NOT_PROMOTED"
+	kindReadOnly              = "READ_ONLY"
+	kindQuotaExceeded         = "QUOTA_EXCEEDED"
+	kindDeadlineExceeded      = "DEADLINE_EXCEEDED"
+	kindInternal              = "INTERNAL"
+)
+
+// Vault path categories are the ResourceName of the ResourceInfo attached to returned statuses
+const (
+	pathCategoryResourceType = "hashicorp-vault-path-category"
+	pathCategoryKV           = "kv"
+	pathCategoryAuth         = "auth"
+	pathCategorySys          = "sys"
+	pathCategoryTOTP         = "totp"
+	pathCategoryCubbyhole    = "cubbyhole"
+)
+
+// retryDelay is the delay suggested in the RetryInfo of retryable errors
+const retryDelay = time.Second
+
+// errorDetail describes an error for callers that handle errors programmatically
+type errorDetail struct {
+	kind         string
+	subject      string // the account the error relates to, e.g. 0x4d6d..., if any
+	pathCategory string // the category of Vault path involved, empty if the error does not relate to a Vault path
+	retryable    bool
+	action       string                   // what the operator or caller can do about the error
+	resource     *errdetails.ResourceInfo // an additional resource, e.g. the Vault path of a permission denial
+}
+
+// detailedError returns a status with code and msg carrying d as google.rpc error details: a PreconditionFailure with a
+// violation of Type kind, Subject subject and Description action, a ResourceInfo naming the Vault path category if
+// there is one, any additional resource, and a RetryInfo if the request can be retried.  If the details cannot be
+// added the status is returned without them.
+func detailedError(code codes.Code, msg string, d errorDetail) error {
+	s := status.New(code, msg)
+	withDetails, err := s.WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{{
+			Type:        d.kind,
+			Subject:     d.subject,
+			Description: d.action,
+		}},
+	})
+	if err != nil {
+		return s.Err()
+	}
+	if d.pathCategory != "" {
+		if withDetails, err = withDetails.WithDetails(&errdetails.ResourceInfo{
+			ResourceType: pathCategoryResourceType,
+			ResourceName: d.pathCategory,
+		}); err != nil {
+			return s.Err()
+		}
+	}
+	if d.resource != nil {
+		if withDetails, err = withDetails.WithDetails(d.resource); err != nil {
+			return s.Err()
+		}
+	}
+	if d.retryable {
+		if withDetails, err = withDetails.WithDetails(&errdetails.RetryInfo{
+			RetryDelay: ptypes.DurationProto(retryDelay),
+		}); err != nil {
+			return s.Err()
+		}
+	}
+	return withDetails.Err()
+}
+
+// notConfigured is returned for requests received before the plugin has been initialized
+func notConfigured() error {
+	return detailedError(codes.Unavailable, "not configured", errorDetail{
+		kind:      kindNotConfigured,
+		retryable: true,
+		action:    "retry once the plugin has been initialized",
+	})
+}
+
+// vaultPathCategory returns the category of a Vault API path, e.g. kv for kv/data/myacct
+func vaultPathCategory(path string) string {
+	switch {
+	case strings.HasPrefix(path, "auth/"):
+		return pathCategoryAuth
+	case strings.HasPrefix(path, "sys/"):
+		return pathCategorySys
+	case strings.HasPrefix(path, "cubbyhole/"):
+		return pathCategoryCubbyhole
+	case strings.Contains(path, "/code/"):
+		return pathCategoryTOTP
+	default:
+		return pathCategoryKV
+	}
+}
+
+// accountManagerErrorDetail describes an error returned by the AccountManager.  ok is false if the error is not one
+// of the errors the AccountManager reports specifically.
+func accountManagerErrorDetail(err error) (d errorDetail, ok bool) {
+	switch e := err.(type) {
+	case *hashicorp.PermissionDeniedError:
+		action := "grant the " + e.Capability + " capability on " + e.Path + " to the plugin's Vault policy"
+		if e.Sentinel != "" {
+			action = "check the conditions of Sentinel policy " + e.Sentinel
+		}
+		return errorDetail{kind: kindVaultPermissionDenied, pathCategory: vaultPathCategory(e.Path), action: action}, true
+	case *hashicorp.RoleError:
+		return errorDetail{kind: kindRoleRefused, action: "use an account whose role allows this request"}, true
+	case *hashicorp.AccountSuspendedError:
+		return errorDetail{kind: kindAccountSuspended, subject: "0x" + e.Address, action: "reinstate the account in the Quorum permissioning contract"}, true
+	case *hashicorp.SignGrantError:
+		return errorDetail{kind: kindSignGrantRefused, subject: "0x" + e.Address, action: "retry with a new sign grant in the quorum-sign-grant metadata"}, true
+	case *hashicorp.FrozenError:
+		return errorDetail{kind: kindAccountFrozen, subject: "0x" + e.Address, action: "unfreeze the account once the investigation is complete"}, true
+	case *hashicorp.SignDomainError:
+		return errorDetail{kind: kindSignDomainRefused, subject: "0x" + e.Address, action: "declare a domain the account can sign in the quorum-sign-domain metadata"}, true
+	case *hashicorp.SignPayloadError:
+		return errorDetail{kind: kindSignPayloadInvalid, subject: "0x" + e.Address, action: "declare the unsigned transaction the digest is the hash of in the quorum-sign-payload-bin metadata, within the signPayloads limits"}, true
+	case *hashicorp.SelectorError:
+		return errorDetail{kind: kindSelectorRefused, subject: "0x" + e.Address, action: "declare a transaction calling a function in the account's signPayloads selectors in the quorum-sign-payload-bin metadata"}, true
+	case *hashicorp.NamespaceError:
+		return errorDetail{kind: kindNamespaceRefused, action: "name a namespace in requestNamespaces allowed in the quorum-vault-namespace metadata, or omit it"}, true
+	case *hashicorp.AddressMismatchError:
+		return errorDetail{kind: kindAddressMismatch, subject: "0x" + e.ConfigAddress, pathCategory: pathCategoryKV, action: "run repair-addresses, or restore the account's secret in Vault"}, true
+	case *hashicorp.ApprovalPendingError:
+		return errorDetail{kind: kindApprovalPending, pathCategory: pathCategorySys, retryable: true, action: "authorize control group accessor " + e.Accessor + " and retry"}, true
+	}
+	switch err {
+	case hashicorp.TOTPRequiredErr:
+		return errorDetail{kind: kindTOTPRequired, pathCategory: pathCategoryTOTP, action: "retry with the account's current TOTP code as the password"}, true
+	case hashicorp.NotPromotedErr:
+		return errorDetail{kind: kindNotPromoted, action: "send the request to the signer, or promote this mirror"}, true
+	case hashicorp.ReadOnlyErr:
+		return errorDetail{kind: kindReadOnly, pathCategory: pathCategoryKV, retryable: true, action: "retry once the Vault primary is available"}, true
+	case hashicorp.NewAccountQuotaExceededErr:
+		return errorDetail{kind: kindQuotaExceeded, retryable: true, action: "retry later, or raise newAccountQuota"}, true
+	}
+	return errorDetail{}, false
+}
+
+// internalError returns an Internal status for an error the AccountManager does not report specifically, marking it
+// retryable if Vault was temporarily unavailable
+func internalError(err error) error {
+	if hashicorp.IsTransient(err) {
+		return detailedError(codes.Internal, err.Error(), errorDetail{
+			kind:      kindVaultUnavailable,
+			retryable: true,
+			action:    "retry, and check the health of Vault if the error persists",
+		})
+	}
+	return detailedError(codes.Internal, err.Error(), errorDetail{
+		kind:   kindInternal,
+		action: "check the plugin logs",
+	})
+}
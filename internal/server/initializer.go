@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/debug"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp"
 	"github.com/jpmorganchase/quorum-account-plugin-sdk-go/proto_common"
 	"google.golang.org/grpc/codes"
@@ -19,6 +20,25 @@ func (p *HashicorpPlugin) Init(_ context.Context, req *proto_common.PluginInitia
 		log.Println("[INFO] plugin initialization took", time.Now().Sub(startTime).Round(time.Microsecond))
 	}()
 
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	// verify the raw config before trusting any of it, so that a tampered config cannot redirect the plugin to a rogue Vault
+	if err := config.VerifyConfigSignature(req.GetRawConfiguration()); err != nil {
+		log.Printf("[ERROR] plugin config rejected: %v", err)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if config.ConfigSigningRequired() {
+		log.Println("[INFO] plugin config signature verified")
+	}
+
+	// check the pinned versions before unmarshalling, as a config for a different schema may be misinterpreted
+	if err := config.CheckCompatibility(req.GetRawConfiguration()); err != nil {
+		log.Printf("[ERROR] plugin config rejected: %v", err)
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
 	conf := new(config.VaultClient)
 
 	if err := json.Unmarshal(req.GetRawConfiguration(), conf); err != nil {
@@ -29,12 +49,67 @@ func (p *HashicorpPlugin) Init(_ context.Context, req *proto_common.PluginInitia
 		return nil, status.Errorf(codes.InvalidArgument, err.Error())
 	}
 
-	am, err := hashicorp.NewAccountManager(*conf)
+	p.initMu.Lock()
+	defer p.initMu.Unlock()
+
+	// start the debug listener before replacing the account manager, so that a failure leaves the previous account
+	// manager serving with its own settings
+	if err := p.startDebug(conf.Debug); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	// the previous account manager continues to serve requests unless it is replaced
+	var am hashicorp.AccountManager
+	var err error
+	if prev := p.instance(); prev != nil {
+		am, err = hashicorp.ReplaceAccountManager(prev.acctManager, *conf)
+	} else {
+		am, err = hashicorp.NewAccountManager(*conf)
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, err.Error())
 	}
+	p.current.Store(&instance{
+		acctManager: am,
+		permissions: conf.Permissions,
+		rpcTimeout:  conf.RPCTimeout,
+	})
+	p.sighup.Do(p.refreshCredentialsOnSIGHUP)
 
-	p.acctManager = am
+	log.Printf("[INFO] plugin initialized: %v", hashicorp.StartupSummary(*conf, am.DebugState()))
 
 	return &proto_common.PluginInitialization_Response{}, nil
 }
+
+// startDebug (re)starts the debug listener if configured.  The listener reports the state of the current account
+// manager, so it is only restarted if its address changes, and the previous listener is kept if the new one cannot be
+// started.
+func (p *HashicorpPlugin) startDebug(conf config.VaultClientDebug) error {
+	if p.debug != nil && p.debugAddr == conf.Address {
+		return nil
+	}
+	var d *debug.Server
+	if conf.Address != "" {
+		var err error
+		d, err = debug.Start(conf.Address, p.debugState)
+		if err != nil {
+			return err
+		}
+	}
+	if p.debug != nil {
+		if err := p.debug.Close(); err != nil {
+			log.Printf("[WARN] unable to stop debug listener: %v", err)
+		}
+	}
+	p.debug, p.debugAddr = d, conf.Address
+	return nil
+}
+
+// debugState returns the debug state of the current account manager
+func (p *HashicorpPlugin) debugState() interface{} {
+	in := p.instance()
+	if in == nil {
+		return nil
+	}
+	return in.acctManager.DebugState()
+}
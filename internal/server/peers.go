@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// checkPeer refuses the request if the permissions allowedPeers do not include the address of the peer that made it.
+// This is defense in depth in case the plugin's gRPC endpoint is exposed beyond the local node.
+func (in *instance) checkPeer(ctx context.Context) error {
+	if len(in.permissions.AllowedPeers) == 0 {
+		return nil
+	}
+	var addr net.Addr
+	if pr, ok := peer.FromContext(ctx); ok {
+		addr = pr.Addr
+	}
+	if !config.PeerAllowed(in.permissions.AllowedPeers, addr) {
+		log.Printf("[WARN] refused request from peer %v not in allowedPeers", addr)
+		return status.Error(codes.PermissionDenied, "peer not allowed by plugin config")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// refreshCredentialsOnSIGHUP re-authenticates with Vault whenever the plugin process receives SIGHUP, so that operators
+// can rotate credentials (e.g. an AppRole secret_id) without waiting for the current token to expire.  The account
+// configs are also reloaded, so that accounts frozen or unfrozen by the operator commands take effect.
+func (p *HashicorpPlugin) refreshCredentialsOnSIGHUP() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	go func() {
+		for range sighup {
+			in := p.instance()
+			if in == nil {
+				continue
+			}
+			log.Println("[INFO] SIGHUP received, refreshing Vault credentials and reloading account configs")
+			if err := in.acctManager.RefreshCredentials(); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+			if err := in.acctManager.ReloadAccounts(); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}
+	}()
+}
@@ -1,11 +1,38 @@
 package server
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/hashicorp/go-plugin"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/debug"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp"
 )
 
 type HashicorpPlugin struct {
 	plugin.Plugin
+	// current holds the *instance set by the last successful Init
+	current atomic.Value
+	// initMu serializes Init and Shutdown, and guards the debug listener
+	initMu    sync.Mutex
+	debug     *debug.Server
+	debugAddr string
+	sighup    sync.Once
+	shutdown  sync.Once
+}
+
+// instance is an account manager and the settings it is served with.  It is replaced as a whole by Init, so that a
+// request sees the permissions and timeout configured alongside the account manager it uses.
+type instance struct {
 	acctManager hashicorp.AccountManager
+	permissions config.VaultClientPermissions
+	rpcTimeout  time.Duration
+}
+
+// instance returns the current instance, or nil if the plugin has not been initialized
+func (p *HashicorpPlugin) instance() *instance {
+	in, _ := p.current.Load().(*instance)
+	return in
 }
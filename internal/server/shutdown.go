@@ -0,0 +1,47 @@
+package server
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Shutdown revokes the Vault tokens obtained by logging in, so that they do not remain valid after the plugin process
+// stops, and releases the state directory.  It is called once the host process has stopped the plugin, or when the
+// plugin process receives SIGTERM.  Only the first call has any effect.
+func (p *HashicorpPlugin) Shutdown() {
+	p.shutdown.Do(func() {
+		p.initMu.Lock()
+		defer p.initMu.Unlock()
+		if p.debug != nil {
+			if err := p.debug.Close(); err != nil {
+				log.Printf("[WARN] unable to stop debug listener: %v", err)
+			}
+		}
+		in := p.instance()
+		if in == nil {
+			return
+		}
+		if err := in.acctManager.RevokeTokens(); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+		if err := in.acctManager.Close(); err != nil {
+			log.Printf("[WARN] unable to close account manager: %v", err)
+		}
+	})
+}
+
+// ShutdownOnSIGTERM shuts down and exits when the plugin process receives SIGTERM, e.g. when its container is stopped
+// before the host process has stopped the plugin
+func (p *HashicorpPlugin) ShutdownOnSIGTERM() {
+	sigterm := make(chan os.Signal, 1)
+	signal.Notify(sigterm, syscall.SIGTERM)
+
+	go func() {
+		<-sigterm
+		log.Println("[INFO] SIGTERM received, shutting down")
+		p.Shutdown()
+		os.Exit(128 + int(syscall.SIGTERM))
+	}()
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-sdk-go/proto"
+	"github.com/jpmorganchase/quorum-account-plugin-sdk-go/proto_common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	hashLength                = 32
+	maxPassphraseLength       = 1024
+	maxRawKeyLength           = 66 // a hex-encoded 32 byte key with 0x prefix
+	maxNewAccountConfigLength = 64 * 1024
+	maxPluginConfigLength     = 1024 * 1024
+)
+
+// validateRequest checks the length and format of the caller-controlled fields of a request before it is handled, so
+// that malformed or oversized input is rejected with InvalidArgument rather than reaching Vault or the account store.
+// The contents of account configs are checked by config.NewAccount.Validate.
+func validateRequest(req interface{}) error {
+	var err error
+	switch r := req.(type) {
+	case *proto_common.PluginInitialization_Request:
+		err = checkMaxLength("plugin config", len(r.RawConfiguration), maxPluginConfigLength)
+	case *proto.OpenRequest:
+		err = checkMaxLength("passphrase", len(r.Passphrase), maxPassphraseLength)
+	case *proto.ContainsRequest:
+		err = checkAddress(r.Address)
+	case *proto.SignRequest:
+		err = firstError(checkAddress(r.Address), checkHash(r.ToSign))
+	case *proto.UnlockAndSignRequest:
+		err = firstError(checkAddress(r.Address), checkHash(r.ToSign), checkMaxLength("passphrase", len(r.Passphrase), maxPassphraseLength))
+	case *proto.TimedUnlockRequest:
+		err = firstError(checkAddress(r.Address), checkMaxLength("password", len(r.Password), maxPassphraseLength))
+		if err == nil && r.Duration < 0 {
+			err = errors.New("unlock duration cannot be negative")
+		}
+	case *proto.LockRequest:
+		err = checkAddress(r.Address)
+	case *proto.NewAccountRequest:
+		err = checkMaxLength("new account config", len(r.NewAccountConfig), maxNewAccountConfigLength)
+	case *proto.ImportRawKeyRequest:
+		err = firstError(checkMaxLength("raw key", len(r.RawKey), maxRawKeyLength), checkMaxLength("new account config", len(r.NewAccountConfig), maxNewAccountConfigLength))
+	}
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func checkAddress(addr []byte) error {
+	_, err := account.NewAddress(addr)
+	return err
+}
+
+// checkHash checks that the data to sign is a hash, as Quorum hashes transactions and messages before they are sent to
+// the plugin
+func checkHash(toSign []byte) error {
+	if len(toSign) != hashLength {
+		return fmt.Errorf("data to sign must be a %v byte hash", hashLength)
+	}
+	return nil
+}
+
+func checkMaxLength(field string, length, max int) error {
+	if length > max {
+		return fmt.Errorf("%v cannot be longer than %v bytes", field, max)
+	}
+	return nil
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+// +build !windows
+
+package state
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+var lockedErr = errors.New("locked")
+
+// lock takes an exclusive advisory lock on f without blocking.  The lock is released by the OS if the process exits.
+func lock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return lockedErr
+	}
+	return err
+}
+
+func unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,21 @@
+// +build windows
+
+package state
+
+import (
+	"errors"
+	"log"
+	"os"
+)
+
+var lockedErr = errors.New("locked")
+
+// lock is not supported on Windows, so the directory must not be shared between plugin instances
+func lock(f *os.File) error {
+	log.Printf("[WARN] locking of the state directory is not supported on Windows: ensure it is not shared between plugin instances")
+	return nil
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,74 @@
+// Package state manages the plugin's state directory, which holds files that must persist across plugin restarts
+// (e.g. the persisted Vault token).  The directory is locked while in use so that two plugin instances cannot share it.
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const lockFile = "LOCK"
+
+type Dir struct {
+	path string
+	lock *os.File
+}
+
+// Open creates the directory if it does not exist and locks it.  An error is returned if the directory is already
+// locked by another plugin instance.
+func Open(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(path, lockFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock(f); err != nil {
+		f.Close()
+		if err == lockedErr {
+			return nil, fmt.Errorf("state directory %v is in use by another plugin instance%v", path, holder(path))
+		}
+		return nil, fmt.Errorf("unable to lock state directory %v: %v", path, err)
+	}
+
+	// record the holder of the lock to help diagnose conflicts
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+
+	return &Dir{path: path, lock: f}, nil
+}
+
+// holder describes the process recorded in the lock file, if known
+func holder(path string) string {
+	b, err := ioutil.ReadFile(filepath.Join(path, lockFile))
+	if err != nil || len(b) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (pid %v)", strings.TrimSpace(string(b)))
+}
+
+// Path returns the path of the directory
+func (d *Dir) Path() string {
+	return d.path
+}
+
+// File returns the path of the named file in the directory
+func (d *Dir) File(name string) string {
+	return filepath.Join(d.path, name)
+}
+
+// Close releases the lock on the directory
+func (d *Dir) Close() error {
+	if err := unlock(d.lock); err != nil {
+		d.lock.Close()
+		return err
+	}
+	return d.lock.Close()
+}
@@ -0,0 +1,49 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_CreatesDirectory(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	path := filepath.Join(tmp, "state")
+	d, err := Open(path)
+	require.NoError(t, err)
+	defer d.Close()
+
+	require.DirExists(t, path)
+	require.Equal(t, path, d.Path())
+	require.Equal(t, filepath.Join(path, "vault-token"), d.File("vault-token"))
+}
+
+func TestOpen_Locked(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("locking is not supported on Windows")
+	}
+
+	tmp, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	d, err := Open(tmp)
+	require.NoError(t, err)
+
+	_, err = Open(tmp)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is in use by another plugin instance (pid ")
+
+	require.NoError(t, d.Close())
+
+	d, err = Open(tmp)
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+}
@@ -72,6 +72,15 @@ func setupPluginAndVaultAndFiles(t *testing.T, ctx *ITContext, args ...map[strin
 		if unlock, ok := args[0]["unlock"]; ok {
 			vaultClientBuilder.WithUnlock(strings.Split(unlock, ","))
 		}
+		if _, ok := args[0]["disableNewAccounts"]; ok {
+			vaultClientBuilder.WithNewAccountsDisabled()
+		}
+		if _, ok := args[0]["disableImportKeys"]; ok {
+			vaultClientBuilder.WithImportKeysDisabled()
+		}
+		if allowedPeers, ok := args[0]["allowedPeers"]; ok {
+			vaultClientBuilder.WithAllowedPeers(strings.Split(allowedPeers, ","))
+		}
 	}
 	conf := vaultClientBuilder.Build(t)
 
@@ -105,6 +114,29 @@ func TestPlugin_Init_InvalidPluginConfig(t *testing.T) {
 	require.EqualError(t, err, "rpc error: code = InvalidArgument desc = vault must be a valid HTTP/HTTPS url")
 }
 
+func TestPlugin_Init_IncompatibleConfig(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	err := ctx.StartPlugin(t)
+	require.NoError(t, err)
+
+	pinnedConf := fmt.Sprintf(`{
+	"vault": "http://vault:8200",
+	"accountDirectory": "file:///path/to/dir",
+	"compatibility": {
+		"configSchema": %v
+	}
+}`, config.ConfigSchemaVersion+1)
+
+	_, err = ctx.AccountManager.Init(context.Background(), &proto_common.PluginInitialization_Request{
+		RawConfiguration: []byte(pinnedConf),
+	})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "rpc error: code = FailedPrecondition desc = plugin config is pinned to config schema version")
+}
+
 func TestPlugin_Status_AccountLockedByDefault(t *testing.T) {
 	ctx := new(ITContext)
 	defer ctx.Cleanup()
@@ -739,6 +771,26 @@ func TestPlugin_NewAccount_IncorrectCASValue(t *testing.T) {
 	require.Contains(t, err.Error(), "invalid CAS value") // response from mock Vault server
 }
 
+func TestPlugin_NewAccount_DisabledByConfig(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, map[string]string{"disableNewAccounts": "true"})
+
+	newAcctConf := fmt.Sprintf(`{"secretName": "newAcct", "overwriteProtection": {"currentVersion": %v}}`, CAS_VALUE)
+
+	_, err := ctx.AccountManager.NewAccount(context.Background(), &proto.NewAccountRequest{NewAccountConfig: []byte(newAcctConf)})
+	require.EqualError(t, err, "rpc error: code = PermissionDenied desc = account creation disabled by plugin config")
+
+	// ensure no new files were created
+	files, _ := ioutil.ReadDir(ctx.AccountConfigDirectory)
+	require.Len(t, files, 1)
+}
+
 func TestPlugin_NewAccount_AddedToAvailableAccounts(t *testing.T) {
 	ctx := new(ITContext)
 	defer ctx.Cleanup()
@@ -861,6 +913,31 @@ func TestPlugin_ImportRawKey_IncorrectCASValue(t *testing.T) {
 	require.Contains(t, err.Error(), "invalid CAS value") // response from mock Vault server
 }
 
+func TestPlugin_ImportRawKey_DisabledByConfig(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, map[string]string{"disableImportKeys": "true"})
+
+	newAcctConf := fmt.Sprintf(`{"secretName": "newAcct", "overwriteProtection": {"currentVersion": %v}}`, CAS_VALUE)
+
+	_, err := ctx.AccountManager.ImportRawKey(context.Background(),
+		&proto.ImportRawKeyRequest{
+			RawKey:           "a0379af19f0b55b0f384f83c95f668ba600b78f487f6414f2d22339273891eec",
+			NewAccountConfig: []byte(newAcctConf),
+		},
+	)
+	require.EqualError(t, err, "rpc error: code = PermissionDenied desc = key import disabled by plugin config")
+
+	// ensure no new files were created
+	files, _ := ioutil.ReadDir(ctx.AccountConfigDirectory)
+	require.Len(t, files, 1)
+}
+
 func TestPlugin_ImportRawKey_AddedToAvailableAccounts(t *testing.T) {
 	ctx := new(ITContext)
 	defer ctx.Cleanup()
@@ -932,3 +1009,129 @@ func TestPlugin_ImportRawKey_ErrorIfAccountExists(t *testing.T) {
 	files, _ = ioutil.ReadDir(ctx.AccountConfigDirectory)
 	require.Len(t, files, 1)
 }
+
+func TestPlugin_RequestValidation(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx)
+
+	acctAddr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	toSign := []byte{188, 76, 145, 93, 105, 137, 107, 25, 143, 2, 146, 167, 35, 115, 162, 189, 205, 13, 82, 188, 203, 252, 236, 17, 217, 200, 76, 15, 255, 113, 176, 188}
+
+	_, err := ctx.AccountManager.Sign(context.Background(), &proto.SignRequest{Address: acctAddr[:19], ToSign: toSign})
+	require.EqualError(t, err, "rpc error: code = InvalidArgument desc = account address must have length 20 bytes")
+
+	_, err = ctx.AccountManager.Sign(context.Background(), &proto.SignRequest{Address: acctAddr, ToSign: append(toSign, 0)})
+	require.EqualError(t, err, "rpc error: code = InvalidArgument desc = data to sign must be a 32 byte hash")
+
+	_, err = ctx.AccountManager.UnlockAndSign(context.Background(), &proto.UnlockAndSignRequest{Address: acctAddr, ToSign: toSign, Passphrase: strings.Repeat("a", 1025)})
+	require.EqualError(t, err, "rpc error: code = InvalidArgument desc = passphrase cannot be longer than 1024 bytes")
+
+	_, err = ctx.AccountManager.TimedUnlock(context.Background(), &proto.TimedUnlockRequest{Address: acctAddr, Duration: -1})
+	require.EqualError(t, err, "rpc error: code = InvalidArgument desc = unlock duration cannot be negative")
+
+	_, err = ctx.AccountManager.ImportRawKey(context.Background(), &proto.ImportRawKeyRequest{RawKey: strings.Repeat("a", 67), NewAccountConfig: []byte(`{"secretName": "newAcct"}`)})
+	require.EqualError(t, err, "rpc error: code = InvalidArgument desc = raw key cannot be longer than 66 bytes")
+
+	_, err = ctx.AccountManager.NewAccount(context.Background(), &proto.NewAccountRequest{NewAccountConfig: []byte(`{"secretName": "../../sys/policy/root", "overwriteProtection": {"insecureDisable": true}}`)})
+	require.EqualError(t, err, "rpc error: code = InvalidArgument desc = "+config.InvalidSecretNamePath)
+
+	files, _ := ioutil.ReadDir(ctx.AccountConfigDirectory)
+	require.Len(t, files, 1)
+}
+
+func TestPlugin_AllowedPeers(t *testing.T) {
+	acctAddr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	toSign := []byte{188, 76, 145, 93, 105, 137, 107, 25, 143, 2, 146, 167, 35, 115, 162, 189, 205, 13, 82, 188, 203, 252, 236, 17, 217, 200, 76, 15, 255, 113, 176, 188}
+
+	t.Run("refused", func(t *testing.T) {
+		ctx := new(ITContext)
+		defer ctx.Cleanup()
+
+		testutil.SetRoleID()
+		testutil.SetSecretID()
+		defer testutil.UnsetAll()
+
+		setupPluginAndVaultAndFiles(t, ctx, map[string]string{"allowedPeers": "10.0.0.0/8"})
+
+		_, err := ctx.AccountManager.UnlockAndSign(context.Background(), &proto.UnlockAndSignRequest{Address: acctAddr, ToSign: toSign})
+		require.EqualError(t, err, "rpc error: code = PermissionDenied desc = peer not allowed by plugin config")
+
+		_, err = ctx.AccountManager.NewAccount(context.Background(), &proto.NewAccountRequest{NewAccountConfig: []byte(`{"secretName": "newAcct", "overwriteProtection": {"currentVersion": 0}}`)})
+		require.EqualError(t, err, "rpc error: code = PermissionDenied desc = peer not allowed by plugin config")
+
+		// read-only requests are not restricted
+		_, err = ctx.AccountManager.Status(context.Background(), &proto.StatusRequest{})
+		require.NoError(t, err)
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		ctx := new(ITContext)
+		defer ctx.Cleanup()
+
+		testutil.SetRoleID()
+		testutil.SetSecretID()
+		defer testutil.UnsetAll()
+
+		setupPluginAndVaultAndFiles(t, ctx, map[string]string{"allowedPeers": "10.0.0.0/8,localhost"})
+
+		_, err := ctx.AccountManager.UnlockAndSign(context.Background(), &proto.UnlockAndSignRequest{Address: acctAddr, ToSign: toSign})
+		require.NoError(t, err)
+	})
+}
+
+func TestPlugin_DevMode(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	require.NoError(t, ctx.StartPlugin(t))
+	ctx.CreateAccountConfigDirectory(t)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	devConf := fmt.Sprintf(`{"dev": true, "accountDirectory": "file://%v/%v"}`, wd, ctx.AccountConfigDirectory)
+
+	_, err = ctx.AccountManager.Init(context.Background(), &proto_common.PluginInitialization_Request{
+		RawConfiguration: []byte(devConf),
+	})
+	require.NoError(t, err)
+
+	// the key is stored in memory rather than Vault
+	imported, err := ctx.AccountManager.ImportRawKey(context.Background(), &proto.ImportRawKeyRequest{
+		RawKey:           "7af58d8bd863ce3fce9508a57dff50a2655663a1411b6634cea6246398380b28",
+		NewAccountConfig: []byte(`{"secretName": "myAcct", "overwriteProtection": {"currentVersion": 0}}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "http://dev.invalid/v1/dev/data/myAcct?version=1", imported.Account.Url)
+
+	acctAddr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	require.Equal(t, acctAddr, imported.Account.Address)
+
+	created, err := ctx.AccountManager.NewAccount(context.Background(), &proto.NewAccountRequest{
+		NewAccountConfig: []byte(`{"secretName": "myAcct", "overwriteProtection": {"currentVersion": 1}}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "http://dev.invalid/v1/dev/data/myAcct?version=2", created.Account.Url)
+
+	accts, err := ctx.AccountManager.Accounts(context.Background(), &proto.AccountsRequest{})
+	require.NoError(t, err)
+	require.Len(t, accts.Accounts, 2)
+
+	_, err = ctx.AccountManager.TimedUnlock(context.Background(), &proto.TimedUnlockRequest{Address: acctAddr})
+	require.NoError(t, err)
+
+	toSign := []byte{188, 76, 145, 93, 105, 137, 107, 25, 143, 2, 146, 167, 35, 115, 162, 189, 205, 13, 82, 188, 203, 252, 236, 17, 217, 200, 76, 15, 255, 113, 176, 188}
+	wantSig := []byte{21, 228, 169, 48, 162, 94, 71, 55, 85, 214, 104, 193, 92, 14, 27, 132, 111, 18, 108, 11, 194, 150, 169, 254, 177, 54, 67, 10, 14, 208, 100, 250, 123, 166, 26, 0, 44, 215, 237, 186, 32, 198, 241, 77, 206, 214, 249, 124, 212, 36, 249, 4, 171, 87, 68, 147, 238, 96, 8, 180, 122, 172, 175, 38, 1}
+
+	signed, err := ctx.AccountManager.Sign(context.Background(), &proto.SignRequest{Address: acctAddr, ToSign: toSign})
+	require.NoError(t, err)
+	require.Equal(t, wantSig, signed.Sig)
+
+	_, err = ctx.AccountManager.UnlockAndSign(context.Background(), &proto.UnlockAndSignRequest{Address: created.Account.Address, ToSign: toSign})
+	require.NoError(t, err)
+}
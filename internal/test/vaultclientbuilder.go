@@ -20,6 +20,10 @@ type VaultClientBuilder struct {
 	caCertUrl     string
 	clientCertUrl string
 	clientKeyUrl  string
+
+	disableNewAccounts bool
+	disableImportKeys  bool
+	allowedPeers       []string
 }
 
 func (b *VaultClientBuilder) WithVaultUrl(s string) *VaultClientBuilder {
@@ -77,6 +81,21 @@ func (b *VaultClientBuilder) WithClientKeyUrl(s string) *VaultClientBuilder {
 	return b
 }
 
+func (b *VaultClientBuilder) WithNewAccountsDisabled() *VaultClientBuilder {
+	b.disableNewAccounts = true
+	return b
+}
+
+func (b *VaultClientBuilder) WithImportKeysDisabled() *VaultClientBuilder {
+	b.disableImportKeys = true
+	return b
+}
+
+func (b *VaultClientBuilder) WithAllowedPeers(s []string) *VaultClientBuilder {
+	b.allowedPeers = s
+	return b
+}
+
 func (b *VaultClientBuilder) Build(t *testing.T) config.VaultClient {
 	var err error
 
@@ -147,5 +166,10 @@ func (b *VaultClientBuilder) Build(t *testing.T) config.VaultClient {
 			ClientCert: clientCert,
 			ClientKey:  clientKey,
 		},
+		Permissions: config.VaultClientPermissions{
+			NewAccounts:  !b.disableNewAccounts,
+			ImportKeys:   !b.disableImportKeys,
+			AllowedPeers: b.allowedPeers,
+		},
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/hashicorp/go-plugin"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/cli"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/server"
 )
 
@@ -21,11 +22,23 @@ var (
 func main() {
 	log.SetFlags(0)          // remove timestamp when logging to host process
 	log.SetOutput(os.Stderr) // host process listens to stderr to log
+
+	// the host process starts the plugin without arguments, so any arguments indicate an operator command
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	impl := &server.HashicorpPlugin{}
+	impl.ShutdownOnSIGTERM()
+
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: defaultHandshakeConfig,
 		Plugins: map[string]plugin.Plugin{
-			"impl": &server.HashicorpPlugin{},
+			"impl": impl,
 		},
 		GRPCServer: plugin.DefaultGRPCServer,
 	})
+
+	// Serve returns once the host process has stopped the plugin
+	impl.Shutdown()
 }